@@ -0,0 +1,522 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"rechtebank/backend/internal/adapters/gemini"
+	"rechtebank/backend/internal/core/domain"
+	"rechtebank/backend/internal/core/ports"
+	"rechtebank/backend/internal/logging"
+)
+
+// defaultLabelsFile is the labels file looked up in the image directory when
+// no -labels flag is given
+const defaultLabelsFile = "labels.json"
+
+// errorColumn is the confusion matrix column for images the analyzer failed on
+const errorColumn = "error"
+
+// Label is the expected outcome for one image of the golden set. Fields that
+// are left out are not checked.
+type Label struct {
+	Admissible  *bool  `json:"admissible,omitempty"`
+	MinScore    *int   `json:"minScore,omitempty"`
+	MaxScore    *int   `json:"maxScore,omitempty"`
+	VerdictType string `json:"verdictType,omitempty"`
+}
+
+// scoreError returns how far score lies outside the expected range, and
+// whether the label has a score range at all
+func (l Label) scoreError(score int) (float64, bool) {
+	switch {
+	case l.MinScore == nil && l.MaxScore == nil:
+		return 0, false
+	case l.MinScore != nil && score < *l.MinScore:
+		return float64(*l.MinScore - score), true
+	case l.MaxScore != nil && score > *l.MaxScore:
+		return float64(score - *l.MaxScore), true
+	}
+	return 0, true
+}
+
+// evalCase is one labelled image of the golden set
+type evalCase struct {
+	File  string
+	Path  string
+	Label Label
+}
+
+// caseResult is the analyzer's outcome for one image
+type caseResult struct {
+	File        string             `json:"file"`
+	Expected    Label              `json:"expected"`
+	Admissible  bool               `json:"admissible"`
+	Score       int                `json:"score"`
+	VerdictType string             `json:"verdictType"`
+	Correct     bool               `json:"correct"`
+	LatencyMS   float64            `json:"latencyMs"`
+	Usage       *domain.TokenUsage `json:"usage,omitempty"`
+	Error       string             `json:"error,omitempty"`
+}
+
+// ratio is a count of correct answers out of the cases that were checked
+type ratio struct {
+	Correct int     `json:"correct"`
+	Total   int     `json:"total"`
+	Rate    float64 `json:"rate"`
+}
+
+func (r *ratio) add(correct bool) {
+	r.Total++
+	if correct {
+		r.Correct++
+	}
+	r.Rate = float64(r.Correct) / float64(r.Total)
+}
+
+// latencyStats summarizes analyzer latency in milliseconds
+type latencyStats struct {
+	P50 float64 `json:"p50Ms"`
+	P90 float64 `json:"p90Ms"`
+	P99 float64 `json:"p99Ms"`
+	Max float64 `json:"maxMs"`
+}
+
+// tokenStats sums the token usage Gemini reported
+type tokenStats struct {
+	Prompt      int     `json:"prompt"`
+	Response    int     `json:"response"`
+	Total       int     `json:"total"`
+	Reported    int     `json:"casesReported"`
+	MeanPerCase float64 `json:"meanPerCase"`
+}
+
+// evalReport is the outcome of an evaluation run, written as JSON so runs
+// against different prompt versions can be compared
+type evalReport struct {
+	Model       string `json:"model"`
+	PromptHash  string `json:"promptHash"`
+	StartedAt   string `json:"startedAt"`
+	Concurrency int    `json:"concurrency"`
+	Cases       int    `json:"cases"`
+	Errors      int    `json:"errors"`
+
+	// Accuracy counts an image as correct when every labelled field matches;
+	// failed analyses count as wrong
+	Accuracy ratio `json:"accuracy"`
+
+	AdmissibleAccuracy  ratio `json:"admissibleAccuracy"`
+	VerdictTypeAccuracy ratio `json:"verdictTypeAccuracy"`
+	ScoreInRange        ratio `json:"scoreInRange"`
+
+	// ScoreMAE is the mean distance between the score and the expected range
+	ScoreMAE float64 `json:"scoreMae"`
+
+	// Confusion counts expected verdict types (rows) against actual ones
+	Confusion map[string]map[string]int `json:"confusionMatrix"`
+
+	Latency latencyStats `json:"latency"`
+	Tokens  tokenStats   `json:"tokens"`
+	Results []caseResult `json:"results"`
+}
+
+// runEval runs the golden set in a directory through the Gemini analyzer
+func runEval(args []string) error {
+	flags := flag.NewFlagSet("eval", flag.ContinueOnError)
+	labelsPath := flags.String("labels", "", "labels file (default <dir>/"+defaultLabelsFile+")")
+	concurrency := flags.Int("concurrency", 4, "number of images analyzed at the same time")
+	jsonPath := flags.String("json", "", "write the report as JSON to this file")
+	timeout := flags.Duration("timeout", 30*time.Second, "timeout per analysis")
+	minAccuracy := flags.Float64("min-accuracy", 0, "fail when accuracy is below this rate (0-1)")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: %s eval [flags] <image-dir>\n", filepath.Base(os.Args[0]))
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("expected exactly one image directory")
+	}
+	if *concurrency < 1 {
+		return fmt.Errorf("concurrency must be at least 1")
+	}
+
+	dir := flags.Arg(0)
+	if *labelsPath == "" {
+		*labelsPath = filepath.Join(dir, defaultLabelsFile)
+	}
+	labels, err := loadLabels(*labelsPath)
+	if err != nil {
+		return err
+	}
+	cases, unlabelled, err := collectCases(dir, labels, filepath.Base(*labelsPath))
+	if err != nil {
+		return err
+	}
+	if len(unlabelled) > 0 {
+		fmt.Fprintf(os.Stderr, "Skipping %d unlabelled files: %s\n", len(unlabelled), strings.Join(unlabelled, ", "))
+	}
+
+	apiKey := os.Getenv("GEMINI_API_KEY")
+	if apiKey == "" {
+		return fmt.Errorf("GEMINI_API_KEY environment variable is required")
+	}
+
+	// Per-request logs would drown the report; only show problems
+	logging.Setup(logging.Config{Format: "text", Level: slog.LevelWarn})
+
+	analyzer, err := gemini.NewGeminiAnalyzer(apiKey, *timeout)
+	if err != nil {
+		return fmt.Errorf("failed to initialize analyzer: %w", err)
+	}
+	defer analyzer.Close()
+
+	startedAt := time.Now().UTC()
+	var done int
+	results := evaluate(context.Background(), analyzer, cases, *concurrency, func(r caseResult) {
+		done++
+		status := "ok"
+		if r.Error != "" {
+			status = "error: " + r.Error
+		} else if !r.Correct {
+			status = "wrong"
+		}
+		fmt.Fprintf(os.Stderr, "[%d/%d] %s %s (%.0fms)\n", done, len(cases), r.File, status, r.LatencyMS)
+	})
+
+	report := buildReport(results)
+	report.Model = gemini.ModelName
+	report.PromptHash = promptHash(gemini.GetSystemPrompt())
+	report.StartedAt = startedAt.Format(time.RFC3339)
+	report.Concurrency = *concurrency
+
+	writeTable(os.Stdout, report)
+
+	if *jsonPath != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode report: %w", err)
+		}
+		if err := os.WriteFile(*jsonPath, append(data, '\n'), 0644); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+	}
+
+	if report.Accuracy.Rate < *minAccuracy {
+		return fmt.Errorf("accuracy %.3f is below %.3f", report.Accuracy.Rate, *minAccuracy)
+	}
+	return nil
+}
+
+// loadLabels reads a labels file: a JSON object mapping file names, relative
+// to the image directory, to their expected outcome
+func loadLabels(path string) (map[string]Label, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read labels: %w", err)
+	}
+	var labels map[string]Label
+	if err := json.Unmarshal(data, &labels); err != nil {
+		return nil, fmt.Errorf("failed to parse labels %s: %w", path, err)
+	}
+	if len(labels) == 0 {
+		return nil, fmt.Errorf("labels file %s has no entries", path)
+	}
+	for file, label := range labels {
+		if label.VerdictType != "" && !slices.Contains(domain.VerdictTypes, label.VerdictType) {
+			return nil, fmt.Errorf("label for %s: unknown verdictType %q", file, label.VerdictType)
+		}
+		if label.MinScore != nil && label.MaxScore != nil && *label.MinScore > *label.MaxScore {
+			return nil, fmt.Errorf("label for %s: minScore is above maxScore", file)
+		}
+	}
+	return labels, nil
+}
+
+// collectCases pairs every label with its image, and lists the files in dir
+// that have no label
+func collectCases(dir string, labels map[string]Label, labelsFile string) ([]evalCase, []string, error) {
+	var cases []evalCase
+	for file, label := range labels {
+		path := filepath.Join(dir, file)
+		if _, err := os.Stat(path); err != nil {
+			return nil, nil, fmt.Errorf("labelled image %s: %w", file, err)
+		}
+		cases = append(cases, evalCase{File: file, Path: path, Label: label})
+	}
+	sort.Slice(cases, func(i, j int) bool { return cases[i].File < cases[j].File })
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read image directory: %w", err)
+	}
+	var unlabelled []string
+	for _, entry := range entries {
+		if entry.IsDir() || entry.Name() == labelsFile {
+			continue
+		}
+		if _, ok := labels[entry.Name()]; !ok {
+			unlabelled = append(unlabelled, entry.Name())
+		}
+	}
+	return cases, unlabelled, nil
+}
+
+// evaluate analyzes every case with at most concurrency analyses in flight.
+// Results keep the order of cases; progress is called as each case finishes.
+func evaluate(ctx context.Context, analyzer ports.IPhotoAnalyzer, cases []evalCase, concurrency int, progress func(caseResult)) []caseResult {
+	results := make([]caseResult, len(cases))
+	var mu sync.Mutex
+	runConcurrently(len(cases), concurrency, func(i int) {
+		result := judgeCase(ctx, analyzer, cases[i])
+		results[i] = result
+		if progress != nil {
+			mu.Lock()
+			progress(result)
+			mu.Unlock()
+		}
+	})
+	return results
+}
+
+// runConcurrently calls work for 0..n-1 with at most concurrency calls in
+// flight, and returns when all calls have finished
+func runConcurrently(n, concurrency int, work func(i int)) {
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(concurrency, n) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				work(i)
+			}
+		}()
+	}
+	for i := range n {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// judgeCase analyzes one image and checks the verdict against its label
+func judgeCase(ctx context.Context, analyzer ports.IPhotoAnalyzer, c evalCase) caseResult {
+	result := caseResult{File: c.File, Expected: c.Label}
+
+	imageData, err := os.ReadFile(c.Path)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	start := time.Now()
+	verdict, err := analyzer.AnalyzePhoto(ctx, imageData)
+	result.LatencyMS = float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Admissible = verdict.Admissible
+	result.Score = verdict.Score
+	result.VerdictType = verdict.Verdict.VerdictType
+	result.Usage = verdict.Usage
+
+	scoreError, hasRange := c.Label.scoreError(verdict.Score)
+	result.Correct = (c.Label.Admissible == nil || *c.Label.Admissible == verdict.Admissible) &&
+		(!hasRange || scoreError == 0) &&
+		(c.Label.VerdictType == "" || c.Label.VerdictType == verdict.Verdict.VerdictType)
+	return result
+}
+
+// buildReport aggregates case results into accuracy, score, latency and
+// token statistics
+func buildReport(results []caseResult) evalReport {
+	report := evalReport{
+		Cases:     len(results),
+		Confusion: map[string]map[string]int{},
+		Results:   results,
+	}
+
+	var latencies []float64
+	var scoreErrors float64
+	for _, r := range results {
+		report.Accuracy.add(r.Error == "" && r.Correct)
+
+		if r.Error != "" {
+			report.Errors++
+			if r.Expected.VerdictType != "" {
+				countConfusion(report.Confusion, r.Expected.VerdictType, errorColumn)
+			}
+			continue
+		}
+
+		latencies = append(latencies, r.LatencyMS)
+		if r.Usage != nil {
+			report.Tokens.Prompt += r.Usage.PromptTokens
+			report.Tokens.Response += r.Usage.ResponseTokens
+			report.Tokens.Reported++
+		}
+
+		if r.Expected.Admissible != nil {
+			report.AdmissibleAccuracy.add(*r.Expected.Admissible == r.Admissible)
+		}
+		if r.Expected.VerdictType != "" {
+			report.VerdictTypeAccuracy.add(r.Expected.VerdictType == r.VerdictType)
+			countConfusion(report.Confusion, r.Expected.VerdictType, r.VerdictType)
+		}
+		if scoreError, ok := r.Expected.scoreError(r.Score); ok {
+			report.ScoreInRange.add(scoreError == 0)
+			scoreErrors += scoreError
+		}
+	}
+
+	if report.ScoreInRange.Total > 0 {
+		report.ScoreMAE = scoreErrors / float64(report.ScoreInRange.Total)
+	}
+
+	report.Tokens.Total = report.Tokens.Prompt + report.Tokens.Response
+	if report.Tokens.Reported > 0 {
+		report.Tokens.MeanPerCase = float64(report.Tokens.Total) / float64(report.Tokens.Reported)
+	}
+
+	sort.Float64s(latencies)
+	report.Latency = latencyStats{
+		P50: percentile(latencies, 50),
+		P90: percentile(latencies, 90),
+		P99: percentile(latencies, 99),
+	}
+	if len(latencies) > 0 {
+		report.Latency.Max = latencies[len(latencies)-1]
+	}
+	return report
+}
+
+func countConfusion(confusion map[string]map[string]int, expected, actual string) {
+	if confusion[expected] == nil {
+		confusion[expected] = map[string]int{}
+	}
+	confusion[expected][actual]++
+}
+
+// percentile returns the nearest-rank percentile of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+// promptHash identifies the prompt version a report was produced with
+func promptHash(systemPrompt string) string {
+	sum := sha256.Sum256([]byte(systemPrompt + "\n" + gemini.GetUserPrompt()))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// confusionLabels returns the verdict types in canonical order, followed by
+// any other values that occur in the matrix
+func confusionLabels(confusion map[string]map[string]int) []string {
+	labels := slices.Clone(domain.VerdictTypes)
+	var extra []string
+	for expected, row := range confusion {
+		for _, name := range append([]string{expected}, slices.Collect(maps.Keys(row))...) {
+			if !slices.Contains(labels, name) && !slices.Contains(extra, name) {
+				extra = append(extra, name)
+			}
+		}
+	}
+	sort.Strings(extra)
+	return append(labels, extra...)
+}
+
+// writeTable prints the report for humans
+func writeTable(w io.Writer, report evalReport) {
+	fmt.Fprintln(w, "=== EVALUATION ===")
+	fmt.Fprintf(w, "Model: %s\n", report.Model)
+	fmt.Fprintf(w, "Prompt: %s\n", report.PromptHash)
+	fmt.Fprintf(w, "Cases: %d (%d errors)\n", report.Cases, report.Errors)
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "=== ACCURACY ===")
+	fmt.Fprintln(tw, "metric\tcorrect\ttotal\trate")
+	for _, row := range []struct {
+		name  string
+		ratio ratio
+	}{
+		{"overall", report.Accuracy},
+		{"admissible", report.AdmissibleAccuracy},
+		{"verdictType", report.VerdictTypeAccuracy},
+		{"score in range", report.ScoreInRange},
+	} {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f%%\n", row.name, row.ratio.Correct, row.ratio.Total, row.ratio.Rate*100)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "Score MAE: %.2f\n", report.ScoreMAE)
+	fmt.Fprintln(w)
+
+	if len(report.Confusion) > 0 {
+		fmt.Fprintln(w, "=== CONFUSION MATRIX (expected \\ actual) ===")
+		labels := confusionLabels(report.Confusion)
+		fmt.Fprintln(tw, "\t"+strings.Join(labels, "\t"))
+		for _, expected := range labels {
+			row, ok := report.Confusion[expected]
+			if !ok {
+				continue
+			}
+			cells := []string{expected}
+			for _, actual := range labels {
+				cells = append(cells, fmt.Sprint(row[actual]))
+			}
+			fmt.Fprintln(tw, strings.Join(cells, "\t"))
+		}
+		tw.Flush()
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintln(w, "=== LATENCY ===")
+	fmt.Fprintf(w, "p50: %.0fms  p90: %.0fms  p99: %.0fms  max: %.0fms\n",
+		report.Latency.P50, report.Latency.P90, report.Latency.P99, report.Latency.Max)
+	fmt.Fprintln(w)
+
+	fmt.Fprintln(w, "=== TOKENS ===")
+	fmt.Fprintf(w, "Prompt: %d  Response: %d  Total: %d  Mean per image: %.0f\n",
+		report.Tokens.Prompt, report.Tokens.Response, report.Tokens.Total, report.Tokens.MeanPerCase)
+
+	var failed []caseResult
+	for _, r := range report.Results {
+		if !r.Correct {
+			failed = append(failed, r)
+		}
+	}
+	if len(failed) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "=== MISSES ===")
+		fmt.Fprintln(tw, "file\tadmissible\tscore\tverdictType\terror")
+		for _, r := range failed {
+			fmt.Fprintf(tw, "%s\t%v\t%d\t%s\t%s\n", r.File, r.Admissible, r.Score, r.VerdictType, r.Error)
+		}
+		tw.Flush()
+	}
+}
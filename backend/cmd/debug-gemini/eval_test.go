@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"rechtebank/backend/internal/core/domain"
+)
+
+// fakeAnalyzer returns the verdict registered for an image's contents
+type fakeAnalyzer struct {
+	verdicts map[string]*domain.VerdictResponse
+	delay    time.Duration
+
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+}
+
+func (a *fakeAnalyzer) AnalyzePhoto(ctx context.Context, imageData []byte) (*domain.VerdictResponse, error) {
+	n := a.inFlight.Add(1)
+	defer a.inFlight.Add(-1)
+	for {
+		peak := a.maxInFlight.Load()
+		if n <= peak || a.maxInFlight.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(a.delay)
+
+	verdict, ok := a.verdicts[string(imageData)]
+	if !ok {
+		return nil, errors.New("analysis failed")
+	}
+	return verdict, nil
+}
+
+func boolPtr(b bool) *bool { return &b }
+func intPtr(i int) *int    { return &i }
+
+// writeGoldenSet writes one file per name, containing the name, plus a labels file
+func writeGoldenSet(t *testing.T, names []string, labels string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, defaultLabelsFile), []byte(labels), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestLoadLabels(t *testing.T) {
+	dir := writeGoldenSet(t, nil, `{"chair.jpg": {"admissible": true, "minScore": 6, "maxScore": 8, "verdictType": "waarschuwing"}}`)
+
+	labels, err := loadLabels(filepath.Join(dir, defaultLabelsFile))
+	if err != nil {
+		t.Fatalf("loadLabels() error = %v", err)
+	}
+	label := labels["chair.jpg"]
+	if label.Admissible == nil || !*label.Admissible || *label.MinScore != 6 || *label.MaxScore != 8 || label.VerdictType != "waarschuwing" {
+		t.Errorf("loadLabels() = %+v", label)
+	}
+}
+
+func TestLoadLabels_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		labels string
+	}{
+		{"empty", `{}`},
+		{"unknown verdict type", `{"chair.jpg": {"verdictType": "gevangenis"}}`},
+		{"inverted score range", `{"chair.jpg": {"minScore": 8, "maxScore": 6}}`},
+		{"not JSON", `chair.jpg: admissible`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeGoldenSet(t, nil, tt.labels)
+			if _, err := loadLabels(filepath.Join(dir, defaultLabelsFile)); err == nil {
+				t.Error("loadLabels() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestCollectCases(t *testing.T) {
+	dir := writeGoldenSet(t, []string{"b.jpg", "a.jpg", "notes.txt"}, "")
+	labels := map[string]Label{"a.jpg": {}, "b.jpg": {}}
+
+	cases, unlabelled, err := collectCases(dir, labels, defaultLabelsFile)
+	if err != nil {
+		t.Fatalf("collectCases() error = %v", err)
+	}
+	if len(cases) != 2 || cases[0].File != "a.jpg" || cases[1].File != "b.jpg" {
+		t.Errorf("collectCases() cases = %+v, want a.jpg and b.jpg in order", cases)
+	}
+	if len(unlabelled) != 1 || unlabelled[0] != "notes.txt" {
+		t.Errorf("collectCases() unlabelled = %v, want [notes.txt]", unlabelled)
+	}
+
+	labels["missing.jpg"] = Label{}
+	if _, _, err := collectCases(dir, labels, defaultLabelsFile); err == nil {
+		t.Error("collectCases() with a missing labelled image: error = nil, want error")
+	}
+}
+
+func TestEvaluate_BoundsConcurrency(t *testing.T) {
+	names := []string{"1.jpg", "2.jpg", "3.jpg", "4.jpg", "5.jpg", "6.jpg"}
+	dir := writeGoldenSet(t, names, "")
+	analyzer := &fakeAnalyzer{verdicts: map[string]*domain.VerdictResponse{}, delay: 20 * time.Millisecond}
+	var cases []evalCase
+	for _, name := range names {
+		analyzer.verdicts[name] = &domain.VerdictResponse{Score: 5}
+		cases = append(cases, evalCase{File: name, Path: filepath.Join(dir, name)})
+	}
+
+	var mu sync.Mutex
+	var progressed int
+	results := evaluate(context.Background(), analyzer, cases, 2, func(caseResult) {
+		mu.Lock()
+		progressed++
+		mu.Unlock()
+	})
+
+	if got := analyzer.maxInFlight.Load(); got != 2 {
+		t.Errorf("max analyses in flight = %d, want 2", got)
+	}
+	if progressed != len(names) {
+		t.Errorf("progress called %d times, want %d", progressed, len(names))
+	}
+	for i, r := range results {
+		if r.File != names[i] || !r.Correct {
+			t.Errorf("results[%d] = %+v, want correct result for %s", i, r, names[i])
+		}
+	}
+}
+
+func TestJudgeCase(t *testing.T) {
+	dir := writeGoldenSet(t, []string{"chair.jpg", "broken.jpg"}, "")
+	analyzer := &fakeAnalyzer{verdicts: map[string]*domain.VerdictResponse{
+		"chair.jpg": {
+			Admissible: true,
+			Score:      9,
+			Verdict:    domain.VerdictDetails{VerdictType: domain.VerdictTypeVrijspraak},
+			Usage:      &domain.TokenUsage{PromptTokens: 300, ResponseTokens: 120},
+		},
+	}}
+
+	tests := []struct {
+		name    string
+		file    string
+		label   Label
+		correct bool
+	}{
+		{"no label", "chair.jpg", Label{}, true},
+		{"all fields match", "chair.jpg", Label{Admissible: boolPtr(true), MinScore: intPtr(8), MaxScore: intPtr(10), VerdictType: domain.VerdictTypeVrijspraak}, true},
+		{"admissible differs", "chair.jpg", Label{Admissible: boolPtr(false)}, false},
+		{"score above range", "chair.jpg", Label{MaxScore: intPtr(7)}, false},
+		{"verdict type differs", "chair.jpg", Label{VerdictType: domain.VerdictTypeSchuldig}, false},
+		{"analysis fails", "broken.jpg", Label{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := judgeCase(context.Background(), analyzer, evalCase{File: tt.file, Path: filepath.Join(dir, tt.file), Label: tt.label})
+			if result.Correct != tt.correct {
+				t.Errorf("judgeCase() correct = %v, want %v (%+v)", result.Correct, tt.correct, result)
+			}
+		})
+	}
+}
+
+func TestBuildReport(t *testing.T) {
+	results := []caseResult{
+		{
+			File:       "straight.jpg",
+			Expected:   Label{Admissible: boolPtr(true), MinScore: intPtr(9), MaxScore: intPtr(10), VerdictType: domain.VerdictTypeVrijspraak},
+			Admissible: true, Score: 9, VerdictType: domain.VerdictTypeVrijspraak, Correct: true,
+			LatencyMS: 100, Usage: &domain.TokenUsage{PromptTokens: 300, ResponseTokens: 100},
+		},
+		{
+			File:       "tilted.jpg",
+			Expected:   Label{Admissible: boolPtr(true), MinScore: intPtr(5), MaxScore: intPtr(8), VerdictType: domain.VerdictTypeWaarschuwing},
+			Admissible: true, Score: 2, VerdictType: domain.VerdictTypeSchuldig, Correct: false,
+			LatencyMS: 300, Usage: &domain.TokenUsage{PromptTokens: 300, ResponseTokens: 140},
+		},
+		{
+			File:       "cat.jpg",
+			Expected:   Label{Admissible: boolPtr(false), VerdictType: domain.VerdictTypeNietOntvankelijk},
+			Admissible: false, Score: 0, VerdictType: domain.VerdictTypeNietOntvankelijk, Correct: true,
+			LatencyMS: 200,
+		},
+		{
+			File:     "broken.jpg",
+			Expected: Label{VerdictType: domain.VerdictTypeWaarschuwing},
+			Error:    "analysis failed",
+		},
+	}
+
+	report := buildReport(results)
+
+	if report.Cases != 4 || report.Errors != 1 {
+		t.Errorf("cases = %d, errors = %d, want 4 and 1", report.Cases, report.Errors)
+	}
+	if report.Accuracy != (ratio{Correct: 2, Total: 4, Rate: 0.5}) {
+		t.Errorf("accuracy = %+v, want 2/4", report.Accuracy)
+	}
+	if report.AdmissibleAccuracy != (ratio{Correct: 3, Total: 3, Rate: 1}) {
+		t.Errorf("admissible accuracy = %+v, want 3/3", report.AdmissibleAccuracy)
+	}
+	if report.ScoreInRange.Correct != 1 || report.ScoreInRange.Total != 2 {
+		t.Errorf("score in range = %+v, want 1/2", report.ScoreInRange)
+	}
+	// straight.jpg is in range, tilted.jpg is 3 below it
+	if report.ScoreMAE != 1.5 {
+		t.Errorf("score MAE = %v, want 1.5", report.ScoreMAE)
+	}
+	if got := report.Confusion[domain.VerdictTypeWaarschuwing]; got[domain.VerdictTypeSchuldig] != 1 || got[errorColumn] != 1 {
+		t.Errorf("confusion[waarschuwing] = %v, want one schuldig and one error", got)
+	}
+	if got := report.Confusion[domain.VerdictTypeVrijspraak][domain.VerdictTypeVrijspraak]; got != 1 {
+		t.Errorf("confusion[vrijspraak][vrijspraak] = %d, want 1", got)
+	}
+	if report.Latency != (latencyStats{P50: 200, P90: 300, P99: 300, Max: 300}) {
+		t.Errorf("latency = %+v", report.Latency)
+	}
+	if report.Tokens != (tokenStats{Prompt: 600, Response: 240, Total: 840, Reported: 2, MeanPerCase: 420}) {
+		t.Errorf("tokens = %+v", report.Tokens)
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	report := buildReport([]caseResult{
+		{File: "chair.jpg", Expected: Label{VerdictType: domain.VerdictTypeSchuldig}, VerdictType: "onbekend", LatencyMS: 120},
+	})
+
+	var buf bytes.Buffer
+	writeTable(&buf, report)
+	output := buf.String()
+
+	for _, want := range []string{"=== ACCURACY ===", "=== CONFUSION MATRIX", "onbekend", "=== MISSES ===", "chair.jpg"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("writeTable() output is missing %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []float64{10, 20, 30, 40, 50, 60, 70, 80, 90, 100}
+	tests := []struct {
+		p        float64
+		expected float64
+	}{
+		{50, 50},
+		{90, 90},
+		{99, 100},
+		{0, 10},
+	}
+	for _, tt := range tests {
+		if got := percentile(sorted, tt.p); got != tt.expected {
+			t.Errorf("percentile(%v) = %v, want %v", tt.p, got, tt.expected)
+		}
+	}
+	if got := percentile(nil, 50); got != 0 {
+		t.Errorf("percentile(nil) = %v, want 0", got)
+	}
+}
+
+func TestRunEval_ArgumentValidation(t *testing.T) {
+	dir := writeGoldenSet(t, []string{"chair.jpg"}, `{"chair.jpg": {"admissible": true}}`)
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"no directory", nil},
+		{"zero concurrency", []string{"-concurrency", "0", dir}},
+		{"missing labels", []string{"-labels", filepath.Join(dir, "nope.json"), dir}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := runEval(tt.args); err == nil {
+				t.Error("runEval() error = nil, want error")
+			}
+		})
+	}
+}
@@ -8,12 +8,15 @@ import (
 	"image"
 	_ "image/jpeg"
 	_ "image/png"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"time"
 
 	"rechtebank/backend/internal/adapters/gemini"
+	"rechtebank/backend/internal/adapters/imaging"
 	"rechtebank/backend/internal/core/domain"
+	"rechtebank/backend/internal/logging"
 
 	genaiSDK "github.com/google/generative-ai-go/genai"
 	_ "golang.org/x/image/webp"
@@ -35,12 +38,23 @@ func main() {
 
 func run() error {
 	// Parse command-line arguments
+	if len(os.Args) >= 2 {
+		switch os.Args[1] {
+		case "eval":
+			return runEval(os.Args[2:])
+		case "replay":
+			return runReplay(os.Args[2:])
+		}
+	}
 	if len(os.Args) != 2 {
-		return fmt.Errorf("usage: %s <image-path>", filepath.Base(os.Args[0]))
+		return fmt.Errorf("usage: %s <image-path> | eval [flags] <image-dir> | replay [flags] <storage-dir>", filepath.Base(os.Args[0]))
 	}
 
 	imagePath := os.Args[1]
 
+	// Debug tool: show everything, including values redacted in production logs
+	logging.Setup(logging.Config{Format: "text", Level: slog.LevelDebug})
+
 	// Validate file exists
 	if _, err := os.Stat(imagePath); os.IsNotExist(err) {
 		return fmt.Errorf("image file not found: %s", imagePath)
@@ -55,7 +69,7 @@ func run() error {
 	// Validate image format
 	mimeType := detectMIMEType(imageData)
 	if mimeType == "" {
-		return fmt.Errorf("unsupported image format (must be JPEG, PNG, or WebP)")
+		return fmt.Errorf("unsupported image format (must be JPEG, PNG, WebP, HEIC, or AVIF)")
 	}
 
 	// Get image dimensions
@@ -95,7 +109,7 @@ func run() error {
 	timeout := 30 * time.Second
 	maxRetries := 3
 	printSection("REQUEST METADATA")
-	fmt.Printf("Model: gemini-2.5-flash-lite\n")
+	fmt.Printf("Model: %s\n", gemini.ModelName)
 	fmt.Printf("Timeout: %v\n", timeout)
 	fmt.Printf("Max Retries: %d\n", maxRetries)
 	fmt.Println()
@@ -182,11 +196,18 @@ func detectMIMEType(data []byte) string {
 		return "webp"
 	}
 
-	return ""
+	// HEIC, HEIF, AVIF: ftyp box with an image brand
+	return imaging.HEIFFormat(data)
 }
 
 func getImageDimensions(imageData []byte) (string, error) {
-	img, _, err := image.DecodeConfig(bytes.NewReader(imageData))
+	var img image.Config
+	var err error
+	if imaging.HEIFFormat(imageData) != "" {
+		img, err = imaging.DecodeHEIFConfig(imageData)
+	} else {
+		img, _, err = image.DecodeConfig(bytes.NewReader(imageData))
+	}
 	if err != nil {
 		return "", err
 	}
@@ -235,7 +256,7 @@ func countAndDisplayTokens(ctx context.Context, apiKey string, imageData []byte,
 	userPrompt := gemini.GetUserPrompt()
 
 	// Create model
-	model := client.GenerativeModel("gemini-2.5-flash-lite")
+	model := client.GenerativeModel(gemini.ModelName)
 
 	// Count tokens
 	resp, err := model.CountTokens(ctx,
@@ -28,6 +28,16 @@ func TestDetectMIMEType(t *testing.T) {
 			data:     []byte{0x52, 0x49, 0x46, 0x46, 0x00, 0x00, 0x00, 0x00, 0x57, 0x45, 0x42, 0x50},
 			expected: "webp",
 		},
+		{
+			name:     "HEIC image",
+			data:     []byte{0x00, 0x00, 0x00, 0x18, 'f', 't', 'y', 'p', 'h', 'e', 'i', 'c', 0x00, 0x00, 0x00, 0x00, 'm', 'i', 'f', '1', 'h', 'e', 'i', 'c'},
+			expected: "heic",
+		},
+		{
+			name:     "AVIF image",
+			data:     []byte{0x00, 0x00, 0x00, 0x14, 'f', 't', 'y', 'p', 'a', 'v', 'i', 'f', 0x00, 0x00, 0x00, 0x00, 'm', 'i', 'f', '1'},
+			expected: "avif",
+		},
 		{
 			name:     "Invalid format",
 			data:     []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
@@ -165,7 +175,7 @@ func TestRunWithInvalidImage(t *testing.T) {
 		t.Error("Expected error for invalid image, got nil")
 	}
 
-	if err != nil && err.Error() != "unsupported image format (must be JPEG, PNG, or WebP)" {
+	if err != nil && err.Error() != "unsupported image format (must be JPEG, PNG, WebP, HEIC, or AVIF)" {
 		t.Errorf("Expected 'unsupported image format' error, got: %v", err)
 	}
 }
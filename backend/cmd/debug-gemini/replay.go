@@ -0,0 +1,448 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"rechtebank/backend/internal/adapters/gemini"
+	"rechtebank/backend/internal/adapters/storage"
+	"rechtebank/backend/internal/core/domain"
+	"rechtebank/backend/internal/core/ports"
+	"rechtebank/backend/internal/logging"
+)
+
+// ruling is the part of a verdict the replay compares
+type ruling struct {
+	Admissible  bool   `json:"admissible"`
+	Score       int    `json:"score"`
+	VerdictType string `json:"verdictType"`
+	Crime       string `json:"crime"`
+}
+
+func rulingOf(verdict *domain.VerdictResponse) ruling {
+	return ruling{
+		Admissible:  verdict.Admissible,
+		Score:       verdict.Score,
+		VerdictType: verdict.Verdict.VerdictType,
+		Crime:       verdict.Verdict.Crime,
+	}
+}
+
+// replayResult compares the stored ruling of a case with the replayed one
+type replayResult struct {
+	ID        string `json:"id"`
+	RequestID string `json:"requestId"`
+	Stored    ruling `json:"stored"`
+	Replayed  ruling `json:"replayed"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ScoreDelta is the replayed score minus the stored one
+func (r replayResult) ScoreDelta() int {
+	return r.Replayed.Score - r.Stored.Score
+}
+
+// AdmissibilityChanged reports whether the replay reversed admissibility
+func (r replayResult) AdmissibilityChanged() bool {
+	return r.Stored.Admissible != r.Replayed.Admissible
+}
+
+// VerdictTypeChanged reports whether the replay reached another verdict type
+func (r replayResult) VerdictTypeChanged() bool {
+	return r.Stored.VerdictType != r.Replayed.VerdictType
+}
+
+// Changed reports whether the replayed ruling differs from the stored one
+func (r replayResult) Changed() bool {
+	return r.Error == "" && (r.AdmissibilityChanged() || r.VerdictTypeChanged() || r.ScoreDelta() != 0)
+}
+
+// skippedCase is a stored case that could not be replayed
+type skippedCase struct {
+	ID     string `json:"id"`
+	Reason string `json:"reason"`
+}
+
+// replaySummary is the diff between stored and replayed rulings
+type replaySummary struct {
+	Model      string `json:"model"`
+	PromptHash string `json:"promptHash"`
+	StartedAt  string `json:"startedAt"`
+	Seed       uint64 `json:"seed,omitempty"`
+
+	Cases    int `json:"cases"`
+	Replayed int `json:"replayed"`
+	Errors   int `json:"errors"`
+	Changed  int `json:"changed"`
+
+	BecameAdmissible   int `json:"becameAdmissible"`
+	BecameInadmissible int `json:"becameInadmissible"`
+
+	// VerdictTypeFlips counts stored verdict types (rows) that were replayed
+	// as another type
+	VerdictTypeFlips map[string]map[string]int `json:"verdictTypeFlips"`
+
+	ScoreChanged      int         `json:"scoreChanged"`
+	MeanScoreDelta    float64     `json:"meanScoreDelta"`
+	MeanAbsScoreDelta float64     `json:"meanAbsScoreDelta"`
+	ScoreDeltas       map[int]int `json:"scoreDeltas"`
+
+	Skipped []skippedCase  `json:"skipped"`
+	Results []replayResult `json:"results"`
+}
+
+// FlipCount returns the number of cases replayed with another verdict type
+func (s replaySummary) FlipCount() int {
+	var flips int
+	for _, row := range s.VerdictTypeFlips {
+		for _, n := range row {
+			flips += n
+		}
+	}
+	return flips
+}
+
+// ChangedResults returns the cases whose ruling changed, most significant
+// change first
+func (s replaySummary) ChangedResults() []replayResult {
+	var changed []replayResult
+	for _, r := range s.Results {
+		if r.Changed() {
+			changed = append(changed, r)
+		}
+	}
+	sort.SliceStable(changed, func(i, j int) bool {
+		a, b := changed[i], changed[j]
+		if a.AdmissibilityChanged() != b.AdmissibilityChanged() {
+			return a.AdmissibilityChanged()
+		}
+		if a.VerdictTypeChanged() != b.VerdictTypeChanged() {
+			return a.VerdictTypeChanged()
+		}
+		return abs(a.ScoreDelta()) > abs(b.ScoreDelta())
+	})
+	return changed
+}
+
+// FailedResults returns the cases the analyzer failed on
+func (s replaySummary) FailedResults() []replayResult {
+	var failed []replayResult
+	for _, r := range s.Results {
+		if r.Error != "" {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+// SortedScoreDeltas returns the score deltas that occurred, in ascending order
+func (s replaySummary) SortedScoreDeltas() []int {
+	deltas := make([]int, 0, len(s.ScoreDeltas))
+	for delta := range s.ScoreDeltas {
+		deltas = append(deltas, delta)
+	}
+	sort.Ints(deltas)
+	return deltas
+}
+
+// replayCase is a stored case selected for replay
+type replayCase struct {
+	storage.StoredCase
+	Stored *domain.VerdictResponse
+}
+
+// runReplay re-judges stored cases with a chosen model and prompt and writes
+// a report of the rulings that changed
+func runReplay(args []string) error {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	model := flags.String("model", gemini.ModelName, "Gemini model to replay with")
+	promptPath := flags.String("prompt", "", "file with the system prompt to replay with (default: the current prompt)")
+	since := flags.String("since", "", "only replay cases stored on or after this date (YYYY-MM-DD)")
+	until := flags.String("until", "", "only replay cases stored on or before this date (YYYY-MM-DD)")
+	sample := flags.Int("sample", 0, "replay a random sample of this many cases (0 replays all)")
+	seed := flags.Uint64("seed", 0, "seed for -sample (default: random)")
+	concurrency := flags.Int("concurrency", 4, "number of cases replayed at the same time")
+	timeout := flags.Duration("timeout", 30*time.Second, "timeout per analysis")
+	format := flags.String("format", "", "report format: markdown or html (default: from -out, else markdown)")
+	outPath := flags.String("out", "", "write the report to this file instead of stdout")
+	jsonPath := flags.String("json", "", "also write the full diff as JSON to this file")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: %s replay [flags] <storage-dir>\n", filepath.Base(os.Args[0]))
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("expected exactly one storage directory")
+	}
+	if *concurrency < 1 {
+		return fmt.Errorf("concurrency must be at least 1")
+	}
+
+	if *format == "" {
+		*format = "markdown"
+		if ext := strings.ToLower(filepath.Ext(*outPath)); ext == ".html" || ext == ".htm" {
+			*format = "html"
+		}
+	}
+	render, ok := reportRenderers[*format]
+	if !ok {
+		return fmt.Errorf("unknown report format %q (must be markdown or html)", *format)
+	}
+
+	sinceDate, err := parseDateFlag("since", *since)
+	if err != nil {
+		return err
+	}
+	untilDate, err := parseDateFlag("until", *until)
+	if err != nil {
+		return err
+	}
+
+	modelConfig := gemini.DefaultModelConfig()
+	modelConfig.Name = *model
+	if *promptPath != "" {
+		prompt, err := os.ReadFile(*promptPath)
+		if err != nil {
+			return fmt.Errorf("failed to read prompt: %w", err)
+		}
+		modelConfig.SystemPrompt = string(prompt)
+	}
+
+	storageDir := flags.Arg(0)
+	if info, err := os.Stat(storageDir); err != nil || !info.IsDir() {
+		return fmt.Errorf("storage directory not found: %s", storageDir)
+	}
+	photoStorage, err := storage.NewPhotoStorage(storageDir)
+	if err != nil {
+		return err
+	}
+	stored, err := photoStorage.ListCases()
+	if err != nil {
+		return err
+	}
+
+	if *sample > 0 && *seed == 0 {
+		*seed = rand.Uint64()
+	}
+	cases, skipped := selectReplayCases(stored, sinceDate, untilDate, *sample, *seed)
+	fmt.Fprintf(os.Stderr, "Replaying %d of %d stored cases (%d skipped)\n", len(cases), len(stored), len(skipped))
+
+	apiKey := os.Getenv("GEMINI_API_KEY")
+	if apiKey == "" {
+		return fmt.Errorf("GEMINI_API_KEY environment variable is required")
+	}
+
+	// Per-request logs would drown the report; only show problems
+	logging.Setup(logging.Config{Format: "text", Level: slog.LevelWarn})
+
+	analyzer, err := gemini.NewGeminiAnalyzerWithModel(apiKey, *timeout, gemini.DefaultCompressionPolicy(), modelConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize analyzer: %w", err)
+	}
+	defer analyzer.Close()
+
+	startedAt := time.Now().UTC()
+	var done int
+	results := replay(context.Background(), analyzer, cases, *concurrency, func(r replayResult) {
+		done++
+		status := "unchanged"
+		if r.Error != "" {
+			status = "error: " + r.Error
+		} else if r.Changed() {
+			status = "changed"
+		}
+		fmt.Fprintf(os.Stderr, "[%d/%d] %s %s\n", done, len(cases), r.ID, status)
+	})
+
+	summary := summarizeReplay(results, skipped)
+	summary.Model = modelConfig.Name
+	summary.PromptHash = promptHash(modelConfig.SystemPrompt)
+	summary.StartedAt = startedAt.Format(time.RFC3339)
+	if *sample > 0 {
+		summary.Seed = *seed
+	}
+
+	out := io.Writer(os.Stdout)
+	if *outPath != "" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			return fmt.Errorf("failed to create report: %w", err)
+		}
+		defer f.Close()
+		out = f
+	}
+	if err := render(out, summary); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+
+	if *jsonPath != "" {
+		data, err := json.MarshalIndent(summary, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode diff: %w", err)
+		}
+		if err := os.WriteFile(*jsonPath, append(data, '\n'), 0644); err != nil {
+			return fmt.Errorf("failed to write diff: %w", err)
+		}
+	}
+	return nil
+}
+
+// parseDateFlag parses an optional YYYY-MM-DD flag value
+func parseDateFlag(name, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("-%s must be a date (YYYY-MM-DD): %w", name, err)
+	}
+	return date, nil
+}
+
+// selectReplayCases picks the stored cases within the date range that can be
+// replayed, optionally sampling them, and lists the ones it skipped
+func selectReplayCases(stored []storage.StoredCase, since, until time.Time, sample int, seed uint64) ([]replayCase, []skippedCase) {
+	var cases []replayCase
+	var skipped []skippedCase
+	for _, c := range stored {
+		if (!since.IsZero() && c.Date.Before(since)) || (!until.IsZero() && c.Date.After(until)) {
+			continue
+		}
+		if c.PhotoPath == "" {
+			skipped = append(skipped, skippedCase{ID: c.ID, Reason: "no stored photo"})
+			continue
+		}
+
+		data, err := os.ReadFile(c.JSONPath)
+		if err != nil {
+			skipped = append(skipped, skippedCase{ID: c.ID, Reason: err.Error()})
+			continue
+		}
+		verdict, err := domain.ParseFlatJSON(data)
+		if err != nil {
+			skipped = append(skipped, skippedCase{ID: c.ID, Reason: "unreadable verdict: " + err.Error()})
+			continue
+		}
+		var rejected struct {
+			QualityIssue string `json:"qualityIssue"`
+		}
+		if json.Unmarshal(data, &rejected) == nil && rejected.QualityIssue != "" {
+			skipped = append(skipped, skippedCase{ID: c.ID, Reason: "not judged by the analyzer (" + rejected.QualityIssue + ")"})
+			continue
+		}
+
+		cases = append(cases, replayCase{StoredCase: c, Stored: verdict})
+	}
+
+	if sample > 0 && sample < len(cases) {
+		rng := rand.New(rand.NewPCG(seed, seed))
+		rng.Shuffle(len(cases), func(i, j int) { cases[i], cases[j] = cases[j], cases[i] })
+		cases = cases[:sample]
+		sort.Slice(cases, func(i, j int) bool { return cases[i].ID < cases[j].ID })
+	}
+	return cases, skipped
+}
+
+// replay re-judges every case with at most concurrency analyses in flight.
+// Results keep the order of cases; progress is called as each case finishes.
+func replay(ctx context.Context, analyzer ports.IPhotoAnalyzer, cases []replayCase, concurrency int, progress func(replayResult)) []replayResult {
+	results := make([]replayResult, len(cases))
+	var mu sync.Mutex
+	runConcurrently(len(cases), concurrency, func(i int) {
+		result := replayOne(ctx, analyzer, cases[i])
+		results[i] = result
+		if progress != nil {
+			mu.Lock()
+			progress(result)
+			mu.Unlock()
+		}
+	})
+	return results
+}
+
+// replayOne re-judges the stored photo of one case
+func replayOne(ctx context.Context, analyzer ports.IPhotoAnalyzer, c replayCase) replayResult {
+	result := replayResult{ID: c.ID, RequestID: c.RequestID(), Stored: rulingOf(c.Stored)}
+
+	imageData, err := os.ReadFile(c.PhotoPath)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	verdict, err := analyzer.AnalyzePhoto(ctx, imageData)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Replayed = rulingOf(verdict)
+	return result
+}
+
+// summarizeReplay counts how the replayed rulings differ from the stored ones
+func summarizeReplay(results []replayResult, skipped []skippedCase) replaySummary {
+	summary := replaySummary{
+		Cases:            len(results) + len(skipped),
+		VerdictTypeFlips: map[string]map[string]int{},
+		ScoreDeltas:      map[int]int{},
+		Skipped:          skipped,
+		Results:          results,
+	}
+
+	var deltaSum, absDeltaSum int
+	for _, r := range results {
+		if r.Error != "" {
+			summary.Errors++
+			continue
+		}
+		summary.Replayed++
+		if r.Changed() {
+			summary.Changed++
+		}
+
+		if r.AdmissibilityChanged() {
+			if r.Replayed.Admissible {
+				summary.BecameAdmissible++
+			} else {
+				summary.BecameInadmissible++
+			}
+		}
+		if r.VerdictTypeChanged() {
+			countConfusion(summary.VerdictTypeFlips, r.Stored.VerdictType, r.Replayed.VerdictType)
+		}
+
+		delta := r.ScoreDelta()
+		summary.ScoreDeltas[delta]++
+		if delta != 0 {
+			summary.ScoreChanged++
+		}
+		deltaSum += delta
+		absDeltaSum += abs(delta)
+	}
+
+	if summary.Replayed > 0 {
+		summary.MeanScoreDelta = float64(deltaSum) / float64(summary.Replayed)
+		summary.MeanAbsScoreDelta = float64(absDeltaSum) / float64(summary.Replayed)
+	}
+	return summary
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
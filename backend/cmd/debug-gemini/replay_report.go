@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// reportRenderers writes a replay summary in each supported format
+var reportRenderers = map[string]func(io.Writer, replaySummary) error{
+	"markdown": func(w io.Writer, s replaySummary) error { return markdownReport.Execute(w, s) },
+	"html":     func(w io.Writer, s replaySummary) error { return htmlReport.Execute(w, s) },
+}
+
+// reportFuncs are the helpers both report templates use
+var reportFuncs = map[string]any{
+	// signed formats a score delta with its sign
+	"signed": func(n int) string {
+		if n > 0 {
+			return fmt.Sprintf("+%d", n)
+		}
+		return fmt.Sprint(n)
+	},
+	// verdictType names a verdict type, including the empty one of old verdicts
+	"verdictType": func(t string) string {
+		if t == "" {
+			return "(none)"
+		}
+		return t
+	},
+	"float": func(f float64) string { return fmt.Sprintf("%.2f", f) },
+	// cell keeps free text from breaking a Markdown table row
+	"cell": strings.NewReplacer("|", "\\|", "\n", " ").Replace,
+}
+
+var markdownReport = template.Must(template.New("markdown").Funcs(reportFuncs).Parse(`# Replay report
+
+- Model: ` + "`{{.Model}}`" + `
+- Prompt: ` + "`{{.PromptHash}}`" + `
+- Started: {{.StartedAt}}{{if .Seed}}
+- Sample seed: {{.Seed}}{{end}}
+
+## Summary
+
+| | Cases |
+|---|---:|
+| Stored cases considered | {{.Cases}} |
+| Replayed | {{.Replayed}} |
+| Skipped | {{len .Skipped}} |
+| Failed | {{.Errors}} |
+| Ruling changed | {{.Changed}} |
+| Became admissible | {{.BecameAdmissible}} |
+| Became inadmissible | {{.BecameInadmissible}} |
+| Verdict type flipped | {{.FlipCount}} |
+| Score changed | {{.ScoreChanged}} |
+
+Mean score delta: {{float .MeanScoreDelta}}, mean absolute score delta: {{float .MeanAbsScoreDelta}}
+{{if .VerdictTypeFlips}}
+## Verdict type flips
+
+| Stored | Replayed | Cases |
+|---|---|---:|
+{{range $from, $row := .VerdictTypeFlips}}{{range $to, $n := $row}}| {{verdictType $from}} | {{verdictType $to}} | {{$n}} |
+{{end}}{{end}}{{end}}{{if .ScoreDeltas}}
+## Score deltas
+
+| Delta | Cases |
+|---:|---:|
+{{range .SortedScoreDeltas}}| {{signed .}} | {{index $.ScoreDeltas .}} |
+{{end}}{{end}}{{with .ChangedResults}}
+## Changed rulings
+
+| Case | Admissible | Score | Verdict type | Crime (replayed) |
+|---|---|---|---|---|
+{{range .}}| ` + "`{{.ID}}`" + ` | {{.Stored.Admissible}}{{if .AdmissibilityChanged}} → **{{.Replayed.Admissible}}**{{end}} | {{.Stored.Score}} → {{.Replayed.Score}} ({{signed .ScoreDelta}}) | {{verdictType .Stored.VerdictType}}{{if .VerdictTypeChanged}} → **{{verdictType .Replayed.VerdictType}}**{{end}} | {{cell .Replayed.Crime}} |
+{{end}}{{end}}{{with .FailedResults}}
+## Failed
+
+| Case | Error |
+|---|---|
+{{range .}}| ` + "`{{.ID}}`" + ` | {{cell .Error}} |
+{{end}}{{end}}{{with .Skipped}}
+## Skipped
+
+| Case | Reason |
+|---|---|
+{{range .}}| ` + "`{{.ID}}`" + ` | {{cell .Reason}} |
+{{end}}{{end}}`))
+
+var htmlReport = htmltemplate.Must(htmltemplate.New("html").Funcs(reportFuncs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Replay report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; }
+td.num { text-align: right; }
+.changed { font-weight: bold; color: #b00; }
+</style>
+</head>
+<body>
+<h1>Replay report</h1>
+<ul>
+<li>Model: <code>{{.Model}}</code></li>
+<li>Prompt: <code>{{.PromptHash}}</code></li>
+<li>Started: {{.StartedAt}}</li>{{if .Seed}}
+<li>Sample seed: {{.Seed}}</li>{{end}}
+</ul>
+
+<h2>Summary</h2>
+<table>
+<tr><td>Stored cases considered</td><td class="num">{{.Cases}}</td></tr>
+<tr><td>Replayed</td><td class="num">{{.Replayed}}</td></tr>
+<tr><td>Skipped</td><td class="num">{{len .Skipped}}</td></tr>
+<tr><td>Failed</td><td class="num">{{.Errors}}</td></tr>
+<tr><td>Ruling changed</td><td class="num">{{.Changed}}</td></tr>
+<tr><td>Became admissible</td><td class="num">{{.BecameAdmissible}}</td></tr>
+<tr><td>Became inadmissible</td><td class="num">{{.BecameInadmissible}}</td></tr>
+<tr><td>Verdict type flipped</td><td class="num">{{.FlipCount}}</td></tr>
+<tr><td>Score changed</td><td class="num">{{.ScoreChanged}}</td></tr>
+</table>
+<p>Mean score delta: {{float .MeanScoreDelta}}, mean absolute score delta: {{float .MeanAbsScoreDelta}}</p>
+{{if .VerdictTypeFlips}}
+<h2>Verdict type flips</h2>
+<table>
+<tr><th>Stored</th><th>Replayed</th><th>Cases</th></tr>
+{{range $from, $row := .VerdictTypeFlips}}{{range $to, $n := $row}}<tr><td>{{verdictType $from}}</td><td>{{verdictType $to}}</td><td class="num">{{$n}}</td></tr>
+{{end}}{{end}}</table>
+{{end}}{{if .ScoreDeltas}}
+<h2>Score deltas</h2>
+<table>
+<tr><th>Delta</th><th>Cases</th></tr>
+{{range .SortedScoreDeltas}}<tr><td class="num">{{signed .}}</td><td class="num">{{index $.ScoreDeltas .}}</td></tr>
+{{end}}</table>
+{{end}}{{with .ChangedResults}}
+<h2>Changed rulings</h2>
+<table>
+<tr><th>Case</th><th>Admissible</th><th>Score</th><th>Verdict type</th><th>Crime (replayed)</th></tr>
+{{range .}}<tr><td><code>{{.ID}}</code></td><td>{{.Stored.Admissible}}{{if .AdmissibilityChanged}} → <span class="changed">{{.Replayed.Admissible}}</span>{{end}}</td><td>{{.Stored.Score}} → {{.Replayed.Score}} ({{signed .ScoreDelta}})</td><td>{{verdictType .Stored.VerdictType}}{{if .VerdictTypeChanged}} → <span class="changed">{{verdictType .Replayed.VerdictType}}</span>{{end}}</td><td>{{.Replayed.Crime}}</td></tr>
+{{end}}</table>
+{{end}}{{with .FailedResults}}
+<h2>Failed</h2>
+<table>
+<tr><th>Case</th><th>Error</th></tr>
+{{range .}}<tr><td><code>{{.ID}}</code></td><td>{{.Error}}</td></tr>
+{{end}}</table>
+{{end}}{{with .Skipped}}
+<h2>Skipped</h2>
+<table>
+<tr><th>Case</th><th>Reason</th></tr>
+{{range .}}<tr><td><code>{{.ID}}</code></td><td>{{.Reason}}</td></tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"rechtebank/backend/internal/adapters/storage"
+	"rechtebank/backend/internal/core/domain"
+)
+
+// writeStoredCase writes a verdict JSON, and a photo unless photo is nil, the
+// way storage lays them out
+func writeStoredCase(t *testing.T, dir, id, verdictJSON string, photo []byte) storage.StoredCase {
+	t.Helper()
+	basePath := filepath.Join(dir, id)
+	if err := os.MkdirAll(filepath.Dir(basePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(basePath+".json", []byte(verdictJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := storage.StoredCase{ID: id, JSONPath: basePath + ".json"}
+	c.Date, _ = time.Parse("2006-01-02", filepath.Dir(id))
+	if photo != nil {
+		if err := os.WriteFile(basePath+".jpg", photo, 0644); err != nil {
+			t.Fatal(err)
+		}
+		c.PhotoPath = basePath + ".jpg"
+	}
+	return c
+}
+
+func TestSelectReplayCases(t *testing.T) {
+	dir := t.TempDir()
+	verdict := `{"admissible":true,"score":7,"verdictType":"waarschuwing"}`
+	stored := []storage.StoredCase{
+		writeStoredCase(t, dir, "2026-01-31/090000_old", verdict, []byte("old")),
+		writeStoredCase(t, dir, "2026-02-01/090000_a", verdict, []byte("a")),
+		writeStoredCase(t, dir, "2026-02-01/100000_flagged", `{"moderation":{"flagged":true}}`, nil),
+		writeStoredCase(t, dir, "2026-02-02/090000_dark", `{"admissible":false,"qualityIssue":"too_dark"}`, []byte("dark")),
+		writeStoredCase(t, dir, "2026-02-02/100000_b", verdict, []byte("b")),
+		writeStoredCase(t, dir, "2026-02-03/090000_new", verdict, []byte("new")),
+	}
+	since, _ := time.Parse("2006-01-02", "2026-02-01")
+	until, _ := time.Parse("2006-01-02", "2026-02-02")
+
+	cases, skipped := selectReplayCases(stored, since, until, 0, 0)
+
+	if len(cases) != 2 || cases[0].ID != "2026-02-01/090000_a" || cases[1].ID != "2026-02-02/100000_b" {
+		t.Fatalf("selectReplayCases() cases = %+v, want a and b", cases)
+	}
+	if cases[0].Stored.Score != 7 || cases[0].RequestID() != "a" {
+		t.Errorf("selectReplayCases() did not read the stored verdict: %+v", cases[0])
+	}
+	if len(skipped) != 2 || skipped[0].ID != "2026-02-01/100000_flagged" || skipped[1].ID != "2026-02-02/090000_dark" {
+		t.Errorf("selectReplayCases() skipped = %+v, want the flagged and the dark case", skipped)
+	}
+}
+
+func TestSelectReplayCases_Sample(t *testing.T) {
+	dir := t.TempDir()
+	var stored []storage.StoredCase
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		stored = append(stored, writeStoredCase(t, dir, "2026-02-01/090000_"+name, `{"score":5}`, []byte(name)))
+	}
+
+	first, _ := selectReplayCases(stored, time.Time{}, time.Time{}, 3, 42)
+	second, _ := selectReplayCases(stored, time.Time{}, time.Time{}, 3, 42)
+
+	if len(first) != 3 {
+		t.Fatalf("selectReplayCases() sampled %d cases, want 3", len(first))
+	}
+	for i := range first {
+		if first[i].ID != second[i].ID {
+			t.Errorf("samples with the same seed differ: %s and %s", first[i].ID, second[i].ID)
+		}
+		if i > 0 && first[i-1].ID >= first[i].ID {
+			t.Errorf("sample is not sorted by ID: %s before %s", first[i-1].ID, first[i].ID)
+		}
+	}
+}
+
+func TestReplay(t *testing.T) {
+	dir := t.TempDir()
+	stored := []storage.StoredCase{
+		writeStoredCase(t, dir, "2026-02-01/090000_same", `{"admissible":true,"score":7,"verdictType":"waarschuwing"}`, []byte("same")),
+		writeStoredCase(t, dir, "2026-02-01/100000_flip", `{"admissible":true,"score":8,"verdictType":"waarschuwing"}`, []byte("flip")),
+		writeStoredCase(t, dir, "2026-02-01/110000_broken", `{"admissible":true,"score":5,"verdictType":"schuldig"}`, []byte("broken")),
+	}
+	analyzer := &fakeAnalyzer{verdicts: map[string]*domain.VerdictResponse{
+		"same": {Admissible: true, Score: 7, Verdict: domain.VerdictDetails{VerdictType: domain.VerdictTypeWaarschuwing}},
+		"flip": {Admissible: false, Score: 0, Verdict: domain.VerdictDetails{VerdictType: domain.VerdictTypeNietOntvankelijk, Crime: "Geen meubilair"}},
+	}}
+	cases, _ := selectReplayCases(stored, time.Time{}, time.Time{}, 0, 0)
+
+	results := replay(context.Background(), analyzer, cases, 2, nil)
+
+	if len(results) != 3 {
+		t.Fatalf("replay() returned %d results, want 3", len(results))
+	}
+	if results[0].Changed() {
+		t.Errorf("results[0] = %+v, want unchanged", results[0])
+	}
+	if !results[1].Changed() || !results[1].AdmissibilityChanged() || !results[1].VerdictTypeChanged() || results[1].ScoreDelta() != -8 {
+		t.Errorf("results[1] = %+v, want admissibility, verdict type and score changed", results[1])
+	}
+	if results[2].Error == "" || results[2].Changed() {
+		t.Errorf("results[2] = %+v, want an error", results[2])
+	}
+}
+
+func TestSummarizeReplay(t *testing.T) {
+	results := []replayResult{
+		{ID: "same", Stored: ruling{true, 7, "waarschuwing", ""}, Replayed: ruling{true, 7, "waarschuwing", ""}},
+		{ID: "harsher", Stored: ruling{true, 7, "waarschuwing", ""}, Replayed: ruling{true, 4, "schuldig", ""}},
+		{ID: "rejected", Stored: ruling{true, 9, "vrijspraak", ""}, Replayed: ruling{false, 0, "niet-ontvankelijk", ""}},
+		{ID: "milder", Stored: ruling{true, 6, "waarschuwing", ""}, Replayed: ruling{true, 8, "waarschuwing", ""}},
+		{ID: "broken", Stored: ruling{true, 5, "schuldig", ""}, Error: "timeout"},
+	}
+	skipped := []skippedCase{{ID: "flagged", Reason: "no stored photo"}}
+
+	summary := summarizeReplay(results, skipped)
+
+	if summary.Cases != 6 || summary.Replayed != 4 || summary.Errors != 1 || summary.Changed != 3 {
+		t.Errorf("cases/replayed/errors/changed = %d/%d/%d/%d, want 6/4/1/3", summary.Cases, summary.Replayed, summary.Errors, summary.Changed)
+	}
+	if summary.BecameInadmissible != 1 || summary.BecameAdmissible != 0 {
+		t.Errorf("became inadmissible/admissible = %d/%d, want 1/0", summary.BecameInadmissible, summary.BecameAdmissible)
+	}
+	if summary.FlipCount() != 2 || summary.VerdictTypeFlips["waarschuwing"]["schuldig"] != 1 || summary.VerdictTypeFlips["vrijspraak"]["niet-ontvankelijk"] != 1 {
+		t.Errorf("verdict type flips = %v", summary.VerdictTypeFlips)
+	}
+	// deltas: 0, -3, -9, +2
+	if summary.ScoreChanged != 3 || summary.MeanScoreDelta != -2.5 || summary.MeanAbsScoreDelta != 3.5 {
+		t.Errorf("score changed/mean/mean abs = %d/%v/%v, want 3/-2.5/3.5", summary.ScoreChanged, summary.MeanScoreDelta, summary.MeanAbsScoreDelta)
+	}
+
+	changed := summary.ChangedResults()
+	if len(changed) != 3 || changed[0].ID != "rejected" || changed[1].ID != "harsher" || changed[2].ID != "milder" {
+		t.Errorf("ChangedResults() = %+v, want rejected, harsher, milder", changed)
+	}
+}
+
+func TestReportRenderers(t *testing.T) {
+	summary := summarizeReplay([]replayResult{
+		{ID: "2026-02-01/090000_a", Stored: ruling{true, 9, "vrijspraak", ""}, Replayed: ruling{false, 0, "niet-ontvankelijk", "<Geen meubilair>"}},
+		{ID: "2026-02-01/100000_b", Error: "timeout"},
+	}, []skippedCase{{ID: "2026-02-01/110000_c", Reason: "no stored photo"}})
+	summary.Model = "gemini-test"
+
+	for format, want := range map[string][]string{
+		"markdown": {"# Replay report", "`gemini-test`", "| vrijspraak | niet-ontvankelijk | 1 |", "**false**", "(-9)", "timeout", "no stored photo", "<Geen meubilair>"},
+		"html":     {"<h1>Replay report</h1>", "<code>gemini-test</code>", "<td>vrijspraak</td><td>niet-ontvankelijk</td>", "(-9)", "timeout", "no stored photo", "&lt;Geen meubilair&gt;"},
+	} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := reportRenderers[format](&buf, summary); err != nil {
+				t.Fatalf("render error = %v", err)
+			}
+			for _, s := range want {
+				if !strings.Contains(buf.String(), s) {
+					t.Errorf("%s report is missing %q:\n%s", format, s, buf.String())
+				}
+			}
+		})
+	}
+}
+
+func TestRunReplay_ArgumentValidation(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"no directory", nil},
+		{"missing directory", []string{filepath.Join(dir, "nope")}},
+		{"unknown format", []string{"-format", "pdf", dir}},
+		{"invalid date", []string{"-since", "yesterday", dir}},
+		{"missing prompt file", []string{"-prompt", filepath.Join(dir, "prompt.txt"), dir}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := runReplay(tt.args); err == nil {
+				t.Error("runReplay() error = nil, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,789 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"rechtebank/backend/internal/adapters/storage"
+	"rechtebank/backend/internal/core/domain"
+)
+
+const (
+	exitSuccess = 0
+	exitError   = 1
+)
+
+// command is an admin subcommand
+type command struct {
+	usage string
+	run   func(s *storage.PhotoStorage, args []string, out io.Writer) error
+}
+
+// commands is filled in init, as the commands print their own usage
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"list":      {"list [-date D] [-since D] [-until D] [-verdict-type T] [-min-score N] [-max-score N] [-json]", runList},
+		"show":      {"show <id|verdict-id|request-id>", runShow},
+		"delete":    {"delete [-dry-run] <id|verdict-id|request-id>...", runDelete},
+		"purge":     {"purge -older-than <days>d [-dry-run]", runPurge},
+		"retention": {"retention [-inadmissible 7d] [-verdicts 90d] [-shared 365d] [-dry-run] [-json]", runRetention},
+		"purge-log": {"purge-log [-json]", runPurgeLog},
+		"publish":   {"publish <id|verdict-id|request-id>...", runPublish},
+		"unpublish": {"unpublish <id|verdict-id|request-id>...", runUnpublish},
+		"hold":      {"hold <id|verdict-id|request-id>...", runHold},
+		"release":   {"release <id|verdict-id|request-id>...", runRelease},
+		"share-id":  {"share-id <request-id>", runShareID},
+		"stats":     {"stats [-json]", runStats},
+		"verify":    {"verify [-min-age 5m] [-fix]", runVerify},
+		"export":    {"export -o <file> [-format tar.gz|zip] [-since D] [-until D]", runExport},
+		"import":    {"import <archive>", runImport},
+	}
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	os.Exit(exitSuccess)
+}
+
+func run(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("rechtbank-admin", flag.ContinueOnError)
+	storagePath := flags.String("storage", getEnvOrDefault("PHOTO_STORAGE_PATH", "./photos"), "photo storage directory")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: rechtbank-admin [-storage dir] <command> [flags] [args]")
+		fmt.Fprintln(flags.Output(), "\ncommands:")
+		names := make([]string, 0, len(commands))
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(flags.Output(), "  %s\n", commands[name].usage)
+		}
+		fmt.Fprintln(flags.Output(), "\nflags:")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("no command given")
+	}
+
+	cmd, ok := commands[flags.Arg(0)]
+	if !ok {
+		flags.Usage()
+		return fmt.Errorf("unknown command %q", flags.Arg(0))
+	}
+
+	// Never create the storage directory: a typo should not look like an
+	// empty court
+	if info, err := os.Stat(*storagePath); err != nil || !info.IsDir() {
+		return fmt.Errorf("storage directory not found: %s", *storagePath)
+	}
+	s, err := storage.NewPhotoStorage(*storagePath)
+	if err != nil {
+		return err
+	}
+	return cmd.run(s, flags.Args()[1:], out)
+}
+
+// newFlagSet returns the flag set of a subcommand
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: rechtbank-admin %s\n", commands[name].usage)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// caseEntry is a stored case with its parsed verdict
+type caseEntry struct {
+	storage.StoredCase
+	Verdict *domain.VerdictResponse
+	Err     error
+}
+
+// withheld reports whether the case's photo was withheld by moderation
+func (e caseEntry) withheld() bool {
+	return e.Verdict != nil && e.Verdict.Moderation != nil && e.Verdict.Moderation.Flagged
+}
+
+func loadCase(c storage.StoredCase) caseEntry {
+	entry := caseEntry{StoredCase: c}
+	data, err := os.ReadFile(c.JSONPath)
+	if err != nil {
+		entry.Err = err
+		return entry
+	}
+	entry.Verdict, entry.Err = domain.ParseFlatJSON(data)
+	return entry
+}
+
+func loadCases(s *storage.PhotoStorage) ([]caseEntry, error) {
+	stored, err := s.ListCases()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]caseEntry, len(stored))
+	for i, c := range stored {
+		entries[i] = loadCase(c)
+	}
+	return entries, nil
+}
+
+// resolveCase finds a case by its storage ID, its share ID or its request ID
+func resolveCase(s *storage.PhotoStorage, ref string) (storage.StoredCase, error) {
+	if c, ok := s.Case(ref); ok {
+		return c, nil
+	}
+	if decoded, err := domain.DecodeVerdictID(ref); err == nil {
+		if c, ok := s.Case(decoded); ok {
+			return c, nil
+		}
+	}
+	c, ok, err := s.CaseByRequestID(ref)
+	if err != nil {
+		return storage.StoredCase{}, err
+	}
+	if !ok {
+		return storage.StoredCase{}, fmt.Errorf("no stored case for %q", ref)
+	}
+	return c, nil
+}
+
+// parseDate parses an optional YYYY-MM-DD flag value
+func parseDate(name, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("-%s must be a date (YYYY-MM-DD)", name)
+	}
+	return date, nil
+}
+
+// parseDays parses an age in days, written as "30d" or "30"
+func parseDays(value string) (int, error) {
+	days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+	if err != nil || days < 0 {
+		return 0, fmt.Errorf("invalid age %q (use e.g. 30d)", value)
+	}
+	return days, nil
+}
+
+func runList(s *storage.PhotoStorage, args []string, out io.Writer) error {
+	flags := newFlagSet("list")
+	date := flags.String("date", "", "only cases stored on this date (YYYY-MM-DD)")
+	since := flags.String("since", "", "only cases stored on or after this date")
+	until := flags.String("until", "", "only cases stored on or before this date")
+	verdictType := flags.String("verdict-type", "", "only cases with this verdict type")
+	minScore := flags.Int("min-score", 0, "only cases scoring at least this")
+	maxScore := flags.Int("max-score", 10, "only cases scoring at most this")
+	asJSON := flags.Bool("json", false, "print JSON lines instead of a table")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *date != "" {
+		*since, *until = *date, *date
+	}
+	sinceDate, err := parseDate("since", *since)
+	if err != nil {
+		return err
+	}
+	untilDate, err := parseDate("until", *until)
+	if err != nil {
+		return err
+	}
+
+	entries, err := loadCases(s)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	if !*asJSON {
+		fmt.Fprintln(tw, "ID\tREQUEST ID\tADMISSIBLE\tSCORE\tVERDICT TYPE\tPHOTO")
+	}
+	encoder := json.NewEncoder(out)
+	for _, e := range entries {
+		if (!sinceDate.IsZero() && e.Date.Before(sinceDate)) || (!untilDate.IsZero() && e.Date.After(untilDate)) {
+			continue
+		}
+		if e.Err != nil {
+			if *verdictType == "" && !*asJSON {
+				fmt.Fprintf(tw, "%s\t%s\t-\t-\t(unreadable)\t-\n", e.ID, e.RequestID())
+			}
+			continue
+		}
+		v := e.Verdict
+		if (*verdictType != "" && v.Verdict.VerdictType != *verdictType) || v.Score < *minScore || v.Score > *maxScore {
+			continue
+		}
+
+		photo := "yes"
+		if e.withheld() {
+			photo = "withheld"
+		} else if e.PhotoPath == "" {
+			photo = "missing"
+		}
+
+		if *asJSON {
+			if err := encoder.Encode(map[string]any{
+				"id":          e.ID,
+				"requestId":   e.RequestID(),
+				"timestamp":   v.Timestamp,
+				"admissible":  v.Admissible,
+				"score":       v.Score,
+				"verdictType": v.Verdict.VerdictType,
+				"photo":       photo,
+			}); err != nil {
+				return err
+			}
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%v\t%d\t%s\t%s\n", e.ID, e.RequestID(), v.Admissible, v.Score, v.Verdict.VerdictType, photo)
+	}
+	return tw.Flush()
+}
+
+func runShow(s *storage.PhotoStorage, args []string, out io.Writer) error {
+	flags := newFlagSet("show")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("expected one case")
+	}
+
+	c, err := resolveCase(s, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	files, err := s.CaseFiles(c)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "ID:         %s\n", c.ID)
+	fmt.Fprintf(out, "Request ID: %s\n", c.RequestID())
+	fmt.Fprintf(out, "Share ID:   %s\n", domain.EncodeVerdictID(c.ID))
+	if class, err := s.RetentionClassOf(c); err == nil {
+		fmt.Fprintf(out, "Retention:  %s\n", class)
+	}
+	fmt.Fprintln(out, "Files:")
+	for _, file := range files {
+		size := "?"
+		if info, err := os.Stat(file); err == nil {
+			size = strconv.FormatInt(info.Size(), 10)
+		}
+		fmt.Fprintf(out, "  %s (%s bytes)\n", file, size)
+	}
+
+	data, err := os.ReadFile(c.JSONPath)
+	if err != nil {
+		return fmt.Errorf("failed to read verdict: %w", err)
+	}
+	fmt.Fprintln(out, "Verdict:")
+	var verdict any
+	if err := json.Unmarshal(data, &verdict); err != nil {
+		return fmt.Errorf("failed to parse verdict: %w", err)
+	}
+	pretty, _ := json.MarshalIndent(verdict, "", "  ")
+	fmt.Fprintln(out, string(pretty))
+	return nil
+}
+
+func runDelete(s *storage.PhotoStorage, args []string, out io.Writer) error {
+	flags := newFlagSet("delete")
+	dryRun := flags.Bool("dry-run", false, "only print what would be deleted")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("expected at least one case")
+	}
+
+	// Resolve everything first, so a typo deletes nothing
+	var cases []storage.StoredCase
+	for _, ref := range flags.Args() {
+		c, err := resolveCase(s, ref)
+		if err != nil {
+			return err
+		}
+		if class, err := s.RetentionClassOf(c); err == nil && class == storage.RetentionLegalHold {
+			return fmt.Errorf("%s is on legal hold (release it first)", c.ID)
+		}
+		cases = append(cases, c)
+	}
+
+	for _, c := range cases {
+		if *dryRun {
+			files, err := s.CaseFiles(c)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "would delete %s (%d files)\n", c.ID, len(files))
+			continue
+		}
+		removed, err := s.DeleteCase(c)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "deleted %s (%d files)\n", c.ID, len(removed))
+	}
+	return nil
+}
+
+func runPurge(s *storage.PhotoStorage, args []string, out io.Writer) error {
+	flags := newFlagSet("purge")
+	olderThan := flags.String("older-than", "", "purge unpublished cases older than this many days, e.g. 30d")
+	dryRun := flags.Bool("dry-run", false, "only print what would be purged")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *olderThan == "" {
+		flags.Usage()
+		return errors.New("-older-than is required")
+	}
+	days, err := parseDays(*olderThan)
+	if err != nil {
+		return err
+	}
+
+	// Same cutoff as the server's retention cleanup; published cases and
+	// cases on legal hold are kept
+	now := time.Now()
+	cutoff := now.AddDate(0, 0, -days)
+	purged, err := s.ApplyRetention(context.Background(), storage.RetentionPolicy{InadmissibleDays: days, VerdictDays: days, SharedDays: days}, now, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		fmt.Fprintf(out, "would purge %d cases stored before %s\n", len(purged), cutoff.Format("2006-01-02"))
+		return nil
+	}
+	fmt.Fprintf(out, "purged %d cases stored before %s\n", len(purged), cutoff.Format("2006-01-02"))
+	return nil
+}
+
+// retentionDaysFlag returns the default of a retention flag, "<days>d", from
+// the server's environment variable
+func retentionDaysFlag(key string, defaultDays int) string {
+	return getEnvOrDefault(key, strconv.Itoa(defaultDays)) + "d"
+}
+
+func runRetention(s *storage.PhotoStorage, args []string, out io.Writer) error {
+	flags := newFlagSet("retention")
+	inadmissible := flags.String("inadmissible", retentionDaysFlag("INADMISSIBLE_RETENTION_DAYS", storage.DefaultInadmissibleRetentionDays), "keep unpublished inadmissible cases this many days")
+	verdicts := flags.String("verdicts", retentionDaysFlag("PHOTO_RETENTION_DAYS", storage.DefaultVerdictRetentionDays), "keep other unpublished verdicts this many days")
+	shared := flags.String("shared", retentionDaysFlag("SHARED_RETENTION_DAYS", storage.DefaultSharedRetentionDays), "keep unpublished shared cases this many days from their first share")
+	dryRun := flags.Bool("dry-run", false, "only print what would be purged")
+	asJSON := flags.Bool("json", false, "print JSON lines instead of text")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var policy storage.RetentionPolicy
+	var err error
+	if policy.InadmissibleDays, err = parseDays(*inadmissible); err != nil {
+		return err
+	}
+	if policy.VerdictDays, err = parseDays(*verdicts); err != nil {
+		return err
+	}
+	if policy.SharedDays, err = parseDays(*shared); err != nil {
+		return err
+	}
+
+	purged, err := s.ApplyRetention(context.Background(), policy, time.Now(), *dryRun)
+	if err != nil {
+		return err
+	}
+
+	verb := "purged"
+	if *dryRun {
+		verb = "would purge"
+	}
+	if *asJSON {
+		encoder := json.NewEncoder(out)
+		for _, p := range purged {
+			if err := encoder.Encode(p); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, p := range purged {
+		fmt.Fprintf(out, "%s %s (%s, %d files)\n", verb, p.ID, p.Class, p.Files)
+	}
+	fmt.Fprintf(out, "%s %d cases\n", verb, len(purged))
+	return nil
+}
+
+func runPurgeLog(s *storage.PhotoStorage, args []string, out io.Writer) error {
+	flags := newFlagSet("purge-log")
+	asJSON := flags.Bool("json", false, "print JSON lines instead of a table")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	entries, err := s.PurgeLog()
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		encoder := json.NewEncoder(out)
+		for _, e := range entries {
+			if err := encoder.Encode(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PURGED AT\tID\tCLASS\tREASON\tFILES")
+	for _, e := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\n", e.PurgedAt.Format(time.RFC3339), e.ID, e.Class, e.Reason, e.Files)
+	}
+	return tw.Flush()
+}
+
+// updateCases resolves every case argument first, so a typo changes nothing,
+// then applies update to each
+func updateCases(s *storage.PhotoStorage, name string, args []string, out io.Writer, done string, update func(storage.StoredCase) error) error {
+	flags := newFlagSet(name)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("expected at least one case")
+	}
+
+	var cases []storage.StoredCase
+	for _, ref := range flags.Args() {
+		c, err := resolveCase(s, ref)
+		if err != nil {
+			return err
+		}
+		cases = append(cases, c)
+	}
+	for _, c := range cases {
+		if err := update(c); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%s %s\n", done, c.ID)
+	}
+	return nil
+}
+
+func runPublish(s *storage.PhotoStorage, args []string, out io.Writer) error {
+	return updateCases(s, "publish", args, out, "published", func(c storage.StoredCase) error {
+		return s.SetPublished(c, true, time.Now())
+	})
+}
+
+func runUnpublish(s *storage.PhotoStorage, args []string, out io.Writer) error {
+	return updateCases(s, "unpublish", args, out, "unpublished", func(c storage.StoredCase) error {
+		return s.SetPublished(c, false, time.Time{})
+	})
+}
+
+func runHold(s *storage.PhotoStorage, args []string, out io.Writer) error {
+	return updateCases(s, "hold", args, out, "placed on legal hold:", func(c storage.StoredCase) error {
+		return s.SetLegalHold(c, true)
+	})
+}
+
+func runRelease(s *storage.PhotoStorage, args []string, out io.Writer) error {
+	return updateCases(s, "release", args, out, "released from legal hold:", func(c storage.StoredCase) error {
+		return s.SetLegalHold(c, false)
+	})
+}
+
+func runShareID(s *storage.PhotoStorage, args []string, out io.Writer) error {
+	flags := newFlagSet("share-id")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("expected one request ID")
+	}
+
+	c, ok, err := s.CaseByRequestID(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("no stored case for request %q", flags.Arg(0))
+	}
+
+	// Refuse what POST /v1/verdict/share refuses
+	entry := loadCase(c)
+	if entry.withheld() {
+		return errors.New("verdict was withheld by moderation and cannot be shared")
+	}
+	if c.PhotoPath == "" {
+		return errors.New("photo is missing, the verdict cannot be shared")
+	}
+
+	fmt.Fprintln(out, domain.EncodeVerdictID(c.ID))
+	return nil
+}
+
+// storageStats summarizes the stored cases
+type storageStats struct {
+	Cases        int            `json:"cases"`
+	Unreadable   int            `json:"unreadable"`
+	Withheld     int            `json:"withheld"`
+	Admissible   int            `json:"admissible"`
+	MeanScore    float64        `json:"meanScore"`
+	VerdictTypes map[string]int `json:"verdictTypes"`
+	PerDay       map[string]int `json:"perDay"`
+	Files        int            `json:"files"`
+	Bytes        int64          `json:"bytes"`
+}
+
+func collectStats(s *storage.PhotoStorage) (storageStats, error) {
+	stats := storageStats{VerdictTypes: map[string]int{}, PerDay: map[string]int{}}
+	entries, err := loadCases(s)
+	if err != nil {
+		return stats, err
+	}
+
+	var scoreSum, scored int
+	for _, e := range entries {
+		stats.Cases++
+		stats.PerDay[e.Date.Format("2006-01-02")]++
+
+		files, err := s.CaseFiles(e.StoredCase)
+		if err != nil {
+			return stats, err
+		}
+		for _, file := range files {
+			if info, err := os.Stat(file); err == nil {
+				stats.Files++
+				stats.Bytes += info.Size()
+			}
+		}
+
+		if e.Err != nil {
+			stats.Unreadable++
+			continue
+		}
+		if e.withheld() {
+			stats.Withheld++
+		}
+		if e.Verdict.Admissible {
+			stats.Admissible++
+		}
+		stats.VerdictTypes[e.Verdict.Verdict.VerdictType]++
+		scoreSum += e.Verdict.Score
+		scored++
+	}
+	if scored > 0 {
+		stats.MeanScore = float64(scoreSum) / float64(scored)
+	}
+	return stats, nil
+}
+
+func runStats(s *storage.PhotoStorage, args []string, out io.Writer) error {
+	flags := newFlagSet("stats")
+	asJSON := flags.Bool("json", false, "print JSON instead of text")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	stats, err := collectStats(s)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(stats)
+	}
+
+	fmt.Fprintf(out, "Cases:      %d (%d admissible, %d withheld, %d unreadable)\n", stats.Cases, stats.Admissible, stats.Withheld, stats.Unreadable)
+	fmt.Fprintf(out, "Mean score: %.2f\n", stats.MeanScore)
+	fmt.Fprintf(out, "Disk usage: %d files, %d bytes\n", stats.Files, stats.Bytes)
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(out, "\nVerdict types:")
+	for _, t := range sortedKeys(stats.VerdictTypes) {
+		name := t
+		if name == "" {
+			name = "(none)"
+		}
+		fmt.Fprintf(tw, "  %s\t%d\n", name, stats.VerdictTypes[t])
+	}
+	tw.Flush()
+	fmt.Fprintln(out, "\nPer day:")
+	for _, day := range sortedKeys(stats.PerDay) {
+		fmt.Fprintf(tw, "  %s\t%d\n", day, stats.PerDay[day])
+	}
+	return tw.Flush()
+}
+
+func runVerify(s *storage.PhotoStorage, args []string, out io.Writer) error {
+	flags := newFlagSet("verify")
+	minAge := flags.Duration("min-age", 5*time.Minute, "ignore files younger than this; they may belong to a save in progress")
+	fix := flags.Bool("fix", false, "delete the orphaned files")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	orphans, err := s.FindOrphans(*minAge)
+	if err != nil {
+		return err
+	}
+	if len(orphans) == 0 {
+		fmt.Fprintln(out, "no orphaned files")
+		return nil
+	}
+
+	for _, o := range orphans {
+		if *fix {
+			if err := os.Remove(o.Path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove %s: %w", o.Path, err)
+			}
+			fmt.Fprintf(out, "removed %s (%s)\n", o.Path, o.Reason)
+			continue
+		}
+		fmt.Fprintf(out, "%s: %s\n", o.Path, o.Reason)
+	}
+	if *fix {
+		return nil
+	}
+	return fmt.Errorf("found %d orphaned files (run with -fix to remove them)", len(orphans))
+}
+
+func runExport(s *storage.PhotoStorage, args []string, out io.Writer) error {
+	flags := newFlagSet("export")
+	output := flags.String("o", "", "archive file to write")
+	format := flags.String("format", "", "archive format, tar.gz or zip (default: from the file name)")
+	since := flags.String("since", "", "only cases stored on or after this date")
+	until := flags.String("until", "", "only cases stored on or before this date")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *output == "" {
+		flags.Usage()
+		return errors.New("-o is required")
+	}
+
+	archiveFormat := storage.ArchiveFormat(*format)
+	if *format == "" {
+		archiveFormat = storage.ArchiveTarGz
+		if strings.HasSuffix(*output, ".zip") {
+			archiveFormat = storage.ArchiveZip
+		}
+	}
+	if archiveFormat != storage.ArchiveTarGz && archiveFormat != storage.ArchiveZip {
+		return fmt.Errorf("unknown format %q (use tar.gz or zip)", *format)
+	}
+	filter := storage.ExportFilter{}
+	var err error
+	if filter.Since, err = parseDate("since", *since); err != nil {
+		return err
+	}
+	if filter.Until, err = parseDate("until", *until); err != nil {
+		return err
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	manifest, err := s.ExportArchive(f, archiveFormat, filter)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		// Never leave a truncated archive behind that looks like a backup
+		os.Remove(*output)
+		return err
+	}
+	fmt.Fprintf(out, "exported %d cases to %s\n", len(manifest.Cases), *output)
+	return nil
+}
+
+// quotaFlag returns the default of a quota flag from the server's
+// environment variable; 0 means unlimited
+func quotaFlag(key string) int64 {
+	value, err := strconv.ParseInt(getEnvOrDefault(key, "0"), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+func runImport(s *storage.PhotoStorage, args []string, out io.Writer) error {
+	flags := newFlagSet("import")
+	maxBytes := flags.Int64("max-bytes", quotaFlag("STORAGE_QUOTA_BYTES"), "stop importing before stored cases exceed this many bytes (0: unlimited)")
+	maxFiles := flags.Int64("max-files", quotaFlag("STORAGE_QUOTA_FILES"), "stop importing before stored cases exceed this many files (0: unlimited)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("expected one archive")
+	}
+
+	// Imported cases count against the server's quota like saved ones
+	if *maxBytes > 0 || *maxFiles > 0 {
+		if _, err := s.EnableQuota(storage.QuotaConfig{MaxBytes: *maxBytes, MaxFiles: *maxFiles}); err != nil {
+			return err
+		}
+	}
+
+	result, err := s.ImportArchive(flags.Arg(0))
+	if result != nil {
+		for _, id := range result.Duplicates {
+			fmt.Fprintf(out, "skipped %s (request ID already stored)\n", id)
+		}
+		fmt.Fprintf(out, "imported %d cases, skipped %d duplicates\n", len(result.Imported), len(result.Duplicates))
+	}
+	return err
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// getEnvOrDefault returns the environment variable value or a default
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
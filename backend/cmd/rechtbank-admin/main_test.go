@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"rechtebank/backend/internal/adapters/storage"
+	"rechtebank/backend/internal/core/domain"
+)
+
+var jpegData = []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10, 'J', 'F', 'I', 'F', 0x00, 0x01}
+
+// newStorage writes a small court archive and returns its directory
+func newStorage(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	old := time.Now().AddDate(0, 0, -40).Format("2006-01-02")
+	files := map[string]string{
+		"2026-02-01/153045_req-1.json": `{"admissible":true,"score":8,"verdictType":"vrijspraak","requestId":"req-1"}`,
+		"2026-02-01/153045_req-1.jpg":  string(jpegData),
+		"2026-02-01/160000_req-2.json": `{"admissible":true,"score":3,"verdictType":"schuldig","requestId":"req-2","moderation":{"flagged":true}}`,
+		"2026-02-02/090000_req-3.json": `{"admissible":false,"score":0,"verdictType":"niet-ontvankelijk","requestId":"req-3"}`,
+		"2026-02-02/090000_req-3.jpg":  string(jpegData),
+		"2026-02-02/100000_lost.jpg":   string(jpegData),
+		old + "/120000_req-old.json":   `{"admissible":true,"score":5,"verdictType":"waarschuwing"}`,
+		old + "/120000_req-old.jpg":    string(jpegData),
+	}
+	for path, data := range files {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(path)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, path), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func runAdmin(t *testing.T, dir string, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	err := run(append([]string{"-storage", dir}, args...), &out)
+	return out.String(), err
+}
+
+func TestList(t *testing.T) {
+	dir := newStorage(t)
+
+	out, err := runAdmin(t, dir, "list", "-date", "2026-02-01")
+	if err != nil {
+		t.Fatalf("list error = %v", err)
+	}
+	if !strings.Contains(out, "2026-02-01/153045_req-1") || !strings.Contains(out, "withheld") || strings.Contains(out, "req-3") {
+		t.Errorf("list -date output:\n%s", out)
+	}
+
+	out, err = runAdmin(t, dir, "list", "-verdict-type", "schuldig", "-json")
+	if err != nil {
+		t.Fatalf("list error = %v", err)
+	}
+	var line map[string]any
+	if err := json.Unmarshal([]byte(out), &line); err != nil || line["requestId"] != "req-2" {
+		t.Errorf("list -verdict-type -json output = %q", out)
+	}
+
+	out, _ = runAdmin(t, dir, "list", "-min-score", "6")
+	if !strings.Contains(out, "req-1") || strings.Contains(out, "req-2") || strings.Contains(out, "req-old") {
+		t.Errorf("list -min-score output:\n%s", out)
+	}
+}
+
+func TestShow(t *testing.T) {
+	dir := newStorage(t)
+
+	for _, ref := range []string{"req-1", "2026-02-01/153045_req-1", domain.EncodeVerdictID("2026-02-01/153045_req-1")} {
+		out, err := runAdmin(t, dir, "show", ref)
+		if err != nil {
+			t.Fatalf("show %s error = %v", ref, err)
+		}
+		if !strings.Contains(out, "Request ID: req-1") || !strings.Contains(out, `"verdictType": "vrijspraak"`) {
+			t.Errorf("show %s output:\n%s", ref, out)
+		}
+	}
+
+	if _, err := runAdmin(t, dir, "show", "req-404"); err == nil {
+		t.Error("show of an unknown case: error = nil, want error")
+	}
+}
+
+func TestShareID(t *testing.T) {
+	dir := newStorage(t)
+
+	out, err := runAdmin(t, dir, "share-id", "req-1")
+	if err != nil {
+		t.Fatalf("share-id error = %v", err)
+	}
+	if got, want := strings.TrimSpace(out), domain.EncodeVerdictID("2026-02-01/153045_req-1"); got != want {
+		t.Errorf("share-id = %q, want %q", got, want)
+	}
+
+	if _, err := runAdmin(t, dir, "share-id", "req-2"); err == nil {
+		t.Error("share-id of a withheld verdict: error = nil, want error")
+	}
+}
+
+func TestDelete(t *testing.T) {
+	dir := newStorage(t)
+
+	if _, err := runAdmin(t, dir, "delete", "-dry-run", "req-1"); err != nil {
+		t.Fatalf("delete -dry-run error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2026-02-01/153045_req-1.jpg")); err != nil {
+		t.Error("delete -dry-run removed the photo")
+	}
+
+	// An unknown reference aborts before anything is deleted
+	if _, err := runAdmin(t, dir, "delete", "req-1", "req-404"); err == nil {
+		t.Error("delete with an unknown case: error = nil, want error")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2026-02-01/153045_req-1.jpg")); err != nil {
+		t.Error("delete with an unknown case removed the photo")
+	}
+
+	out, err := runAdmin(t, dir, "delete", "req-1")
+	if err != nil {
+		t.Fatalf("delete error = %v", err)
+	}
+	if !strings.Contains(out, "deleted 2026-02-01/153045_req-1 (2 files)") {
+		t.Errorf("delete output = %q", out)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2026-02-01/153045_req-1.json")); !os.IsNotExist(err) {
+		t.Error("delete kept the verdict")
+	}
+}
+
+func TestPurge(t *testing.T) {
+	dir := newStorage(t)
+
+	// The fixed February 2026 cases are more than 30 days old as well
+	out, err := runAdmin(t, dir, "purge", "-older-than", "10000d", "-dry-run")
+	if err != nil || !strings.Contains(out, "would purge 0 cases") {
+		t.Errorf("purge -dry-run = %q, %v", out, err)
+	}
+
+	out, err = runAdmin(t, dir, "purge", "-older-than", "30d", "-dry-run")
+	if err != nil || !strings.Contains(out, "would purge") || strings.Contains(out, "would purge 0 ") {
+		t.Errorf("purge -dry-run = %q, %v", out, err)
+	}
+
+	before, _ := runAdmin(t, dir, "list")
+	if _, err := runAdmin(t, dir, "purge", "-older-than", "30d"); err != nil {
+		t.Fatalf("purge error = %v", err)
+	}
+	after, _ := runAdmin(t, dir, "list")
+	if !strings.Contains(before, "req-old") || strings.Contains(after, "req-old") {
+		t.Errorf("purge did not remove the old case:\n%s", after)
+	}
+
+	if _, err := runAdmin(t, dir, "purge"); err == nil {
+		t.Error("purge without -older-than: error = nil, want error")
+	}
+}
+
+func TestRetention(t *testing.T) {
+	dir := newStorage(t)
+
+	if out, err := runAdmin(t, dir, "publish", "req-1"); err != nil || !strings.Contains(out, "published 2026-02-01/153045_req-1") {
+		t.Fatalf("publish = %q, %v", out, err)
+	}
+	if _, err := runAdmin(t, dir, "hold", "req-2"); err != nil {
+		t.Fatalf("hold error = %v", err)
+	}
+	if out, _ := runAdmin(t, dir, "show", "req-2"); !strings.Contains(out, "Retention:  legal-hold") {
+		t.Errorf("show output:\n%s", out)
+	}
+
+	// Only the inadmissible case is past its period; req-old is a verdict
+	out, err := runAdmin(t, dir, "retention", "-dry-run")
+	if err != nil || !strings.Contains(out, "would purge 2026-02-02/090000_req-3 (inadmissible, 2 files)") || !strings.Contains(out, "would purge 1 cases") {
+		t.Errorf("retention -dry-run = %q, %v", out, err)
+	}
+	if out, _ := runAdmin(t, dir, "purge-log"); strings.Contains(out, "req-3") {
+		t.Errorf("dry run was logged:\n%s", out)
+	}
+
+	if out, err := runAdmin(t, dir, "retention", "-verdicts", "30d"); err != nil || !strings.Contains(out, "purged 2 cases") {
+		t.Errorf("retention = %q, %v", out, err)
+	}
+	after, _ := runAdmin(t, dir, "list")
+	if strings.Contains(after, "req-3") || strings.Contains(after, "req-old") || !strings.Contains(after, "req-1") || !strings.Contains(after, "req-2") {
+		t.Errorf("retention kept the wrong cases:\n%s", after)
+	}
+	if out, _ := runAdmin(t, dir, "purge-log"); !strings.Contains(out, "2026-02-02/090000_req-3") || !strings.Contains(out, "req-old") {
+		t.Errorf("purge-log output:\n%s", out)
+	}
+
+	// Cases on legal hold cannot be deleted until released
+	if _, err := runAdmin(t, dir, "delete", "req-2"); err == nil {
+		t.Error("delete of a held case: error = nil, want error")
+	}
+	if _, err := runAdmin(t, dir, "release", "req-2"); err != nil {
+		t.Fatalf("release error = %v", err)
+	}
+	if _, err := runAdmin(t, dir, "delete", "req-2"); err != nil {
+		t.Errorf("delete after release error = %v", err)
+	}
+}
+
+func TestStats(t *testing.T) {
+	dir := newStorage(t)
+
+	out, err := runAdmin(t, dir, "stats", "-json")
+	if err != nil {
+		t.Fatalf("stats error = %v", err)
+	}
+	var stats storageStats
+	if err := json.Unmarshal([]byte(out), &stats); err != nil {
+		t.Fatalf("stats output is not JSON: %v", err)
+	}
+	if stats.Cases != 4 || stats.Withheld != 1 || stats.Admissible != 3 || stats.VerdictTypes["schuldig"] != 1 || stats.PerDay["2026-02-02"] != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+	if stats.Files != 7 || stats.Bytes == 0 {
+		t.Errorf("stats files/bytes = %d/%d, want 7 files", stats.Files, stats.Bytes)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	dir := newStorage(t)
+
+	out, err := runAdmin(t, dir, "verify", "-min-age", "0")
+	if err == nil {
+		t.Error("verify with orphans: error = nil, want error")
+	}
+	if !strings.Contains(out, "100000_lost.jpg: photo without verdict") || strings.Contains(out, "req-2") {
+		t.Errorf("verify output:\n%s", out)
+	}
+
+	if _, err := runAdmin(t, dir, "verify", "-min-age", "0", "-fix"); err != nil {
+		t.Fatalf("verify -fix error = %v", err)
+	}
+	out, err = runAdmin(t, dir, "verify", "-min-age", "0")
+	if err != nil || !strings.Contains(out, "no orphaned files") {
+		t.Errorf("verify after -fix = %q, %v", out, err)
+	}
+}
+
+func TestRun_Usage(t *testing.T) {
+	dir := newStorage(t)
+
+	if _, err := runAdmin(t, dir); err == nil {
+		t.Error("no command: error = nil, want error")
+	}
+	if _, err := runAdmin(t, dir, "explode"); err == nil {
+		t.Error("unknown command: error = nil, want error")
+	}
+	if _, err := runAdmin(t, filepath.Join(dir, "missing"), "list"); err == nil {
+		t.Error("missing storage directory: error = nil, want error")
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	dir := newStorage(t)
+	archive := filepath.Join(t.TempDir(), "backup.zip")
+
+	out, err := runAdmin(t, dir, "export", "-o", archive, "-since", "2026-02-01", "-until", "2026-02-02")
+	if err != nil || !strings.Contains(out, "exported 3 cases") {
+		t.Fatalf("export = %q, %v", out, err)
+	}
+
+	target := t.TempDir()
+	out, err = runAdmin(t, target, "import", archive)
+	if err != nil || !strings.Contains(out, "imported 3 cases, skipped 0 duplicates") {
+		t.Fatalf("import = %q, %v", out, err)
+	}
+	if _, err := os.Stat(filepath.Join(target, "2026-02-01/153045_req-1.jpg")); err != nil {
+		t.Errorf("import did not restore the photo: %v", err)
+	}
+
+	out, err = runAdmin(t, target, "import", archive)
+	if err != nil || !strings.Contains(out, "imported 0 cases, skipped 3 duplicates") {
+		t.Errorf("second import = %q, %v", out, err)
+	}
+
+	// The first case's verdict and photo fill the quota
+	full := t.TempDir()
+	out, err = runAdmin(t, full, "import", "-max-files", "2", archive)
+	if !errors.Is(err, storage.ErrStorageFull) || !strings.Contains(out, "imported 1 cases") {
+		t.Errorf("import over quota = %q, %v", out, err)
+	}
+
+	if _, err := runAdmin(t, dir, "export", "-o", archive, "-format", "rar"); err == nil {
+		t.Error("export with an unknown format: error = nil, want error")
+	}
+	if _, err := runAdmin(t, dir, "export"); err == nil {
+		t.Error("export without -o: error = nil, want error")
+	}
+}
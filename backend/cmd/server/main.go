@@ -4,23 +4,40 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
 	"rechtebank/backend/internal/adapters/gemini"
 	httpAdapter "rechtebank/backend/internal/adapters/http"
 	"rechtebank/backend/internal/adapters/http/handlers"
+	"rechtebank/backend/internal/adapters/imaging"
 	"rechtebank/backend/internal/adapters/storage"
 	"rechtebank/backend/internal/adapters/validator"
 	"rechtebank/backend/internal/config"
+	"rechtebank/backend/internal/core/domain"
+	"rechtebank/backend/internal/core/ports"
 	"rechtebank/backend/internal/core/services"
+	"rechtebank/backend/internal/health"
+	"rechtebank/backend/internal/logging"
+	"rechtebank/backend/internal/scheduler"
+	"rechtebank/backend/internal/telemetry"
 
 	"github.com/gin-gonic/gin"
 )
 
+// partialCaseMinAge keeps the partial case scans away from files another
+// replica sharing the volume may still be saving
+const partialCaseMinAge = time.Minute
+
+// jobDisabled is the schedule that turns a background job off
+const jobDisabled = "off"
+
 func main() {
 	// Load configuration
 	cfg, err := config.Load()
@@ -28,47 +45,256 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	// Configure structured logging
+	logLevel, err := logging.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		log.Fatalf("Failed to configure logging: %v", err)
+	}
+	componentLevels, err := logging.ParseComponentLevels(cfg.LogComponentLevels)
+	if err != nil {
+		log.Fatalf("Failed to configure logging: %v", err)
+	}
+	logging.Setup(logging.Config{
+		Format:          cfg.LogFormat,
+		Level:           logLevel,
+		ComponentLevels: componentLevels,
+	})
+
 	// Set Gin mode based on environment
 	if !cfg.IsDevelopment() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
 	// Log startup information
-	log.Printf("Starting Rechtebank Backend API")
-	log.Printf("  Port: %s", cfg.Port)
-	log.Printf("  Environment: %s", cfg.Environment)
-	log.Printf("  CORS Origin: %s", cfg.CORSOrigin)
-	log.Printf("  Gemini Timeout: %s", cfg.GeminiTimeout)
-	log.Printf("  Photo Storage: %s", cfg.PhotoStoragePath)
-	log.Printf("  Photo Retention: %d days", cfg.PhotoRetentionDays)
+	slog.Info("Starting Rechtebank Backend API",
+		slog.String("port", cfg.Port),
+		slog.String("environment", cfg.Environment),
+		slog.String("cors_origin", cfg.CORSOrigin),
+		slog.Duration("gemini_timeout", cfg.GeminiTimeout),
+		slog.String("photo_storage", cfg.PhotoStoragePath),
+		slog.String("save_queue", cfg.SaveQueuePath),
+		slog.Int("photo_retention_days", cfg.PhotoRetentionDays),
+		slog.Int("inadmissible_retention_days", cfg.InadmissibleRetentionDays),
+		slog.Bool("retention_dry_run", cfg.RetentionDryRun),
+		slog.String("otlp_endpoint", cfg.OTLPEndpoint),
+		slog.String("log_level", logLevel.String()))
+
+	// Initialize tracing (no-op exporter when no OTLP endpoint is configured)
+	shutdownTracing, err := telemetry.Setup(context.Background(), telemetry.Config{
+		Endpoint:    cfg.OTLPEndpoint,
+		ServiceName: cfg.TraceServiceName,
+		SampleRatio: cfg.TraceSampleRatio,
+	})
+	if err != nil {
+		fatal("Failed to initialize tracing", err)
+	}
 
 	// Initialize dependencies
 	// 1. Validator
-	photoValidator := validator.NewPhotoValidator()
+	photoValidator := validator.NewPhotoValidatorWithLimits(validator.Limits{
+		MaxFileSize:  cfg.MaxFileSize,
+		MaxPixels:    cfg.MaxImagePixels,
+		MaxDimension: cfg.MaxImageDimension,
+		Quality: validator.QualityThresholds{
+			MinBrightness: cfg.QualityMinBrightness,
+			MaxBrightness: cfg.QualityMaxBrightness,
+			MinContrast:   cfg.QualityMinContrast,
+			MinSharpness:  cfg.QualityMinSharpness,
+		},
+	})
+
+	// 2. Photo Sanitizer (strips EXIF/GPS, applies orientation)
+	photoSanitizer := imaging.NewSanitizer(cfg.RecordCaptureMetadata)
 
-	// 2. Gemini Analyzer
-	geminiAnalyzer, err := gemini.NewGeminiAnalyzer(cfg.GeminiAPIKey, cfg.GeminiTimeout)
+	// 3. Gemini Analyzer
+	geminiAnalyzer, err := gemini.NewGeminiAnalyzerWithCompression(cfg.GeminiAPIKey, cfg.GeminiTimeout, gemini.CompressionPolicy{
+		MaxDimension:  cfg.CompressionMaxDimension,
+		Quality:       cfg.CompressionQuality,
+		MinQuality:    cfg.CompressionMinQuality,
+		MaxBytes:      cfg.CompressionMaxBytes,
+		MaxTokens:     cfg.CompressionMaxTokens,
+		ConvertToJPEG: cfg.CompressionConvertJPEG,
+		Adaptive:      cfg.CompressionAdaptive,
+	})
 	if err != nil {
-		log.Fatalf("Failed to initialize Gemini analyzer: %v", err)
+		fatal("Failed to initialize Gemini analyzer", err)
 	}
 	defer geminiAnalyzer.Close()
 
-	// 3. Photo Storage
-	photoStorage, err := storage.NewPhotoStorage(cfg.PhotoStoragePath)
+	// 4. Photo Storage
+	photoStorage, err := storage.NewPhotoStorageWithVariants(cfg.PhotoStoragePath, storage.VariantConfig{
+		Widths:  cfg.PhotoVariantWidths,
+		Formats: variantFormats(cfg.PhotoVariantFormats),
+	})
 	if err != nil {
-		log.Fatalf("Failed to initialize photo storage: %v", err)
+		fatal("Failed to initialize photo storage", err)
 	}
 
-	// 4. Verdict Service
-	verdictService := services.NewVerdictService(geminiAnalyzer, photoValidator)
+	// Photos used to be stored as .jpg whatever their format; fix their extensions
+	if migrated, err := photoStorage.MigratePhotoFormats(); err != nil {
+		slog.Warn("Failed to migrate photo formats", slog.Any("error", err))
+	} else if migrated > 0 {
+		slog.Info("Migrated mislabelled photos", slog.Int("count", migrated))
+	}
+
+	// A crash mid-save can leave a photo without its verdict; move such
+	// partial cases aside before serving them
+	if quarantined, err := photoStorage.QuarantinePartialCases(context.Background(), partialCaseMinAge); err != nil {
+		slog.Warn("Failed to quarantine partial cases", slog.Any("error", err))
+	} else if len(quarantined) > 0 {
+		slog.Warn("Quarantined partial cases", slog.Int("files", len(quarantined)))
+	}
+
+	// Past the quota, judging goes on but verdicts are no longer stored
+	usage, err := photoStorage.EnableQuota(storage.QuotaConfig{
+		MaxBytes:  cfg.StorageQuotaBytes,
+		MaxFiles:  cfg.StorageQuotaFiles,
+		HighWater: cfg.StorageQuotaHighWater,
+		LowWater:  cfg.StorageQuotaLowWater,
+	})
+	if err != nil {
+		fatal("Failed to count stored cases", err)
+	}
+	slog.Info("Storage usage", slog.Int64("bytes", usage.Bytes), slog.Int64("files", usage.Files),
+		slog.Bool("degraded", photoStorage.Degraded()))
+
+	// Saves go through a write-ahead queue, so a verdict returned to a client
+	// survives storage failures and restarts. Replicas sharing the queue
+	// directory claim each save with a lease before attempting it.
+	hostname, _ := os.Hostname()
+	holder := fmt.Sprintf("%s/%d", hostname, os.Getpid())
+	saveLocker, err := scheduler.NewFileLocker(filepath.Join(cfg.SaveQueuePath, ".leases"), holder)
+	if err != nil {
+		fatal("Failed to initialize save queue leases", err)
+	}
+	saveQueue, err := storage.NewSaveQueue(cfg.SaveQueuePath, photoStorage, storage.QueueConfig{
+		MaxAttempts:    cfg.SaveQueueMaxAttempts,
+		InitialBackoff: cfg.SaveQueueInitialBackoff,
+		MaxBackoff:     cfg.SaveQueueMaxBackoff,
+		Locker:         saveLocker,
+	})
+	if err != nil {
+		fatal("Failed to initialize save queue", err)
+	}
+	if pending := saveQueue.Len(); pending > 0 {
+		slog.Info("Resuming queued saves", slog.Int("count", pending))
+	}
+	saveQueue.Start()
+
+	// 5. Content moderation, classifying each photo in a separate Gemini request
+	var contentModerator ports.IContentModerator
+	if cfg.ModerationEnabled {
+		threshold, ok := domain.ParseHarmProbability(cfg.ModerationThreshold)
+		if !ok {
+			fatal("Invalid MODERATION_THRESHOLD", fmt.Errorf("%q is not one of negligible, low, medium, high", cfg.ModerationThreshold))
+		}
+		contentModerator = gemini.NewSafetyRatingModerator(geminiAnalyzer, threshold)
+	}
+
+	// 6. Verdict Service
+	verdictService := services.NewVerdictService(geminiAnalyzer, photoValidator, photoSanitizer, contentModerator)
+
+	// 7. Background jobs, run by one replica at a time under a lock file on
+	// the shared volume
+	jobLocker, err := scheduler.NewFileLocker(cfg.JobLockPath, holder)
+	if err != nil {
+		fatal("Failed to initialize job locks", err)
+	}
+	jobs := scheduler.New(jobLocker)
+	cleanupTracker := health.NewJobTracker()
+	retentionPolicy := storage.RetentionPolicy{
+		InadmissibleDays: cfg.InadmissibleRetentionDays,
+		VerdictDays:      cfg.PhotoRetentionDays,
+		SharedDays:       cfg.SharedRetentionDays,
+	}
+
+	cleanupSchedule := scheduleJob(jobs, cfg.CleanupSchedule, scheduler.Job{
+		Name:       "cleanup",
+		Jitter:     cfg.JobJitter,
+		RunOnStart: true,
+		Tracker:    cleanupTracker,
+		Run: func(ctx context.Context) (string, error) {
+			purged, err := photoStorage.ApplyRetention(ctx, retentionPolicy, time.Now(), cfg.RetentionDryRun)
+			if err != nil {
+				return "", err
+			}
+
+			// Pick up cases the admin CLI has removed or imported since
+			if _, err := photoStorage.RecountUsage(); err != nil {
+				slog.Warn("Failed to recount storage usage", slog.Any("error", err))
+			}
 
-	// 5. HTTP Handlers
-	judgeHandler := handlers.NewJudgeHandler(verdictService, photoStorage)
-	verdictHandler := handlers.NewVerdictHandler(cfg.PhotoStoragePath)
+			if cfg.RetentionDryRun {
+				for _, p := range purged {
+					slog.Info("Retention would purge case", slog.String("id", p.ID), slog.String("class", string(p.Class)))
+				}
+				return fmt.Sprintf("dry run, would purge %d cases", len(purged)), nil
+			}
+			return fmt.Sprintf("purged %d cases", len(purged)), nil
+		},
+	})
 
-	// 6. Router
-	router := httpAdapter.NewRouter(judgeHandler, verdictHandler, httpAdapter.RouterConfig{
+	scheduleJob(jobs, cfg.CompactionSchedule, scheduler.Job{
+		Name:   "compaction",
+		Jitter: cfg.JobJitter,
+		Run: func(ctx context.Context) (string, error) {
+			quarantined, err := photoStorage.QuarantinePartialCases(ctx, partialCaseMinAge)
+			if err != nil {
+				return "", err
+			}
+			removed, err := photoStorage.RemoveEmptyDays()
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("quarantined %d files, removed %d empty days", len(quarantined), removed), nil
+		},
+	})
+
+	scheduleJob(jobs, cfg.VariantBackfillSchedule, scheduler.Job{
+		Name:   "variant-backfill",
+		Jitter: cfg.JobJitter,
+		Run: func(ctx context.Context) (string, error) {
+			written, err := photoStorage.BackfillVariants(ctx)
+			return fmt.Sprintf("wrote %d variants", written), err
+		},
+	})
+
+	scheduleJob(jobs, cfg.StatsRollupSchedule, scheduler.Job{
+		Name:   "stats-rollup",
+		Jitter: cfg.JobJitter,
+		Run: func(ctx context.Context) (string, error) {
+			rollup, err := photoStorage.RollupStats(ctx, time.Now())
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%d cases", rollup.Cases), nil
+		},
+	})
+
+	// 8. Readiness checks
+	readinessChecks := []health.Check{
+		health.StorageWritable(cfg.PhotoStoragePath),
+		health.DiskSpace(cfg.PhotoStoragePath, uint64(cfg.HealthMinFreeDiskBytes)),
+		health.NewCachedProbe("analyzer", cfg.HealthAnalyzerProbeTTL, geminiAnalyzer.Ping),
+	}
+	if cleanupSchedule != nil {
+		// Allow one missed run before reporting the cleanup job as stale
+		readinessChecks = append(readinessChecks,
+			health.JobFreshness("cleanup", cleanupTracker, 2*scheduler.Period(cleanupSchedule, time.Now())+cfg.JobJitter))
+	}
+	readinessChecker := health.NewChecker(5*time.Second, readinessChecks...)
+
+	// 9. HTTP Handlers
+	judgeHandler := handlers.NewJudgeHandler(verdictService, saveQueue, cfg.MaxFileSize)
+	verdictHandler := handlers.NewVerdictHandler(cfg.PhotoStoragePath, photoStorage, saveQueue, photoStorage)
+	healthHandler := handlers.NewHealthHandler(readinessChecker)
+	adminHandler := handlers.NewAdminHandler(jobs, photoStorage)
+
+	// 10. Router
+	router := httpAdapter.NewRouter(judgeHandler, verdictHandler, healthHandler, httpAdapter.RouterConfig{
 		CORSOrigin: cfg.CORSOrigin,
+		Admin:      adminHandler,
+		AdminToken: cfg.AdminToken,
 	})
 
 	// Create HTTP server
@@ -80,43 +306,13 @@ func main() {
 		IdleTimeout:  120 * time.Second,
 	}
 
-	// Create context for cleanup goroutine
-	cleanupCtx, cleanupCancel := context.WithCancel(context.Background())
-	defer cleanupCancel()
-
-	// Start cleanup job in background
-	go func() {
-		ticker := time.NewTicker(24 * time.Hour) // Run daily
-		defer ticker.Stop()
-
-		// Run once on startup
-		if err := photoStorage.CleanupOldPhotos(cfg.PhotoRetentionDays); err != nil {
-			log.Printf("Warning: Failed to cleanup old photos: %v", err)
-		} else {
-			log.Printf("Photo cleanup completed successfully")
-		}
-
-		// Then run daily
-		for {
-			select {
-			case <-cleanupCtx.Done():
-				log.Println("Cleanup job stopped")
-				return
-			case <-ticker.C:
-				if err := photoStorage.CleanupOldPhotos(cfg.PhotoRetentionDays); err != nil {
-					log.Printf("Warning: Failed to cleanup old photos: %v", err)
-				} else {
-					log.Printf("Photo cleanup completed successfully")
-				}
-			}
-		}
-	}()
+	jobs.Start()
 
 	// Start server in goroutine
 	go func() {
-		log.Printf("Server listening on port %s", cfg.Port)
+		slog.Info("Server listening", slog.String("port", cfg.Port))
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server error: %v", err)
+			fatal("Server error", err)
 		}
 	}()
 
@@ -125,18 +321,70 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("Shutting down server...")
+	slog.Info("Shutting down server...")
 
-	// Cancel cleanup goroutine
-	cleanupCancel()
+	// Cancel running background jobs
+	jobs.Stop()
 
 	// Give outstanding requests 30 seconds to complete
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		fatal("Server forced to shutdown", err)
+	}
+
+	// Try to store the queued verdicts; whatever is left is saved on the
+	// next start
+	if err := saveQueue.Close(ctx); err != nil {
+		slog.Error("Queued saves did not finish", slog.Any("error", err))
+	}
+
+	// Flush any spans still buffered in the exporter
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Warn("Failed to flush traces", slog.Any("error", err))
+	}
+
+	slog.Info("Server exited gracefully")
+}
+
+// fatal logs an error and exits the process
+func fatal(msg string, err error) {
+	slog.Error(msg, slog.Any("error", err))
+	os.Exit(1)
+}
+
+// scheduleJob adds job to jobs on the schedule spec and returns the parsed
+// schedule, or nil when spec is "off"
+func scheduleJob(jobs *scheduler.Scheduler, spec string, job scheduler.Job) scheduler.Schedule {
+	if spec == jobDisabled {
+		slog.Info("Background job disabled", slog.String("job", job.Name))
+		return nil
+	}
+	schedule, err := scheduler.ParseSchedule(spec)
+	if err != nil {
+		fatal("Invalid schedule for job "+job.Name, err)
+	}
+	job.Schedule = schedule
+	if err := jobs.Add(job, spec); err != nil {
+		fatal("Failed to schedule job "+job.Name, err)
 	}
+	return schedule
+}
 
-	log.Println("Server exited gracefully")
+// variantFormats maps PHOTO_VARIANT_FORMATS names to photo formats, skipping
+// formats variants cannot be stored in
+func variantFormats(names []string) []domain.PhotoFormat {
+	var formats []domain.PhotoFormat
+	for _, name := range names {
+		switch strings.ToLower(name) {
+		case "webp":
+			formats = append(formats, domain.PhotoFormatWebP)
+		case "jpeg", "jpg":
+			formats = append(formats, domain.PhotoFormatJPEG)
+		default:
+			slog.Warn("Ignoring unsupported photo variant format", slog.String("format", name))
+		}
+	}
+	return formats
 }
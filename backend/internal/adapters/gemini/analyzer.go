@@ -5,12 +5,16 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
 
+	"rechtebank/backend/internal/adapters/imaging"
 	"rechtebank/backend/internal/core/domain"
+	"rechtebank/backend/internal/logging"
+	"rechtebank/backend/internal/telemetry"
 
 	"github.com/google/generative-ai-go/genai"
+	"go.opentelemetry.io/otel/attribute"
 	"google.golang.org/api/option"
 )
 
@@ -120,8 +124,13 @@ const systemPrompt = `Je bent de Eerwaarde Rechter van de Meubilair-rechtbank (R
 
 WEES CREATIEF, HUMORISTISCH EN OVERDREVEN FORMEEL IN JE JURIDISCHE TAALGEBRUIK, MAAR HOUD DE JURISDICTIE STRIKT BEPERKT TOT MEUBELS!`
 
+var logger = logging.Component(logging.ComponentGemini)
+
 const userPrompt = "Analyseer dit meubelstuk en spreek je vonnis uit."
 
+// ModelName is the Gemini model that judges photos
+const ModelName = "gemini-2.5-flash-lite"
+
 // GetSystemPrompt returns the system prompt used by the Gemini analyzer
 func GetSystemPrompt() string {
 	return systemPrompt
@@ -145,19 +154,40 @@ type VerdictSchema struct {
 
 // RealGeminiClient wraps the actual Gemini API client
 type RealGeminiClient struct {
-	client *genai.Client
-	model  *genai.GenerativeModel
+	client      *genai.Client
+	model       *genai.GenerativeModel
+	classifier  *genai.GenerativeModel
+	compression CompressionPolicy
+	metrics     *compressionMetrics
+}
+
+// ModelConfig selects the Gemini model and the system prompt photos are
+// judged with
+type ModelConfig struct {
+	Name         string
+	SystemPrompt string
+}
+
+// DefaultModelConfig returns the model and prompt used in production
+func DefaultModelConfig() ModelConfig {
+	return ModelConfig{Name: ModelName, SystemPrompt: systemPrompt}
 }
 
 // NewRealGeminiClient creates a new client connected to the Gemini API
 func NewRealGeminiClient(ctx context.Context, apiKey string) (*RealGeminiClient, error) {
+	return NewRealGeminiClientWithModel(ctx, apiKey, DefaultModelConfig())
+}
+
+// NewRealGeminiClientWithModel creates a new client that judges photos with
+// the given model and system prompt
+func NewRealGeminiClientWithModel(ctx context.Context, apiKey string, modelConfig ModelConfig) (*RealGeminiClient, error) {
 	client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Gemini client: %w", err)
 	}
 
-	model := client.GenerativeModel("gemini-2.5-flash-lite")
-	model.SystemInstruction = genai.NewUserContent(genai.Text(systemPrompt))
+	model := client.GenerativeModel(modelConfig.Name)
+	model.SystemInstruction = genai.NewUserContent(genai.Text(modelConfig.SystemPrompt))
 
 	// Configure JSON schema for structured output
 	model.ResponseMIMEType = "application/json"
@@ -172,82 +202,177 @@ func NewRealGeminiClient(ctx context.Context, apiKey string) (*RealGeminiClient,
 			"observation": {Type: genai.TypeString},
 			"verdictType": {
 				Type: genai.TypeString,
-				Enum: []string{"vrijspraak", "waarschuwing", "schuldig"},
+				Enum: domain.VerdictTypes,
 			},
 		},
 		Required: []string{"admissible", "score", "crime", "sentence", "reasoning", "observation", "verdictType"},
 	}
 
+	// Photos are classified for moderation in a separate request, as the
+	// safety ratings of an analysis only score the verdict text
+	classifier := client.GenerativeModel(modelConfig.Name)
+	classifier.SystemInstruction = genai.NewUserContent(genai.Text(classificationPrompt))
+	classifier.ResponseMIMEType = "application/json"
+	classifier.ResponseSchema = classificationSchema()
+
+	metrics, err := newCompressionMetrics()
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to create compression metrics: %w", err)
+	}
+
 	return &RealGeminiClient{
-		client: client,
-		model:  model,
+		client:      client,
+		model:       model,
+		classifier:  classifier,
+		compression: DefaultCompressionPolicy(),
+		metrics:     metrics,
 	}, nil
 }
 
 // GenerateContent sends an image to Gemini and returns the verdict
 func (c *RealGeminiClient) GenerateContent(ctx context.Context, imageData []byte) (*GeminiResponse, error) {
 	// Compress image before sending to API
-	compressedData, err := compressImage(imageData)
-	if err != nil {
-		log.Printf("[GEMINI] Compression error (using original): %v", err)
-		compressedData = imageData
-	}
+	_, compressSpan := telemetry.StartSpan(ctx, "compressImage",
+		attribute.Int("image.original_size", len(imageData)))
+	compression := c.compression.Compress(imageData)
+	compressedData := compression.Data
+	compressSpan.SetAttributes(
+		attribute.Int("image.compressed_size", len(compressedData)),
+		attribute.String("image.format", compression.Format),
+		attribute.Int("image.width", compression.Width),
+		attribute.Int("image.height", compression.Height),
+		attribute.Int("image.quality", compression.Quality),
+		attribute.Int("image.compression_attempts", compression.Attempts),
+		attribute.Int("image.estimated_tokens", compression.EstimatedTokens),
+		attribute.Bool("image.over_budget", compression.OverBudget))
+	compressSpan.End()
+	c.metrics.record(ctx, compression)
 
 	// Detect MIME type from compressed image
 	mimeType := detectMIMEType(compressedData)
 	if mimeType == "" {
-		return nil, errors.New("unsupported image format")
+		return nil, domain.NewError(domain.ErrCodeUnsupportedFormat, "unsupported image format")
 	}
 
-	log.Printf("[GEMINI] Sending to API: size=%d bytes, mimeType=%s", len(compressedData), mimeType)
+	logger.InfoContext(ctx, "Sending to API", slog.Int("size", len(compressedData)), slog.String("mime_type", mimeType))
 
 	resp, err := c.model.GenerateContent(ctx,
 		genai.ImageData(mimeType, compressedData),
 		genai.Text(userPrompt),
 	)
 	if err != nil {
-		log.Printf("[GEMINI] API error: %v", err)
+		var blockedErr *genai.BlockedError
+		if errors.As(err, &blockedErr) {
+			logger.WarnContext(ctx, "Content blocked by API", slog.String("reason", blockedErr.Error()))
+			return &GeminiResponse{Blocked: true, SafetyRatings: blockedRatings(blockedErr)}, nil
+		}
+		logger.ErrorContext(ctx, "API error", slog.Any("error", err))
 		return nil, err
 	}
 
+	var usage *domain.TokenUsage
+	if resp.UsageMetadata != nil {
+		usage = &domain.TokenUsage{
+			PromptTokens:   int(resp.UsageMetadata.PromptTokenCount),
+			ResponseTokens: int(resp.UsageMetadata.CandidatesTokenCount),
+		}
+		logger.InfoContext(ctx, "Token usage",
+			slog.Int("prompt_tokens", usage.PromptTokens),
+			slog.Int("estimated_image_tokens", compression.EstimatedTokens),
+			slog.Int("response_tokens", usage.ResponseTokens))
+	}
+
 	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
-		log.Printf("[GEMINI] Empty response from API")
+		logger.ErrorContext(ctx, "Empty response from API")
 		return nil, &InvalidResponseError{Message: "empty response from Gemini"}
 	}
 
 	// Extract text from response
 	textPart, ok := resp.Candidates[0].Content.Parts[0].(genai.Text)
 	if !ok {
-		log.Printf("[GEMINI] Unexpected response format")
+		logger.ErrorContext(ctx, "Unexpected response format")
 		return nil, &InvalidResponseError{Message: "unexpected response format"}
 	}
 
 	// Store raw JSON
 	rawJSON := string(textPart)
-	log.Printf("[GEMINI] Raw API response: %s", rawJSON)
+	logger.DebugContext(ctx, "Raw API response", logging.Sensitive("raw_json", rawJSON))
 
 	// Parse JSON response
 	var schema VerdictSchema
 	if err := json.Unmarshal([]byte(textPart), &schema); err != nil {
-		log.Printf("[GEMINI] Failed to parse JSON: %v", err)
+		logger.ErrorContext(ctx, "Failed to parse JSON", slog.Any("error", err))
 		return nil, &InvalidResponseError{Message: fmt.Sprintf("failed to parse response: %v", err)}
 	}
 
-	log.Printf("[GEMINI] Parsed verdict: admissible=%v, score=%d, crime=%s, verdictType=%s",
-		schema.Admissible, schema.Score, schema.Crime, schema.VerdictType)
+	logger.InfoContext(ctx, "Parsed verdict",
+		slog.Bool("admissible", schema.Admissible),
+		slog.Int("score", schema.Score),
+		logging.Sensitive("crime", schema.Crime),
+		slog.String("verdict_type", schema.VerdictType))
+
+	var ratings []*genai.SafetyRating
+	if resp.PromptFeedback != nil {
+		ratings = append(ratings, resp.PromptFeedback.SafetyRatings...)
+	}
+	ratings = append(ratings, resp.Candidates[0].SafetyRatings...)
 
 	return &GeminiResponse{
-		Admissible:  schema.Admissible,
-		Score:       schema.Score,
-		Crime:       schema.Crime,
-		Sentence:    schema.Sentence,
-		Reasoning:   schema.Reasoning,
-		Observation: schema.Observation,
-		VerdictType: schema.VerdictType,
-		RawJSON:     rawJSON,
+		Admissible:    schema.Admissible,
+		Score:         schema.Score,
+		Crime:         schema.Crime,
+		Sentence:      schema.Sentence,
+		Reasoning:     schema.Reasoning,
+		Observation:   schema.Observation,
+		VerdictType:   schema.VerdictType,
+		RawJSON:       rawJSON,
+		SafetyRatings: convertSafetyRatings(ratings),
+		Usage:         usage,
 	}, nil
 }
 
+// ClassifyImage rates the photo itself in each moderated harm category. A
+// photo Gemini refuses to classify is returned as a blocked rating.
+func (c *RealGeminiClient) ClassifyImage(ctx context.Context, imageData []byte) ([]domain.SafetyRating, error) {
+	compressedData := c.compression.Compress(imageData).Data
+	mimeType := detectMIMEType(compressedData)
+	if mimeType == "" {
+		return nil, domain.NewError(domain.ErrCodeUnsupportedFormat, "unsupported image format")
+	}
+
+	resp, err := c.classifier.GenerateContent(ctx,
+		genai.ImageData(mimeType, compressedData),
+		genai.Text(classificationRequest),
+	)
+	if err != nil {
+		var blockedErr *genai.BlockedError
+		if errors.As(err, &blockedErr) {
+			logger.WarnContext(ctx, "Classification blocked by API", slog.String("reason", blockedErr.Error()))
+			return blockedRatings(blockedErr), nil
+		}
+		return nil, err
+	}
+
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
+		return nil, &InvalidResponseError{Message: "empty classification from Gemini"}
+	}
+	textPart, ok := resp.Candidates[0].Content.Parts[0].(genai.Text)
+	if !ok {
+		return nil, &InvalidResponseError{Message: "unexpected classification format"}
+	}
+	return parseClassification([]byte(textPart))
+}
+
+// Ping verifies that the API is reachable and the API key is accepted, using
+// a token count request that does not generate content
+func (c *RealGeminiClient) Ping(ctx context.Context) error {
+	if _, err := c.model.CountTokens(ctx, genai.Text(userPrompt)); err != nil {
+		return fmt.Errorf("Gemini API unreachable: %w", err)
+	}
+	return nil
+}
+
 // Close closes the Gemini client
 func (c *RealGeminiClient) Close() error {
 	return c.client.Close()
@@ -274,20 +399,36 @@ func detectMIMEType(data []byte) string {
 		return "webp"
 	}
 
-	return ""
+	// HEIC, HEIF, AVIF: ftyp box with an image brand
+	return imaging.HEIFFormat(data)
 }
 
-// NewGeminiAnalyzer creates a new GeminiAnalyzer with the given API key
+// NewGeminiAnalyzer creates a new GeminiAnalyzer with the given API key that
+// compresses photos with the default compression policy
 func NewGeminiAnalyzer(apiKey string, timeout time.Duration) (*GeminiAnalyzer, error) {
+	return NewGeminiAnalyzerWithCompression(apiKey, timeout, DefaultCompressionPolicy())
+}
+
+// NewGeminiAnalyzerWithCompression creates a new GeminiAnalyzer that
+// compresses photos with the given policy before sending them
+func NewGeminiAnalyzerWithCompression(apiKey string, timeout time.Duration, compression CompressionPolicy) (*GeminiAnalyzer, error) {
+	return NewGeminiAnalyzerWithModel(apiKey, timeout, compression, DefaultModelConfig())
+}
+
+// NewGeminiAnalyzerWithModel creates a new GeminiAnalyzer that judges photos
+// with the given model and system prompt, e.g. to try a new prompt on
+// stored cases
+func NewGeminiAnalyzerWithModel(apiKey string, timeout time.Duration, compression CompressionPolicy, model ModelConfig) (*GeminiAnalyzer, error) {
 	if apiKey == "" {
 		return nil, errors.New("GEMINI_API_KEY environment variable is required")
 	}
 
 	ctx := context.Background()
-	client, err := NewRealGeminiClient(ctx, apiKey)
+	client, err := NewRealGeminiClientWithModel(ctx, apiKey, model)
 	if err != nil {
 		return nil, err
 	}
+	client.compression = compression
 
 	return &GeminiAnalyzer{
 		realClient: client,
@@ -314,6 +455,16 @@ type GeminiResponse struct {
 	Observation string
 	VerdictType string
 	RawJSON     string // The raw JSON string from Gemini
+
+	// SafetyRatings are Gemini's classifications of the prompt and response
+	SafetyRatings []domain.SafetyRating
+
+	// Blocked is set when Gemini refused to answer on safety grounds; the
+	// verdict fields are empty then
+	Blocked bool
+
+	// Usage is the token count Gemini reported for the request
+	Usage *domain.TokenUsage
 }
 
 // GeminiClientInterface defines the interface for the Gemini client
@@ -351,10 +502,17 @@ func (a *GeminiAnalyzer) AnalyzePhoto(ctx context.Context, imageData []byte) (*d
 	client := a.getClient()
 
 	for i := 0; i < attempts; i++ {
-		ctxWithTimeout, cancel := context.WithTimeout(ctx, a.timeout)
+		attemptCtx, span := telemetry.StartSpan(ctx, "GeminiClient.GenerateContent",
+			attribute.Int("gemini.attempt", i+1))
+		ctxWithTimeout, cancel := context.WithTimeout(attemptCtx, a.timeout)
 		response, err := client.GenerateContent(ctxWithTimeout, imageData)
 		cancel()
+		telemetry.RecordError(span, err)
+		span.End()
 
+		if err == nil && response.Blocked {
+			return contentBlockedVerdict(response.SafetyRatings)
+		}
 		if err == nil {
 			return &domain.VerdictResponse{
 				Admissible: response.Admissible,
@@ -366,7 +524,9 @@ func (a *GeminiAnalyzer) AnalyzePhoto(ctx context.Context, imageData []byte) (*d
 					Observation: response.Observation,
 					VerdictType: response.VerdictType,
 				},
-				RawJSON: response.RawJSON,
+				RawJSON:       response.RawJSON,
+				SafetyRatings: response.SafetyRatings,
+				Usage:         response.Usage,
 			}, nil
 		}
 
@@ -374,7 +534,7 @@ func (a *GeminiAnalyzer) AnalyzePhoto(ctx context.Context, imageData []byte) (*d
 
 		// Check for specific error types
 		if errors.Is(err, context.DeadlineExceeded) {
-			return nil, errors.New("AI analysis timeout")
+			return nil, domain.WrapError(domain.ErrCodeAnalyzerTimeout, "AI analysis timeout", err)
 		}
 
 		var rateLimitErr *RateLimitError
@@ -388,15 +548,26 @@ func (a *GeminiAnalyzer) AnalyzePhoto(ctx context.Context, imageData []byte) (*d
 				time.Sleep(rateLimitErr.RetryAfter)
 				continue
 			}
-			return nil, errors.New("AI analysis service temporarily unavailable")
+			return nil, &domain.Error{
+				Code:       domain.ErrCodeAnalyzerUnavailable,
+				Message:    "AI analysis service temporarily unavailable",
+				RetryAfter: rateLimitErr.RetryAfter,
+				Err:        err,
+			}
+		}
+
+		// Errors that already carry a code (e.g. an undecodable image) pass through
+		var domainErr *domain.Error
+		if errors.As(err, &domainErr) {
+			return nil, err
 		}
 
 		var invalidErr *InvalidResponseError
 		if errors.As(err, &invalidErr) {
-			return nil, errors.New("Invalid AI response format")
+			return nil, domain.WrapError(domain.ErrCodeAnalyzerInvalidResponse, "Invalid AI response format", err)
 		}
 
-		return nil, fmt.Errorf("AI analysis failed: %w", err)
+		return nil, domain.WrapError(domain.ErrCodeAnalyzerUnavailable, "AI analysis failed", err)
 	}
 
 	return nil, lastErr
@@ -409,6 +580,33 @@ func (a *GeminiAnalyzer) getClient() GeminiClientInterface {
 	return a.realClient
 }
 
+// ClassifyImage implements ImageClassifier, classifying the photo within the
+// analyzer timeout
+func (a *GeminiAnalyzer) ClassifyImage(ctx context.Context, imageData []byte) ([]domain.SafetyRating, error) {
+	if a.realClient == nil {
+		return nil, errors.New("Gemini client not initialized")
+	}
+
+	ctx, span := telemetry.StartSpan(ctx, "GeminiClient.ClassifyImage")
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+	ratings, err := a.realClient.ClassifyImage(ctx, imageData)
+	telemetry.RecordError(span, err)
+	return ratings, err
+}
+
+// Ping checks that the Gemini API is reachable within the analyzer timeout
+func (a *GeminiAnalyzer) Ping(ctx context.Context) error {
+	if a.realClient == nil {
+		return errors.New("Gemini client not initialized")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+	return a.realClient.Ping(ctx)
+}
+
 // Close closes the analyzer and its resources
 func (a *GeminiAnalyzer) Close() error {
 	if a.realClient != nil {
@@ -2,17 +2,28 @@ package gemini
 
 import (
 	"bytes"
-	"fmt"
+	"context"
 	"image"
+	"image/color"
+	"image/draw"
 	"image/jpeg"
 	_ "image/jpeg" // Register JPEG decoder
 	"image/png"
 	_ "image/png" // Register PNG decoder
-	"log"
+	"log/slog"
 
-	"golang.org/x/image/draw"
+	"rechtebank/backend/internal/adapters/imaging"
+	"rechtebank/backend/internal/core/domain"
+	"rechtebank/backend/internal/logging"
+	"rechtebank/backend/internal/telemetry"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	_ "golang.org/x/image/webp" // Register WebP decoder
 )
 
+var compressionLogger = logging.Component(logging.ComponentCompression)
+
 // Compression constants
 // These values are chosen to balance file size reduction with image quality retention
 // for furniture recognition by the Gemini LLM API.
@@ -32,134 +43,137 @@ const (
 	// - Furniture can be recognized clearly at 1600px resolution
 	// - Reduces token consumption without affecting recognition accuracy
 	MaxDimension = 1600
-)
 
-// compressJPEG compresses a JPEG image to quality 75
-// Returns the original if compressed version would be larger
-func compressJPEG(data []byte) ([]byte, error) {
-	// Decode the image
-	img, err := jpeg.Decode(bytes.NewReader(data))
-	if err != nil {
-		return nil, fmt.Errorf("failed to decode JPEG: %w", err)
-	}
+	// MinAdaptiveQuality is the lowest quality adaptive compression goes to.
+	// Below 40, JPEG artefacts start to blur the edges the analyzer measures.
+	MinAdaptiveQuality = 40
 
-	// Encode with quality 75
-	var buf bytes.Buffer
-	err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: JPEGQuality})
-	if err != nil {
-		return nil, fmt.Errorf("failed to encode JPEG: %w", err)
-	}
+	// adaptiveQualityStep is how much adaptive compression lowers the quality
+	// per attempt
+	adaptiveQualityStep = 10
 
-	compressed := buf.Bytes()
+	// maxAdaptiveDownscales bounds how often adaptive compression shrinks an
+	// image that does not fit the byte budget even at MinAdaptiveQuality
+	maxAdaptiveDownscales = 3
+)
 
-	// Fallback to original if compressed is larger
-	if len(compressed) >= len(data) {
-		return data, nil
-	}
+// Gemini bills images by tile: an image of at most 384x384 pixels costs 258
+// tokens, larger images are cut into 768x768 tiles of 258 tokens each
+const (
+	tokensPerImageTile = 258
+	smallImageSize     = 384
+	imageTileSize      = 768
+)
 
-	return compressed, nil
-}
+// CompressionPolicy controls how photos are compressed before they are sent
+// to the analyzer
+type CompressionPolicy struct {
+	// MaxDimension is the maximum width or height in pixels
+	MaxDimension int
 
-// compressPNG compresses a PNG image using BestSpeed compression level
-// Returns the original if compressed version would be larger
-func compressPNG(data []byte) ([]byte, error) {
-	// Decode the image
-	img, err := png.Decode(bytes.NewReader(data))
-	if err != nil {
-		return nil, fmt.Errorf("failed to decode PNG: %w", err)
-	}
+	// Quality is the JPEG/WebP quality (1-100)
+	Quality int
 
-	// Encode with BestSpeed compression
-	var buf bytes.Buffer
-	encoder := png.Encoder{CompressionLevel: png.BestSpeed}
-	err = encoder.Encode(&buf, img)
-	if err != nil {
-		return nil, fmt.Errorf("failed to encode PNG: %w", err)
-	}
+	// MaxBytes is the target size of the compressed image; 0 means no budget.
+	// Without Adaptive it is only reported, not enforced.
+	MaxBytes int
 
-	compressed := buf.Bytes()
+	// MaxTokens is the target image token cost; images are scaled down until
+	// their estimated cost fits. 0 means no budget.
+	MaxTokens int
 
-	// Fallback to original if compressed is larger
-	if len(compressed) >= len(data) {
-		return data, nil
-	}
+	// ConvertToJPEG re-encodes PNG and WebP uploads as JPEG
+	ConvertToJPEG bool
 
-	return compressed, nil
+	// Adaptive lowers the quality step by step, down to MinQuality, until the
+	// image fits MaxBytes, and scales it down when that is not enough
+	Adaptive bool
+
+	// MinQuality is the lowest quality adaptive compression uses
+	MinQuality int
 }
 
-// passThroughWebP returns WebP images unchanged as they are already efficiently compressed
-func passThroughWebP(data []byte) []byte {
-	return data
+// DefaultCompressionPolicy returns the policy used when none is configured:
+// resize to MaxDimension, re-encode at JPEGQuality, keep the upload's format
+func DefaultCompressionPolicy() CompressionPolicy {
+	return CompressionPolicy{
+		MaxDimension: MaxDimension,
+		Quality:      JPEGQuality,
+		MinQuality:   MinAdaptiveQuality,
+	}
 }
 
-// resizeIfNeeded resizes an image if either dimension exceeds MaxDimension
-// Maintains aspect ratio and returns the original if within limits
-func resizeIfNeeded(img image.Image) image.Image {
-	bounds := img.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
+// CompressionResult describes a compressed image, for logging and tracing
+type CompressionResult struct {
+	// Data is the image to send; the original when compression did not help
+	Data []byte
 
-	// Check if resize is needed
-	if width <= MaxDimension && height <= MaxDimension {
-		return img
-	}
+	// Format is the format of Data ("jpeg", "png", "webp", ...)
+	Format string
 
-	// Calculate new dimensions maintaining aspect ratio
-	var newWidth, newHeight int
-	if width > height {
-		// Width is the limiting dimension
-		newWidth = MaxDimension
-		newHeight = (height * MaxDimension) / width
-	} else {
-		// Height is the limiting dimension
-		newHeight = MaxDimension
-		newWidth = (width * MaxDimension) / height
-	}
+	OriginalSize int
+	Width        int
+	Height       int
+
+	// Quality is the JPEG/WebP quality of Data, or 0 when it was not
+	// re-encoded lossily
+	Quality int
+
+	// Attempts is the number of encodes adaptive compression needed
+	Attempts int
 
-	// Create new image with calculated dimensions
-	resized := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
+	// EstimatedTokens is the estimated image token cost of Data
+	EstimatedTokens int
 
-	// Use high-quality scaling
-	draw.BiLinear.Scale(resized, resized.Bounds(), img, img.Bounds(), draw.Over, nil)
+	// OverBudget is set when Data still exceeds MaxBytes
+	OverBudget bool
+}
 
-	return resized
+// Ratio returns the original size divided by the compressed size
+func (r *CompressionResult) Ratio() float64 {
+	if len(r.Data) == 0 {
+		return 0
+	}
+	return float64(r.OriginalSize) / float64(len(r.Data))
 }
 
-// compressImage compresses an image based on its MIME type to reduce token consumption
-// for Gemini LLM API calls while maintaining sufficient quality for furniture analysis.
+// EstimateImageTokens estimates what Gemini charges for an image of the given
+// size
+func EstimateImageTokens(width, height int) int {
+	if width <= smallImageSize && height <= smallImageSize {
+		return tokensPerImageTile
+	}
+	tiles := ((width + imageTileSize - 1) / imageTileSize) * ((height + imageTileSize - 1) / imageTileSize)
+	return tiles * tokensPerImageTile
+}
+
+// Compress compresses an image according to the policy to reduce token
+// consumption for Gemini LLM API calls while maintaining sufficient quality
+// for furniture analysis.
 //
 // Compression Strategy:
-// 1. JPEG: Re-encode at quality 75 (typically 50-60% size reduction)
-// 2. PNG: Re-encode with BestSpeed compression level (fast, moderate compression)
-// 3. WebP: Pass through unchanged (already efficiently compressed)
-// 4. Resize: If either dimension > 1600px, resize proportionally before compression
-// 5. Fallback: Return original image if compression fails or produces larger output
-//
-// Error Handling:
-// - Invalid/unknown formats return original data
-// - Decode/encode errors return original data
-// - All errors are logged but don't fail the request
+// 1. Resize: scale down to MaxDimension, then further until MaxTokens fits
+// 2. JPEG: Re-encode at Quality
+// 3. PNG: Re-encode with BestSpeed compression level, or as JPEG with ConvertToJPEG
+// 4. WebP: Pass through unless it needs resizing, exceeds MaxBytes or is converted
+// 5. HEIC/HEIF/AVIF: Convert to JPEG, even if the result is larger
+// 6. Adaptive: lower the quality (then the size) until MaxBytes fits
+// 7. Fallback: Return original image if compression fails or produces larger output
 //
-// Resizes if needed, then applies format-specific compression
-// Falls back to original image on any error
-func compressImage(imageData []byte) ([]byte, error) {
+// Errors are logged but never fail the request: the original is returned.
+func (p CompressionPolicy) Compress(imageData []byte) *CompressionResult {
 	originalSize := len(imageData)
+	result := &CompressionResult{Data: imageData, OriginalSize: originalSize}
 
 	// Detect MIME type
 	mimeType := detectMIMEType(imageData)
+	result.Format = mimeType
 	if mimeType == "" {
 		// Unknown format, return original
-		log.Printf("[COMPRESSION] Skipped: unknown image format, originalSize=%d", originalSize)
-		return imageData, nil
-	}
-
-	// WebP: pass through unchanged
-	if mimeType == "webp" {
-		log.Printf("[COMPRESSION] WebP pass-through: originalSize=%d, imageFormat=%s", originalSize, mimeType)
-		return passThroughWebP(imageData), nil
+		compressionLogger.Info("Skipped: unknown image format", slog.Int("original_size", originalSize))
+		return result
 	}
 
-	// For JPEG and PNG: decode, resize if needed, then compress
 	var img image.Image
 	var decodeErr error
 
@@ -168,60 +182,226 @@ func compressImage(imageData []byte) ([]byte, error) {
 		img, decodeErr = jpeg.Decode(bytes.NewReader(imageData))
 	case "png":
 		img, decodeErr = png.Decode(bytes.NewReader(imageData))
+	case "webp":
+		img, _, decodeErr = image.Decode(bytes.NewReader(imageData))
+	case imaging.FormatHEIC, imaging.FormatHEIF, imaging.FormatAVIF:
+		img, decodeErr = imaging.DecodeHEIF(imageData)
 	default:
 		// Unknown format, return original
-		log.Printf("[COMPRESSION] Skipped: unsupported format %s, originalSize=%d", mimeType, originalSize)
-		return imageData, nil
+		compressionLogger.Info("Skipped: unsupported format", slog.String("image_format", mimeType), slog.Int("original_size", originalSize))
+		return result
 	}
 
 	if decodeErr != nil {
 		// Failed to decode, return original
-		log.Printf("[COMPRESSION] Decode failed: %v, using original, originalSize=%d, imageFormat=%s", decodeErr, originalSize, mimeType)
-		return imageData, nil
+		if mimeType == "webp" {
+			compressionLogger.Info("WebP pass-through", slog.Int("original_size", originalSize), slog.String("image_format", mimeType))
+		} else {
+			compressionLogger.Warn("Decode failed, using original", slog.Any("error", decodeErr), slog.Int("original_size", originalSize), slog.String("image_format", mimeType))
+		}
+		return result
 	}
 
-	// Resize if needed
-	img = resizeIfNeeded(img)
+	originalBounds := img.Bounds()
+	img = p.resize(img)
+	resized := img.Bounds() != originalBounds
+	result.Width, result.Height = img.Bounds().Dx(), img.Bounds().Dy()
+	result.EstimatedTokens = EstimateImageTokens(result.Width, result.Height)
+
+	format := mimeType
+	switch {
+	case mimeType != "jpeg" && mimeType != "png" && mimeType != "webp":
+		// Gemini does not accept AVIF, and HEIC support is patchy
+		format = "jpeg"
+	case p.ConvertToJPEG:
+		format = "jpeg"
+	}
 
-	// Compress based on format
-	var compressed []byte
-	var compressErr error
+	// WebP is already efficiently compressed; leave it alone when it fits
+	if mimeType == "webp" && format == "webp" && !resized && !p.overBudget(originalSize) {
+		compressionLogger.Info("WebP pass-through", slog.Int("original_size", originalSize), slog.String("image_format", mimeType))
+		return result
+	}
 
-	switch mimeType {
-	case "jpeg":
-		// Encode with quality 75
-		var buf bytes.Buffer
-		compressErr = jpeg.Encode(&buf, img, &jpeg.Options{Quality: JPEGQuality})
-		if compressErr == nil {
-			compressed = buf.Bytes()
+	compressed, err := p.encode(img, format, result)
+	if err != nil {
+		// Compression failed, return original
+		compressionLogger.Warn("Encode failed, using original", slog.Any("error", err), slog.Int("original_size", originalSize), slog.String("image_format", mimeType))
+		result.Format, result.Quality = mimeType, 0
+		result.Width, result.Height = originalBounds.Dx(), originalBounds.Dy()
+		result.EstimatedTokens = EstimateImageTokens(result.Width, result.Height)
+		return result
+	}
+
+	// Return compressed if smaller, otherwise return original. Converted
+	// images are always used, because the conversion is the point.
+	compressedSize := len(compressed)
+	if compressedSize < originalSize || result.Format != mimeType || resized {
+		result.Data = compressed
+		result.OverBudget = p.overBudget(compressedSize)
+		compressionLogger.Info("Success",
+			slog.Int("original_size", originalSize),
+			slog.Int("compressed_size", compressedSize),
+			slog.Float64("compression_ratio", result.Ratio()),
+			slog.String("image_format", mimeType),
+			slog.String("output_format", result.Format),
+			slog.Int("quality", result.Quality),
+			slog.Int("attempts", result.Attempts),
+			slog.Int("estimated_tokens", result.EstimatedTokens),
+			slog.Bool("over_budget", result.OverBudget))
+		return result
+	}
+
+	// Compressed is larger, use original
+	compressionLogger.Info("Skipped: compressed larger than original",
+		slog.Int("original_size", originalSize),
+		slog.Int("compressed_size", compressedSize),
+		slog.String("image_format", mimeType))
+	result.Format, result.Quality = mimeType, 0
+	result.OverBudget = p.overBudget(originalSize)
+	return result
+}
+
+// resize scales img down to MaxDimension, and further until its estimated
+// token cost fits MaxTokens
+func (p CompressionPolicy) resize(img image.Image) image.Image {
+	if p.MaxDimension > 0 {
+		img = imaging.ResizeToFit(img, p.MaxDimension)
+	}
+	if p.MaxTokens <= 0 {
+		return img
+	}
+
+	for {
+		bounds := img.Bounds()
+		if EstimateImageTokens(bounds.Dx(), bounds.Dy()) <= p.MaxTokens {
+			return img
 		}
-	case "png":
-		// Encode with BestSpeed compression
+		// Drop a row or column of tiles along the longest side
+		longest := max(bounds.Dx(), bounds.Dy())
+		target := (longest - 1) / imageTileSize * imageTileSize
+		if target < imageTileSize {
+			target = smallImageSize
+		}
+		img = imaging.ResizeToFit(img, target)
+		if target == smallImageSize {
+			return img
+		}
+	}
+}
+
+// overBudget reports whether size exceeds the byte budget
+func (p CompressionPolicy) overBudget(size int) bool {
+	return p.MaxBytes > 0 && size > p.MaxBytes
+}
+
+// encode encodes img in format, recording the quality, attempts, format and
+// final size in result. In adaptive mode it lowers the quality and then the
+// size until the byte budget fits.
+func (p CompressionPolicy) encode(img image.Image, format string, result *CompressionResult) ([]byte, error) {
+	adaptive := p.Adaptive && p.MaxBytes > 0
+
+	if format == "png" {
 		var buf bytes.Buffer
 		encoder := png.Encoder{CompressionLevel: png.BestSpeed}
-		compressErr = encoder.Encode(&buf, img)
-		if compressErr == nil {
-			compressed = buf.Bytes()
+		if err := encoder.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+		result.Format, result.Quality, result.Attempts = "png", 0, 1
+		if !adaptive || !p.overBudget(buf.Len()) {
+			return buf.Bytes(), nil
 		}
+		// PNG has no quality to lower; fall back to JPEG
+		format = "jpeg"
 	}
 
-	if compressErr != nil {
-		// Compression failed, return original
-		log.Printf("[COMPRESSION] Encode failed: %v, using original, originalSize=%d, imageFormat=%s", compressErr, originalSize, mimeType)
-		return imageData, nil
+	photoFormat := domain.PhotoFormatJPEG
+	if format == "webp" {
+		photoFormat = domain.PhotoFormatWebP
+	} else {
+		img = flatten(img)
 	}
 
-	// Return compressed if smaller, otherwise return original
-	compressedSize := len(compressed)
-	if compressedSize < originalSize {
-		compressionRatio := float64(originalSize) / float64(compressedSize)
-		log.Printf("[COMPRESSION] Success: originalSize=%d, compressedSize=%d, compressionRatio=%.2fx, imageFormat=%s",
-			originalSize, compressedSize, compressionRatio, mimeType)
-		return compressed, nil
+	minQuality := min(max(p.MinQuality, 1), p.Quality)
+	quality := p.Quality
+	downscales := 0
+	for {
+		data, err := imaging.Encode(img, photoFormat, quality)
+		if err != nil {
+			return nil, err
+		}
+		result.Attempts++
+		result.Format, result.Quality = format, quality
+		bounds := img.Bounds()
+		result.Width, result.Height = bounds.Dx(), bounds.Dy()
+		result.EstimatedTokens = EstimateImageTokens(result.Width, result.Height)
+
+		if !adaptive || !p.overBudget(len(data)) {
+			return data, nil
+		}
+		if quality > minQuality {
+			quality = max(quality-adaptiveQualityStep, minQuality)
+			continue
+		}
+		if downscales == maxAdaptiveDownscales {
+			return data, nil
+		}
+		downscales++
+		img = imaging.ResizeToFit(img, max(bounds.Dx(), bounds.Dy())*3/4)
 	}
+}
 
-	// Compressed is larger, use original
-	log.Printf("[COMPRESSION] Skipped: compressed larger than original, originalSize=%d, compressedSize=%d, imageFormat=%s",
-		originalSize, compressedSize, mimeType)
-	return imageData, nil
+// flatten draws images with transparency onto white, since JPEG has no alpha
+// channel and transparent pixels would otherwise turn black
+func flatten(img image.Image) image.Image {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && opaque.Opaque() {
+		return img
+	}
+	flat := image.NewRGBA(img.Bounds())
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+	return flat
+}
+
+// compressionMetrics records the sizes of compressed images as histograms
+type compressionMetrics struct {
+	originalSize    metric.Int64Histogram
+	compressedSize  metric.Int64Histogram
+	estimatedTokens metric.Int64Histogram
+}
+
+// newCompressionMetrics creates the compression histograms on the backend meter
+func newCompressionMetrics() (*compressionMetrics, error) {
+	meter := telemetry.Meter()
+	originalSize, err := meter.Int64Histogram("image.original_size",
+		metric.WithDescription("Size of uploaded images before compression"),
+		metric.WithUnit("By"))
+	if err != nil {
+		return nil, err
+	}
+	compressedSize, err := meter.Int64Histogram("image.compressed_size",
+		metric.WithDescription("Size of images sent to the analyzer after compression"),
+		metric.WithUnit("By"))
+	if err != nil {
+		return nil, err
+	}
+	estimatedTokens, err := meter.Int64Histogram("image.estimated_tokens",
+		metric.WithDescription("Estimated image token cost of compressed images"),
+		metric.WithUnit("{token}"))
+	if err != nil {
+		return nil, err
+	}
+	return &compressionMetrics{
+		originalSize:    originalSize,
+		compressedSize:  compressedSize,
+		estimatedTokens: estimatedTokens,
+	}, nil
+}
+
+// record adds a compression result to the histograms, by output format
+func (m *compressionMetrics) record(ctx context.Context, result *CompressionResult) {
+	attrs := metric.WithAttributes(attribute.String("image.format", result.Format))
+	m.originalSize.Record(ctx, int64(result.OriginalSize), attrs)
+	m.compressedSize.Record(ctx, int64(len(result.Data)), attrs)
+	m.estimatedTokens.Record(ctx, int64(result.EstimatedTokens), attrs)
 }
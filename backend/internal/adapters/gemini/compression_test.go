@@ -2,14 +2,24 @@ package gemini
 
 import (
 	"bytes"
+	"context"
 	"image"
 	"image/color"
 	"image/jpeg"
 	"image/png"
-	"log"
+	"log/slog"
 	"os"
 	"strings"
 	"testing"
+
+	"rechtebank/backend/internal/adapters/imaging"
+	"rechtebank/backend/internal/core/domain"
+	"rechtebank/backend/internal/logging"
+	"rechtebank/backend/internal/telemetry"
+
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
 )
 
 // createTestJPEGWithDimensions creates a test JPEG image with the specified dimensions
@@ -52,34 +62,26 @@ func createTestPNG(width, height int) []byte {
 	return buf.Bytes()
 }
 
-// TestCompressJPEG tests that JPEG compression produces smaller output at quality 75
-func TestCompressJPEG(t *testing.T) {
-	// Create a test JPEG image (high quality)
+// TestCompressionPolicy_JPEG tests that JPEG uploads are re-encoded smaller at JPEGQuality
+func TestCompressionPolicy_JPEG(t *testing.T) {
 	originalJPEG := createTestJPEGWithDimensions(800, 600)
-	originalSize := len(originalJPEG)
-
-	// Compress the JPEG
-	compressed, err := compressJPEG(originalJPEG)
-	if err != nil {
-		t.Fatalf("compressJPEG failed: %v", err)
-	}
 
-	compressedSize := len(compressed)
+	result := DefaultCompressionPolicy().Compress(originalJPEG)
 
-	// Verify compression reduced size
-	if compressedSize >= originalSize {
-		t.Errorf("Expected compressed size (%d) to be smaller than original (%d)", compressedSize, originalSize)
+	if len(result.Data) >= len(originalJPEG) {
+		t.Errorf("Expected compressed size (%d) to be smaller than original (%d)", len(result.Data), len(originalJPEG))
 	}
-
-	// Verify output is still valid JPEG
-	if detectMIMEType(compressed) != "jpeg" {
+	if detectMIMEType(result.Data) != "jpeg" {
 		t.Error("Compressed output is not a valid JPEG")
 	}
+	if result.Quality != JPEGQuality {
+		t.Errorf("Expected quality %d, got %d", JPEGQuality, result.Quality)
+	}
 }
 
-// TestCompressJPEG_FallbackWhenLarger tests fallback to original when compressed is larger
-func TestCompressJPEG_FallbackWhenLarger(t *testing.T) {
-	// Create a small, already optimized JPEG (low quality)
+// TestCompressionPolicy_KeepsOriginalWhenLarger tests fallback to the original when re-encoding does not help
+func TestCompressionPolicy_KeepsOriginalWhenLarger(t *testing.T) {
+	// A small solid image, already encoded compactly
 	img := image.NewRGBA(image.Rect(0, 0, 100, 100))
 	for y := 0; y < 100; y++ {
 		for x := 0; x < 100; x++ {
@@ -87,77 +89,42 @@ func TestCompressJPEG_FallbackWhenLarger(t *testing.T) {
 		}
 	}
 
-	var buf bytes.Buffer
-	jpeg.Encode(&buf, img, &jpeg.Options{Quality: 50}) // Already low quality
-	original := buf.Bytes()
-
-	// Compress - should return original if compressed would be larger
-	result, err := compressJPEG(original)
-	if err != nil {
-		t.Fatalf("compressJPEG failed: %v", err)
-	}
-
-	// Result should be <= original size (either compressed smaller or fallback to original)
-	if len(result) > len(original) {
-		t.Errorf("Result size (%d) should not be larger than original (%d)", len(result), len(original))
+	var pngBuf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	encoder.Encode(&pngBuf, img)
+
+	for name, original := range map[string][]byte{
+		"jpeg": encodeTestJPEG(img, 50),
+		"png":  pngBuf.Bytes(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			result := DefaultCompressionPolicy().Compress(original)
+			if len(result.Data) > len(original) {
+				t.Errorf("Result size (%d) should not be larger than original (%d)", len(result.Data), len(original))
+			}
+			if result.Format != name {
+				t.Errorf("Expected format %s, got %s", name, result.Format)
+			}
+		})
 	}
 }
 
-// TestCompressPNG tests that PNG compression with BestSpeed level produces smaller output
-func TestCompressPNG(t *testing.T) {
-	// Create a test PNG image
+// TestCompressionPolicy_PNG tests that PNG uploads stay PNG
+func TestCompressionPolicy_PNG(t *testing.T) {
 	originalPNG := createTestPNG(800, 600)
-	originalSize := len(originalPNG)
-
-	// Compress the PNG
-	compressed, err := compressPNG(originalPNG)
-	if err != nil {
-		t.Fatalf("compressPNG failed: %v", err)
-	}
 
-	compressedSize := len(compressed)
+	result := DefaultCompressionPolicy().Compress(originalPNG)
 
-	// Verify compression reduced size (or at least didn't increase it significantly)
-	// Note: PNG compression can be variable depending on content
-	maxAllowedSize := int(float64(originalSize) * 1.1)
-	if compressedSize > maxAllowedSize {
-		t.Errorf("Compressed size (%d) should not be significantly larger than original (%d)", compressedSize, originalSize)
+	// PNG compression varies with content; it must not grow much
+	maxAllowedSize := int(float64(len(originalPNG)) * 1.1)
+	if len(result.Data) > maxAllowedSize {
+		t.Errorf("Compressed size (%d) should not be significantly larger than original (%d)", len(result.Data), len(originalPNG))
 	}
-
-	// Verify output is still valid PNG
-	if detectMIMEType(compressed) != "png" {
+	if detectMIMEType(result.Data) != "png" {
 		t.Error("Compressed output is not a valid PNG")
 	}
 }
 
-// TestCompressPNG_FallbackWhenLarger tests fallback to original when compressed PNG is larger
-func TestCompressPNG_FallbackWhenLarger(t *testing.T) {
-	// Create a small PNG with solid color (already well compressed)
-	img := image.NewRGBA(image.Rect(0, 0, 100, 100))
-	for y := 0; y < 100; y++ {
-		for x := 0; x < 100; x++ {
-			img.Set(x, y, color.RGBA{R: 128, G: 128, B: 128, A: 255})
-		}
-	}
-
-	var buf bytes.Buffer
-	// Use BestCompression for original to make it hard to compress further
-	encoder := png.Encoder{CompressionLevel: png.BestCompression}
-	encoder.Encode(&buf, img)
-	original := buf.Bytes()
-
-	// Compress - should return original if compressed would be larger
-	result, err := compressPNG(original)
-	if err != nil {
-		t.Fatalf("compressPNG failed: %v", err)
-	}
-
-	// Result should be <= original size
-	if len(result) > len(original) {
-		t.Errorf("Result size (%d) should not be larger than original (%d)", len(result), len(original))
-	}
-}
-
 // createTestWebP creates a minimal valid WebP file for testing
 func createTestWebP() []byte {
 	// Minimal WebP file header (RIFF....WEBPVP8 ...)
@@ -174,209 +141,102 @@ func createTestWebP() []byte {
 	return webp
 }
 
-// TestPassThroughWebP tests that WebP images pass through unchanged
-func TestPassThroughWebP(t *testing.T) {
-	// Create a test WebP
+// TestCompressionPolicy_WebPPassThrough tests that WebP images pass through unchanged
+func TestCompressionPolicy_WebPPassThrough(t *testing.T) {
 	originalWebP := createTestWebP()
-	originalSize := len(originalWebP)
-
-	// Pass through WebP (should return unchanged)
-	result := passThroughWebP(originalWebP)
 
-	// Verify size is unchanged
-	if len(result) != originalSize {
-		t.Errorf("WebP pass-through changed size from %d to %d", originalSize, len(result))
-	}
+	result := DefaultCompressionPolicy().Compress(originalWebP)
 
-	// Verify content is identical
-	for i := 0; i < len(originalWebP); i++ {
-		if result[i] != originalWebP[i] {
-			t.Errorf("WebP pass-through changed content at byte %d", i)
-			break
-		}
+	if !bytes.Equal(result.Data, originalWebP) {
+		t.Error("WebP pass-through changed the image")
 	}
-
-	// Verify output is still valid WebP
-	if detectMIMEType(result) != "webp" {
+	if detectMIMEType(result.Data) != "webp" {
 		t.Error("Pass-through output is not a valid WebP")
 	}
 }
 
-// TestResizeIfNeeded_Oversized tests resizing images over 1600px
-func TestResizeIfNeeded_Oversized(t *testing.T) {
-	// Create a large image (2000x1500)
-	img := image.NewRGBA(image.Rect(0, 0, 2000, 1500))
-
-	// Resize
-	resized := resizeIfNeeded(img)
-
-	bounds := resized.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
+// TestCompressionPolicy_ResizeOversized tests resizing images over MaxDimension
+func TestCompressionPolicy_ResizeOversized(t *testing.T) {
+	resized := DefaultCompressionPolicy().resize(image.NewRGBA(image.Rect(0, 0, 2000, 1500)))
 
-	// Should be resized to fit within 1600x1600
+	width, height := resized.Bounds().Dx(), resized.Bounds().Dy()
 	if width > MaxDimension || height > MaxDimension {
 		t.Errorf("Resized image (%dx%d) exceeds maximum dimension %d", width, height, MaxDimension)
 	}
-
-	// Verify it was actually resized (not passed through)
 	if width == 2000 && height == 1500 {
 		t.Error("Image was not resized when it should have been")
 	}
 }
 
-// TestResizeIfNeeded_MaintainsAspectRatio tests that resize maintains aspect ratio
-func TestResizeIfNeeded_MaintainsAspectRatio(t *testing.T) {
-	// Create a wide image (3200x1200) - aspect ratio 8:3
-	img := image.NewRGBA(image.Rect(0, 0, 3200, 1200))
-
-	// Resize
-	resized := resizeIfNeeded(img)
-
-	bounds := resized.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
+// TestCompressionPolicy_ResizeMaintainsAspectRatio tests that resize maintains aspect ratio
+func TestCompressionPolicy_ResizeMaintainsAspectRatio(t *testing.T) {
+	resized := DefaultCompressionPolicy().resize(image.NewRGBA(image.Rect(0, 0, 3200, 1200)))
 
-	// Original aspect ratio: 3200/1200 = 2.666...
+	width, height := resized.Bounds().Dx(), resized.Bounds().Dy()
 	originalAspect := 3200.0 / 1200.0
 	newAspect := float64(width) / float64(height)
-
-	// Aspect ratio should be maintained (within small tolerance for rounding)
 	if newAspect < originalAspect-0.01 || newAspect > originalAspect+0.01 {
 		t.Errorf("Aspect ratio not maintained: original=%.3f, new=%.3f", originalAspect, newAspect)
 	}
-
-	// Should be limited by width (1600px wide, proportionally shorter height)
 	if width != MaxDimension {
 		t.Errorf("Expected width to be %d, got %d", MaxDimension, width)
 	}
 }
 
-// TestResizeIfNeeded_WithinLimit tests images within size limit remain unchanged
-func TestResizeIfNeeded_WithinLimit(t *testing.T) {
-	// Create an image within limits (1200x800)
-	img := image.NewRGBA(image.Rect(0, 0, 1200, 800))
+// TestCompressionPolicy_ResizeWithinLimit tests images within size limit remain unchanged
+func TestCompressionPolicy_ResizeWithinLimit(t *testing.T) {
+	resized := DefaultCompressionPolicy().resize(image.NewRGBA(image.Rect(0, 0, 1200, 800)))
 
-	// Resize
-	resized := resizeIfNeeded(img)
-
-	bounds := resized.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
-
-	// Should remain unchanged
-	if width != 1200 || height != 800 {
+	if width, height := resized.Bounds().Dx(), resized.Bounds().Dy(); width != 1200 || height != 800 {
 		t.Errorf("Image size changed from 1200x800 to %dx%d when it should have stayed the same", width, height)
 	}
 }
 
-// TestCompressImage_JPEG tests that compressImage routes JPEG to correct handler
-func TestCompressImage_JPEG(t *testing.T) {
-	// Create a test JPEG
-	jpegData := createTestJPEGWithDimensions(800, 600)
-	originalSize := len(jpegData)
-
-	// Compress
-	compressed, err := compressImage(jpegData)
-	if err != nil {
-		t.Fatalf("compressImage failed: %v", err)
-	}
-
-	// Should be compressed (or at least not larger)
-	maxExpected := int(float64(originalSize) * 1.1)
-	if len(compressed) > maxExpected {
-		t.Errorf("Compressed JPEG size (%d) is larger than expected based on original (%d)", len(compressed), originalSize)
-	}
-
-	// Should still be a JPEG
-	if detectMIMEType(compressed) != "jpeg" {
-		t.Error("Compressed output is not a JPEG")
-	}
-}
-
-// TestCompressImage_PNG tests that compressImage routes PNG to correct handler
-func TestCompressImage_PNG(t *testing.T) {
-	// Create a test PNG
-	pngData := createTestPNG(800, 600)
-
-	// Compress
-	compressed, err := compressImage(pngData)
-	if err != nil {
-		t.Fatalf("compressImage failed: %v", err)
-	}
-
-	// Should still be a PNG
-	if detectMIMEType(compressed) != "png" {
-		t.Error("Compressed output is not a PNG")
-	}
-}
-
-// TestCompressImage_WebP tests that compressImage passes through WebP
-func TestCompressImage_WebP(t *testing.T) {
-	// Create a test WebP
-	webpData := createTestWebP()
-
-	// Compress (should pass through)
-	result, err := compressImage(webpData)
-	if err != nil {
-		t.Fatalf("compressImage failed: %v", err)
-	}
-
-	// Should be unchanged
-	if len(result) != len(webpData) {
-		t.Errorf("WebP size changed from %d to %d", len(webpData), len(result))
-	}
-
-	// Should still be a WebP
-	if detectMIMEType(result) != "webp" {
-		t.Error("Result is not a WebP")
+// TestCompressionPolicy_HEIFConvertsToJPEG tests that HEIC and AVIF are always sent as JPEG
+func TestCompressionPolicy_HEIFConvertsToJPEG(t *testing.T) {
+	for _, fixture := range []string{"sample.heic", "sample.avif"} {
+		t.Run(fixture, func(t *testing.T) {
+			data, err := os.ReadFile("../imaging/testdata/" + fixture)
+			if err != nil {
+				t.Fatalf("failed to read fixture: %v", err)
+			}
+
+			result := DefaultCompressionPolicy().Compress(data).Data
+
+			if detectMIMEType(result) != "jpeg" {
+				t.Errorf("Result is %q, want jpeg", detectMIMEType(result))
+			}
+			if _, err := jpeg.Decode(bytes.NewReader(result)); err != nil {
+				t.Errorf("Result does not decode as JPEG: %v", err)
+			}
+		})
 	}
 }
 
-// TestCompressImage_InvalidFormat tests error handling for invalid image
-func TestCompressImage_InvalidFormat(t *testing.T) {
-	// Create invalid image data
+// TestCompressionPolicy_InvalidFormat tests that unknown data is returned as is
+func TestCompressionPolicy_InvalidFormat(t *testing.T) {
 	invalidData := []byte{0x00, 0x01, 0x02, 0x03}
 
-	// Should fail gracefully and return original
-	result, err := compressImage(invalidData)
-	if err != nil {
-		t.Fatalf("compressImage should not error on invalid format, got: %v", err)
-	}
+	result := DefaultCompressionPolicy().Compress(invalidData)
 
-	// Should return original data when compression fails
-	if len(result) != len(invalidData) {
+	if !bytes.Equal(result.Data, invalidData) {
 		t.Error("Should return original data when format is invalid")
 	}
 }
 
-// TestCompressImage_WithResize tests integration of resizing before compression
-func TestCompressImage_WithResize(t *testing.T) {
-	// Create a large JPEG (2400x1800)
+// TestCompressionPolicy_WithResize tests resizing before compression
+func TestCompressionPolicy_WithResize(t *testing.T) {
 	largeJPEG := createTestJPEGWithDimensions(2400, 1800)
 
-	// Compress (should resize and then compress)
-	compressed, err := compressImage(largeJPEG)
-	if err != nil {
-		t.Fatalf("compressImage failed: %v", err)
-	}
+	compressed := DefaultCompressionPolicy().Compress(largeJPEG).Data
 
-	// Decode to verify dimensions
 	img, err := jpeg.Decode(bytes.NewReader(compressed))
 	if err != nil {
 		t.Fatalf("Failed to decode compressed image: %v", err)
 	}
-
-	bounds := img.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
-
-	// Should be resized to max 1600px
-	if width > MaxDimension || height > MaxDimension {
+	if width, height := img.Bounds().Dx(), img.Bounds().Dy(); width > MaxDimension || height > MaxDimension {
 		t.Errorf("Compressed image (%dx%d) exceeds maximum dimension %d", width, height, MaxDimension)
 	}
-
-	// Should be significantly smaller than original
 	if len(compressed) >= len(largeJPEG) {
 		t.Errorf("Compressed large image (%d bytes) should be smaller than original (%d bytes)", len(compressed), len(largeJPEG))
 	}
@@ -384,21 +244,12 @@ func TestCompressImage_WithResize(t *testing.T) {
 
 // captureLogOutput captures log output during function execution
 func captureLogOutput(fn func()) string {
-	// Create a pipe to capture log output
-	r, w, _ := os.Pipe()
-	oldOutput := log.Writer()
-	log.SetOutput(w)
+	var buf bytes.Buffer
+	logging.Setup(logging.Config{Format: "text", Level: slog.LevelInfo, Output: &buf})
+	defer logging.Setup(logging.Config{Format: "text", Level: slog.LevelInfo})
 
-	// Execute function
 	fn()
 
-	// Restore original output
-	log.SetOutput(oldOutput)
-	w.Close()
-
-	// Read captured output
-	var buf bytes.Buffer
-	buf.ReadFrom(r)
 	return buf.String()
 }
 
@@ -409,24 +260,24 @@ func TestCompressionLogging_Success(t *testing.T) {
 
 	// Capture logs
 	logOutput := captureLogOutput(func() {
-		compressImage(jpegData)
+		DefaultCompressionPolicy().Compress(jpegData)
 	})
 
 	// Verify log contains expected fields
-	if !strings.Contains(logOutput, "[COMPRESSION]") {
-		t.Error("Log should contain [COMPRESSION] tag")
+	if !strings.Contains(logOutput, "component=compression") {
+		t.Error("Log should contain compression component")
 	}
-	if !strings.Contains(logOutput, "originalSize=") {
-		t.Error("Log should contain originalSize")
+	if !strings.Contains(logOutput, "original_size=") {
+		t.Error("Log should contain original_size")
 	}
-	if !strings.Contains(logOutput, "compressedSize=") {
-		t.Error("Log should contain compressedSize")
+	if !strings.Contains(logOutput, "compressed_size=") {
+		t.Error("Log should contain compressed_size")
 	}
-	if !strings.Contains(logOutput, "compressionRatio=") {
-		t.Error("Log should contain compressionRatio")
+	if !strings.Contains(logOutput, "compression_ratio=") {
+		t.Error("Log should contain compression_ratio")
 	}
-	if !strings.Contains(logOutput, "imageFormat=") {
-		t.Error("Log should contain imageFormat")
+	if !strings.Contains(logOutput, "image_format=") {
+		t.Error("Log should contain image_format")
 	}
 }
 
@@ -437,17 +288,17 @@ func TestCompressionLogging_WebPPassthrough(t *testing.T) {
 
 	// Capture logs
 	logOutput := captureLogOutput(func() {
-		compressImage(webpData)
+		DefaultCompressionPolicy().Compress(webpData)
 	})
 
 	// Verify log indicates pass-through
-	if !strings.Contains(logOutput, "[COMPRESSION]") {
-		t.Error("Log should contain [COMPRESSION] tag")
+	if !strings.Contains(logOutput, "component=compression") {
+		t.Error("Log should contain compression component")
 	}
 	if !strings.Contains(logOutput, "WebP pass-through") {
 		t.Error("Log should indicate WebP pass-through")
 	}
-	if !strings.Contains(logOutput, "imageFormat=webp") {
+	if !strings.Contains(logOutput, "image_format=webp") {
 		t.Error("Log should contain imageFormat=webp")
 	}
 }
@@ -459,14 +310,256 @@ func TestCompressionLogging_Skipped(t *testing.T) {
 
 	// Capture logs
 	logOutput := captureLogOutput(func() {
-		compressImage(invalidData)
+		DefaultCompressionPolicy().Compress(invalidData)
 	})
 
 	// Verify log indicates skipped compression
-	if !strings.Contains(logOutput, "[COMPRESSION]") {
-		t.Error("Log should contain [COMPRESSION] tag")
+	if !strings.Contains(logOutput, "component=compression") {
+		t.Error("Log should contain compression component")
 	}
 	if !strings.Contains(logOutput, "Skipped") {
 		t.Error("Log should indicate compression was skipped")
 	}
 }
+
+// createNoisyImage creates an image with pseudo-random detail, which JPEG
+// cannot compress well at high quality
+func createNoisyImage(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	seed := uint32(1)
+	for i := range img.Pix {
+		seed = seed*1664525 + 1013904223
+		img.Pix[i] = uint8(seed >> 24)
+	}
+	return img
+}
+
+func encodeTestJPEG(img image.Image, quality int) []byte {
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+	return buf.Bytes()
+}
+
+// TestCompressionPolicy_MaxDimension tests that the configured dimension is used
+func TestCompressionPolicy_MaxDimension(t *testing.T) {
+	policy := DefaultCompressionPolicy()
+	policy.MaxDimension = 500
+
+	result := policy.Compress(createTestJPEGWithDimensions(1000, 800))
+
+	if result.Width != 500 || result.Height != 400 {
+		t.Errorf("Result is %dx%d, want 500x400", result.Width, result.Height)
+	}
+	config, err := jpeg.DecodeConfig(bytes.NewReader(result.Data))
+	if err != nil {
+		t.Fatalf("Result does not decode as JPEG: %v", err)
+	}
+	if config.Width != 500 {
+		t.Errorf("Encoded width is %d, want 500", config.Width)
+	}
+}
+
+// TestCompressionPolicy_ConvertToJPEG tests that PNG and WebP are converted to JPEG
+func TestCompressionPolicy_ConvertToJPEG(t *testing.T) {
+	policy := DefaultCompressionPolicy()
+	policy.ConvertToJPEG = true
+
+	webpData, err := imaging.Encode(createNoisyImage(200, 100), domain.PhotoFormatWebP, 90)
+	if err != nil {
+		t.Fatalf("failed to create WebP: %v", err)
+	}
+
+	for name, data := range map[string][]byte{
+		"png":  createTestPNG(400, 300),
+		"webp": webpData,
+	} {
+		t.Run(name, func(t *testing.T) {
+			result := policy.Compress(data)
+
+			if result.Format != "jpeg" || detectMIMEType(result.Data) != "jpeg" {
+				t.Errorf("Result is %q (detected %q), want jpeg", result.Format, detectMIMEType(result.Data))
+			}
+			if result.Quality != JPEGQuality {
+				t.Errorf("Quality is %d, want %d", result.Quality, JPEGQuality)
+			}
+		})
+	}
+}
+
+// TestCompressionPolicy_ConvertToJPEG_FlattensTransparency tests that transparent
+// pixels become white rather than black
+func TestCompressionPolicy_ConvertToJPEG_FlattensTransparency(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 16, 16))) // fully transparent
+
+	policy := DefaultCompressionPolicy()
+	policy.ConvertToJPEG = true
+	result := policy.Compress(buf.Bytes())
+
+	img, err := jpeg.Decode(bytes.NewReader(result.Data))
+	if err != nil {
+		t.Fatalf("Result does not decode as JPEG: %v", err)
+	}
+	r, g, b, _ := img.At(8, 8).RGBA()
+	if r>>8 < 250 || g>>8 < 250 || b>>8 < 250 {
+		t.Errorf("Transparent pixel became (%d,%d,%d), want white", r>>8, g>>8, b>>8)
+	}
+}
+
+// TestCompressionPolicy_WebPResized tests that oversized WebP is no longer passed through
+func TestCompressionPolicy_WebPResized(t *testing.T) {
+	webpData, err := imaging.Encode(createNoisyImage(800, 400), domain.PhotoFormatWebP, 75)
+	if err != nil {
+		t.Fatalf("failed to create WebP: %v", err)
+	}
+
+	policy := DefaultCompressionPolicy()
+	policy.MaxDimension = 400
+	result := policy.Compress(webpData)
+
+	if detectMIMEType(result.Data) != "webp" {
+		t.Fatalf("Result is %q, want webp", detectMIMEType(result.Data))
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(result.Data))
+	if err != nil {
+		t.Fatalf("Result does not decode: %v", err)
+	}
+	if config.Width != 400 || config.Height != 200 {
+		t.Errorf("Result is %dx%d, want 400x200", config.Width, config.Height)
+	}
+}
+
+// TestCompressionPolicy_AdaptiveMeetsBudget tests that adaptive mode lowers the
+// quality until the byte budget fits
+func TestCompressionPolicy_AdaptiveMeetsBudget(t *testing.T) {
+	original := encodeTestJPEG(createNoisyImage(400, 300), 95)
+	atDefault := DefaultCompressionPolicy().Compress(original)
+
+	policy := DefaultCompressionPolicy()
+	policy.Adaptive = true
+	policy.MaxBytes = len(atDefault.Data) * 2 / 3
+	result := policy.Compress(original)
+
+	if len(result.Data) > policy.MaxBytes {
+		t.Errorf("Result is %d bytes, budget is %d", len(result.Data), policy.MaxBytes)
+	}
+	if result.OverBudget {
+		t.Error("Result should not be reported over budget")
+	}
+	if result.Quality >= JPEGQuality || result.Quality < MinAdaptiveQuality {
+		t.Errorf("Quality is %d, want between %d and %d", result.Quality, MinAdaptiveQuality, JPEGQuality)
+	}
+	if result.Attempts < 2 {
+		t.Errorf("Attempts is %d, want at least 2", result.Attempts)
+	}
+}
+
+// TestCompressionPolicy_AdaptiveDownscales tests that adaptive mode shrinks the
+// image when the lowest quality is not enough
+func TestCompressionPolicy_AdaptiveDownscales(t *testing.T) {
+	policy := DefaultCompressionPolicy()
+	policy.Adaptive = true
+	policy.MaxBytes = 2000
+	result := policy.Compress(encodeTestJPEG(createNoisyImage(400, 300), 95))
+
+	if result.Quality != MinAdaptiveQuality {
+		t.Errorf("Quality is %d, want %d", result.Quality, MinAdaptiveQuality)
+	}
+	if result.Width >= 400 {
+		t.Errorf("Width is %d, want the image scaled down", result.Width)
+	}
+}
+
+// TestCompressionPolicy_BudgetWithoutAdaptive tests that the budget is only
+// reported when adaptive mode is off
+func TestCompressionPolicy_BudgetWithoutAdaptive(t *testing.T) {
+	policy := DefaultCompressionPolicy()
+	policy.MaxBytes = 1000
+	result := policy.Compress(encodeTestJPEG(createNoisyImage(400, 300), 95))
+
+	if result.Quality != JPEGQuality || result.Attempts != 1 {
+		t.Errorf("Got quality %d after %d attempts, want %d after 1", result.Quality, result.Attempts, JPEGQuality)
+	}
+	if !result.OverBudget {
+		t.Error("Result should be reported over budget")
+	}
+}
+
+// TestCompressionPolicy_MaxTokens tests that images are scaled down to fit the token budget
+func TestCompressionPolicy_MaxTokens(t *testing.T) {
+	policy := DefaultCompressionPolicy()
+	policy.MaxTokens = 2 * tokensPerImageTile
+	result := policy.Compress(createTestJPEGWithDimensions(1600, 1200))
+
+	if result.EstimatedTokens > policy.MaxTokens {
+		t.Errorf("Estimated tokens %d exceed budget %d", result.EstimatedTokens, policy.MaxTokens)
+	}
+	if result.Width != 768 || result.Height != 576 {
+		t.Errorf("Result is %dx%d, want 768x576", result.Width, result.Height)
+	}
+}
+
+// TestEstimateImageTokens tests the tile-based token estimate
+func TestEstimateImageTokens(t *testing.T) {
+	tests := []struct {
+		width, height, tokens int
+	}{
+		{100, 100, 258},
+		{384, 384, 258},
+		{385, 100, 258},
+		{768, 768, 258},
+		{1600, 1200, 3 * 2 * 258},
+	}
+	for _, tt := range tests {
+		if got := EstimateImageTokens(tt.width, tt.height); got != tt.tokens {
+			t.Errorf("EstimateImageTokens(%d, %d) = %d, want %d", tt.width, tt.height, got, tt.tokens)
+		}
+	}
+}
+
+func TestCompressionMetrics_RecordsSizes(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	previous := otel.GetMeterProvider()
+	otel.SetMeterProvider(telemetry.NewMeterProvider(telemetry.Config{}, sdkmetric.WithReader(reader)))
+	t.Cleanup(func() { otel.SetMeterProvider(previous) })
+
+	metrics, err := newCompressionMetrics()
+	if err != nil {
+		t.Fatalf("newCompressionMetrics failed: %v", err)
+	}
+	result := DefaultCompressionPolicy().Compress(createTestJPEGWithDimensions(800, 600))
+	metrics.record(context.Background(), result)
+
+	var collected metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &collected); err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	want := map[string]int64{
+		"image.original_size":    int64(result.OriginalSize),
+		"image.compressed_size":  int64(len(result.Data)),
+		"image.estimated_tokens": int64(result.EstimatedTokens),
+	}
+	for _, scope := range collected.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			expected, ok := want[m.Name]
+			if !ok {
+				continue
+			}
+			histogram, ok := m.Data.(metricdata.Histogram[int64])
+			if !ok || len(histogram.DataPoints) != 1 {
+				t.Fatalf("%s: expected one histogram data point, got %#v", m.Name, m.Data)
+			}
+			point := histogram.DataPoints[0]
+			if point.Count != 1 || point.Sum != expected {
+				t.Errorf("%s: expected one value of %d, got count %d sum %d", m.Name, expected, point.Count, point.Sum)
+			}
+			if format, _ := point.Attributes.Value("image.format"); format.AsString() != "jpeg" {
+				t.Errorf("%s: expected image.format jpeg, got %q", m.Name, format.AsString())
+			}
+			delete(want, m.Name)
+		}
+	}
+	if len(want) > 0 {
+		t.Errorf("missing metrics: %v", want)
+	}
+}
@@ -0,0 +1,204 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"rechtebank/backend/internal/core/domain"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// harmCategories maps Gemini's harm categories to the names recorded in
+// moderation results
+var harmCategories = map[genai.HarmCategory]string{
+	genai.HarmCategoryDerogatory:       "derogatory",
+	genai.HarmCategoryToxicity:         "toxicity",
+	genai.HarmCategoryViolence:         "violence",
+	genai.HarmCategorySexual:           "sexual",
+	genai.HarmCategoryMedical:          "medical",
+	genai.HarmCategoryDangerous:        "dangerous",
+	genai.HarmCategoryHarassment:       "harassment",
+	genai.HarmCategoryHateSpeech:       "hate_speech",
+	genai.HarmCategorySexuallyExplicit: "sexually_explicit",
+	genai.HarmCategoryDangerousContent: "dangerous_content",
+}
+
+var harmProbabilities = map[genai.HarmProbability]domain.HarmProbability{
+	genai.HarmProbabilityNegligible: domain.HarmProbabilityNegligible,
+	genai.HarmProbabilityLow:        domain.HarmProbabilityLow,
+	genai.HarmProbabilityMedium:     domain.HarmProbabilityMedium,
+	genai.HarmProbabilityHigh:       domain.HarmProbabilityHigh,
+}
+
+// convertSafetyRatings converts Gemini's safety ratings to domain ratings
+func convertSafetyRatings(ratings []*genai.SafetyRating) []domain.SafetyRating {
+	var converted []domain.SafetyRating
+	for _, rating := range ratings {
+		if rating == nil {
+			continue
+		}
+		category, ok := harmCategories[rating.Category]
+		if !ok {
+			category = "unspecified"
+		}
+		converted = append(converted, domain.SafetyRating{
+			Category:    category,
+			Probability: harmProbabilities[rating.Probability],
+			Blocked:     rating.Blocked,
+		})
+	}
+	return converted
+}
+
+// blockedRatings returns the ratings explaining why Gemini blocked a request.
+// A blocked request without ratings is still recorded as blocked.
+func blockedRatings(err *genai.BlockedError) []domain.SafetyRating {
+	var ratings []*genai.SafetyRating
+	if err.PromptFeedback != nil {
+		ratings = append(ratings, err.PromptFeedback.SafetyRatings...)
+	}
+	if err.Candidate != nil {
+		ratings = append(ratings, err.Candidate.SafetyRatings...)
+	}
+
+	converted := convertSafetyRatings(ratings)
+	for _, rating := range converted {
+		if rating.Blocked {
+			return converted
+		}
+	}
+	return append(converted, domain.SafetyRating{Category: "unspecified", Probability: domain.HarmProbabilityUnknown, Blocked: true})
+}
+
+// contentBlockedVerdict returns the verdict for a photo Gemini refused to judge
+func contentBlockedVerdict(ratings []domain.SafetyRating) (*domain.VerdictResponse, error) {
+	verdict := domain.ContentBlockedVerdict()
+	raw, err := verdict.FlatJSON(nil)
+	if err != nil {
+		return nil, domain.WrapError(domain.ErrCodeInternal, "failed to encode verdict", err)
+	}
+	verdict.RawJSON = raw
+	verdict.SafetyRatings = ratings
+	return verdict, nil
+}
+
+// moderatedCategories are the harm categories the image classifier must rate
+// every photo in
+var moderatedCategories = []string{"sexually_explicit", "violence", "hate_speech", "dangerous_content", "harassment"}
+
+// classificationPrompt instructs the model to classify the photo itself
+const classificationPrompt = `You are the content safety classifier of a public gallery of furniture photos. Rate how likely the photo shows each kind of harmful content, judging everything visible in it: people, text, symbols and objects. Rate every category as "negligible", "low", "medium" or "high".`
+
+// classificationRequest accompanies the photo in a classification request
+const classificationRequest = "Classify this photo."
+
+// classificationSchema returns the response schema of a classification: one
+// probability per moderated category
+func classificationSchema() *genai.Schema {
+	probabilities := []string{"negligible", "low", "medium", "high"}
+	properties := make(map[string]*genai.Schema, len(moderatedCategories))
+	for _, category := range moderatedCategories {
+		properties[category] = &genai.Schema{Type: genai.TypeString, Enum: probabilities}
+	}
+	return &genai.Schema{
+		Type:       genai.TypeObject,
+		Properties: properties,
+		Required:   moderatedCategories,
+	}
+}
+
+// parseClassification converts a classification response to ratings. A
+// category that is missing or has an unrecognized value is rated unknown.
+func parseClassification(data []byte) ([]domain.SafetyRating, error) {
+	var classification map[string]string
+	if err := json.Unmarshal(data, &classification); err != nil {
+		return nil, &InvalidResponseError{Message: fmt.Sprintf("failed to parse classification: %v", err)}
+	}
+	ratings := make([]domain.SafetyRating, 0, len(moderatedCategories))
+	for _, category := range moderatedCategories {
+		probability, _ := domain.ParseHarmProbability(classification[category])
+		ratings = append(ratings, domain.SafetyRating{Category: category, Probability: probability})
+	}
+	return ratings, nil
+}
+
+// ImageClassifier rates a photo itself, rather than a response about it, in
+// each of the moderated harm categories
+type ImageClassifier interface {
+	ClassifyImage(ctx context.Context, imageData []byte) ([]domain.SafetyRating, error)
+}
+
+// SafetyRatingModerator flags photos using safety ratings of the photo
+// itself, from a classification request separate from the analysis. The
+// analysis' own ratings score the verdict text, so only a refusal counts.
+type SafetyRatingModerator struct {
+	classifier ImageClassifier
+	threshold  domain.HarmProbability
+}
+
+// NewSafetyRatingModerator creates a moderator that flags photos the
+// classifier rates at or above threshold in any harm category, and photos
+// Gemini blocked
+func NewSafetyRatingModerator(classifier ImageClassifier, threshold domain.HarmProbability) *SafetyRatingModerator {
+	return &SafetyRatingModerator{classifier: classifier, threshold: threshold}
+}
+
+// Moderate implements ports.IContentModerator. A category the classifier did
+// not rate cannot be cleared, so the photo is withheld as
+// domain.ModerationUnavailable.
+func (m *SafetyRatingModerator) Moderate(ctx context.Context, imageData []byte, verdict *domain.VerdictResponse) (*domain.ModerationResult, error) {
+	result := &domain.ModerationResult{}
+	for _, rating := range verdict.SafetyRatings {
+		if rating.Blocked {
+			flag(result, rating.Category)
+		}
+	}
+
+	if !result.Flagged {
+		ratings, err := m.classifier.ClassifyImage(ctx, imageData)
+		if err != nil {
+			return nil, err
+		}
+		m.rate(result, ratings)
+	}
+
+	if result.Flagged {
+		logger.WarnContext(ctx, "Photo flagged by moderation", slog.Any("categories", result.Categories))
+	}
+	return result, nil
+}
+
+// rate flags the categories the classifier blocked, rated at or above the
+// threshold, or left unrated
+func (m *SafetyRatingModerator) rate(result *domain.ModerationResult, ratings []domain.SafetyRating) {
+	for _, rating := range ratings {
+		if rating.Blocked {
+			flag(result, rating.Category)
+		}
+	}
+	if result.Flagged {
+		return
+	}
+
+	for _, category := range moderatedCategories {
+		i := slices.IndexFunc(ratings, func(r domain.SafetyRating) bool { return r.Category == category })
+		switch {
+		case i < 0 || ratings[i].Probability == domain.HarmProbabilityUnknown:
+			flag(result, domain.ModerationUnavailable)
+		case ratings[i].Probability >= m.threshold:
+			flag(result, category)
+		}
+	}
+}
+
+// flag marks result as flagged because of category
+func flag(result *domain.ModerationResult, category string) {
+	result.Flagged = true
+	if !slices.Contains(result.Categories, category) {
+		result.Categories = append(result.Categories, category)
+	}
+}
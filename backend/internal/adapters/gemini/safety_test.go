@@ -0,0 +1,252 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"rechtebank/backend/internal/core/domain"
+
+	"github.com/google/generative-ai-go/genai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertSafetyRatings(t *testing.T) {
+	ratings := convertSafetyRatings([]*genai.SafetyRating{
+		{Category: genai.HarmCategorySexuallyExplicit, Probability: genai.HarmProbabilityHigh, Blocked: true},
+		nil,
+		{Category: genai.HarmCategoryHarassment, Probability: genai.HarmProbabilityNegligible},
+		{Category: genai.HarmCategoryUnspecified, Probability: genai.HarmProbabilityUnspecified},
+	})
+
+	assert.Equal(t, []domain.SafetyRating{
+		{Category: "sexually_explicit", Probability: domain.HarmProbabilityHigh, Blocked: true},
+		{Category: "harassment", Probability: domain.HarmProbabilityNegligible},
+		{Category: "unspecified", Probability: domain.HarmProbabilityUnknown},
+	}, ratings)
+}
+
+func TestBlockedRatings(t *testing.T) {
+	t.Run("keeps the blocking rating", func(t *testing.T) {
+		ratings := blockedRatings(&genai.BlockedError{
+			PromptFeedback: &genai.PromptFeedback{SafetyRatings: []*genai.SafetyRating{
+				{Category: genai.HarmCategoryDangerousContent, Probability: genai.HarmProbabilityMedium, Blocked: true},
+			}},
+		})
+		assert.Equal(t, []domain.SafetyRating{
+			{Category: "dangerous_content", Probability: domain.HarmProbabilityMedium, Blocked: true},
+		}, ratings)
+	})
+
+	t.Run("records a block without ratings", func(t *testing.T) {
+		ratings := blockedRatings(&genai.BlockedError{Candidate: &genai.Candidate{}})
+		require.Len(t, ratings, 1)
+		assert.True(t, ratings[0].Blocked)
+		assert.Equal(t, "unspecified", ratings[0].Category)
+	})
+}
+
+// stubClassifier returns fixed ratings and counts its calls
+type stubClassifier struct {
+	ratings []domain.SafetyRating
+	err     error
+	calls   int
+}
+
+func (c *stubClassifier) ClassifyImage(ctx context.Context, imageData []byte) ([]domain.SafetyRating, error) {
+	c.calls++
+	return c.ratings, c.err
+}
+
+// cleanRatings rates every moderated category negligible, except the overrides
+func cleanRatings(overrides ...domain.SafetyRating) []domain.SafetyRating {
+	var ratings []domain.SafetyRating
+	for _, category := range moderatedCategories {
+		rating := domain.SafetyRating{Category: category, Probability: domain.HarmProbabilityNegligible}
+		for _, override := range overrides {
+			if override.Category == category {
+				rating = override
+			}
+		}
+		ratings = append(ratings, rating)
+	}
+	return ratings
+}
+
+func TestSafetyRatingModerator_Moderate(t *testing.T) {
+	tests := []struct {
+		name       string
+		ratings    []domain.SafetyRating
+		flagged    bool
+		categories []string
+	}{
+		{
+			name:    "all categories negligible",
+			ratings: cleanRatings(),
+			flagged: false,
+		},
+		{
+			name:    "below threshold",
+			ratings: cleanRatings(domain.SafetyRating{Category: "harassment", Probability: domain.HarmProbabilityLow}),
+			flagged: false,
+		},
+		{
+			name: "at threshold",
+			ratings: cleanRatings(
+				domain.SafetyRating{Category: "harassment", Probability: domain.HarmProbabilityLow},
+				domain.SafetyRating{Category: "sexually_explicit", Probability: domain.HarmProbabilityMedium},
+			),
+			flagged:    true,
+			categories: []string{"sexually_explicit"},
+		},
+		{
+			name: "blocked regardless of probability",
+			ratings: []domain.SafetyRating{
+				{Category: "unspecified", Probability: domain.HarmProbabilityUnknown, Blocked: true},
+				{Category: "unspecified", Probability: domain.HarmProbabilityUnknown, Blocked: true},
+			},
+			flagged:    true,
+			categories: []string{"unspecified"},
+		},
+		{
+			name:       "unknown probability is not clean",
+			ratings:    cleanRatings(domain.SafetyRating{Category: "violence", Probability: domain.HarmProbabilityUnknown}),
+			flagged:    true,
+			categories: []string{domain.ModerationUnavailable},
+		},
+		{
+			name:       "missing rating is not clean",
+			ratings:    cleanRatings()[1:],
+			flagged:    true,
+			categories: []string{domain.ModerationUnavailable},
+		},
+		{
+			name:       "no ratings",
+			flagged:    true,
+			categories: []string{domain.ModerationUnavailable},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			moderator := NewSafetyRatingModerator(&stubClassifier{ratings: tt.ratings}, domain.HarmProbabilityMedium)
+			result, err := moderator.Moderate(context.Background(), []byte{0xFF, 0xD8, 0xFF}, &domain.VerdictResponse{})
+			require.NoError(t, err)
+			assert.Equal(t, tt.flagged, result.Flagged)
+			assert.Equal(t, tt.categories, result.Categories)
+		})
+	}
+}
+
+func TestSafetyRatingModerator_FlagsPhotoDespiteHarmlessVerdict(t *testing.T) {
+	classifier := &stubClassifier{ratings: cleanRatings(
+		domain.SafetyRating{Category: "sexually_explicit", Probability: domain.HarmProbabilityHigh},
+	)}
+	moderator := NewSafetyRatingModerator(classifier, domain.HarmProbabilityMedium)
+
+	// The analysis' ratings score the harmless verdict text
+	verdict := &domain.VerdictResponse{
+		Admissible: true,
+		Score:      7,
+		SafetyRatings: []domain.SafetyRating{
+			{Category: "sexually_explicit", Probability: domain.HarmProbabilityNegligible},
+			{Category: "harassment", Probability: domain.HarmProbabilityNegligible},
+		},
+	}
+
+	result, err := moderator.Moderate(context.Background(), []byte{0xFF, 0xD8, 0xFF}, verdict)
+
+	require.NoError(t, err)
+	assert.True(t, result.Flagged)
+	assert.Equal(t, []string{"sexually_explicit"}, result.Categories)
+	assert.Equal(t, 1, classifier.calls)
+}
+
+func TestSafetyRatingModerator_BlockedAnalysisSkipsClassifier(t *testing.T) {
+	classifier := &stubClassifier{ratings: cleanRatings()}
+	moderator := NewSafetyRatingModerator(classifier, domain.HarmProbabilityMedium)
+	verdict := &domain.VerdictResponse{SafetyRatings: []domain.SafetyRating{
+		{Category: "dangerous_content", Probability: domain.HarmProbabilityMedium, Blocked: true},
+	}}
+
+	result, err := moderator.Moderate(context.Background(), nil, verdict)
+
+	require.NoError(t, err)
+	assert.True(t, result.Flagged)
+	assert.Equal(t, []string{"dangerous_content"}, result.Categories)
+	assert.Zero(t, classifier.calls)
+}
+
+func TestSafetyRatingModerator_ClassifierError(t *testing.T) {
+	moderator := NewSafetyRatingModerator(&stubClassifier{err: errors.New("quota exceeded")}, domain.HarmProbabilityMedium)
+
+	_, err := moderator.Moderate(context.Background(), nil, &domain.VerdictResponse{})
+
+	assert.Error(t, err)
+}
+
+func TestParseClassification(t *testing.T) {
+	ratings, err := parseClassification([]byte(`{"sexually_explicit":"high","violence":"low","hate_speech":"negligible","harassment":"sometimes"}`))
+
+	require.NoError(t, err)
+	assert.Equal(t, []domain.SafetyRating{
+		{Category: "sexually_explicit", Probability: domain.HarmProbabilityHigh},
+		{Category: "violence", Probability: domain.HarmProbabilityLow},
+		{Category: "hate_speech", Probability: domain.HarmProbabilityNegligible},
+		{Category: "dangerous_content", Probability: domain.HarmProbabilityUnknown},
+		{Category: "harassment", Probability: domain.HarmProbabilityUnknown},
+	}, ratings)
+
+	_, err = parseClassification([]byte("not json"))
+	var invalid *InvalidResponseError
+	assert.ErrorAs(t, err, &invalid)
+}
+
+func TestGeminiAnalyzer_AnalyzePhoto_Blocked(t *testing.T) {
+	mockClient := new(MockGeminiClient)
+	analyzer := &GeminiAnalyzer{
+		client:  mockClient,
+		timeout: 30 * time.Second,
+	}
+
+	imageData := []byte{0xFF, 0xD8, 0xFF}
+	ratings := []domain.SafetyRating{{Category: "sexually_explicit", Probability: domain.HarmProbabilityHigh, Blocked: true}}
+	mockClient.On("GenerateContent", mock.Anything, imageData).Return(&GeminiResponse{Blocked: true, SafetyRatings: ratings}, nil)
+
+	result, err := analyzer.AnalyzePhoto(context.Background(), imageData)
+
+	require.NoError(t, err)
+	assert.False(t, result.Admissible)
+	assert.Equal(t, 0, result.Score)
+	assert.Equal(t, "Bewijsmateriaal ontoelaatbaar", result.Verdict.Crime)
+	assert.Equal(t, domain.VerdictTypeNietOntvankelijk, result.Verdict.VerdictType)
+	assert.Equal(t, ratings, result.SafetyRatings)
+	assert.Contains(t, result.RawJSON, `"admissible":false`)
+	mockClient.AssertExpectations(t)
+}
+
+func TestGeminiAnalyzer_AnalyzePhoto_PassesSafetyRatings(t *testing.T) {
+	mockClient := new(MockGeminiClient)
+	analyzer := &GeminiAnalyzer{
+		client:  mockClient,
+		timeout: 30 * time.Second,
+	}
+
+	imageData := []byte{0xFF, 0xD8, 0xFF}
+	ratings := []domain.SafetyRating{{Category: "harassment", Probability: domain.HarmProbabilityNegligible}}
+	mockClient.On("GenerateContent", mock.Anything, imageData).Return(&GeminiResponse{
+		Admissible:    true,
+		Score:         7,
+		Crime:         "Lichte scheefstand",
+		SafetyRatings: ratings,
+	}, nil)
+
+	result, err := analyzer.AnalyzePhoto(context.Background(), imageData)
+
+	require.NoError(t, err)
+	assert.True(t, result.Admissible)
+	assert.Equal(t, ratings, result.SafetyRatings)
+}
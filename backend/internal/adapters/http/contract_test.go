@@ -0,0 +1,242 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/jpeg"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"rechtebank/backend/internal/adapters/http/handlers"
+	"rechtebank/backend/internal/adapters/http/openapi"
+	"rechtebank/backend/internal/core/domain"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	// Photo responses are opaque bytes to the validator
+	for _, format := range domain.PhotoFormats {
+		openapi3filter.RegisterBodyDecoder(format.MIMEType, openapi3filter.FileBodyDecoder)
+	}
+}
+
+// contractFixture is a router backed by a mock service and a temporary
+// storage directory containing one saved verdict
+type contractFixture struct {
+	router    *gin.Engine
+	specRoute routers.Router
+	service   *MockVerdictService
+	verdictID string
+}
+
+func newContractFixture(t *testing.T) *contractFixture {
+	doc, err := openapi3.NewLoader().LoadFromData(openapi.Spec)
+	require.NoError(t, err)
+	specRouter, err := gorillamux.NewRouter(doc)
+	require.NoError(t, err)
+
+	storageDir := t.TempDir()
+	dateDir := filepath.Join(storageDir, "2026-02-01")
+	require.NoError(t, os.MkdirAll(dateDir, 0755))
+	var photo bytes.Buffer
+	require.NoError(t, jpeg.Encode(&photo, image.NewGray(image.Rect(0, 0, 64, 48)), nil))
+	require.NoError(t, os.WriteFile(filepath.Join(dateDir, "153045_abc123.jpg"), photo.Bytes(), 0644))
+	verdictJSON := `{"admissible":true,"score":8,"crime":"Scheve zitting","sentence":"Berisping","reasoning":"Artikel 42","observation":"Een stoel","verdictType":"waarschuwing","requestId":"abc123","timestamp":"2026-02-01T15:30:45Z"}`
+	require.NoError(t, os.WriteFile(filepath.Join(dateDir, "153045_abc123.json"), []byte(verdictJSON), 0644))
+
+	service := new(MockVerdictService)
+	router := NewRouter(
+		handlers.NewJudgeHandler(service, nil, testMaxFileSize),
+		handlers.NewVerdictHandler(storageDir, nil, nil, nil),
+		handlers.NewHealthHandler(nil),
+		RouterConfig{CORSOrigin: "*"},
+	)
+
+	return &contractFixture{
+		router:    router,
+		specRoute: specRouter,
+		service:   service,
+		verdictID: domain.EncodeVerdictID("2026-02-01/153045_abc123"),
+	}
+}
+
+// serveAndValidate sends the request through the real router and validates
+// both the request and the response against the OpenAPI document
+func (f *contractFixture) serveAndValidate(t *testing.T, req *http.Request, body []byte) *httptest.ResponseRecorder {
+	t.Helper()
+
+	// Validate against a copy so the handler still sees the full body
+	specReq := req.Clone(context.Background())
+	specReq.Body = io.NopCloser(bytes.NewReader(body))
+	specReq.URL.Scheme = ""
+	specReq.URL.Host = ""
+
+	route, pathParams, err := f.specRoute.FindRoute(specReq)
+	require.NoError(t, err, "route %s %s is not documented", req.Method, req.URL.Path)
+
+	input := &openapi3filter.RequestValidationInput{
+		Request:    specReq,
+		PathParams: pathParams,
+		Route:      route,
+		Options:    &openapi3filter.Options{ExcludeRequestBody: len(body) == 0},
+	}
+
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	f.router.ServeHTTP(w, req)
+
+	err = openapi3filter.ValidateResponse(context.Background(), &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 w.Code,
+		Header:                 w.Header(),
+		Body:                   io.NopCloser(bytes.NewReader(w.Body.Bytes())),
+	})
+	assert.NoError(t, err, "response for %s %s (%d) violates spec: %s", req.Method, req.URL.Path, w.Code, w.Body.String())
+
+	return w
+}
+
+func multipartPhoto(t *testing.T, content []byte) ([]byte, string) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	part, err := writer.CreateFormFile("photo", "stoel.jpg")
+	require.NoError(t, err)
+	_, err = part.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return buf.Bytes(), writer.FormDataContentType()
+}
+
+func TestContract_ProbeEndpoints(t *testing.T) {
+	f := newContractFixture(t)
+
+	for _, path := range []string{"/health", "/livez", "/readyz", "/openapi.json"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := f.serveAndValidate(t, req, nil)
+		assert.Equal(t, http.StatusOK, w.Code, path)
+	}
+}
+
+func TestContract_Judge(t *testing.T) {
+	f := newContractFixture(t)
+	imageData := []byte{0xFF, 0xD8, 0xFF, 0xE0}
+
+	f.service.On("JudgePhoto", mock.Anything, imageData, mock.Anything).Return(&domain.VerdictResponse{
+		Admissible: true,
+		Score:      7,
+		Verdict: domain.VerdictDetails{
+			Crime:       "Scheve zitting",
+			Sentence:    "Berisping",
+			Reasoning:   "Artikel 42",
+			Observation: "Een stoel",
+			VerdictType: domain.VerdictTypeWaarschuwing,
+		},
+		RequestID: "550e8400-e29b-41d4-a716-446655440000",
+		Timestamp: "2026-02-01T15:30:45Z",
+	}, nil).Once()
+
+	body, contentType := multipartPhoto(t, imageData)
+	req := httptest.NewRequest(http.MethodPost, "/v1/judge", nil)
+	req.Header.Set("Content-Type", contentType)
+	w := f.serveAndValidate(t, req, body)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Error responses share the same documented shape
+	f.service.On("JudgePhoto", mock.Anything, imageData, mock.Anything).Return(nil,
+		domain.NewError(domain.ErrCodeAnalyzerTimeout, "AI analysis timeout")).Once()
+
+	body, contentType = multipartPhoto(t, imageData)
+	req = httptest.NewRequest(http.MethodPost, "/v1/judge", nil)
+	req.Header.Set("Content-Type", contentType)
+	w = f.serveAndValidate(t, req, body)
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+}
+
+func TestContract_GetVerdict(t *testing.T) {
+	f := newContractFixture(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/verdict/"+f.verdictID, nil)
+	w := f.serveAndValidate(t, req, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/verdict/"+domain.EncodeVerdictID("2026-02-01/000000_missing"), nil)
+	w = f.serveAndValidate(t, req, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestContract_GetVerdictImage(t *testing.T) {
+	f := newContractFixture(t)
+	imagePath := "/v1/verdict/" + f.verdictID + "/image"
+
+	req := httptest.NewRequest(http.MethodGet, imagePath, nil)
+	w := f.serveAndValidate(t, req, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/jpeg", w.Header().Get("Content-Type"))
+
+	req = httptest.NewRequest(http.MethodGet, imagePath, nil)
+	req.Header.Set("If-None-Match", w.Header().Get("ETag"))
+	w = f.serveAndValidate(t, req, nil)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	req = httptest.NewRequest(http.MethodGet, imagePath+"?w=32", nil)
+	w = f.serveAndValidate(t, req, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/verdict/"+domain.EncodeVerdictID("2026-02-01/000000_missing")+"/image", nil)
+	w = f.serveAndValidate(t, req, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestContract_ShareVerdict(t *testing.T) {
+	f := newContractFixture(t)
+
+	share := func(payload any) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(http.MethodPost, "/v1/verdict/share", nil)
+		req.Header.Set("Content-Type", "application/json")
+		return f.serveAndValidate(t, req, body)
+	}
+
+	w := share(handlers.ShareRequest{Timestamp: "2026-02-01T15:30:45Z", RequestID: "abc123"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = share(handlers.ShareRequest{Timestamp: "2026-02-01T15:30:45Z", RequestID: "unknown"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestContract_EveryRouteIsDocumented(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromData(openapi.Spec)
+	require.NoError(t, err)
+
+	f := newContractFixture(t)
+	for _, route := range f.router.Routes() {
+		// Convert gin ":param" segments to OpenAPI "{param}"
+		segments := strings.Split(route.Path, "/")
+		for i, segment := range segments {
+			if strings.HasPrefix(segment, ":") {
+				segments[i] = "{" + segment[1:] + "}"
+			}
+		}
+		path := strings.Join(segments, "/")
+
+		item := doc.Paths.Find(path)
+		require.NotNil(t, item, "path %s is not documented", path)
+		assert.NotNil(t, item.GetOperation(route.Method), "%s %s is not documented", route.Method, path)
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"time"
+
+	"rechtebank/backend/internal/adapters/storage"
+	"rechtebank/backend/internal/core/domain"
+	"rechtebank/backend/internal/scheduler"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JobLister lists the scheduled background jobs with their run history
+type JobLister interface {
+	Jobs() []scheduler.JobStatus
+}
+
+// StatsRollups provides the stats rollups of stored cases
+type StatsRollups interface {
+	LatestStats() (storage.StatsRollup, bool, error)
+	RollupStats(ctx context.Context, now time.Time) (storage.StatsRollup, error)
+}
+
+// JobsResponse is the response of GET /admin/jobs
+type JobsResponse struct {
+	Jobs []scheduler.JobStatus `json:"jobs"`
+}
+
+// AdminHandler serves the operator endpoints under /admin
+type AdminHandler struct {
+	jobs  JobLister
+	stats StatsRollups
+}
+
+// NewAdminHandler creates a new AdminHandler
+func NewAdminHandler(jobs JobLister, stats StatsRollups) *AdminHandler {
+	return &AdminHandler{
+		jobs:  jobs,
+		stats: stats,
+	}
+}
+
+// RequireBearerToken rejects requests without "Authorization: Bearer <token>"
+// with UNAUTHORIZED
+func RequireBearerToken(token string) gin.HandlerFunc {
+	expected := []byte("Bearer " + token)
+	return func(c *gin.Context) {
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), expected) != 1 {
+			respondError(c, domain.ErrCodeUnauthorized)
+			return
+		}
+		c.Next()
+	}
+}
+
+// Jobs handles GET /admin/jobs requests, listing every background job with
+// its next run and most recent runs
+func (h *AdminHandler) Jobs(c *gin.Context) {
+	c.JSON(http.StatusOK, JobsResponse{Jobs: h.jobs.Jobs()})
+}
+
+// Stats handles GET /admin/stats requests, returning the latest stats rollup.
+// Before the first scheduled rollup one is made on the spot.
+func (h *AdminHandler) Stats(c *gin.Context) {
+	rollup, ok, err := h.stats.LatestStats()
+	if err == nil && !ok {
+		rollup, err = h.stats.RollupStats(c.Request.Context(), time.Now())
+	}
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, rollup)
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"rechtebank/backend/internal/adapters/storage"
+	"rechtebank/backend/internal/scheduler"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticJobs []scheduler.JobStatus
+
+func (j staticJobs) Jobs() []scheduler.JobStatus { return j }
+
+// fakeRollups has a latest rollup when latest is set, and counts the rollups
+// made on demand
+type fakeRollups struct {
+	latest *storage.StatsRollup
+	err    error
+	rolled int
+}
+
+func (f *fakeRollups) LatestStats() (storage.StatsRollup, bool, error) {
+	if f.latest == nil {
+		return storage.StatsRollup{}, false, f.err
+	}
+	return *f.latest, true, f.err
+}
+
+func (f *fakeRollups) RollupStats(ctx context.Context, now time.Time) (storage.StatsRollup, error) {
+	f.rolled++
+	return storage.StatsRollup{GeneratedAt: now, Cases: 2}, nil
+}
+
+func newAdminRouter(handler *AdminHandler) *gin.Engine {
+	router := gin.New()
+	admin := router.Group("/admin", RequireBearerToken("s3cret"))
+	admin.GET("/jobs", handler.Jobs)
+	admin.GET("/stats", handler.Stats)
+	return router
+}
+
+func adminRequest(router *gin.Engine, path, authorization string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAdminHandler_RequiresToken(t *testing.T) {
+	router := newAdminRouter(NewAdminHandler(staticJobs{}, &fakeRollups{}))
+
+	for _, authorization := range []string{"", "Bearer wrong", "s3cret", "Basic s3cret"} {
+		w := adminRequest(router, "/admin/jobs", authorization)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, authorization)
+		assert.Contains(t, w.Body.String(), "UNAUTHORIZED")
+	}
+	assert.Equal(t, http.StatusOK, adminRequest(router, "/admin/jobs", "Bearer s3cret").Code)
+}
+
+func TestAdminHandler_Jobs(t *testing.T) {
+	started := time.Date(2026, 2, 1, 3, 0, 0, 0, time.UTC)
+	jobs := staticJobs{{
+		Name:     "cleanup",
+		Schedule: "0 3 * * *",
+		NextRun:  started.Add(24 * time.Hour),
+		Runs: []scheduler.Run{{
+			Job:        "cleanup",
+			Status:     scheduler.RunSucceeded,
+			StartedAt:  started,
+			FinishedAt: started.Add(time.Second),
+			Summary:    "purged 3 cases",
+		}},
+	}}
+	router := newAdminRouter(NewAdminHandler(jobs, &fakeRollups{}))
+
+	w := adminRequest(router, "/admin/jobs", "Bearer s3cret")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response JobsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, []scheduler.JobStatus(jobs), response.Jobs)
+}
+
+func TestAdminHandler_Stats(t *testing.T) {
+	latest := &storage.StatsRollup{Cases: 7}
+	rollups := &fakeRollups{latest: latest}
+	router := newAdminRouter(NewAdminHandler(staticJobs{}, rollups))
+
+	w := adminRequest(router, "/admin/stats", "Bearer s3cret")
+	require.Equal(t, http.StatusOK, w.Code)
+	var rollup storage.StatsRollup
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rollup))
+	assert.Equal(t, 7, rollup.Cases)
+	assert.Zero(t, rollups.rolled)
+}
+
+func TestAdminHandler_Stats_RollsUpBeforeFirstRollup(t *testing.T) {
+	rollups := &fakeRollups{}
+	router := newAdminRouter(NewAdminHandler(staticJobs{}, rollups))
+
+	w := adminRequest(router, "/admin/stats", "Bearer s3cret")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, rollups.rolled)
+	assert.Contains(t, w.Body.String(), `"cases":2`)
+}
+
+func TestAdminHandler_Stats_ReadError(t *testing.T) {
+	router := newAdminRouter(NewAdminHandler(staticJobs{}, &fakeRollups{err: errors.New("disk gone")}))
+
+	w := adminRequest(router, "/admin/stats", "Bearer s3cret")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NotContains(t, w.Body.String(), "disk gone")
+}
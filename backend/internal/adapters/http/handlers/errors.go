@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"rechtebank/backend/internal/core/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Languages error messages are available in; Dutch is the default
+const (
+	languageDutch   = "nl"
+	languageEnglish = "en"
+	defaultLanguage = languageDutch
+)
+
+// ErrorResponse is the JSON body of every error response
+type ErrorResponse struct {
+	Code    domain.ErrorCode `json:"code"`
+	Message string           `json:"error"`
+}
+
+// errorSpec describes how an error code is presented to clients
+type errorSpec struct {
+	status   int
+	messages map[string]string
+}
+
+// errorCatalogue maps every error code to its HTTP status and localized messages
+var errorCatalogue = map[domain.ErrorCode]errorSpec{
+	domain.ErrCodeInvalidRequest: {http.StatusBadRequest, map[string]string{
+		languageDutch:   "Het verzoek is ongeldig.",
+		languageEnglish: "The request is invalid.",
+	}},
+	domain.ErrCodePhotoRequired: {http.StatusBadRequest, map[string]string{
+		languageDutch:   "Er is geen foto meegestuurd.",
+		languageEnglish: "A photo is required.",
+	}},
+	domain.ErrCodePhotoTooLarge: {http.StatusRequestEntityTooLarge, map[string]string{
+		languageDutch:   "De foto is te groot.",
+		languageEnglish: "The photo is too large.",
+	}},
+	domain.ErrCodeUnsupportedFormat: {http.StatusUnsupportedMediaType, map[string]string{
+		languageDutch:   "Dit bestandsformaat wordt niet ondersteund. Gebruik JPEG, PNG, WebP, HEIC of AVIF.",
+		languageEnglish: "Unsupported image format. Use JPEG, PNG, WebP, HEIC or AVIF.",
+	}},
+	domain.ErrCodeImageTooLarge: {http.StatusRequestEntityTooLarge, map[string]string{
+		languageDutch:   "De foto heeft te veel pixels.",
+		languageEnglish: "The photo has too many pixels.",
+	}},
+	domain.ErrCodeImageCorrupt: {http.StatusUnprocessableEntity, map[string]string{
+		languageDutch:   "De foto is beschadigd en kan niet worden geopend.",
+		languageEnglish: "The photo is damaged and cannot be opened.",
+	}},
+	domain.ErrCodeAnimatedImage: {http.StatusUnsupportedMediaType, map[string]string{
+		languageDutch:   "Bewegende beelden worden niet als bewijs toegelaten.",
+		languageEnglish: "Animated images are not admissible as evidence.",
+	}},
+	domain.ErrCodeAnalyzerTimeout: {http.StatusGatewayTimeout, map[string]string{
+		languageDutch:   "De rechter heeft te lang beraadslaagd. Probeer het opnieuw.",
+		languageEnglish: "The judge took too long to deliberate. Please try again.",
+	}},
+	domain.ErrCodeAnalyzerUnavailable: {http.StatusServiceUnavailable, map[string]string{
+		languageDutch:   "De rechter is tijdelijk niet beschikbaar. Probeer het later opnieuw.",
+		languageEnglish: "The judge is temporarily unavailable. Please try again later.",
+	}},
+	domain.ErrCodeAnalyzerInvalidResponse: {http.StatusBadGateway, map[string]string{
+		languageDutch:   "De rechter deed een onleesbare uitspraak. Probeer het opnieuw.",
+		languageEnglish: "The judge returned an unreadable verdict. Please try again.",
+	}},
+	domain.ErrCodeInvalidVerdictID: {http.StatusBadRequest, map[string]string{
+		languageDutch:   "Ongeldig vonnisnummer.",
+		languageEnglish: "Invalid verdict ID.",
+	}},
+	domain.ErrCodeVerdictNotFound: {http.StatusNotFound, map[string]string{
+		languageDutch:   "Dit vonnis is niet gevonden.",
+		languageEnglish: "Verdict not found.",
+	}},
+	domain.ErrCodeVerdictWithheld: {http.StatusForbidden, map[string]string{
+		languageDutch:   "Dit vonnis is verzegeld en kan niet worden gedeeld.",
+		languageEnglish: "This verdict was withheld by moderation and cannot be shared.",
+	}},
+	domain.ErrCodeStorageFailure: {http.StatusInternalServerError, map[string]string{
+		languageDutch:   "Het archief van de rechtbank is onbereikbaar.",
+		languageEnglish: "The court archive could not be accessed.",
+	}},
+	domain.ErrCodeStorageFull: {http.StatusServiceUnavailable, map[string]string{
+		languageDutch:   "Het archief van de rechtbank is vol; vonnissen worden tijdelijk niet bewaard of gedeeld.",
+		languageEnglish: "The court archive is full; verdicts are temporarily not stored or shared.",
+	}},
+	domain.ErrCodeUnauthorized: {http.StatusUnauthorized, map[string]string{
+		languageDutch:   "Geen toegang tot de griffie.",
+		languageEnglish: "Not authorized for the court registry.",
+	}},
+	domain.ErrCodeInternal: {http.StatusInternalServerError, map[string]string{
+		languageDutch:   "Er ging iets mis bij de rechtbank.",
+		languageEnglish: "Something went wrong at the court.",
+	}},
+}
+
+// respondError writes the error response for code in the client's language
+func respondError(c *gin.Context, code domain.ErrorCode) {
+	spec, ok := errorCatalogue[code]
+	if !ok {
+		code = domain.ErrCodeInternal
+		spec = errorCatalogue[code]
+	}
+
+	language := preferredLanguage(c.GetHeader("Accept-Language"))
+	c.Header("Content-Language", language)
+	c.AbortWithStatusJSON(spec.status, ErrorResponse{
+		Code:    code,
+		Message: spec.messages[language],
+	})
+}
+
+// respondWithError maps err onto the error catalogue. Errors without a code are
+// logged and reported as INTERNAL_ERROR so internals never reach the client.
+func respondWithError(c *gin.Context, err error) {
+	var domainErr *domain.Error
+	if !errors.As(err, &domainErr) {
+		logger.ErrorContext(c.Request.Context(), "Unhandled error", slog.Any("error", err))
+		respondError(c, domain.ErrCodeInternal)
+		return
+	}
+
+	if errorCatalogue[domainErr.Code].status >= http.StatusInternalServerError {
+		logger.WarnContext(c.Request.Context(), "Request failed",
+			slog.String("code", string(domainErr.Code)),
+			slog.Any("error", err))
+	}
+
+	if domainErr.RetryAfter > 0 {
+		seconds := int(math.Ceil(domainErr.RetryAfter.Seconds()))
+		c.Header("Retry-After", fmt.Sprintf("%d", seconds))
+	}
+	respondError(c, domainErr.Code)
+}
+
+// preferredLanguage picks the supported language with the highest quality
+// value from an Accept-Language header
+func preferredLanguage(acceptLanguage string) string {
+	best, bestQuality := defaultLanguage, 0.0
+	for _, entry := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(entry), ";")
+
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+
+		primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if primary != languageDutch && primary != languageEnglish {
+			continue
+		}
+		if quality > bestQuality {
+			best, bestQuality = primary, quality
+		}
+	}
+	return best
+}
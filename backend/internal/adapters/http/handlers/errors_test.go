@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"rechtebank/backend/internal/core/domain"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorCatalogue_CoversEveryCode(t *testing.T) {
+	for _, code := range domain.ErrorCodes {
+		spec, ok := errorCatalogue[code]
+		require.True(t, ok, "no catalogue entry for %s", code)
+		assert.GreaterOrEqual(t, spec.status, 400, code)
+		assert.NotEmpty(t, spec.messages[languageDutch], "missing Dutch message for %s", code)
+		assert.NotEmpty(t, spec.messages[languageEnglish], "missing English message for %s", code)
+	}
+	assert.Len(t, errorCatalogue, len(domain.ErrorCodes))
+}
+
+func TestPreferredLanguage(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected string
+	}{
+		{"", "nl"},
+		{"en", "en"},
+		{"en-US,en;q=0.9", "en"},
+		{"nl-NL,nl;q=0.9,en;q=0.8", "nl"},
+		{"de-DE,de;q=0.9,en;q=0.5", "en"},
+		{"en;q=0.3,nl;q=0.7", "nl"},
+		{"fr", "nl"},
+		{"EN-gb", "en"},
+		{"en;q=abc", "nl"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			assert.Equal(t, tt.expected, preferredLanguage(tt.header))
+		})
+	}
+}
+
+func serveError(t *testing.T, err error, acceptLanguage string) (*httptest.ResponseRecorder, ErrorResponse) {
+	router := gin.New()
+	router.GET("/", func(c *gin.Context) { respondWithError(c, err) })
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if acceptLanguage != "" {
+		req.Header.Set("Accept-Language", acceptLanguage)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return w, response
+}
+
+func TestRespondWithError_Localized(t *testing.T) {
+	err := domain.NewError(domain.ErrCodeVerdictNotFound, "verdict file missing")
+
+	w, response := serveError(t, err, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "nl", w.Header().Get("Content-Language"))
+	assert.Equal(t, domain.ErrCodeVerdictNotFound, response.Code)
+	assert.Equal(t, "Dit vonnis is niet gevonden.", response.Message)
+
+	w, response = serveError(t, err, "en-GB,en;q=0.9")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "en", w.Header().Get("Content-Language"))
+	assert.Equal(t, "Verdict not found.", response.Message)
+}
+
+func TestRespondWithError_WrappedCode(t *testing.T) {
+	err := domain.WrapError(domain.ErrCodeAnalyzerTimeout, "AI analysis timeout", errors.New("context deadline exceeded"))
+
+	w, response := serveError(t, err, "en")
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.Equal(t, domain.ErrCodeAnalyzerTimeout, response.Code)
+	assert.NotContains(t, w.Body.String(), "deadline")
+}
+
+func TestRespondWithError_RetryAfterRoundsUp(t *testing.T) {
+	err := &domain.Error{Code: domain.ErrCodeAnalyzerUnavailable, Message: "rate limited", RetryAfter: 1500 * time.Millisecond}
+
+	w, _ := serveError(t, err, "")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+}
+
+func TestRespondWithError_UncodedErrorDoesNotLeak(t *testing.T) {
+	err := errors.New("open /data/photos/2026-02-01/153045_abc.json: permission denied")
+
+	w, response := serveError(t, err, "en")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, domain.ErrCodeInternal, response.Code)
+	assert.NotContains(t, w.Body.String(), "/data/photos")
+}
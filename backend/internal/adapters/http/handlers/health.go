@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"rechtebank/backend/internal/health"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReadinessChecker defines the interface for running dependency checks
+type ReadinessChecker interface {
+	Run(ctx context.Context) health.Report
+}
+
+// HealthHandler handles liveness and readiness probes
+type HealthHandler struct {
+	checker ReadinessChecker
+}
+
+// NewHealthHandler creates a new HealthHandler. A nil checker reports ready
+// with no dependency checks.
+func NewHealthHandler(checker ReadinessChecker) *HealthHandler {
+	return &HealthHandler{
+		checker: checker,
+	}
+}
+
+// Live handles GET /livez requests. It only reports that the process is
+// serving requests and never checks dependencies, so a failing dependency
+// does not cause the container to be restarted.
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":    "alive",
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// Ready handles GET /readyz requests, returning a per-check breakdown with
+// 200 when every dependency is healthy and 503 otherwise
+func (h *HealthHandler) Ready(c *gin.Context) {
+	if h.checker == nil {
+		c.JSON(http.StatusOK, health.Report{
+			Status:    health.StatusOK,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Checks:    map[string]health.CheckResult{},
+		})
+		return
+	}
+
+	report := h.checker.Run(c.Request.Context())
+	if !report.Ready() {
+		c.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"rechtebank/backend/internal/health"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthHandler_Live(t *testing.T) {
+	handler := NewHealthHandler(nil)
+
+	router := gin.New()
+	router.GET("/livez", handler.Live)
+
+	req := httptest.NewRequest(http.MethodGet, "/livez", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "alive")
+}
+
+func TestHealthHandler_Ready_AllChecksPass(t *testing.T) {
+	checker := health.NewChecker(time.Second,
+		health.StorageWritable(t.TempDir()),
+		health.NewCheckFunc("analyzer", func(context.Context) error { return nil }),
+	)
+	handler := NewHealthHandler(checker)
+
+	router := gin.New()
+	router.GET("/readyz", handler.Ready)
+
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var report health.Report
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, health.StatusOK, report.Status)
+	assert.Equal(t, health.StatusOK, report.Checks["storage"].Status)
+	assert.Equal(t, health.StatusOK, report.Checks["analyzer"].Status)
+}
+
+func TestHealthHandler_Ready_FailingCheck(t *testing.T) {
+	checker := health.NewChecker(time.Second,
+		health.NewCheckFunc("analyzer", func(context.Context) error { return errors.New("API key revoked") }),
+	)
+	handler := NewHealthHandler(checker)
+
+	router := gin.New()
+	router.GET("/readyz", handler.Ready)
+
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	var report health.Report
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, health.StatusFail, report.Status)
+	assert.Equal(t, "API key revoked", report.Checks["analyzer"].Message)
+}
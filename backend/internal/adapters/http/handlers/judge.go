@@ -2,19 +2,24 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
-	"fmt"
 	_ "image/jpeg"
 	_ "image/png"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 
 	"rechtebank/backend/internal/core/domain"
+	"rechtebank/backend/internal/logging"
+	"rechtebank/backend/internal/telemetry"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+var logger = logging.Component(logging.ComponentJudge)
+
 // VerdictServiceInterface defines the interface for the verdict service
 type VerdictServiceInterface interface {
 	JudgePhoto(ctx context.Context, imageData []byte, metadata domain.PhotoMetadata) (*domain.VerdictResponse, error)
@@ -22,138 +27,202 @@ type VerdictServiceInterface interface {
 
 // PhotoStorageInterface defines the interface for photo storage
 type PhotoStorageInterface interface {
-	SavePhoto(imageData []byte, llmResponse []byte, requestID string, timestamp string) (string, error)
+	SavePhoto(imageData []byte, llmResponse []byte, storageKey string, requestID string, timestamp string) (string, error)
 }
 
+// multipartOverhead is the allowance for multipart boundaries, part headers and
+// other small form fields on top of the photo itself
+const multipartOverhead = 64 * 1024
+
+// errPhotoMissing is returned by readPhoto when the form has no photo part
+var errPhotoMissing = errors.New("photo part missing")
+
 // JudgeHandler handles POST /v1/judge requests
 type JudgeHandler struct {
-	service VerdictServiceInterface
-	storage PhotoStorageInterface
+	service     VerdictServiceInterface
+	storage     PhotoStorageInterface
+	maxFileSize int64
 }
 
-// NewJudgeHandler creates a new JudgeHandler
-func NewJudgeHandler(service VerdictServiceInterface, storage PhotoStorageInterface) *JudgeHandler {
+// NewJudgeHandler creates a new JudgeHandler that accepts photos up to
+// maxFileSize bytes. As in the validator, a maxFileSize of 0 or less means no
+// limit.
+func NewJudgeHandler(service VerdictServiceInterface, storage PhotoStorageInterface, maxFileSize int64) *JudgeHandler {
 	return &JudgeHandler{
-		service: service,
-		storage: storage,
+		service:     service,
+		storage:     storage,
+		maxFileSize: maxFileSize,
 	}
 }
 
 // Handle processes the photo upload request
 func (h *JudgeHandler) Handle(c *gin.Context) {
+	ctx, span := telemetry.StartSpan(c.Request.Context(), "JudgeHandler.Handle")
+	defer span.End()
+
 	// Check content type
 	contentType := c.ContentType()
 	if contentType == "" || !isMultipartFormData(contentType) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Content-Type must be multipart/form-data"})
+		respondError(c, domain.ErrCodeInvalidRequest)
 		return
 	}
 
-	// Get the file from form
-	file, header, err := c.Request.FormFile("photo")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Photo file is required"})
-		return
+	// Reject uploads that announce an oversized body before reading any of it
+	if h.maxFileSize > 0 {
+		bodyLimit := h.maxFileSize + multipartOverhead
+		if c.Request.ContentLength > bodyLimit {
+			respondError(c, domain.ErrCodePhotoTooLarge)
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, bodyLimit)
 	}
-	defer file.Close()
 
-	// Read file data
-	imageData, err := io.ReadAll(file)
+	// Stream the photo part into memory
+	imageData, metadata, err := h.readPhoto(c.Request)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.Is(err, errPhotoMissing):
+			respondError(c, domain.ErrCodePhotoRequired)
+		case errors.As(err, &maxBytesErr):
+			respondError(c, domain.ErrCodePhotoTooLarge)
+		default:
+			respondWithError(c, err)
+		}
 		return
 	}
-
-	// Create metadata
-	metadata := domain.PhotoMetadata{
-		Filename:    header.Filename,
-		ContentType: header.Header.Get("Content-Type"),
-		Size:        int64(len(imageData)),
-	}
+	span.SetAttributes(attribute.Int64("photo.size", metadata.Size))
 
 	// Log incoming photo details
-	log.Printf("[JUDGE] Incoming photo: filename=%s, size=%d bytes, content-type=%s",
-		metadata.Filename, metadata.Size, metadata.ContentType)
+	logger.InfoContext(ctx, "Incoming photo",
+		logging.Sensitive("filename", metadata.Filename),
+		slog.Int64("size", metadata.Size),
+		slog.String("content_type", metadata.ContentType))
 
 	// Call service
-	result, err := h.service.JudgePhoto(c.Request.Context(), imageData, metadata)
+	result, err := h.service.JudgePhoto(ctx, imageData, metadata)
 	if err != nil {
-		h.handleError(c, err)
+		telemetry.RecordError(span, err)
+		respondWithError(c, err)
 		return
 	}
 
 	// Log the Gemini response
-	log.Printf("[JUDGE] Gemini response: admissible=%v, score=%d, requestID=%s",
-		result.Admissible, result.Score, result.RequestID)
-	log.Printf("[JUDGE] Gemini raw JSON: %s", result.RawJSON)
-
-	// Save photo to disk (async, don't fail request if this fails)
+	logger.InfoContext(ctx, "Verdict reached",
+		slog.Bool("admissible", result.Admissible),
+		slog.Int("score", result.Score),
+		slog.String("verdict_type", result.Verdict.VerdictType))
+	logger.DebugContext(ctx, "Gemini raw JSON", logging.Sensitive("raw_json", result.RawJSON))
+
+	// Save photo before answering, so the requestId the client receives can
+	// be shared; storage queues the save, and a failure does not fail the
+	// request but is flagged in the response
 	if h.storage != nil && result.RequestID != "" {
-		go func() {
-			if _, err := h.storage.SavePhoto(imageData, []byte(result.RawJSON), result.RequestID, result.Timestamp); err != nil {
-				// Log error but don't fail the request
-				fmt.Printf("Failed to save photo: %v\n", err)
-			}
-		}()
+		// Store the sanitized photo so no EXIF/GPS data ends up on disk or in shares
+		storedImage := result.ImageData
+		if storedImage == nil {
+			storedImage = imageData
+		}
+		// Flagged photos never touch the disk; the verdict is kept for the record
+		if result.Moderation != nil && result.Moderation.Flagged {
+			storedImage = nil
+		}
+
+		saveCtx, saveSpan := telemetry.StartSpan(ctx, "PhotoStorage.SavePhoto",
+			attribute.String("request.id", result.RequestID))
+		if _, err := h.storage.SavePhoto(storedImage, storedVerdictJSON(result), result.StorageKey, result.RequestID, result.Timestamp); err != nil {
+			telemetry.RecordError(saveSpan, err)
+			logger.ErrorContext(saveCtx, "Failed to save photo",
+				slog.String("request_id", result.RequestID),
+				slog.Any("error", err))
+			result.StorageDegraded = true
+		}
+		saveSpan.End()
 	}
 
 	c.JSON(http.StatusOK, result)
 }
 
-func (h *JudgeHandler) handleError(c *gin.Context, err error) {
-	var validationErr *ValidationError
-	if errors.As(err, &validationErr) {
-		c.JSON(validationErr.StatusCode, gin.H{"error": validationErr.Message})
-		return
+// storedVerdictJSON returns the analyzer's raw JSON, extended with the capture
+// metadata and moderation result when present
+func storedVerdictJSON(result *domain.VerdictResponse) []byte {
+	if result.Capture == nil && result.Moderation == nil {
+		return []byte(result.RawJSON)
 	}
 
-	var rateLimitErr *RateLimitError
-	if errors.As(err, &rateLimitErr) {
-		c.Header("Retry-After", fmt.Sprintf("%d", rateLimitErr.RetryAfter))
-		c.JSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
-		return
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(result.RawJSON), &fields); err != nil {
+		// Let storage report the malformed JSON
+		return []byte(result.RawJSON)
 	}
-
-	var apiErr *APIError
-	if errors.As(err, &apiErr) {
-		c.JSON(apiErr.StatusCode, gin.H{"error": apiErr.Message})
-		return
+	if result.Capture != nil {
+		fields["capture"] = result.Capture
+	}
+	if result.Moderation != nil {
+		fields["moderation"] = result.Moderation
 	}
 
-	// Default to internal server error
-	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-}
-
-func isMultipartFormData(contentType string) bool {
-	return len(contentType) >= 19 && contentType[:19] == "multipart/form-data"
-}
-
-// Error types for HTTP responses
-
-// ValidationError represents a validation error with specific status code
-type ValidationError struct {
-	Message    string
-	StatusCode int
-}
-
-func (e *ValidationError) Error() string {
-	return e.Message
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return []byte(result.RawJSON)
+	}
+	return data
 }
 
-// RateLimitError indicates rate limiting with retry information
-type RateLimitError struct {
-	RetryAfter int
-}
+// readPhoto reads the "photo" part of a multipart upload. Parts are streamed
+// rather than parsed with ParseMultipartForm, so large files are never spooled
+// to temporary files on disk.
+func (h *JudgeHandler) readPhoto(r *http.Request) ([]byte, domain.PhotoMetadata, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, domain.PhotoMetadata{}, domain.WrapError(domain.ErrCodeInvalidRequest, "invalid multipart request", err)
+	}
 
-func (e *RateLimitError) Error() string {
-	return "rate limit exceeded"
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, domain.PhotoMetadata{}, errPhotoMissing
+		}
+		if err != nil {
+			return nil, domain.PhotoMetadata{}, readError(err)
+		}
+
+		if part.FormName() != "photo" {
+			part.Close()
+			continue
+		}
+		defer part.Close()
+
+		// Read one byte past the limit to detect oversized photos
+		var reader io.Reader = part
+		if h.maxFileSize > 0 {
+			reader = io.LimitReader(part, h.maxFileSize+1)
+		}
+		imageData, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, domain.PhotoMetadata{}, readError(err)
+		}
+		if h.maxFileSize > 0 && int64(len(imageData)) > h.maxFileSize {
+			return nil, domain.PhotoMetadata{}, domain.NewError(domain.ErrCodePhotoTooLarge, "photo exceeds upload limit")
+		}
+
+		return imageData, domain.PhotoMetadata{
+			Filename:    part.FileName(),
+			ContentType: part.Header.Get("Content-Type"),
+			Size:        int64(len(imageData)),
+		}, nil
+	}
 }
 
-// APIError represents an API error with specific status code
-type APIError struct {
-	Message    string
-	StatusCode int
+// readError keeps body limit errors recognisable and marks anything else as a bad request
+func readError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return err
+	}
+	return domain.WrapError(domain.ErrCodeInvalidRequest, "failed to read upload", err)
 }
 
-func (e *APIError) Error() string {
-	return e.Message
+func isMultipartFormData(contentType string) bool {
+	return len(contentType) >= 19 && contentType[:19] == "multipart/form-data"
 }
@@ -9,14 +9,19 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"rechtebank/backend/internal/core/domain"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
+// testMaxFileSize is the upload limit handlers are created with in tests
+const testMaxFileSize = 10 * 1024 * 1024
+
 // MockVerdictService mocks the verdict service
 type MockVerdictService struct {
 	mock.Mock
@@ -55,7 +60,7 @@ func createMultipartRequest(t *testing.T, fieldName, filename string, content []
 
 func TestJudgeHandler_SuccessfulUpload(t *testing.T) {
 	mockService := new(MockVerdictService)
-	handler := NewJudgeHandler(mockService, nil)
+	handler := NewJudgeHandler(mockService, nil, testMaxFileSize)
 
 	// JPEG magic bytes + some data
 	imageData := []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10, 0x4A, 0x46, 0x49, 0x46, 0x00, 0x01}
@@ -98,7 +103,7 @@ func TestJudgeHandler_SuccessfulUpload(t *testing.T) {
 
 func TestJudgeHandler_MissingFile(t *testing.T) {
 	mockService := new(MockVerdictService)
-	handler := NewJudgeHandler(mockService, nil)
+	handler := NewJudgeHandler(mockService, nil, testMaxFileSize)
 
 	// Request without file
 	var buf bytes.Buffer
@@ -117,12 +122,12 @@ func TestJudgeHandler_MissingFile(t *testing.T) {
 
 	var errorResponse map[string]string
 	json.Unmarshal(w.Body.Bytes(), &errorResponse)
-	assert.Equal(t, "Photo file is required", errorResponse["error"])
+	assert.Equal(t, "PHOTO_REQUIRED", errorResponse["code"])
 }
 
 func TestJudgeHandler_InvalidContentType(t *testing.T) {
 	mockService := new(MockVerdictService)
-	handler := NewJudgeHandler(mockService, nil)
+	handler := NewJudgeHandler(mockService, nil, testMaxFileSize)
 
 	req := httptest.NewRequest(http.MethodPost, "/v1/judge", bytes.NewReader([]byte("not multipart")))
 	req.Header.Set("Content-Type", "application/json")
@@ -136,16 +141,16 @@ func TestJudgeHandler_InvalidContentType(t *testing.T) {
 
 	var errorResponse map[string]string
 	json.Unmarshal(w.Body.Bytes(), &errorResponse)
-	assert.Equal(t, "Content-Type must be multipart/form-data", errorResponse["error"])
+	assert.Equal(t, "INVALID_REQUEST", errorResponse["code"])
 }
 
 func TestJudgeHandler_FileTooLarge(t *testing.T) {
 	mockService := new(MockVerdictService)
-	handler := NewJudgeHandler(mockService, nil)
+	handler := NewJudgeHandler(mockService, nil, testMaxFileSize)
 
 	imageData := []byte{0xFF, 0xD8, 0xFF}
 
-	validationErr := &ValidationError{Message: "Photo file size must not exceed 10MB", StatusCode: http.StatusRequestEntityTooLarge}
+	validationErr := domain.NewError(domain.ErrCodePhotoTooLarge, "Photo file size must not exceed 10MB")
 	mockService.On("JudgePhoto", mock.Anything, imageData, mock.Anything).Return(nil, validationErr)
 
 	req, _ := createMultipartRequest(t, "photo", "large.jpg", imageData)
@@ -159,16 +164,17 @@ func TestJudgeHandler_FileTooLarge(t *testing.T) {
 
 	var errorResponse map[string]string
 	json.Unmarshal(w.Body.Bytes(), &errorResponse)
-	assert.Equal(t, "Photo file size must not exceed 10MB", errorResponse["error"])
+	assert.Equal(t, "PHOTO_TOO_LARGE", errorResponse["code"])
+	assert.Equal(t, "De foto is te groot.", errorResponse["error"])
 }
 
-func TestJudgeHandler_RateLimitError(t *testing.T) {
+func TestJudgeHandler_RetryAfter(t *testing.T) {
 	mockService := new(MockVerdictService)
-	handler := NewJudgeHandler(mockService, nil)
+	handler := NewJudgeHandler(mockService, nil, testMaxFileSize)
 
 	imageData := []byte{0xFF, 0xD8, 0xFF}
 
-	rateLimitErr := &RateLimitError{RetryAfter: 30}
+	rateLimitErr := &domain.Error{Code: domain.ErrCodeAnalyzerUnavailable, Message: "rate limit exceeded", RetryAfter: 30 * time.Second}
 	mockService.On("JudgePhoto", mock.Anything, imageData, mock.Anything).Return(nil, rateLimitErr)
 
 	req, _ := createMultipartRequest(t, "photo", "test.jpg", imageData)
@@ -178,17 +184,17 @@ func TestJudgeHandler_RateLimitError(t *testing.T) {
 	router.POST("/v1/judge", handler.Handle)
 	router.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
 	assert.Equal(t, "30", w.Header().Get("Retry-After"))
 
 	var errorResponse map[string]string
 	json.Unmarshal(w.Body.Bytes(), &errorResponse)
-	assert.Contains(t, errorResponse["error"], "rate limit")
+	assert.Equal(t, "ANALYZER_UNAVAILABLE", errorResponse["code"])
 }
 
 func TestJudgeHandler_InternalServerError(t *testing.T) {
 	mockService := new(MockVerdictService)
-	handler := NewJudgeHandler(mockService, nil)
+	handler := NewJudgeHandler(mockService, nil, testMaxFileSize)
 
 	imageData := []byte{0xFF, 0xD8, 0xFF}
 
@@ -202,15 +208,21 @@ func TestJudgeHandler_InternalServerError(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	// Internal error details must not leak to the client
+	var errorResponse map[string]string
+	json.Unmarshal(w.Body.Bytes(), &errorResponse)
+	assert.Equal(t, "INTERNAL_ERROR", errorResponse["code"])
+	assert.NotContains(t, w.Body.String(), "AI analysis service unavailable")
 }
 
 func TestJudgeHandler_BadGateway(t *testing.T) {
 	mockService := new(MockVerdictService)
-	handler := NewJudgeHandler(mockService, nil)
+	handler := NewJudgeHandler(mockService, nil, testMaxFileSize)
 
 	imageData := []byte{0xFF, 0xD8, 0xFF}
 
-	badGatewayErr := &APIError{Message: "AI analysis failed", StatusCode: http.StatusBadGateway}
+	badGatewayErr := domain.NewError(domain.ErrCodeAnalyzerInvalidResponse, "Invalid AI response format")
 	mockService.On("JudgePhoto", mock.Anything, imageData, mock.Anything).Return(nil, badGatewayErr)
 
 	req, _ := createMultipartRequest(t, "photo", "test.jpg", imageData)
@@ -225,11 +237,11 @@ func TestJudgeHandler_BadGateway(t *testing.T) {
 
 func TestJudgeHandler_ServiceUnavailable(t *testing.T) {
 	mockService := new(MockVerdictService)
-	handler := NewJudgeHandler(mockService, nil)
+	handler := NewJudgeHandler(mockService, nil, testMaxFileSize)
 
 	imageData := []byte{0xFF, 0xD8, 0xFF}
 
-	serviceErr := &APIError{Message: "AI analysis service temporarily unavailable", StatusCode: http.StatusServiceUnavailable}
+	serviceErr := domain.NewError(domain.ErrCodeAnalyzerUnavailable, "AI analysis service temporarily unavailable")
 	mockService.On("JudgePhoto", mock.Anything, imageData, mock.Anything).Return(nil, serviceErr)
 
 	req, _ := createMultipartRequest(t, "photo", "test.jpg", imageData)
@@ -244,11 +256,11 @@ func TestJudgeHandler_ServiceUnavailable(t *testing.T) {
 
 func TestJudgeHandler_GatewayTimeout(t *testing.T) {
 	mockService := new(MockVerdictService)
-	handler := NewJudgeHandler(mockService, nil)
+	handler := NewJudgeHandler(mockService, nil, testMaxFileSize)
 
 	imageData := []byte{0xFF, 0xD8, 0xFF}
 
-	timeoutErr := &APIError{Message: "AI analysis timeout", StatusCode: http.StatusGatewayTimeout}
+	timeoutErr := domain.NewError(domain.ErrCodeAnalyzerTimeout, "AI analysis timeout")
 	mockService.On("JudgePhoto", mock.Anything, imageData, mock.Anything).Return(nil, timeoutErr)
 
 	req, _ := createMultipartRequest(t, "photo", "test.jpg", imageData)
@@ -263,7 +275,7 @@ func TestJudgeHandler_GatewayTimeout(t *testing.T) {
 
 func TestJudgeHandler_MultipartFormDataParsing(t *testing.T) {
 	mockService := new(MockVerdictService)
-	handler := NewJudgeHandler(mockService, nil)
+	handler := NewJudgeHandler(mockService, nil, testMaxFileSize)
 
 	// PNG magic bytes
 	imageData := []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A, 0x00, 0x00, 0x00, 0x0D}
@@ -294,3 +306,206 @@ func TestJudgeHandler_MultipartFormDataParsing(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	mockService.AssertExpectations(t)
 }
+
+func TestJudgeHandler_RejectsOversizedContentLength(t *testing.T) {
+	mockService := new(MockVerdictService)
+	handler := NewJudgeHandler(mockService, nil, 1024)
+
+	req, _ := createMultipartRequest(t, "photo", "large.jpg", bytes.Repeat([]byte{0xFF}, 200*1024))
+	w := httptest.NewRecorder()
+
+	router := gin.New()
+	router.POST("/v1/judge", handler.Handle)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	var errorResponse map[string]string
+	json.Unmarshal(w.Body.Bytes(), &errorResponse)
+	assert.Equal(t, "PHOTO_TOO_LARGE", errorResponse["code"])
+	mockService.AssertNotCalled(t, "JudgePhoto", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestJudgeHandler_RejectsOversizedStreamedPhoto(t *testing.T) {
+	mockService := new(MockVerdictService)
+	handler := NewJudgeHandler(mockService, nil, 1024)
+
+	// Photo just over the limit but within the multipart allowance, without a
+	// Content-Length so the limit has to be enforced while reading
+	req, _ := createMultipartRequest(t, "photo", "large.jpg", bytes.Repeat([]byte{0xFF}, 1025))
+	req.ContentLength = -1
+	w := httptest.NewRecorder()
+
+	router := gin.New()
+	router.POST("/v1/judge", handler.Handle)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	mockService.AssertNotCalled(t, "JudgePhoto", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestJudgeHandler_BodyLimitAppliesToWholeRequest(t *testing.T) {
+	mockService := new(MockVerdictService)
+	handler := NewJudgeHandler(mockService, nil, 1024)
+
+	// A huge non-photo field must not get past the body limit either
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	writer.WriteField("padding", string(bytes.Repeat([]byte("x"), 200*1024)))
+	part, _ := writer.CreateFormFile("photo", "test.jpg")
+	part.Write([]byte{0xFF, 0xD8, 0xFF})
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/judge", &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.ContentLength = -1
+	w := httptest.NewRecorder()
+
+	router := gin.New()
+	router.POST("/v1/judge", handler.Handle)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	mockService.AssertNotCalled(t, "JudgePhoto", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestJudgeHandler_ZeroMaxFileSizeMeansUnlimited(t *testing.T) {
+	mockService := new(MockVerdictService)
+	handler := NewJudgeHandler(mockService, nil, 0)
+
+	imageData := bytes.Repeat([]byte{0xFF}, 200*1024)
+	mockService.On("JudgePhoto", mock.Anything, imageData, mock.Anything).
+		Return(&domain.VerdictResponse{Admissible: true, RequestID: "test-123"}, nil)
+
+	req, _ := createMultipartRequest(t, "photo", "large.jpg", imageData)
+	w := httptest.NewRecorder()
+
+	router := gin.New()
+	router.POST("/v1/judge", handler.Handle)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestJudgeHandler_SkipsOtherFormFields(t *testing.T) {
+	mockService := new(MockVerdictService)
+	handler := NewJudgeHandler(mockService, nil, testMaxFileSize)
+
+	imageData := []byte{0xFF, 0xD8, 0xFF, 0xE0}
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	writer.WriteField("note", "stoel in de woonkamer")
+	part, _ := writer.CreateFormFile("photo", "stoel.jpg")
+	part.Write(imageData)
+	writer.Close()
+
+	mockService.On("JudgePhoto", mock.Anything, imageData, mock.MatchedBy(func(m domain.PhotoMetadata) bool {
+		return m.Filename == "stoel.jpg" && m.Size == int64(len(imageData))
+	})).Return(&domain.VerdictResponse{Admissible: true, Score: 8}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/judge", &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+
+	router := gin.New()
+	router.POST("/v1/judge", handler.Handle)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+// savedPhoto records a SavePhoto call
+type savedPhoto struct {
+	imageData  []byte
+	verdict    map[string]any
+	storageKey string
+}
+
+// recordingStorage reports SavePhoto calls, which happen in the background
+type recordingStorage struct {
+	saved chan savedPhoto
+}
+
+func (s *recordingStorage) SavePhoto(imageData []byte, llmResponse []byte, storageKey string, requestID string, timestamp string) (string, error) {
+	var verdict map[string]any
+	json.Unmarshal(llmResponse, &verdict)
+	s.saved <- savedPhoto{imageData: imageData, verdict: verdict, storageKey: storageKey}
+	return "", nil
+}
+
+func TestJudgeHandler_FlaggedPhotoIsNotStored(t *testing.T) {
+	mockService := new(MockVerdictService)
+	storage := &recordingStorage{saved: make(chan savedPhoto, 1)}
+	handler := NewJudgeHandler(mockService, storage, testMaxFileSize)
+
+	imageData := []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10, 0x4A, 0x46, 0x49, 0x46, 0x00, 0x01}
+	mockService.On("JudgePhoto", mock.Anything, imageData, mock.Anything).Return(&domain.VerdictResponse{
+		Admissible: true,
+		Score:      6,
+		RequestID:  "test-123",
+		Timestamp:  "2026-01-31T10:00:00Z",
+		StorageKey: "2026-01-31/100000_test-123",
+		Moderation: &domain.ModerationResult{Flagged: true, Categories: []string{"sexually_explicit"}},
+		RawJSON:    `{"admissible":true,"score":6}`,
+		ImageData:  imageData,
+	}, nil)
+
+	req, _ := createMultipartRequest(t, "photo", "test.jpg", imageData)
+	w := httptest.NewRecorder()
+	router := gin.New()
+	router.POST("/v1/judge", handler.Handle)
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var response map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, map[string]any{"flagged": true, "categories": []any{"sexually_explicit"}}, response["moderation"])
+	assert.NotContains(t, response, "storageKey")
+
+	select {
+	case saved := <-storage.saved:
+		assert.Nil(t, saved.imageData)
+		assert.Equal(t, "2026-01-31/100000_test-123", saved.storageKey)
+		assert.Equal(t, true, saved.verdict["moderation"].(map[string]any)["flagged"])
+		assert.Equal(t, float64(6), saved.verdict["score"])
+	case <-time.After(2 * time.Second):
+		t.Fatal("verdict was not saved")
+	}
+}
+
+// refusingStorage refuses every save, like a full photo storage
+type refusingStorage struct{}
+
+func (refusingStorage) SavePhoto(imageData []byte, llmResponse []byte, storageKey string, requestID string, timestamp string) (string, error) {
+	return "", domain.NewError(domain.ErrCodeStorageFull, "storage quota reached")
+}
+
+func TestJudgeHandler_UnsavedVerdictIsFlagged(t *testing.T) {
+	mockService := new(MockVerdictService)
+	handler := NewJudgeHandler(mockService, refusingStorage{}, testMaxFileSize)
+
+	imageData := []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10, 0x4A, 0x46, 0x49, 0x46, 0x00, 0x01}
+	mockService.On("JudgePhoto", mock.Anything, imageData, mock.Anything).Return(&domain.VerdictResponse{
+		Admissible: true,
+		Score:      6,
+		RequestID:  "test-123",
+		Timestamp:  "2026-01-31T10:00:00Z",
+		RawJSON:    `{"admissible":true,"score":6}`,
+	}, nil)
+
+	req, _ := createMultipartRequest(t, "photo", "test.jpg", imageData)
+	w := httptest.NewRecorder()
+	router := gin.New()
+	router.POST("/v1/judge", handler.Handle)
+	router.ServeHTTP(w, req)
+
+	// Judging still works; the client learns the verdict cannot be shared
+	require.Equal(t, http.StatusOK, w.Code)
+	var response map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, true, response["storageDegraded"])
+	assert.Equal(t, float64(6), response["score"])
+}
@@ -1,35 +1,111 @@
 package handlers
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"image"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
+	"rechtebank/backend/internal/adapters/imaging"
 	"rechtebank/backend/internal/core/domain"
 
 	"github.com/gin-gonic/gin"
 )
 
-// VerdictWithImageResponse combines verdict data with base64-encoded image
+// VerdictWithImageResponse combines verdict data with the URL of its photo
 type VerdictWithImageResponse struct {
-	Verdict domain.VerdictResponse `json:"verdict"`
-	Image   string                 `json:"image"` // data URL format: "data:image/jpeg;base64,..."
+	Verdict  domain.VerdictResponse `json:"verdict"`
+	ImageURL string                 `json:"imageUrl"` // relative to the API root: "/v1/verdict/{id}/image"
+
+	// Image is the photo as a data URL ("data:image/png;base64,..."), only
+	// included with ?inline=true. Deprecated: use ImageURL.
+	Image string `json:"image,omitempty"`
+}
+
+// Image endpoint settings
+const (
+	// imageCacheControl lets browsers and proxies keep photos for a day; the
+	// photo behind a verdict ID never changes, and the ETag makes
+	// revalidation cheap after that
+	imageCacheControl = "public, max-age=86400"
+
+	// maxImageWidth is the largest width accepted for ?w= variants
+	maxImageWidth = 2048
+
+	// variantJPEGQuality is used when encoding resized variants
+	variantJPEGQuality = 80
+)
+
+// PhotoVariantStore provides pre-generated resized copies of stored photos
+type PhotoVariantStore interface {
+	// Variant returns the path of the variant of the photo at verdictPath
+	// (storage path without extension), or "" when that width and format
+	// are not stored
+	Variant(verdictPath string, width int, format domain.PhotoFormat) (string, error)
+
+	// VariantWidth returns the stored variant width to serve for a
+	// requested width
+	VariantWidth(width int) int
 }
 
+// SaveStatus reports verdicts that were returned to a client but are not
+// stored yet, and whether storage is too full to store any
+type SaveStatus interface {
+	IsPending(requestID string) bool
+	Degraded() bool
+}
+
+// ShareRecorder records that a stored verdict's share ID was handed out, so
+// retention keeps it for the shared period rather than its class's
+type ShareRecorder interface {
+	MarkShared(key string, at time.Time) error
+}
+
+// pendingRetryAfter is the Retry-After sent while a verdict is being filed
+const pendingRetryAfter = 2 * time.Second
+
 // VerdictHandler handles verdict retrieval requests
 type VerdictHandler struct {
 	storagePath string
+	variants    PhotoVariantStore
+	saves       SaveStatus
+	shares      ShareRecorder
 }
 
-// NewVerdictHandler creates a new VerdictHandler
-func NewVerdictHandler(storagePath string) *VerdictHandler {
+// NewVerdictHandler creates a new VerdictHandler. variants may be nil, in
+// which case every ?w= request is resized on the fly. saves may be nil when
+// saves are not queued. shares may be nil when shares need not be recorded,
+// e.g. without retention.
+func NewVerdictHandler(storagePath string, variants PhotoVariantStore, saves SaveStatus, shares ShareRecorder) *VerdictHandler {
 	return &VerdictHandler{
 		storagePath: storagePath,
+		variants:    variants,
+		saves:       saves,
+		shares:      shares,
+	}
+}
+
+// decodeStorageKey decodes a verdict ID into the storage key it stands for.
+// IDs that do not decode to a valid storage key are rejected before any file
+// is touched, so an ID cannot point outside the storage directory.
+func decodeStorageKey(encodedID string) (string, bool) {
+	key, err := domain.DecodeVerdictID(encodedID)
+	if err != nil {
+		return "", false
+	}
+	if _, ok := domain.StorageKeyRequestID(key); !ok {
+		return "", false
 	}
+	return key, true
 }
 
 // GetByID handles GET /v1/verdict/:id requests
@@ -38,154 +114,375 @@ func (h *VerdictHandler) GetByID(c *gin.Context) {
 	encodedID := c.Param("id")
 
 	// Decode base64url ID to get file path
-	filePath, err := domain.DecodeVerdictID(encodedID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid verdict ID"})
+	filePath, ok := decodeStorageKey(encodedID)
+	if !ok {
+		respondError(c, domain.ErrCodeInvalidVerdictID)
 		return
 	}
 
 	// Construct full file paths
 	baseFilePath := filepath.Join(h.storagePath, filePath)
 	jsonPath := baseFilePath + ".json"
-	photoPath := baseFilePath + ".jpg"
+	photoPath, format, found := findPhoto(baseFilePath)
 
 	// Read verdict JSON file
 	verdictData, err := os.ReadFile(jsonPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Verdict data not found"})
+			respondError(c, domain.ErrCodeVerdictNotFound)
 		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read verdict data"})
+			respondWithError(c, domain.WrapError(domain.ErrCodeStorageFailure, "failed to read verdict data", err))
 		}
 		return
 	}
 
 	// Parse verdict JSON (stored in flat format on disk)
-	var flatVerdict struct {
-		Admissible  bool   `json:"admissible"`
-		Score       int    `json:"score"`
-		Crime       string `json:"crime"`
-		Sentence    string `json:"sentence"`
-		Reasoning   string `json:"reasoning"`
-		Observation string `json:"observation"`
-		VerdictType string `json:"verdictType"`
-		RequestID   string `json:"requestId"`
-		Timestamp   string `json:"timestamp"`
-	}
-	if err := json.Unmarshal(verdictData, &flatVerdict); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse verdict data"})
-		return
-	}
-
-	// Convert to VerdictResponse structure
-	verdict := domain.VerdictResponse{
-		Admissible: flatVerdict.Admissible,
-		Score:      flatVerdict.Score,
-		Verdict: domain.VerdictDetails{
-			Crime:       flatVerdict.Crime,
-			Sentence:    flatVerdict.Sentence,
-			Reasoning:   flatVerdict.Reasoning,
-			Observation: flatVerdict.Observation,
-			VerdictType: flatVerdict.VerdictType,
-		},
-		RequestID: flatVerdict.RequestID,
-		Timestamp: flatVerdict.Timestamp,
-	}
-
-	// Read photo file
-	photoData, err := os.ReadFile(photoPath)
+	verdict, err := domain.ParseFlatJSON(verdictData)
 	if err != nil {
-		if os.IsNotExist(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Photo file not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read photo file"})
-		}
+		respondWithError(c, domain.WrapError(domain.ErrCodeStorageFailure, "failed to parse verdict data", err))
+		return
+	}
+
+	// Photos flagged by moderation were never stored and must not be shown;
+	// the moderation outcome of other verdicts stays internal
+	if verdict.Moderation != nil && verdict.Moderation.Flagged {
+		respondError(c, domain.ErrCodeVerdictWithheld)
 		return
 	}
+	verdict.Moderation = nil
 
-	// Encode photo as base64 data URL
-	base64Photo := base64.StdEncoding.EncodeToString(photoData)
-	imageDataURL := fmt.Sprintf("data:image/jpeg;base64,%s", base64Photo)
+	if !found {
+		respondError(c, domain.ErrCodeVerdictNotFound)
+		return
+	}
 
-	// Return combined response
 	response := VerdictWithImageResponse{
-		Verdict: verdict,
-		Image:   imageDataURL,
+		Verdict:  *verdict,
+		ImageURL: fmt.Sprintf("/v1/verdict/%s/image", encodedID),
+	}
+
+	// Compatibility with clients that still expect the photo inline
+	if c.Query("inline") == "true" {
+		photoData, err := os.ReadFile(photoPath)
+		if err != nil {
+			respondWithError(c, domain.WrapError(domain.ErrCodeStorageFailure, "failed to read photo file", err))
+			return
+		}
+		response.Image = fmt.Sprintf("data:%s;base64,%s", format.MIMEType, base64.StdEncoding.EncodeToString(photoData))
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
-// ShareRequest represents the request body for POST /v1/verdict/share
+// GetImage handles GET /v1/verdict/:id/image requests. The photo is served
+// as stored, or scaled down to ?w= pixels wide, with validators for
+// conditional requests. ?w= is snapped to a stored variant width, so clients
+// cannot make every request resize the photo; variants are served from
+// disk, as WebP when the client accepts it. Only without stored variants is
+// the photo resized on the fly to JPEG.
+func (h *VerdictHandler) GetImage(c *gin.Context) {
+	filePath, ok := decodeStorageKey(c.Param("id"))
+	if !ok {
+		respondError(c, domain.ErrCodeInvalidVerdictID)
+		return
+	}
+
+	width := 0
+	if w := c.Query("w"); w != "" {
+		var err error
+		width, err = strconv.Atoi(w)
+		if err != nil || width < 1 || width > maxImageWidth {
+			respondError(c, domain.ErrCodeInvalidRequest)
+			return
+		}
+	}
+
+	photoPath, format, found := findPhoto(filepath.Join(h.storagePath, filePath))
+	if !found {
+		respondError(c, domain.ErrCodeVerdictNotFound)
+		return
+	}
+
+	if width > 0 && h.variants != nil {
+		width = h.variants.VariantWidth(width)
+		for _, variantFormat := range acceptedVariantFormats(c.GetHeader("Accept")) {
+			variantPath, err := h.variants.Variant(filePath, width, variantFormat)
+			if domain.ErrorCodeOf(err) == domain.ErrCodeStorageFull {
+				// No room to store the variant: resize on the fly below
+				break
+			}
+			if err != nil {
+				respondWithError(c, domain.WrapError(domain.ErrCodeStorageFailure, "failed to load photo variant", err))
+				return
+			}
+			if variantPath != "" {
+				c.Header("Vary", "Accept")
+				serveImageFile(c, variantPath, variantFormat)
+				return
+			}
+		}
+	}
+
+	if width == 0 {
+		serveImageFile(c, photoPath, format)
+		return
+	}
+
+	photoData, info, err := readImageFile(photoPath)
+	if err != nil {
+		respondWithError(c, domain.WrapError(domain.ErrCodeStorageFailure, "failed to read photo file", err))
+		return
+	}
+
+	etag := imageETag(photoData)
+	contentType := format.MIMEType
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(photoData))
+	if err != nil {
+		respondWithError(c, domain.WrapError(domain.ErrCodeStorageFailure, "failed to read photo header", err))
+		return
+	}
+	// Never upscale: narrower photos are served as stored
+	if width < config.Width {
+		photoData, err = imaging.Resized(photoData, width, domain.PhotoFormatJPEG, variantJPEGQuality)
+		if err != nil {
+			respondWithError(c, domain.WrapError(domain.ErrCodeStorageFailure, "failed to resize photo", err))
+			return
+		}
+		etag = fmt.Sprintf("%s-w%d", etag, width)
+		contentType = domain.PhotoFormatJPEG.MIMEType
+	}
+
+	serveImage(c, photoData, contentType, etag, info.ModTime())
+}
+
+// acceptedVariantFormats lists the variant formats to try for an Accept
+// header, preferred first. JPEG is always acceptable.
+func acceptedVariantFormats(accept string) []domain.PhotoFormat {
+	if strings.Contains(accept, domain.PhotoFormatWebP.MIMEType) {
+		return []domain.PhotoFormat{domain.PhotoFormatWebP, domain.PhotoFormatJPEG}
+	}
+	return []domain.PhotoFormat{domain.PhotoFormatJPEG}
+}
+
+// serveImageFile serves a stored image file as is
+func serveImageFile(c *gin.Context, path string, format domain.PhotoFormat) {
+	data, info, err := readImageFile(path)
+	if err != nil {
+		respondWithError(c, domain.WrapError(domain.ErrCodeStorageFailure, "failed to read photo file", err))
+		return
+	}
+	serveImage(c, data, format.MIMEType, imageETag(data), info.ModTime())
+}
+
+// serveImage writes image data with caching headers, answering conditional
+// and range requests
+func serveImage(c *gin.Context, data []byte, contentType, etag string, modTime time.Time) {
+	c.Header("Content-Type", contentType)
+	c.Header("ETag", `"`+etag+`"`)
+	c.Header("Cache-Control", imageCacheControl)
+	http.ServeContent(c.Writer, c.Request, "", modTime, bytes.NewReader(data))
+}
+
+func readImageFile(path string) ([]byte, os.FileInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, info, nil
+}
+
+// imageETag derives an ETag from image content
+func imageETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// findPhoto returns the path and format of the photo stored for the verdict
+// at basePath (path without extension)
+func findPhoto(basePath string) (string, domain.PhotoFormat, bool) {
+	for _, format := range domain.PhotoFormats {
+		path := basePath + format.Extension
+		if _, err := os.Stat(path); err == nil {
+			return path, format, true
+		}
+	}
+	return "", domain.PhotoFormat{}, false
+}
+
+// ShareRequest represents the request body for POST /v1/verdict/share.
+// Together, RequestID and Timestamp name the verdict's storage key.
 type ShareRequest struct {
-	Timestamp string `json:"timestamp" binding:"required"`
+	Timestamp string `json:"timestamp,omitempty"`
 	RequestID string `json:"requestId" binding:"required"`
 }
 
+// legacyLookupDays is how many date directories are searched for a verdict
+// that is not stored under the key of its timestamp
+const legacyLookupDays = 2
+
+// findVerdictKey returns the storage key of the verdict saved for requestID
+// at timestamp, or "" if there is none. Verdicts are stored under the key of
+// their timestamp, so that path is looked up directly. Only saves queued
+// before verdicts carried a storage key landed under a later second; for
+// those the timestamp's date directory and the next are searched. Without a
+// timestamp, as older clients send, the most recent date directories are.
+func findVerdictKey(storagePath, requestID, timestamp string) (string, error) {
+	if requestID == "" || strings.ContainsAny(requestID, `/\`) || strings.HasPrefix(requestID, ".") {
+		return "", nil
+	}
+
+	var dateDirs []string
+	if at, err := time.Parse(time.RFC3339, timestamp); err == nil {
+		key := domain.NewStorageKey(at, requestID)
+		_, err := os.Stat(filepath.Join(storagePath, filepath.FromSlash(key)) + ".json")
+		if err == nil {
+			return key, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		day := at.UTC()
+		dateDirs = []string{day.Format(domain.StorageKeyDateLayout), day.AddDate(0, 0, 1).Format(domain.StorageKeyDateLayout)}
+	} else {
+		recent, err := recentDateDirs(storagePath, legacyLookupDays)
+		if err != nil {
+			return "", err
+		}
+		dateDirs = recent
+	}
+
+	suffix := "_" + requestID + ".json"
+	for _, dateDir := range dateDirs {
+		entries, err := os.ReadDir(filepath.Join(storagePath, dateDir))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		for _, entry := range entries {
+			if !strings.HasSuffix(entry.Name(), suffix) {
+				continue
+			}
+			key := dateDir + "/" + strings.TrimSuffix(entry.Name(), ".json")
+			if domain.ValidStorageKey(key, requestID) {
+				return key, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// recentDateDirs returns the names of the n most recent date directories,
+// newest first
+func recentDateDirs(storagePath string, n int) ([]string, error) {
+	entries, err := os.ReadDir(storagePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var dateDirs []string
+	for i := len(entries) - 1; i >= 0 && len(dateDirs) < n; i-- {
+		if !entries[i].IsDir() {
+			continue
+		}
+		if _, err := time.Parse(domain.StorageKeyDateLayout, entries[i].Name()); err != nil {
+			continue
+		}
+		dateDirs = append(dateDirs, entries[i].Name())
+	}
+	return dateDirs, nil
+}
+
 // ShareResponse represents the response for POST /v1/verdict/share
 type ShareResponse struct {
 	ID string `json:"id"`
 }
 
+// sharePending is the status of a verdict that is still being filed
+const sharePending = "pending"
+
+// SharePendingResponse is the 202 response for POST /v1/verdict/share while
+// the verdict is still being filed; clients retry after Retry-After
+type SharePendingResponse struct {
+	Status    string `json:"status"`
+	RequestID string `json:"requestId"`
+}
+
 // CreateShareURL handles POST /v1/verdict/share requests
 func (h *VerdictHandler) CreateShareURL(c *gin.Context) {
 	var req ShareRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		respondError(c, domain.ErrCodeInvalidRequest)
 		return
 	}
 
-	// Parse timestamp to extract date (YYYY-MM-DD) and time (HHMMSS)
-	// Expected format: "2026-02-01T15:30:45Z" or similar ISO 8601
-	// Extract YYYY-MM-DD for directory
-	dateDir := ""
-	timeStr := ""
+	// Sharing is disabled while storage is full, as new verdicts are not stored
+	if h.saves != nil && h.saves.Degraded() {
+		respondError(c, domain.ErrCodeStorageFull)
+		return
+	}
 
-	if len(req.Timestamp) >= 10 {
-		dateDir = req.Timestamp[:10] // YYYY-MM-DD
+	key, err := findVerdictKey(h.storagePath, req.RequestID, req.Timestamp)
+	if err != nil {
+		respondWithError(c, domain.WrapError(domain.ErrCodeStorageFailure, "failed to look up verdict", err))
+		return
 	}
 
-	// Extract time component (HHMMSS)
-	// If timestamp is "2026-02-01T15:30:45Z", we need to extract "153045"
-	if strings.Contains(req.Timestamp, "T") {
-		parts := strings.Split(req.Timestamp, "T")
-		if len(parts) > 1 {
-			timePart := strings.Split(parts[1], ":") // ["15", "30", "45Z"]
-			if len(timePart) >= 3 {
-				// Clean seconds part (remove Z or other timezone info)
-				seconds := strings.TrimRight(timePart[2], "Z")
-				seconds = strings.Split(seconds, ".")[0]      // Remove milliseconds if present
-				timeStr = timePart[0] + timePart[1] + seconds // HHMMSS
-			}
+	if key == "" {
+		if h.saves != nil && h.saves.IsPending(req.RequestID) {
+			c.Header("Retry-After", strconv.Itoa(int(pendingRetryAfter.Seconds())))
+			c.JSON(http.StatusAccepted, SharePendingResponse{Status: sharePending, RequestID: req.RequestID})
+			return
 		}
+		respondError(c, domain.ErrCodeVerdictNotFound)
+		return
 	}
 
-	if dateDir == "" || timeStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timestamp format"})
+	baseFilePath := filepath.Join(h.storagePath, filepath.FromSlash(key))
+	verdictData, err := os.ReadFile(baseFilePath + ".json")
+	if os.IsNotExist(err) {
+		// Removed since it was found
+		respondError(c, domain.ErrCodeVerdictNotFound)
+		return
+	}
+	if err != nil {
+		respondWithError(c, domain.WrapError(domain.ErrCodeStorageFailure, "failed to read verdict data", err))
 		return
 	}
 
-	// Construct file path
-	filename := fmt.Sprintf("%s_%s", timeStr, req.RequestID)
-	filePath := fmt.Sprintf("%s/%s", dateDir, filename)
-
-	// Verify both files exist
-	baseFilePath := filepath.Join(h.storagePath, filePath)
-	jsonPath := baseFilePath + ".json"
-	photoPath := baseFilePath + ".jpg"
-
-	if _, err := os.Stat(jsonPath); os.IsNotExist(err) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Verdict not found"})
+	// Photos flagged by moderation were never stored and must not be shared
+	var stored struct {
+		Moderation *domain.ModerationResult `json:"moderation"`
+	}
+	if json.Unmarshal(verdictData, &stored) == nil && stored.Moderation != nil && stored.Moderation.Flagged {
+		respondError(c, domain.ErrCodeVerdictWithheld)
 		return
 	}
 
-	if _, err := os.Stat(photoPath); os.IsNotExist(err) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Verdict not found"})
+	if _, _, found := findPhoto(baseFilePath); !found {
+		respondError(c, domain.ErrCodeVerdictNotFound)
 		return
 	}
 
+	// A shared verdict is kept for the shared period, whatever its class;
+	// only an admin can publish it for good
+	if h.shares != nil {
+		if err := h.shares.MarkShared(key, time.Now()); err != nil {
+			respondWithError(c, domain.WrapError(domain.ErrCodeStorageFailure, "failed to record share", err))
+			return
+		}
+	}
+
 	// Generate base64url ID
-	encodedID := domain.EncodeVerdictID(filePath)
+	encodedID := domain.EncodeVerdictID(key)
 
 	response := ShareResponse{
 		ID: encodedID,
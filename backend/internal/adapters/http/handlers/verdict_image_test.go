@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"rechtebank/backend/internal/core/domain"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// imageFixture stores a photo for verdict "2026-02-01/153045_abc123" and
+// returns a router serving its image endpoint and the encoded verdict ID
+func imageFixture(t *testing.T, extension string, photo []byte) (*gin.Engine, string) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	fullDir := filepath.Join(tmpDir, "2026-02-01")
+	require.NoError(t, os.MkdirAll(fullDir, 0755))
+	photoPath := filepath.Join(fullDir, "153045_abc123"+extension)
+	require.NoError(t, os.WriteFile(photoPath, photo, 0644))
+
+	modTime := time.Date(2026, 2, 1, 15, 30, 45, 0, time.UTC)
+	require.NoError(t, os.Chtimes(photoPath, modTime, modTime))
+
+	router := gin.New()
+	router.GET("/v1/verdict/:id/image", NewVerdictHandler(tmpDir, nil, nil, nil).GetImage)
+	return router, domain.EncodeVerdictID("2026-02-01/153045_abc123")
+}
+
+func encodeJPEG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height)), nil))
+	return buf.Bytes()
+}
+
+func getImage(router *gin.Engine, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestVerdictHandler_GetImage_ServesPhoto(t *testing.T) {
+	photo := encodeJPEG(t, 80, 60)
+	router, id := imageFixture(t, ".jpg", photo)
+
+	w := getImage(router, "/v1/verdict/"+id+"/image", nil)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, photo, w.Body.Bytes())
+	assert.Equal(t, "image/jpeg", w.Header().Get("Content-Type"))
+	assert.Equal(t, "public, max-age=86400", w.Header().Get("Cache-Control"))
+	assert.Equal(t, "Sun, 01 Feb 2026 15:30:45 GMT", w.Header().Get("Last-Modified"))
+	assert.Regexp(t, `^"[0-9a-f]{16}"$`, w.Header().Get("ETag"))
+}
+
+func TestVerdictHandler_GetImage_StoredFormat(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 8, 8))))
+	router, id := imageFixture(t, ".png", buf.Bytes())
+
+	w := getImage(router, "/v1/verdict/"+id+"/image", nil)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+}
+
+func TestVerdictHandler_GetImage_ConditionalRequests(t *testing.T) {
+	router, id := imageFixture(t, ".jpg", encodeJPEG(t, 80, 60))
+	path := "/v1/verdict/" + id + "/image"
+	etag := getImage(router, path, nil).Header().Get("ETag")
+
+	w := getImage(router, path, map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.Bytes())
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+
+	w = getImage(router, path, map[string]string{"If-None-Match": `"something-else"`})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = getImage(router, path, map[string]string{"If-Modified-Since": "Sun, 01 Feb 2026 15:30:45 GMT"})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	w = getImage(router, path, map[string]string{"If-Modified-Since": "Sat, 31 Jan 2026 00:00:00 GMT"})
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestVerdictHandler_GetImage_Variant(t *testing.T) {
+	router, id := imageFixture(t, ".jpg", encodeJPEG(t, 800, 600))
+	path := "/v1/verdict/" + id + "/image"
+
+	w := getImage(router, path+"?w=400", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/jpeg", w.Header().Get("Content-Type"))
+
+	config, err := jpeg.DecodeConfig(bytes.NewReader(w.Body.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, 400, config.Width)
+	assert.Equal(t, 300, config.Height)
+
+	// Variants have their own validators
+	variantETag := w.Header().Get("ETag")
+	assert.NotEqual(t, getImage(router, path, nil).Header().Get("ETag"), variantETag)
+	assert.Equal(t, http.StatusNotModified, getImage(router, path+"?w=400", map[string]string{"If-None-Match": variantETag}).Code)
+}
+
+func TestVerdictHandler_GetImage_VariantWiderThanPhoto(t *testing.T) {
+	photo := encodeJPEG(t, 80, 60)
+	router, id := imageFixture(t, ".jpg", photo)
+
+	w := getImage(router, "/v1/verdict/"+id+"/image?w=400", nil)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, photo, w.Body.Bytes())
+}
+
+func TestVerdictHandler_GetImage_InvalidWidth(t *testing.T) {
+	router, id := imageFixture(t, ".jpg", encodeJPEG(t, 80, 60))
+
+	for _, width := range []string{"0", "-5", "abc", "4096"} {
+		w := getImage(router, "/v1/verdict/"+id+"/image?w="+width, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, "w=%s", width)
+	}
+}
+
+func TestVerdictHandler_GetImage_NotFound(t *testing.T) {
+	router, _ := imageFixture(t, ".jpg", encodeJPEG(t, 80, 60))
+
+	w := getImage(router, "/v1/verdict/"+domain.EncodeVerdictID("2026-02-01/000000_missing")+"/image", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = getImage(router, "/v1/verdict/!!!/image", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// stubVariantStore serves pre-generated variants from a map keyed by MIME type
+type stubVariantStore struct {
+	width int
+	paths map[string]string
+	err   error
+}
+
+func (s *stubVariantStore) VariantWidth(width int) int { return s.width }
+
+func (s *stubVariantStore) Variant(verdictPath string, width int, format domain.PhotoFormat) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	if width != s.width {
+		return "", nil
+	}
+	return s.paths[format.MIMEType], nil
+}
+
+// variantFixture stores an 800x600 photo with stored 400px variants and
+// returns a router whose handler serves them
+func variantFixture(t *testing.T, store *stubVariantStore) (*gin.Engine, string) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	fullDir := filepath.Join(tmpDir, "2026-02-01")
+	require.NoError(t, os.MkdirAll(fullDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(fullDir, "153045_abc123.jpg"), encodeJPEG(t, 800, 600), 0644))
+
+	store.width = 400
+	store.paths = map[string]string{}
+	for format, data := range map[domain.PhotoFormat][]byte{
+		domain.PhotoFormatJPEG: []byte("stored jpeg variant"),
+		domain.PhotoFormatWebP: []byte("stored webp variant"),
+	} {
+		path := filepath.Join(fullDir, "153045_abc123.w400"+format.Extension)
+		require.NoError(t, os.WriteFile(path, data, 0644))
+		store.paths[format.MIMEType] = path
+	}
+
+	router := gin.New()
+	router.GET("/v1/verdict/:id/image", NewVerdictHandler(tmpDir, store, nil, nil).GetImage)
+	return router, domain.EncodeVerdictID("2026-02-01/153045_abc123")
+}
+
+func TestVerdictHandler_GetImage_StoredVariant(t *testing.T) {
+	router, id := variantFixture(t, &stubVariantStore{})
+	path := "/v1/verdict/" + id + "/image?w=400"
+
+	w := getImage(router, path, map[string]string{"Accept": "image/avif,image/webp,*/*"})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/webp", w.Header().Get("Content-Type"))
+	assert.Equal(t, "stored webp variant", w.Body.String())
+	assert.Equal(t, "Accept", w.Header().Get("Vary"))
+	assert.Equal(t, "public, max-age=86400", w.Header().Get("Cache-Control"))
+
+	w = getImage(router, path, map[string]string{"Accept": "image/jpeg,*/*"})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/jpeg", w.Header().Get("Content-Type"))
+	assert.Equal(t, "stored jpeg variant", w.Body.String())
+	assert.Equal(t, "Accept", w.Header().Get("Vary"))
+}
+
+func TestVerdictHandler_GetImage_WidthSnappedToStoredVariant(t *testing.T) {
+	router, id := variantFixture(t, &stubVariantStore{})
+
+	w := getImage(router, "/v1/verdict/"+id+"/image?w=200", map[string]string{"Accept": "image/webp"})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/webp", w.Header().Get("Content-Type"))
+	assert.Equal(t, "stored webp variant", w.Body.String())
+}
+
+func TestVerdictHandler_GetImage_VariantStoreError(t *testing.T) {
+	router, id := variantFixture(t, &stubVariantStore{err: os.ErrPermission})
+
+	w := getImage(router, "/v1/verdict/"+id+"/image?w=400", nil)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestVerdictHandler_GetImage_ResizesWhenStorageIsFull(t *testing.T) {
+	storageFull := domain.NewError(domain.ErrCodeStorageFull, "storage quota reached")
+	router, id := variantFixture(t, &stubVariantStore{err: storageFull})
+
+	w := getImage(router, "/v1/verdict/"+id+"/image?w=400", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	config, err := jpeg.DecodeConfig(bytes.NewReader(w.Body.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, 400, config.Width)
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/base64"
 	"encoding/json"
 	"net/http"
@@ -9,11 +10,13 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"rechtebank/backend/internal/core/domain"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func init() {
@@ -52,7 +55,7 @@ func TestVerdictHandler_GetByID_Success(t *testing.T) {
 	os.WriteFile(jsonPath, verdictJSON, 0644)
 
 	// Create handler
-	handler := NewVerdictHandler(tmpDir)
+	handler := NewVerdictHandler(tmpDir, nil, nil, nil)
 
 	// Encode ID
 	encodedID := domain.EncodeVerdictID(dateDir + "/" + filename)
@@ -77,7 +80,16 @@ func TestVerdictHandler_GetByID_Success(t *testing.T) {
 	assert.Equal(t, verdictData.Score, response.Verdict.Score)
 	assert.Equal(t, verdictData.RequestID, response.Verdict.RequestID)
 
-	// Check image data (should be base64 data URL)
+	// The photo is referenced by URL, not inlined
+	assert.Equal(t, "/v1/verdict/"+encodedID+"/image", response.ImageURL)
+	assert.Empty(t, response.Image)
+
+	// The compatibility flag keeps the old inline shape
+	req = httptest.NewRequest(http.MethodGet, "/v1/verdict/"+encodedID+"?inline=true", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
 	assert.Contains(t, response.Image, "data:image/jpeg;base64,")
 
 	// Decode and verify image
@@ -87,9 +99,35 @@ func TestVerdictHandler_GetByID_Success(t *testing.T) {
 	assert.Equal(t, photoData, decoded)
 }
 
+func TestVerdictHandler_GetByID_InlinesStoredFormat(t *testing.T) {
+	tmpDir := t.TempDir()
+	dateDir := "2026-02-01"
+	filename := "153045_abc123"
+	fullDir := filepath.Join(tmpDir, dateDir)
+	os.MkdirAll(fullDir, 0755)
+
+	photoData := []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A} // PNG magic bytes
+	os.WriteFile(filepath.Join(fullDir, filename+".png"), photoData, 0644)
+	os.WriteFile(filepath.Join(fullDir, filename+".json"),
+		[]byte(`{"admissible":true,"score":8,"requestId":"abc123","mimeType":"image/png"}`), 0644)
+
+	router := gin.New()
+	router.GET("/v1/verdict/:id", NewVerdictHandler(tmpDir, nil, nil, nil).GetByID)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/verdict/"+domain.EncodeVerdictID(dateDir+"/"+filename)+"?inline=true", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response VerdictWithImageResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "data:image/png;base64,"+base64.StdEncoding.EncodeToString(photoData), response.Image)
+}
+
 func TestVerdictHandler_GetByID_InvalidID(t *testing.T) {
 	tmpDir := t.TempDir()
-	handler := NewVerdictHandler(tmpDir)
+	handler := NewVerdictHandler(tmpDir, nil, nil, nil)
 
 	router := gin.New()
 	router.GET("/v1/verdict/:id", handler.GetByID)
@@ -102,7 +140,65 @@ func TestVerdictHandler_GetByID_InvalidID(t *testing.T) {
 
 	var response map[string]string
 	json.Unmarshal(w.Body.Bytes(), &response)
-	assert.Contains(t, response["error"], "Invalid verdict ID")
+	assert.Equal(t, "INVALID_VERDICT_ID", response["code"])
+}
+
+func TestVerdictHandler_RejectsPathTraversal(t *testing.T) {
+	tmpDir := t.TempDir()
+	storageDir := filepath.Join(tmpDir, "storage")
+	secretDir := filepath.Join(tmpDir, "secret")
+	require.NoError(t, os.MkdirAll(storageDir, 0755))
+	require.NoError(t, os.MkdirAll(secretDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(secretDir, "private.jpg"), []byte{0xFF, 0xD8, 0xFF, 0xE0}, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(secretDir, "private.json"), []byte(`{"score":1}`), 0644))
+
+	handler := NewVerdictHandler(storageDir, nil, nil, nil)
+	router := gin.New()
+	router.GET("/v1/verdict/:id", handler.GetByID)
+	router.GET("/v1/verdict/:id/image", handler.GetImage)
+
+	for _, key := range []string{"../secret/private", "2026-02-01/../../secret/private", "2026-02-01/153045_x/../../../secret/private"} {
+		id := domain.EncodeVerdictID(key)
+		for _, path := range []string{"/v1/verdict/" + id, "/v1/verdict/" + id + "/image"} {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code, "%s (%s)", path, key)
+			var response map[string]string
+			json.Unmarshal(w.Body.Bytes(), &response)
+			assert.Equal(t, "INVALID_VERDICT_ID", response["code"], "%s (%s)", path, key)
+		}
+	}
+}
+
+func TestVerdictHandler_GetByID_ModerationStaysInternal(t *testing.T) {
+	tmpDir := t.TempDir()
+	fullDir := filepath.Join(tmpDir, "2026-02-01")
+	require.NoError(t, os.MkdirAll(fullDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(fullDir, "153045_abc123.jpg"), []byte{0xFF, 0xD8}, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(fullDir, "153045_abc123.json"),
+		[]byte(`{"admissible":true,"score":7,"requestId":"abc123","moderation":{"flagged":false}}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(fullDir, "160000_flagged.json"),
+		[]byte(`{"admissible":true,"requestId":"flagged","moderation":{"flagged":true,"categories":["sexually_explicit"]}}`), 0644))
+
+	router := gin.New()
+	router.GET("/v1/verdict/:id", NewVerdictHandler(tmpDir, nil, nil, nil).GetByID)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/verdict/"+domain.EncodeVerdictID("2026-02-01/153045_abc123"), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "moderation")
+
+	var response VerdictWithImageResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 7, response.Verdict.Score)
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/verdict/"+domain.EncodeVerdictID("2026-02-01/160000_flagged"), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
 }
 
 func TestVerdictHandler_GetByID_MissingVerdictJSON(t *testing.T) {
@@ -119,7 +215,7 @@ func TestVerdictHandler_GetByID_MissingVerdictJSON(t *testing.T) {
 	os.WriteFile(photoPath, photoData, 0644)
 	// Don't create JSON file
 
-	handler := NewVerdictHandler(tmpDir)
+	handler := NewVerdictHandler(tmpDir, nil, nil, nil)
 	encodedID := domain.EncodeVerdictID(dateDir + "/" + filename)
 
 	router := gin.New()
@@ -133,7 +229,7 @@ func TestVerdictHandler_GetByID_MissingVerdictJSON(t *testing.T) {
 
 	var response map[string]string
 	json.Unmarshal(w.Body.Bytes(), &response)
-	assert.Contains(t, response["error"], "Verdict data not found")
+	assert.Equal(t, "VERDICT_NOT_FOUND", response["code"])
 }
 
 func TestVerdictHandler_GetByID_MissingPhoto(t *testing.T) {
@@ -154,7 +250,7 @@ func TestVerdictHandler_GetByID_MissingPhoto(t *testing.T) {
 	os.WriteFile(jsonPath, verdictJSON, 0644)
 	// Don't create photo file
 
-	handler := NewVerdictHandler(tmpDir)
+	handler := NewVerdictHandler(tmpDir, nil, nil, nil)
 	encodedID := domain.EncodeVerdictID(dateDir + "/" + filename)
 
 	router := gin.New()
@@ -168,7 +264,7 @@ func TestVerdictHandler_GetByID_MissingPhoto(t *testing.T) {
 
 	var response map[string]string
 	json.Unmarshal(w.Body.Bytes(), &response)
-	assert.Contains(t, response["error"], "Photo file not found")
+	assert.Equal(t, "VERDICT_NOT_FOUND", response["code"])
 }
 
 func TestVerdictHandler_GetByID_FileReadError(t *testing.T) {
@@ -193,7 +289,7 @@ func TestVerdictHandler_GetByID_FileReadError(t *testing.T) {
 	os.Chmod(fullDir, 0000)
 	defer os.Chmod(fullDir, 0755) // Restore for cleanup
 
-	handler := NewVerdictHandler(tmpDir)
+	handler := NewVerdictHandler(tmpDir, nil, nil, nil)
 	encodedID := domain.EncodeVerdictID(dateDir + "/" + filename)
 
 	router := gin.New()
@@ -229,7 +325,7 @@ func TestVerdictHandler_CreateShareURL_Success(t *testing.T) {
 	jsonPath := filepath.Join(fullDir, filename+".json")
 	os.WriteFile(jsonPath, verdictJSON, 0644)
 
-	handler := NewVerdictHandler(tmpDir)
+	handler := NewVerdictHandler(tmpDir, nil, nil, nil)
 
 	router := gin.New()
 	router.POST("/v1/verdict/share", handler.CreateShareURL)
@@ -254,9 +350,28 @@ func TestVerdictHandler_CreateShareURL_Success(t *testing.T) {
 	assert.Equal(t, dateDir+"/"+filename, decoded)
 }
 
+func TestVerdictHandler_CreateShareURL_WebPPhoto(t *testing.T) {
+	tmpDir := t.TempDir()
+	fullDir := filepath.Join(tmpDir, "2026-02-01")
+	os.MkdirAll(fullDir, 0755)
+	os.WriteFile(filepath.Join(fullDir, "153045_abc123.webp"), []byte("RIFF\x1a\x00\x00\x00WEBP"), 0644)
+	os.WriteFile(filepath.Join(fullDir, "153045_abc123.json"), []byte(`{"mimeType":"image/webp"}`), 0644)
+
+	router := gin.New()
+	router.POST("/v1/verdict/share", NewVerdictHandler(tmpDir, nil, nil, nil).CreateShareURL)
+
+	reqBody := `{"timestamp":"2026-02-01T15:30:45Z","requestId":"abc123"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/verdict/share", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
 func TestVerdictHandler_CreateShareURL_MissingFiles(t *testing.T) {
 	tmpDir := t.TempDir()
-	handler := NewVerdictHandler(tmpDir)
+	handler := NewVerdictHandler(tmpDir, nil, nil, nil)
 
 	router := gin.New()
 	router.POST("/v1/verdict/share", handler.CreateShareURL)
@@ -271,12 +386,68 @@ func TestVerdictHandler_CreateShareURL_MissingFiles(t *testing.T) {
 
 	var response map[string]string
 	json.Unmarshal(w.Body.Bytes(), &response)
-	assert.Contains(t, response["error"], "Verdict not found")
+	assert.Equal(t, "VERDICT_NOT_FOUND", response["code"])
+}
+
+// pendingSaves reports the listed request IDs as still queued
+type pendingSaves map[string]bool
+
+func (p pendingSaves) IsPending(requestID string) bool { return p[requestID] }
+
+func (p pendingSaves) Degraded() bool { return false }
+
+// fullStorage reports storage as full
+type fullStorage struct{ pendingSaves }
+
+func (fullStorage) Degraded() bool { return true }
+
+func TestVerdictHandler_CreateShareURL_PendingSave(t *testing.T) {
+	handler := NewVerdictHandler(t.TempDir(), nil, pendingSaves{"queued": true}, nil)
+
+	router := gin.New()
+	router.POST("/v1/verdict/share", handler.CreateShareURL)
+
+	for requestID, wantStatus := range map[string]int{"queued": http.StatusAccepted, "unknown": http.StatusNotFound} {
+		reqBody := `{"timestamp":"2026-02-01T15:30:45Z","requestId":"` + requestID + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/v1/verdict/share", strings.NewReader(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, wantStatus, w.Code, requestID)
+		if wantStatus == http.StatusAccepted {
+			assert.Equal(t, "2", w.Header().Get("Retry-After"))
+			var response SharePendingResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, SharePendingResponse{Status: "pending", RequestID: "queued"}, response)
+		}
+	}
+}
+
+func TestVerdictHandler_CreateShareURL_StorageFull(t *testing.T) {
+	tmpDir := t.TempDir()
+	fullDir := filepath.Join(tmpDir, "2026-02-01")
+	os.MkdirAll(fullDir, 0755)
+	os.WriteFile(filepath.Join(fullDir, "153045_abc123.jpg"), []byte{0xFF, 0xD8}, 0644)
+	os.WriteFile(filepath.Join(fullDir, "153045_abc123.json"), []byte(`{"admissible":true}`), 0644)
+
+	router := gin.New()
+	router.POST("/v1/verdict/share", NewVerdictHandler(tmpDir, nil, fullStorage{}, nil).CreateShareURL)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/verdict/share", strings.NewReader(`{"requestId":"abc123"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	var response map[string]string
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "STORAGE_FULL", response["code"])
 }
 
 func TestVerdictHandler_CreateShareURL_InvalidRequest(t *testing.T) {
 	tmpDir := t.TempDir()
-	handler := NewVerdictHandler(tmpDir)
+	handler := NewVerdictHandler(tmpDir, nil, nil, nil)
 
 	router := gin.New()
 	router.POST("/v1/verdict/share", handler.CreateShareURL)
@@ -290,3 +461,156 @@ func TestVerdictHandler_CreateShareURL_InvalidRequest(t *testing.T) {
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
+
+func TestVerdictHandler_CreateShareURL_LooksUpByRequestID(t *testing.T) {
+	tmpDir := t.TempDir()
+	// Filed just before midnight UTC, already the next day for the client
+	fullDir := filepath.Join(tmpDir, "2026-02-01")
+	os.MkdirAll(fullDir, 0755)
+	os.WriteFile(filepath.Join(fullDir, "235959_abc123.jpg"), []byte{0xFF, 0xD8}, 0644)
+	os.WriteFile(filepath.Join(fullDir, "235959_abc123.json"), []byte(`{"admissible":true}`), 0644)
+	os.MkdirAll(filepath.Join(tmpDir, "2026-02-02"), 0755)
+
+	router := gin.New()
+	router.POST("/v1/verdict/share", NewVerdictHandler(tmpDir, nil, nil, nil).CreateShareURL)
+
+	tests := []struct {
+		name    string
+		reqBody string
+	}{
+		{"local timestamp", `{"timestamp":"2026-02-02T00:59:59+01:00","requestId":"abc123"}`},
+		{"mismatched timestamp", `{"timestamp":"2026-02-01T23:59:58Z","requestId":"abc123"}`},
+		{"no timestamp", `{"requestId":"abc123"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/verdict/share", strings.NewReader(tt.reqBody))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusOK, w.Code)
+			var response ShareResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			decoded, err := domain.DecodeVerdictID(response.ID)
+			require.NoError(t, err)
+			assert.Equal(t, "2026-02-01/235959_abc123", decoded)
+		})
+	}
+}
+
+func TestVerdictHandler_CreateShareURL_LimitsFallbackSearch(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, dateDir := range []string{"2026-01-01", "2026-02-01", "2026-02-02"} {
+		os.MkdirAll(filepath.Join(tmpDir, dateDir), 0755)
+	}
+	// An old verdict, only found under the key of its own timestamp
+	os.WriteFile(filepath.Join(tmpDir, "2026-01-01", "120000_old.jpg"), []byte{0xFF, 0xD8}, 0644)
+	os.WriteFile(filepath.Join(tmpDir, "2026-01-01", "120000_old.json"), []byte(`{"admissible":true}`), 0644)
+
+	router := gin.New()
+	router.POST("/v1/verdict/share", NewVerdictHandler(tmpDir, nil, nil, nil).CreateShareURL)
+
+	tests := []struct {
+		name       string
+		reqBody    string
+		wantStatus int
+	}{
+		{"own timestamp", `{"timestamp":"2026-01-01T12:00:00Z","requestId":"old"}`, http.StatusOK},
+		{"other day", `{"timestamp":"2026-02-01T12:00:00Z","requestId":"old"}`, http.StatusNotFound},
+		{"no timestamp", `{"requestId":"old"}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/verdict/share", strings.NewReader(tt.reqBody))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestVerdictHandler_CreateShareURL_UnsafeRequestID(t *testing.T) {
+	tmpDir := t.TempDir()
+	fullDir := filepath.Join(tmpDir, "2026-02-01")
+	os.MkdirAll(fullDir, 0755)
+	os.WriteFile(filepath.Join(fullDir, "153045_abc123.jpg"), []byte{0xFF, 0xD8}, 0644)
+	os.WriteFile(filepath.Join(fullDir, "153045_abc123.json"), []byte(`{"admissible":true}`), 0644)
+
+	router := gin.New()
+	router.POST("/v1/verdict/share", NewVerdictHandler(tmpDir, nil, nil, nil).CreateShareURL)
+
+	for _, requestID := range []string{"../2026-02-01/153045_abc123", "123_abc123/x", ".json"} {
+		body, _ := json.Marshal(ShareRequest{RequestID: requestID})
+		req := httptest.NewRequest(http.MethodPost, "/v1/verdict/share", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code, requestID)
+	}
+}
+
+func TestVerdictHandler_CreateShareURL_FlaggedVerdict(t *testing.T) {
+	tmpDir := t.TempDir()
+	fullDir := filepath.Join(tmpDir, "2026-02-01")
+	os.MkdirAll(fullDir, 0755)
+	os.WriteFile(filepath.Join(fullDir, "153045_abc123.json"),
+		[]byte(`{"admissible":true,"moderation":{"flagged":true,"categories":["sexually_explicit"]}}`), 0644)
+
+	router := gin.New()
+	router.POST("/v1/verdict/share", NewVerdictHandler(tmpDir, nil, nil, nil).CreateShareURL)
+
+	reqBody := `{"timestamp":"2026-02-01T15:30:45Z","requestId":"abc123"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/verdict/share", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	var response map[string]string
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "VERDICT_WITHHELD", response["code"])
+}
+
+// recordingShares records the keys of shared verdicts
+type recordingShares struct {
+	keys []string
+	err  error
+}
+
+func (r *recordingShares) MarkShared(key string, at time.Time) error {
+	r.keys = append(r.keys, key)
+	return r.err
+}
+
+func TestVerdictHandler_CreateShareURL_RecordsShare(t *testing.T) {
+	tmpDir := t.TempDir()
+	fullDir := filepath.Join(tmpDir, "2026-02-01")
+	os.MkdirAll(fullDir, 0755)
+	os.WriteFile(filepath.Join(fullDir, "153045_abc123.jpg"), []byte{0xFF, 0xD8}, 0644)
+	os.WriteFile(filepath.Join(fullDir, "153045_abc123.json"), []byte(`{"admissible":false}`), 0644)
+
+	share := func(shares *recordingShares) *httptest.ResponseRecorder {
+		router := gin.New()
+		router.POST("/v1/verdict/share", NewVerdictHandler(tmpDir, nil, nil, shares).CreateShareURL)
+		req := httptest.NewRequest(http.MethodPost, "/v1/verdict/share", strings.NewReader(`{"requestId":"abc123"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	shares := &recordingShares{}
+	w := share(shares)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"2026-02-01/153045_abc123"}, shares.keys)
+
+	// No share ID is handed out for a share that could not be recorded
+	w = share(&recordingShares{err: os.ErrPermission})
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NotContains(t, w.Body.String(), `"id"`)
+}
@@ -0,0 +1,12 @@
+package openapi
+
+import (
+	_ "embed"
+)
+
+// Spec is the OpenAPI 3 document describing every route registered by http.NewRouter.
+// It is served at /openapi.json; schemas are checked against the Go response
+// types and handler responses are validated against it in tests.
+//
+//go:embed openapi.json
+var Spec []byte
@@ -0,0 +1,119 @@
+package openapi
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"rechtebank/backend/internal/adapters/http/handlers"
+	"rechtebank/backend/internal/adapters/storage"
+	"rechtebank/backend/internal/core/domain"
+	"rechtebank/backend/internal/health"
+	"rechtebank/backend/internal/scheduler"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadSpec(t *testing.T) *openapi3.T {
+	doc, err := openapi3.NewLoader().LoadFromData(Spec)
+	require.NoError(t, err)
+	require.NoError(t, doc.Validate(context.Background()))
+	return doc
+}
+
+// jsonFields returns the JSON property names of a struct type and the subset
+// that is always serialized (no omitempty)
+func jsonFields(typ reflect.Type) (all []string, required []string) {
+	for i := 0; i < typ.NumField(); i++ {
+		tag := typ.Field(i).Tag.Get("json")
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "-" || name == "" {
+			continue
+		}
+		all = append(all, name)
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	sort.Strings(all)
+	sort.Strings(required)
+	return all, required
+}
+
+func schemaFields(schema *openapi3.Schema) (all []string, required []string) {
+	for name := range schema.Properties {
+		all = append(all, name)
+	}
+	required = append(required, schema.Required...)
+	sort.Strings(all)
+	sort.Strings(required)
+	return all, required
+}
+
+func TestSpec_IsValidOpenAPI3(t *testing.T) {
+	doc := loadSpec(t)
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+}
+
+func TestSpec_SchemasMatchGoTypes(t *testing.T) {
+	doc := loadSpec(t)
+
+	types := map[string]reflect.Type{
+		"VerdictResponse":          reflect.TypeOf(domain.VerdictResponse{}),
+		"VerdictDetails":           reflect.TypeOf(domain.VerdictDetails{}),
+		"VerdictWithImageResponse": reflect.TypeOf(handlers.VerdictWithImageResponse{}),
+		"ShareRequest":             reflect.TypeOf(handlers.ShareRequest{}),
+		"ShareResponse":            reflect.TypeOf(handlers.ShareResponse{}),
+		"SharePendingResponse":     reflect.TypeOf(handlers.SharePendingResponse{}),
+		"ErrorResponse":            reflect.TypeOf(handlers.ErrorResponse{}),
+		"CaptureMetadata":          reflect.TypeOf(domain.CaptureMetadata{}),
+		"ModerationResult":         reflect.TypeOf(domain.ModerationResult{}),
+		"ReadinessReport":          reflect.TypeOf(health.Report{}),
+		"CheckResult":              reflect.TypeOf(health.CheckResult{}),
+		"JobsResponse":             reflect.TypeOf(handlers.JobsResponse{}),
+		"JobStatus":                reflect.TypeOf(scheduler.JobStatus{}),
+		"JobRun":                   reflect.TypeOf(scheduler.Run{}),
+		"StatsRollup":              reflect.TypeOf(storage.StatsRollup{}),
+		"StorageUsage":             reflect.TypeOf(storage.Usage{}),
+	}
+
+	for name, typ := range types {
+		t.Run(name, func(t *testing.T) {
+			ref, ok := doc.Components.Schemas[name]
+			require.True(t, ok, "schema %s missing from spec", name)
+
+			goAll, goRequired := jsonFields(typ)
+			specAll, specRequired := schemaFields(ref.Value)
+			assert.Equal(t, goAll, specAll, "properties of %s drifted from %s", name, typ)
+			assert.Equal(t, goRequired, specRequired, "required fields of %s drifted from %s", name, typ)
+		})
+	}
+}
+
+func TestSpec_VerdictTypeEnumMatchesDomain(t *testing.T) {
+	doc := loadSpec(t)
+
+	enum := doc.Components.Schemas["VerdictDetails"].Value.Properties["verdictType"].Value.Enum
+	var values []string
+	for _, v := range enum {
+		values = append(values, v.(string))
+	}
+
+	assert.ElementsMatch(t, domain.VerdictTypes, values)
+}
+
+func TestSpec_ErrorCodeEnumMatchesDomain(t *testing.T) {
+	doc := loadSpec(t)
+
+	enum := doc.Components.Schemas["ErrorResponse"].Value.Properties["code"].Value.Enum
+	var values []domain.ErrorCode
+	for _, v := range enum {
+		values = append(values, domain.ErrorCode(v.(string)))
+	}
+
+	assert.ElementsMatch(t, domain.ErrorCodes, values)
+}
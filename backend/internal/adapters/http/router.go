@@ -1,26 +1,49 @@
 package http
 
 import (
+	"log/slog"
 	"net/http"
 	"time"
 
 	"rechtebank/backend/internal/adapters/http/handlers"
+	"rechtebank/backend/internal/adapters/http/openapi"
+	"rechtebank/backend/internal/logging"
+	"rechtebank/backend/internal/telemetry"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// probePaths are health check endpoints excluded from request logging and tracing
+var probePaths = map[string]bool{
+	"/health": true,
+	"/livez":  true,
+	"/readyz": true,
+}
+
 // RouterConfig holds configuration for the router
 type RouterConfig struct {
 	CORSOrigin string
+
+	// Admin serves the /admin endpoints to requests carrying AdminToken as
+	// a bearer token; without both the endpoints do not exist
+	Admin      *handlers.AdminHandler
+	AdminToken string
 }
 
 // NewRouter creates a new Gin router with all middleware and routes configured
-func NewRouter(judgeHandler *handlers.JudgeHandler, verdictHandler *handlers.VerdictHandler, config RouterConfig) *gin.Engine {
+func NewRouter(judgeHandler *handlers.JudgeHandler, verdictHandler *handlers.VerdictHandler, healthHandler *handlers.HealthHandler, config RouterConfig) *gin.Engine {
 	router := gin.New()
 
 	// Add middleware
 	router.Use(gin.Recovery())
+	router.Use(requestIDMiddleware())
 	router.Use(loggingMiddleware())
+	router.Use(tracingMiddleware())
 	router.Use(corsMiddleware(config.CORSOrigin))
 
 	// Health check endpoint
@@ -31,22 +54,116 @@ func NewRouter(judgeHandler *handlers.JudgeHandler, verdictHandler *handlers.Ver
 		})
 	})
 
+	// Liveness and dependency-aware readiness probes
+	router.GET("/livez", healthHandler.Live)
+	router.GET("/readyz", healthHandler.Ready)
+
+	// API description
+	router.GET("/openapi.json", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json", openapi.Spec)
+	})
+
 	// API v1 routes
 	v1 := router.Group("/v1")
 	{
 		v1.POST("/judge", judgeHandler.Handle)
 		v1.GET("/verdict/:id", verdictHandler.GetByID)
+		v1.GET("/verdict/:id/image", verdictHandler.GetImage)
 		v1.POST("/verdict/share", verdictHandler.CreateShareURL)
 	}
 
+	// Operator endpoints
+	if config.Admin != nil && config.AdminToken != "" {
+		admin := router.Group("/admin", handlers.RequireBearerToken(config.AdminToken))
+		{
+			admin.GET("/jobs", config.Admin.Jobs)
+			admin.GET("/stats", config.Admin.Stats)
+		}
+	}
+
 	return router
 }
 
+// requestIDMiddleware assigns each request an ID, stores it in the request
+// context for logging and the verdict response, and returns it in
+// X-Request-ID. An X-Request-ID sent by the client is only logged.
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := logging.NewRequestID()
+
+		ctx := logging.WithRequestID(c.Request.Context(), requestID)
+		if clientRequestID := logging.ClientRequestID(c.GetHeader(logging.RequestIDHeader)); clientRequestID != "" {
+			ctx = logging.WithClientRequestID(ctx, clientRequestID)
+		}
+		c.Request = c.Request.WithContext(ctx)
+		c.Header(logging.RequestIDHeader, requestID)
+
+		c.Next()
+	}
+}
+
 // loggingMiddleware logs request information
 func loggingMiddleware() gin.HandlerFunc {
-	return gin.LoggerWithConfig(gin.LoggerConfig{
-		SkipPaths: []string{"/health"},
-	})
+	logger := logging.Component(logging.ComponentHTTP)
+
+	return func(c *gin.Context) {
+		if probePaths[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		c.Next()
+
+		logger.InfoContext(c.Request.Context(), "Request handled",
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", c.Writer.Status()),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()))
+	}
+}
+
+// tracingMiddleware starts a server span for each request, continuing the
+// W3C trace context (traceparent/tracestate headers) sent by the frontend
+func tracingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if probePaths[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		opts := []trace.SpanStartOption{
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Request.Method),
+				attribute.String("http.route", route),
+			),
+		}
+		// The frontend names a trace but exports no spans and leaves sampling
+		// to us: start a new trace, sampled by ratio, linked to the frontend's
+		// instead of one hanging off a parent that never arrives
+		if remote := trace.SpanContextFromContext(ctx); remote.IsValid() && !remote.IsSampled() {
+			opts = append(opts, trace.WithNewRoot(), trace.WithLinks(trace.Link{SpanContext: remote}))
+		}
+		ctx, span := telemetry.Tracer().Start(ctx, c.Request.Method+" "+route, opts...)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
 }
 
 // corsMiddleware handles CORS headers
@@ -59,7 +176,8 @@ func corsMiddleware(allowedOrigin string) gin.HandlerFunc {
 
 		c.Header("Access-Control-Allow-Origin", origin)
 		c.Header("Access-Control-Allow-Methods", "POST, GET, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, traceparent, tracestate, X-Request-ID")
+		c.Header("Access-Control-Expose-Headers", "X-Request-ID")
 		c.Header("Access-Control-Max-Age", "86400")
 
 		if c.Request.Method == "OPTIONS" {
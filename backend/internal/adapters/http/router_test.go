@@ -8,12 +8,21 @@ import (
 
 	"rechtebank/backend/internal/adapters/http/handlers"
 	"rechtebank/backend/internal/core/domain"
+	"rechtebank/backend/internal/scheduler"
+	"rechtebank/backend/internal/telemetry"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
+// testMaxFileSize is the upload limit handlers are created with in tests
+const testMaxFileSize = 10 * 1024 * 1024
+
 // MockVerdictService for router tests
 type MockVerdictService struct {
 	mock.Mock
@@ -33,9 +42,9 @@ func init() {
 
 func TestRouter_HealthEndpoint(t *testing.T) {
 	mockService := new(MockVerdictService)
-	handler := handlers.NewJudgeHandler(mockService, nil)
-	verdictHandler := handlers.NewVerdictHandler("")
-	router := NewRouter(handler, verdictHandler, RouterConfig{CORSOrigin: "*"})
+	handler := handlers.NewJudgeHandler(mockService, nil, testMaxFileSize)
+	verdictHandler := handlers.NewVerdictHandler("", nil, nil, nil)
+	router := NewRouter(handler, verdictHandler, handlers.NewHealthHandler(nil), RouterConfig{CORSOrigin: "*"})
 
 	req := httptest.NewRequest(http.MethodGet, "/health", nil)
 	w := httptest.NewRecorder()
@@ -49,9 +58,9 @@ func TestRouter_HealthEndpoint(t *testing.T) {
 
 func TestRouter_CORS_PreflightRequest(t *testing.T) {
 	mockService := new(MockVerdictService)
-	handler := handlers.NewJudgeHandler(mockService, nil)
-	verdictHandler := handlers.NewVerdictHandler("")
-	router := NewRouter(handler, verdictHandler, RouterConfig{CORSOrigin: "http://localhost:5173"})
+	handler := handlers.NewJudgeHandler(mockService, nil, testMaxFileSize)
+	verdictHandler := handlers.NewVerdictHandler("", nil, nil, nil)
+	router := NewRouter(handler, verdictHandler, handlers.NewHealthHandler(nil), RouterConfig{CORSOrigin: "http://localhost:5173"})
 
 	req := httptest.NewRequest(http.MethodOptions, "/v1/judge", nil)
 	req.Header.Set("Origin", "http://localhost:5173")
@@ -67,9 +76,9 @@ func TestRouter_CORS_PreflightRequest(t *testing.T) {
 
 func TestRouter_CORS_PostRequest(t *testing.T) {
 	mockService := new(MockVerdictService)
-	handler := handlers.NewJudgeHandler(mockService, nil)
-	verdictHandler := handlers.NewVerdictHandler("")
-	router := NewRouter(handler, verdictHandler, RouterConfig{CORSOrigin: "http://localhost:5173"})
+	handler := handlers.NewJudgeHandler(mockService, nil, testMaxFileSize)
+	verdictHandler := handlers.NewVerdictHandler("", nil, nil, nil)
+	router := NewRouter(handler, verdictHandler, handlers.NewHealthHandler(nil), RouterConfig{CORSOrigin: "http://localhost:5173"})
 
 	req := httptest.NewRequest(http.MethodPost, "/v1/judge", nil)
 	req.Header.Set("Origin", "http://localhost:5173")
@@ -84,9 +93,9 @@ func TestRouter_CORS_PostRequest(t *testing.T) {
 
 func TestRouter_CORS_DefaultOrigin(t *testing.T) {
 	mockService := new(MockVerdictService)
-	handler := handlers.NewJudgeHandler(mockService, nil)
-	verdictHandler := handlers.NewVerdictHandler("")
-	router := NewRouter(handler, verdictHandler, RouterConfig{CORSOrigin: ""})
+	handler := handlers.NewJudgeHandler(mockService, nil, testMaxFileSize)
+	verdictHandler := handlers.NewVerdictHandler("", nil, nil, nil)
+	router := NewRouter(handler, verdictHandler, handlers.NewHealthHandler(nil), RouterConfig{CORSOrigin: ""})
 
 	req := httptest.NewRequest(http.MethodGet, "/health", nil)
 	w := httptest.NewRecorder()
@@ -98,9 +107,9 @@ func TestRouter_CORS_DefaultOrigin(t *testing.T) {
 
 func TestRouter_V1JudgeEndpoint(t *testing.T) {
 	mockService := new(MockVerdictService)
-	handler := handlers.NewJudgeHandler(mockService, nil)
-	verdictHandler := handlers.NewVerdictHandler("")
-	router := NewRouter(handler, verdictHandler, RouterConfig{CORSOrigin: "*"})
+	handler := handlers.NewJudgeHandler(mockService, nil, testMaxFileSize)
+	verdictHandler := handlers.NewVerdictHandler("", nil, nil, nil)
+	router := NewRouter(handler, verdictHandler, handlers.NewHealthHandler(nil), RouterConfig{CORSOrigin: "*"})
 
 	// Request without proper content type should fail with 400
 	req := httptest.NewRequest(http.MethodPost, "/v1/judge", nil)
@@ -114,9 +123,9 @@ func TestRouter_V1JudgeEndpoint(t *testing.T) {
 
 func TestRouter_NotFound(t *testing.T) {
 	mockService := new(MockVerdictService)
-	handler := handlers.NewJudgeHandler(mockService, nil)
-	verdictHandler := handlers.NewVerdictHandler("")
-	router := NewRouter(handler, verdictHandler, RouterConfig{CORSOrigin: "*"})
+	handler := handlers.NewJudgeHandler(mockService, nil, testMaxFileSize)
+	verdictHandler := handlers.NewVerdictHandler("", nil, nil, nil)
+	router := NewRouter(handler, verdictHandler, handlers.NewHealthHandler(nil), RouterConfig{CORSOrigin: "*"})
 
 	req := httptest.NewRequest(http.MethodGet, "/unknown", nil)
 	w := httptest.NewRecorder()
@@ -125,3 +134,130 @@ func TestRouter_NotFound(t *testing.T) {
 
 	assert.Equal(t, http.StatusNotFound, w.Code)
 }
+
+func TestRouter_Tracing_PropagatesTraceparent(t *testing.T) {
+	_, err := telemetry.Setup(context.Background(), telemetry.Config{})
+	assert.NoError(t, err)
+
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(telemetry.NewTracerProvider(telemetry.Config{SampleRatio: 1.0}, sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	mockService := new(MockVerdictService)
+	handler := handlers.NewJudgeHandler(mockService, nil, testMaxFileSize)
+	verdictHandler := handlers.NewVerdictHandler("", nil, nil, nil)
+	router := NewRouter(handler, verdictHandler, handlers.NewHealthHandler(nil), RouterConfig{CORSOrigin: "*"})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/judge", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	spans := recorder.Ended()
+	assert.NotEmpty(t, spans)
+	server := spans[len(spans)-1]
+	assert.Equal(t, "POST /v1/judge", server.Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+}
+
+func TestRouter_Tracing_UnsampledTraceparentStartsLinkedTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(telemetry.NewTracerProvider(telemetry.Config{SampleRatio: 1.0}, sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	handler := handlers.NewJudgeHandler(new(MockVerdictService), nil, testMaxFileSize)
+	verdictHandler := handlers.NewVerdictHandler("", nil, nil, nil)
+	router := NewRouter(handler, verdictHandler, handlers.NewHealthHandler(nil), RouterConfig{CORSOrigin: "*"})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/judge", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Sampled by the backend's ratio, without an orphaned parent
+	spans := recorder.Ended()
+	require.NotEmpty(t, spans)
+	server := spans[len(spans)-1]
+	assert.Equal(t, "POST /v1/judge", server.Name())
+	assert.False(t, server.Parent().IsValid())
+	assert.NotEqual(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+	require.Len(t, server.Links(), 1)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.Links()[0].SpanContext.TraceID().String())
+}
+
+func TestRouter_CORS_AllowsTraceHeaders(t *testing.T) {
+	mockService := new(MockVerdictService)
+	handler := handlers.NewJudgeHandler(mockService, nil, testMaxFileSize)
+	verdictHandler := handlers.NewVerdictHandler("", nil, nil, nil)
+	router := NewRouter(handler, verdictHandler, handlers.NewHealthHandler(nil), RouterConfig{CORSOrigin: "*"})
+
+	req := httptest.NewRequest(http.MethodOptions, "/v1/judge", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Contains(t, w.Header().Get("Access-Control-Allow-Headers"), "traceparent")
+}
+
+func TestRouter_RequestID_GeneratedAndEchoed(t *testing.T) {
+	mockService := new(MockVerdictService)
+	handler := handlers.NewJudgeHandler(mockService, nil, testMaxFileSize)
+	verdictHandler := handlers.NewVerdictHandler("", nil, nil, nil)
+	router := NewRouter(handler, verdictHandler, handlers.NewHealthHandler(nil), RouterConfig{CORSOrigin: "*"})
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Len(t, w.Header().Get("X-Request-ID"), 36)
+
+	// A client's ID is only logged; the server picks the one it stores
+	req = httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.Header.Set("X-Request-ID", "550e8400-e29b-41d4-a716-446655440000")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Len(t, w.Header().Get("X-Request-ID"), 36)
+	assert.NotEqual(t, "550e8400-e29b-41d4-a716-446655440000", w.Header().Get("X-Request-ID"))
+}
+
+func TestRouter_ProbeEndpoints(t *testing.T) {
+	mockService := new(MockVerdictService)
+	handler := handlers.NewJudgeHandler(mockService, nil, testMaxFileSize)
+	verdictHandler := handlers.NewVerdictHandler("", nil, nil, nil)
+	router := NewRouter(handler, verdictHandler, handlers.NewHealthHandler(nil), RouterConfig{CORSOrigin: "*"})
+
+	for _, path := range []string{"/livez", "/readyz"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code, path)
+	}
+}
+
+func TestRouter_AdminEndpoints(t *testing.T) {
+	handler := handlers.NewJudgeHandler(new(MockVerdictService), nil, testMaxFileSize)
+	verdictHandler := handlers.NewVerdictHandler("", nil, nil, nil)
+	admin := handlers.NewAdminHandler(scheduler.New(nil), nil)
+
+	// Without a token the endpoints do not exist
+	router := NewRouter(handler, verdictHandler, handlers.NewHealthHandler(nil), RouterConfig{Admin: admin})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/jobs", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	router = NewRouter(handler, verdictHandler, handlers.NewHealthHandler(nil), RouterConfig{Admin: admin, AdminToken: "s3cret"})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/jobs", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/jobs", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"jobs":[]}`, w.Body.String())
+}
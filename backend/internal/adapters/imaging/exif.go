@@ -0,0 +1,156 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+// EXIF tags read by the parser
+const (
+	tagOrientation        = 0x0112
+	tagModel              = 0x0110
+	tagExifIFDPointer     = 0x8769
+	tagDateTimeOriginal   = 0x9003
+	tagOffsetTimeOriginal = 0x9011
+)
+
+// EXIF field types used by the tags above
+const (
+	typeASCII = 2
+	typeShort = 3
+	typeLong  = 4
+)
+
+var exifHeader = []byte("Exif\x00\x00")
+
+// ExifData holds the few EXIF fields the backend cares about
+type ExifData struct {
+	// Orientation is the EXIF orientation (1-8); 1 when absent
+	Orientation int
+
+	// Model is the camera model, e.g. "Pixel 8"
+	Model string
+
+	// DateTimeOriginal is the capture time as "YYYY:MM:DD HH:MM:SS"
+	DateTimeOriginal string
+
+	// OffsetTimeOriginal is the UTC offset of DateTimeOriginal, e.g. "+02:00"
+	OffsetTimeOriginal string
+}
+
+// tiffReader reads values from a TIFF structure with a given byte order
+type tiffReader struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+// ParseExif parses the payload of a JPEG APP1 Exif segment (starting with
+// "Exif\0\0"). Unknown or malformed fields are ignored rather than failing.
+func ParseExif(segment []byte) (*ExifData, error) {
+	if !bytes.HasPrefix(segment, exifHeader) {
+		return nil, errors.New("missing Exif header")
+	}
+	tiff := segment[len(exifHeader):]
+	if len(tiff) < 8 {
+		return nil, errors.New("truncated TIFF header")
+	}
+
+	r := &tiffReader{data: tiff}
+	switch string(tiff[:2]) {
+	case "II":
+		r.order = binary.LittleEndian
+	case "MM":
+		r.order = binary.BigEndian
+	default:
+		return nil, errors.New("invalid TIFF byte order")
+	}
+	if r.order.Uint16(tiff[2:4]) != 42 {
+		return nil, errors.New("invalid TIFF magic number")
+	}
+
+	exif := &ExifData{Orientation: 1}
+	ifd0 := int(r.order.Uint32(tiff[4:8]))
+
+	r.walkIFD(ifd0, func(tag, typ uint16, count uint32, value []byte) {
+		switch tag {
+		case tagOrientation:
+			if typ == typeShort && count >= 1 {
+				if o := int(r.order.Uint16(value)); o >= 1 && o <= 8 {
+					exif.Orientation = o
+				}
+			}
+		case tagModel:
+			exif.Model = r.ascii(typ, count, value)
+		case tagExifIFDPointer:
+			if typ == typeLong && count == 1 {
+				r.walkIFD(int(r.order.Uint32(value)), func(tag, typ uint16, count uint32, value []byte) {
+					switch tag {
+					case tagDateTimeOriginal:
+						exif.DateTimeOriginal = r.ascii(typ, count, value)
+					case tagOffsetTimeOriginal:
+						exif.OffsetTimeOriginal = r.ascii(typ, count, value)
+					}
+				})
+			}
+		}
+	})
+
+	return exif, nil
+}
+
+// walkIFD calls fn for every entry of the IFD at offset. value holds the
+// entry's data, resolved through its offset when it does not fit inline.
+func (r *tiffReader) walkIFD(offset int, fn func(tag, typ uint16, count uint32, value []byte)) {
+	if offset < 8 || offset+2 > len(r.data) {
+		return
+	}
+	entries := int(r.order.Uint16(r.data[offset:]))
+	for i := 0; i < entries; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(r.data) {
+			return
+		}
+		tag := r.order.Uint16(r.data[entry:])
+		typ := r.order.Uint16(r.data[entry+2:])
+		count := r.order.Uint32(r.data[entry+4:])
+
+		size := int64(count) * int64(typeSize(typ))
+		if size == 0 {
+			continue
+		}
+		value := r.data[entry+8 : entry+12]
+		if size > 4 {
+			start := int64(r.order.Uint32(value))
+			if start+size > int64(len(r.data)) {
+				continue
+			}
+			value = r.data[start : start+size]
+		}
+		fn(tag, typ, count, value)
+	}
+}
+
+// ascii returns an ASCII value without its NUL terminator and padding
+func (r *tiffReader) ascii(typ uint16, count uint32, value []byte) string {
+	if typ != typeASCII || int(count) > len(value) {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimRight(string(value[:count]), "\x00"))
+}
+
+// typeSize returns the size in bytes of one value of a TIFF field type
+func typeSize(typ uint16) int {
+	switch typ {
+	case 1, 2, 6, 7: // BYTE, ASCII, SBYTE, UNDEFINED
+		return 1
+	case 3, 8: // SHORT, SSHORT
+		return 2
+	case 4, 9, 11: // LONG, SLONG, FLOAT
+		return 4
+	case 5, 10, 12: // RATIONAL, SRATIONAL, DOUBLE
+		return 8
+	}
+	return 0
+}
@@ -0,0 +1,56 @@
+package imaging
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExif(t *testing.T) {
+	payload := buildExif(
+		[]ifdEntry{
+			asciiEntry(tagModel, "Pixel 8"),
+			shortEntry(tagOrientation, 6),
+		},
+		[]ifdEntry{
+			asciiEntry(tagDateTimeOriginal, "2026:02:01 15:30:45"),
+			asciiEntry(tagOffsetTimeOriginal, "+01:00"),
+		},
+	)
+
+	exif, err := ParseExif(payload)
+	require.NoError(t, err)
+	assert.Equal(t, 6, exif.Orientation)
+	assert.Equal(t, "Pixel 8", exif.Model)
+	assert.Equal(t, "2026:02:01 15:30:45", exif.DateTimeOriginal)
+	assert.Equal(t, "+01:00", exif.OffsetTimeOriginal)
+}
+
+func TestParseExif_Defaults(t *testing.T) {
+	exif, err := ParseExif(buildExif(nil, nil))
+	require.NoError(t, err)
+	assert.Equal(t, 1, exif.Orientation)
+	assert.Empty(t, exif.Model)
+}
+
+func TestParseExif_IgnoresInvalidOrientation(t *testing.T) {
+	exif, err := ParseExif(buildExif([]ifdEntry{shortEntry(tagOrientation, 42)}, nil))
+	require.NoError(t, err)
+	assert.Equal(t, 1, exif.Orientation)
+}
+
+func TestParseExif_Malformed(t *testing.T) {
+	_, err := ParseExif([]byte("not exif"))
+	assert.Error(t, err)
+
+	_, err = ParseExif([]byte("Exif\x00\x00XX"))
+	assert.Error(t, err)
+
+	// Out-of-range offsets are skipped rather than panicking
+	payload := buildExif([]ifdEntry{asciiEntry(tagModel, "Pixel 8")}, nil)
+	truncated := payload[:len(payload)-4]
+	exif, err := ParseExif(truncated)
+	require.NoError(t, err)
+	assert.Empty(t, exif.Model)
+}
@@ -0,0 +1,116 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+
+	"github.com/gen2brain/avif"
+	"github.com/gen2brain/heic"
+)
+
+// Formats of the HEIF family (ISO base media file format containers)
+const (
+	FormatHEIC = "heic"
+	FormatHEIF = "heif"
+	FormatAVIF = "avif"
+)
+
+// heifBrand describes an ftyp brand: the format it implies and whether it
+// announces an image sequence rather than a still image
+type heifBrand struct {
+	format   string
+	sequence bool
+}
+
+var heifBrands = map[string]heifBrand{
+	"avif": {FormatAVIF, false},
+	"avis": {FormatAVIF, true},
+	"heic": {FormatHEIC, false},
+	"heix": {FormatHEIC, false},
+	"heim": {FormatHEIC, false},
+	"heis": {FormatHEIC, false},
+	"hevc": {FormatHEIC, true},
+	"hevx": {FormatHEIC, true},
+	"hevm": {FormatHEIC, true},
+	"hevs": {FormatHEIC, true},
+	"mif1": {FormatHEIF, false},
+	"msf1": {FormatHEIF, true},
+}
+
+// HEIFFormat returns FormatHEIC, FormatHEIF or FormatAVIF when data starts
+// with a matching ftyp box, or "" for anything else. A specific codec brand
+// (AVIF or HEVC) wins over the generic mif1/msf1 brands.
+func HEIFFormat(data []byte) string {
+	format, _ := heifType(data)
+	return format
+}
+
+// IsHEIFSequence reports whether a HEIF/AVIF file is an image sequence
+// (animation or burst) according to its major brand
+func IsHEIFSequence(data []byte) bool {
+	_, sequence := heifType(data)
+	return sequence
+}
+
+func heifType(data []byte) (format string, sequence bool) {
+	if len(data) < 16 || string(data[4:8]) != "ftyp" {
+		return "", false
+	}
+	size := int(binary.BigEndian.Uint32(data[:4]))
+	if size < 16 || size > len(data) {
+		return "", false
+	}
+
+	major := heifBrands[string(data[8:12])]
+	format = major.format
+
+	// Compatible brands follow the major brand and minor version
+	for i := 16; i+4 <= size; i += 4 {
+		brand, ok := heifBrands[string(data[i:i+4])]
+		if ok && (format == "" || format == FormatHEIF) {
+			format = brand.format
+		}
+	}
+	return format, major.sequence
+}
+
+// DecodeHEIF decodes the primary image of a HEIC, HEIF or AVIF file. Image
+// transformations stored in the container (rotation, mirroring) are applied.
+func DecodeHEIF(data []byte) (image.Image, error) {
+	switch HEIFFormat(data) {
+	case FormatAVIF:
+		return avif.Decode(bytes.NewReader(data))
+	case FormatHEIC, FormatHEIF:
+		return heic.Decode(bytes.NewReader(data))
+	}
+	return nil, errors.New("not a HEIF or AVIF image")
+}
+
+// DecodeHEIFConfig returns the dimensions of a HEIC, HEIF or AVIF file
+func DecodeHEIFConfig(data []byte) (image.Config, error) {
+	switch HEIFFormat(data) {
+	case FormatAVIF:
+		return avif.DecodeConfig(bytes.NewReader(data))
+	case FormatHEIC, FormatHEIF:
+		return heic.DecodeConfig(bytes.NewReader(data))
+	}
+	return image.Config{}, errors.New("not a HEIF or AVIF image")
+}
+
+// ConvertHEIFToJPEG decodes a HEIC, HEIF or AVIF file and re-encodes it as
+// JPEG, the format browsers and the analyzer accept everywhere
+func ConvertHEIFToJPEG(data []byte, quality int) ([]byte, error) {
+	img, err := DecodeHEIF(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", HEIFFormat(data), err)
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, fmt.Errorf("failed to encode JPEG: %w", err)
+	}
+	return buf.Bytes(), nil
+}
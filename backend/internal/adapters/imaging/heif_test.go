@@ -0,0 +1,100 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ftyp builds an ftyp box with the given major and compatible brands
+func ftyp(major string, compatible ...string) []byte {
+	size := 16 + 4*len(compatible)
+	box := []byte{0, 0, 0, byte(size)}
+	box = append(box, "ftyp"...)
+	box = append(box, major...)
+	box = append(box, 0, 0, 0, 0)
+	for _, brand := range compatible {
+		box = append(box, brand...)
+	}
+	return box
+}
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	require.NoError(t, err)
+	return data
+}
+
+func TestHEIFFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		format   string
+		sequence bool
+	}{
+		{"iPhone HEIC", ftyp("heic", "mif1", "heic"), FormatHEIC, false},
+		{"generic HEIF", ftyp("mif1", "mif1"), FormatHEIF, false},
+		{"generic HEIF with HEVC brand", ftyp("mif1", "mif1", "heic"), FormatHEIC, false},
+		{"AVIF", ftyp("avif", "mif1", "miaf"), FormatAVIF, false},
+		{"AVIF sequence", ftyp("avis", "avif", "msf1"), FormatAVIF, true},
+		{"HEVC sequence", ftyp("hevc", "msf1"), FormatHEIC, true},
+		{"MP4 video", ftyp("isom", "iso2", "mp41"), "", false},
+		{"JPEG", []byte{0xFF, 0xD8, 0xFF, 0xE0, 0, 0x10, 'J', 'F', 'I', 'F', 0, 1, 1, 0, 0, 1}, "", false},
+		{"truncated", ftyp("heic")[:12], "", false},
+		{"box larger than data", append([]byte{0, 0, 1, 0}, ftyp("heic")[4:]...), "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.format, HEIFFormat(tt.data))
+			assert.Equal(t, tt.sequence, IsHEIFSequence(tt.data))
+		})
+	}
+}
+
+func TestHEIFFormat_Fixtures(t *testing.T) {
+	assert.Equal(t, FormatHEIC, HEIFFormat(readFixture(t, "sample.heic")))
+	assert.Equal(t, FormatAVIF, HEIFFormat(readFixture(t, "sample.avif")))
+	assert.True(t, IsHEIFSequence(readFixture(t, "animated.avifs")))
+}
+
+func TestConvertHEIFToJPEG(t *testing.T) {
+	for _, name := range []string{"sample.heic", "sample.avif"} {
+		t.Run(name, func(t *testing.T) {
+			data := readFixture(t, name)
+
+			config, err := DecodeHEIFConfig(data)
+			require.NoError(t, err)
+
+			converted, err := ConvertHEIFToJPEG(data, SanitizedJPEGQuality)
+			require.NoError(t, err)
+
+			img, err := jpeg.Decode(bytes.NewReader(converted))
+			require.NoError(t, err)
+			assert.Equal(t, image.Rect(0, 0, config.Width, config.Height), img.Bounds())
+		})
+	}
+}
+
+func TestConvertHEIFToJPEG_Corrupt(t *testing.T) {
+	data := readFixture(t, "sample.heic")
+
+	_, err := ConvertHEIFToJPEG(data[:len(data)/2], SanitizedJPEGQuality)
+	assert.Error(t, err)
+
+	_, err = ConvertHEIFToJPEG([]byte("not an image at all"), SanitizedJPEGQuality)
+	assert.Error(t, err)
+}
+
+func TestSanitize_HEIFBecomesJPEG(t *testing.T) {
+	result, err := NewSanitizer(true).Sanitize(readFixture(t, "sample.heic"))
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(result.Data, jpegSOI))
+	assert.NotContains(t, string(result.Data), "Exif")
+}
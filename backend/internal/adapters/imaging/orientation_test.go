@@ -0,0 +1,65 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// letterImage builds the 3x2 image
+//
+//	a b c
+//	d e f
+//
+// where each letter is a distinct grey level
+func letterImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	for i, letter := range "abcdef" {
+		img.Set(i%3, i/3, letterColor(letter))
+	}
+	return img
+}
+
+func letterColor(letter rune) color.RGBA {
+	v := uint8(letter-'a'+1) * 40
+	return color.RGBA{v, v, v, 255}
+}
+
+func TestApplyOrientation(t *testing.T) {
+	tests := []struct {
+		orientation int
+		expected    []string // rows of the upright image
+	}{
+		{1, []string{"abc", "def"}},
+		{2, []string{"cba", "fed"}},
+		{3, []string{"fed", "cba"}},
+		{4, []string{"def", "abc"}},
+		{5, []string{"ad", "be", "cf"}},
+		{6, []string{"da", "eb", "fc"}},
+		{7, []string{"fc", "eb", "da"}},
+		{8, []string{"cf", "be", "ad"}},
+	}
+
+	for _, tt := range tests {
+		result := ApplyOrientation(letterImage(), tt.orientation)
+
+		bounds := result.Bounds()
+		assert.Equal(t, len(tt.expected[0]), bounds.Dx(), "width for orientation %d", tt.orientation)
+		assert.Equal(t, len(tt.expected), bounds.Dy(), "height for orientation %d", tt.orientation)
+
+		for y, row := range tt.expected {
+			for x, letter := range row {
+				assert.Equal(t, letterColor(letter), color.RGBAModel.Convert(result.At(x, y)),
+					"orientation %d pixel (%d,%d)", tt.orientation, x, y)
+			}
+		}
+	}
+}
+
+func TestApplyOrientation_InvalidIsUnchanged(t *testing.T) {
+	img := letterImage()
+	assert.Same(t, img, ApplyOrientation(img, 0))
+	assert.Same(t, img, ApplyOrientation(img, 9))
+}
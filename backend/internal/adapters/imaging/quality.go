@@ -0,0 +1,82 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// qualitySampleSize is the longest side photos are scaled to before their
+// quality is measured. Measuring at a fixed size keeps the blur score
+// independent of the camera's resolution, and keeps the check cheap.
+const qualitySampleSize = 512
+
+// QualityMetrics describes how readable a photo is
+type QualityMetrics struct {
+	// Brightness is the mean luma (0-255)
+	Brightness float64
+
+	// Contrast is the standard deviation of the luma
+	Contrast float64
+
+	// Sharpness is the variance of the Laplacian of the luma; blurry and
+	// featureless photos score low
+	Sharpness float64
+}
+
+// MeasureQuality computes brightness, contrast and sharpness of img
+func MeasureQuality(img image.Image) QualityMetrics {
+	luma := grayscale(ResizeToFit(img, qualitySampleSize))
+	bounds := luma.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	var sum, sumSquares float64
+	for _, v := range luma.Pix {
+		sum += float64(v)
+		sumSquares += float64(v) * float64(v)
+	}
+	n := float64(len(luma.Pix))
+	mean := sum / n
+
+	return QualityMetrics{
+		Brightness: mean,
+		Contrast:   math.Sqrt(max(sumSquares/n-mean*mean, 0)),
+		Sharpness:  laplacianVariance(luma, width, height),
+	}
+}
+
+// grayscale converts img to an 8-bit luma image at the origin
+func grayscale(img image.Image) *image.Gray {
+	bounds := img.Bounds()
+	gray := image.NewGray(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			gray.SetGray(x, y, color.GrayModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.Gray))
+		}
+	}
+	return gray
+}
+
+// laplacianVariance applies the 4-neighbour Laplacian kernel to the interior
+// pixels and returns the variance of the response
+func laplacianVariance(luma *image.Gray, width, height int) float64 {
+	if width < 3 || height < 3 {
+		return 0
+	}
+
+	var sum, sumSquares float64
+	for y := 1; y < height-1; y++ {
+		row := y * luma.Stride
+		for x := 1; x < width-1; x++ {
+			i := row + x
+			response := float64(luma.Pix[i-1]) + float64(luma.Pix[i+1]) +
+				float64(luma.Pix[i-luma.Stride]) + float64(luma.Pix[i+luma.Stride]) -
+				4*float64(luma.Pix[i])
+			sum += response
+			sumSquares += response * response
+		}
+	}
+	n := float64((width - 2) * (height - 2))
+	mean := sum / n
+	return max(sumSquares/n-mean*mean, 0)
+}
@@ -0,0 +1,50 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMeasureQuality_Uniform(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 100, 80))
+	for i := range img.Pix {
+		img.Pix[i] = 40
+	}
+
+	metrics := MeasureQuality(img)
+
+	assert.InDelta(t, 40, metrics.Brightness, 0.5)
+	assert.InDelta(t, 0, metrics.Contrast, 0.5)
+	assert.InDelta(t, 0, metrics.Sharpness, 0.5)
+}
+
+func TestMeasureQuality_SharpEdgesScoreHigherThanBlur(t *testing.T) {
+	sharp := image.NewGray(image.Rect(0, 0, 256, 256))
+	for y := 0; y < 256; y++ {
+		for x := 0; x < 256; x++ {
+			if (x/8+y/8)%2 == 0 {
+				sharp.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	blurred := scale(scale(sharp, 16, 16), 256, 256)
+
+	sharpMetrics := MeasureQuality(sharp)
+	blurredMetrics := MeasureQuality(blurred)
+
+	assert.InDelta(t, 127.5, sharpMetrics.Brightness, 1)
+	assert.InDelta(t, 127.5, sharpMetrics.Contrast, 1)
+	assert.Greater(t, sharpMetrics.Sharpness, 100*blurredMetrics.Sharpness)
+}
+
+func TestMeasureQuality_LargeImagesAreSampled(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4000, 3000))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+
+	assert.InDelta(t, 255, MeasureQuality(img).Brightness, 0.5)
+}
@@ -0,0 +1,94 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+
+	"rechtebank/backend/internal/core/domain"
+
+	"github.com/gen2brain/webp"
+	"golang.org/x/image/draw"
+)
+
+// ResizeToFit scales img down so that neither dimension exceeds
+// maxDimension, keeping its aspect ratio. Images within the limit are
+// returned unchanged.
+func ResizeToFit(img image.Image, maxDimension int) image.Image {
+	bounds := img.Bounds()
+	width := bounds.Dx()
+	height := bounds.Dy()
+
+	// Check if resize is needed
+	if width <= maxDimension && height <= maxDimension {
+		return img
+	}
+
+	// Calculate new dimensions maintaining aspect ratio
+	if width > height {
+		// Width is the limiting dimension
+		return scale(img, maxDimension, (height*maxDimension)/width)
+	}
+	// Height is the limiting dimension
+	return scale(img, (width*maxDimension)/height, maxDimension)
+}
+
+// ResizeToWidth scales img down to the given width, keeping its aspect ratio.
+// Images that are already at most width pixels wide are returned unchanged.
+func ResizeToWidth(img image.Image, width int) image.Image {
+	bounds := img.Bounds()
+	if width <= 0 || bounds.Dx() <= width {
+		return img
+	}
+	return scale(img, width, bounds.Dy()*width/bounds.Dx())
+}
+
+// scale draws img into a new RGBA image of the given size
+func scale(img image.Image, width, height int) image.Image {
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+
+	resized := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	// Use high-quality scaling
+	draw.BiLinear.Scale(resized, resized.Bounds(), img, img.Bounds(), draw.Over, nil)
+
+	return resized
+}
+
+// Encode encodes img in a stored photo format. quality applies to JPEG and
+// WebP; PNG is always lossless.
+func Encode(img image.Image, format domain.PhotoFormat, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case domain.PhotoFormatJPEG:
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+	case domain.PhotoFormatPNG:
+		err = png.Encode(&buf, img)
+	case domain.PhotoFormatWebP:
+		err = webp.Encode(&buf, img, webp.Options{Quality: quality, Method: webp.DefaultMethod})
+	default:
+		return nil, fmt.Errorf("cannot encode %s", format.MIMEType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", format.MIMEType, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Resized decodes a JPEG, PNG or WebP photo, scales it down to the given
+// width and encodes the result in format
+func Resized(data []byte, width int, format domain.PhotoFormat, quality int) ([]byte, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	return Encode(ResizeToWidth(img, width), format, quality)
+}
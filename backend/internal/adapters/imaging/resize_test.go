@@ -0,0 +1,80 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"rechtebank/backend/internal/core/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResizeToWidth(t *testing.T) {
+	img := splitImage(800, 600)
+
+	resized := ResizeToWidth(img, 400)
+	assert.Equal(t, image.Rect(0, 0, 400, 300), resized.Bounds())
+}
+
+func TestResizeToWidth_NoUpscale(t *testing.T) {
+	img := splitImage(200, 100)
+
+	assert.Same(t, img, ResizeToWidth(img, 400))
+	assert.Same(t, img, ResizeToWidth(img, 200))
+}
+
+func TestResizeToWidth_ExtremeAspectRatio(t *testing.T) {
+	resized := ResizeToWidth(splitImage(1000, 2), 10)
+	assert.Equal(t, image.Rect(0, 0, 10, 1), resized.Bounds())
+}
+
+func TestResized(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, splitImage(800, 600)))
+
+	resized, err := Resized(buf.Bytes(), 400, domain.PhotoFormatJPEG, 80)
+	require.NoError(t, err)
+
+	img, err := jpeg.Decode(bytes.NewReader(resized))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 400, 300), img.Bounds())
+}
+
+func TestResized_Corrupt(t *testing.T) {
+	_, err := Resized([]byte("not an image"), 400, domain.PhotoFormatJPEG, 80)
+	assert.Error(t, err)
+}
+
+func TestResizeToFit(t *testing.T) {
+	assert.Equal(t, image.Rect(0, 0, 100, 50), ResizeToFit(splitImage(400, 200), 100).Bounds())
+	assert.Equal(t, image.Rect(0, 0, 50, 100), ResizeToFit(splitImage(200, 400), 100).Bounds())
+
+	small := splitImage(80, 40)
+	assert.Same(t, small, ResizeToFit(small, 100))
+}
+
+func TestEncode(t *testing.T) {
+	img := splitImage(32, 16)
+
+	for _, format := range domain.PhotoFormats {
+		t.Run(format.MIMEType, func(t *testing.T) {
+			data, err := Encode(img, format, 80)
+			require.NoError(t, err)
+
+			detected, ok := domain.DetectPhotoFormat(data)
+			require.True(t, ok)
+			assert.Equal(t, format, detected)
+
+			decoded, _, err := image.Decode(bytes.NewReader(data))
+			require.NoError(t, err)
+			assert.Equal(t, img.Bounds(), decoded.Bounds())
+		})
+	}
+
+	_, err := Encode(img, domain.PhotoFormat{MIMEType: "image/gif"}, 80)
+	assert.Error(t, err)
+}
@@ -0,0 +1,256 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/jpeg"
+	_ "image/png"
+	"time"
+
+	"rechtebank/backend/internal/core/domain"
+
+	_ "golang.org/x/image/webp"
+)
+
+// SanitizedJPEGQuality is the quality used when a JPEG has to be re-encoded to
+// apply its orientation, or a HEIF/AVIF photo is converted. It stays high
+// because the analyzer compresses again.
+const SanitizedJPEGQuality = 90
+
+// exifTimeLayout is the layout of EXIF date/time fields
+const exifTimeLayout = "2006:01:02 15:04:05"
+
+var (
+	jpegSOI      = []byte{0xFF, 0xD8}
+	pngSignature = []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A}
+)
+
+// Sanitizer removes metadata (EXIF, GPS, XMP, comments) from uploaded photos
+// and applies the EXIF orientation to the pixels, so stored and shared images
+// display upright everywhere and reveal nothing about where they were taken.
+type Sanitizer struct {
+	recordCaptureMetadata bool
+}
+
+// NewSanitizer creates a Sanitizer. When recordCaptureMetadata is set, the
+// camera model and capture time are kept in the result.
+func NewSanitizer(recordCaptureMetadata bool) *Sanitizer {
+	return &Sanitizer{recordCaptureMetadata: recordCaptureMetadata}
+}
+
+// Sanitize returns the photo without metadata. JPEG, PNG and WebP keep their
+// format; HEIC, HEIF and AVIF are converted to JPEG, which drops all metadata.
+func (s *Sanitizer) Sanitize(imageData []byte) (*domain.SanitizedPhoto, error) {
+	switch {
+	case HEIFFormat(imageData) != "":
+		data, err := ConvertHEIFToJPEG(imageData, SanitizedJPEGQuality)
+		if err != nil {
+			return nil, err
+		}
+		return &domain.SanitizedPhoto{Data: data}, nil
+	case bytes.HasPrefix(imageData, jpegSOI):
+		return s.sanitizeJPEG(imageData)
+	case bytes.HasPrefix(imageData, pngSignature):
+		data, err := stripPNG(imageData)
+		if err != nil {
+			return nil, err
+		}
+		return &domain.SanitizedPhoto{Data: data}, nil
+	case len(imageData) >= 12 && string(imageData[:4]) == "RIFF" && string(imageData[8:12]) == "WEBP":
+		data, err := stripWebP(imageData)
+		if err != nil {
+			return nil, err
+		}
+		return &domain.SanitizedPhoto{Data: data}, nil
+	}
+	return nil, errors.New("unsupported image format")
+}
+
+func (s *Sanitizer) sanitizeJPEG(imageData []byte) (*domain.SanitizedPhoto, error) {
+	stripped, exif, err := stripJPEG(imageData)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &domain.SanitizedPhoto{Data: stripped}
+	if exif == nil {
+		return result, nil
+	}
+
+	if s.recordCaptureMetadata {
+		result.Capture = captureMetadata(exif)
+	}
+
+	// Rotating requires a re-encode, which also drops any remaining metadata
+	if exif.Orientation > 1 {
+		img, err := jpeg.Decode(bytes.NewReader(imageData))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode JPEG: %w", err)
+		}
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, ApplyOrientation(img, exif.Orientation), &jpeg.Options{Quality: SanitizedJPEGQuality}); err != nil {
+			return nil, fmt.Errorf("failed to encode JPEG: %w", err)
+		}
+		result.Data = buf.Bytes()
+	}
+
+	return result, nil
+}
+
+// captureMetadata extracts the non-identifying fields that may be recorded
+func captureMetadata(exif *ExifData) *domain.CaptureMetadata {
+	capture := &domain.CaptureMetadata{CameraModel: exif.Model}
+
+	if exif.DateTimeOriginal != "" {
+		if exif.OffsetTimeOriginal != "" {
+			if t, err := time.Parse(exifTimeLayout+"-07:00", exif.DateTimeOriginal+exif.OffsetTimeOriginal); err == nil {
+				capture.CapturedAt = t.Format(time.RFC3339)
+			}
+		}
+		if capture.CapturedAt == "" {
+			// Without an offset the capture time is the camera's local time
+			if t, err := time.Parse(exifTimeLayout, exif.DateTimeOriginal); err == nil {
+				capture.CapturedAt = t.Format("2006-01-02T15:04:05")
+			}
+		}
+	}
+
+	if capture.CameraModel == "" && capture.CapturedAt == "" {
+		return nil
+	}
+	return capture
+}
+
+// stripJPEG removes APP and comment segments that can carry metadata, keeping
+// only JFIF, ICC colour profiles and the Adobe colour transform marker. The
+// parsed EXIF data is returned when present.
+func stripJPEG(data []byte) ([]byte, *ExifData, error) {
+	var out bytes.Buffer
+	out.Write(jpegSOI)
+
+	var exif *ExifData
+	i := len(jpegSOI)
+	for {
+		if i+4 > len(data) || data[i] != 0xFF {
+			return nil, nil, errors.New("malformed JPEG segment")
+		}
+		marker := data[i+1]
+
+		// Start of scan: the rest is entropy-coded image data
+		if marker == 0xDA {
+			out.Write(data[i:])
+			return out.Bytes(), exif, nil
+		}
+
+		length := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		end := i + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, nil, errors.New("truncated JPEG segment")
+		}
+		payload := data[i+4 : end]
+
+		if marker == 0xE1 && bytes.HasPrefix(payload, exifHeader) && exif == nil {
+			// A malformed EXIF block is dropped like any other
+			exif, _ = ParseExif(payload)
+		}
+		if keepJPEGSegment(marker, payload) {
+			out.Write(data[i:end])
+		}
+		i = end
+	}
+}
+
+func keepJPEGSegment(marker byte, payload []byte) bool {
+	switch {
+	case marker == 0xE0: // APP0
+		return bytes.HasPrefix(payload, []byte("JFIF\x00"))
+	case marker == 0xE2: // APP2
+		return bytes.HasPrefix(payload, []byte("ICC_PROFILE\x00"))
+	case marker == 0xEE: // APP14
+		return bytes.HasPrefix(payload, []byte("Adobe"))
+	case marker >= 0xE1 && marker <= 0xEF, marker == 0xFE: // other APPn, COM
+		return false
+	}
+	return true
+}
+
+// pngMetadataChunks are the ancillary PNG chunks that can carry metadata
+var pngMetadataChunks = map[string]bool{
+	"tEXt": true,
+	"iTXt": true,
+	"zTXt": true,
+	"eXIf": true,
+	"tIME": true,
+}
+
+// stripPNG removes text, EXIF and timestamp chunks from a PNG
+func stripPNG(data []byte) ([]byte, error) {
+	var out bytes.Buffer
+	out.Write(pngSignature)
+
+	i := len(pngSignature)
+	for i < len(data) {
+		if i+12 > len(data) {
+			return nil, errors.New("truncated PNG chunk")
+		}
+		length := int(binary.BigEndian.Uint32(data[i : i+4]))
+		end := i + 12 + length // length + type + data + CRC
+		if length < 0 || end > len(data) {
+			return nil, errors.New("truncated PNG chunk")
+		}
+		if !pngMetadataChunks[string(data[i+4:i+8])] {
+			out.Write(data[i:end])
+		}
+		i = end
+	}
+	return out.Bytes(), nil
+}
+
+// VP8X flags announcing EXIF and XMP chunks
+const (
+	webpFlagEXIF = 0x08
+	webpFlagXMP  = 0x04
+)
+
+// stripWebP removes EXIF and XMP chunks from a WebP and clears their VP8X flags
+func stripWebP(data []byte) ([]byte, error) {
+	var out bytes.Buffer
+	out.Write(data[:12]) // RIFF header; size is fixed up below
+
+	i := 12
+	for i < len(data) {
+		if i+8 > len(data) {
+			return nil, errors.New("truncated WebP chunk")
+		}
+		chunkType := string(data[i : i+4])
+		length := int(binary.LittleEndian.Uint32(data[i+4 : i+8]))
+		end := i + 8 + length + length%2 // chunks are padded to an even size
+		if end == len(data)+1 {
+			// Tolerate a missing pad byte after the final chunk
+			end = len(data)
+		}
+		if length < 0 || end > len(data) {
+			return nil, errors.New("truncated WebP chunk")
+		}
+
+		switch chunkType {
+		case "EXIF", "XMP ":
+			// dropped
+		case "VP8X":
+			chunk := append([]byte{}, data[i:end]...)
+			if len(chunk) > 8 {
+				chunk[8] &^= webpFlagEXIF | webpFlagXMP
+			}
+			out.Write(chunk)
+		default:
+			out.Write(data[i:end])
+		}
+		i = end
+	}
+
+	result := out.Bytes()
+	binary.LittleEndian.PutUint32(result[4:8], uint32(len(result)-8))
+	return result, nil
+}
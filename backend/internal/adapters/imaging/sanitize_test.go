@@ -0,0 +1,193 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"rechtebank/backend/internal/core/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gpsMarker is embedded in fixtures to detect leaked location data
+const gpsMarker = "52.3676N4.9041E"
+
+// exifWithGPS builds an EXIF payload with camera, time, orientation and a GPS-like value
+func exifWithGPS(orientation uint16) []byte {
+	return buildExif(
+		[]ifdEntry{
+			asciiEntry(tagModel, "Pixel 8"),
+			shortEntry(tagOrientation, orientation),
+			asciiEntry(0x8825, gpsMarker), // stands in for the GPS IFD
+		},
+		[]ifdEntry{
+			asciiEntry(tagDateTimeOriginal, "2026:02:01 15:30:45"),
+			asciiEntry(tagOffsetTimeOriginal, "+01:00"),
+		},
+	)
+}
+
+func TestSanitize_JPEGStripsMetadata(t *testing.T) {
+	original := withSegment(encodeJPEG(splitImage(40, 20)), 0xE1, exifWithGPS(1))
+	original = withSegment(original, 0xFE, []byte("taken at home"))
+	original = withSegment(original, 0xE1, []byte("http://ns.adobe.com/xap/1.0/\x00<x:xmpmeta/>"))
+	require.Contains(t, string(original), gpsMarker)
+
+	result, err := NewSanitizer(false).Sanitize(original)
+	require.NoError(t, err)
+
+	assert.NotContains(t, string(result.Data), gpsMarker)
+	assert.NotContains(t, string(result.Data), "Exif")
+	assert.NotContains(t, string(result.Data), "taken at home")
+	assert.NotContains(t, string(result.Data), "xmpmeta")
+	assert.Nil(t, result.Capture)
+
+	// Orientation 1 is stripped losslessly: the scan data is untouched
+	_, scanOriginal, _ := bytes.Cut(original, []byte{0xFF, 0xDA})
+	_, scanResult, _ := bytes.Cut(result.Data, []byte{0xFF, 0xDA})
+	assert.Equal(t, scanOriginal, scanResult)
+
+	img, err := jpeg.Decode(bytes.NewReader(result.Data))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 40, 20), img.Bounds())
+}
+
+func TestSanitize_JPEGKeepsColourProfile(t *testing.T) {
+	icc := []byte("ICC_PROFILE\x00\x01\x01fake-profile")
+	original := withSegment(encodeJPEG(splitImage(16, 16)), 0xE2, icc)
+
+	result, err := NewSanitizer(false).Sanitize(original)
+	require.NoError(t, err)
+	assert.Contains(t, string(result.Data), "fake-profile")
+}
+
+func TestSanitize_JPEGAppliesOrientation(t *testing.T) {
+	// Stored sideways: the camera says rotate 90 degrees clockwise to display
+	original := withSegment(encodeJPEG(splitImage(40, 20)), 0xE1, exifWithGPS(6))
+
+	result, err := NewSanitizer(false).Sanitize(original)
+	require.NoError(t, err)
+	assert.NotContains(t, string(result.Data), gpsMarker)
+
+	img, err := jpeg.Decode(bytes.NewReader(result.Data))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 20, 40), img.Bounds())
+
+	// The left (red) half ends up on top
+	top := color.RGBAModel.Convert(img.At(10, 5)).(color.RGBA)
+	bottom := color.RGBAModel.Convert(img.At(10, 35)).(color.RGBA)
+	assert.Greater(t, top.R, top.B)
+	assert.Greater(t, bottom.B, bottom.R)
+}
+
+func TestSanitize_RecordsCaptureMetadata(t *testing.T) {
+	original := withSegment(encodeJPEG(splitImage(16, 16)), 0xE1, exifWithGPS(1))
+
+	result, err := NewSanitizer(true).Sanitize(original)
+	require.NoError(t, err)
+	require.NotNil(t, result.Capture)
+	assert.Equal(t, &domain.CaptureMetadata{
+		CameraModel: "Pixel 8",
+		CapturedAt:  "2026-02-01T15:30:45+01:00",
+	}, result.Capture)
+}
+
+func TestSanitize_CaptureTimeWithoutOffset(t *testing.T) {
+	payload := buildExif(nil, []ifdEntry{asciiEntry(tagDateTimeOriginal, "2026:02:01 15:30:45")})
+	original := withSegment(encodeJPEG(splitImage(16, 16)), 0xE1, payload)
+
+	result, err := NewSanitizer(true).Sanitize(original)
+	require.NoError(t, err)
+	require.NotNil(t, result.Capture)
+	assert.Equal(t, "2026-02-01T15:30:45", result.Capture.CapturedAt)
+	assert.Empty(t, result.Capture.CameraModel)
+}
+
+func TestSanitize_JPEGWithoutExif(t *testing.T) {
+	original := encodeJPEG(splitImage(16, 16))
+
+	result, err := NewSanitizer(true).Sanitize(original)
+	require.NoError(t, err)
+	assert.Equal(t, original, result.Data)
+	assert.Nil(t, result.Capture)
+}
+
+// pngChunk encodes a PNG chunk with a valid CRC
+func pngChunk(chunkType string, payload []byte) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(payload)))
+	chunk = append(chunk, chunkType...)
+	chunk = append(chunk, payload...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+func TestSanitize_PNGStripsTextAndExif(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, splitImage(16, 16)))
+	encoded := buf.Bytes()
+
+	// Insert metadata chunks after IHDR (signature + 25 byte IHDR chunk)
+	const ihdrEnd = 8 + 25
+	original := append([]byte{}, encoded[:ihdrEnd]...)
+	original = append(original, pngChunk("tEXt", []byte("Location\x00"+gpsMarker))...)
+	original = append(original, pngChunk("eXIf", exifWithGPS(1)[6:])...)
+	original = append(original, encoded[ihdrEnd:]...)
+
+	result, err := NewSanitizer(false).Sanitize(original)
+	require.NoError(t, err)
+	assert.NotContains(t, string(result.Data), gpsMarker)
+
+	_, err = png.Decode(bytes.NewReader(result.Data))
+	assert.NoError(t, err)
+}
+
+func TestSanitize_WebPStripsExif(t *testing.T) {
+	// 1x1 lossless WebP; its VP8L chunk is wrapped in an extended (VP8X) file
+	simple, _ := base64.StdEncoding.DecodeString("UklGRhoAAABXRUJQVlA4TA0AAAAvAAAAEAcQERGIiP4HAA==")
+	vp8l := simple[12:]
+
+	exif := exifWithGPS(1)[6:]
+	var body []byte
+	body = append(body, "WEBP"...)
+	body = append(body, "VP8X"...)
+	body = binary.LittleEndian.AppendUint32(body, 10)
+	body = append(body, webpFlagEXIF, 0, 0, 0, 0, 0, 0, 0, 0, 0) // 1x1 canvas
+	body = append(body, vp8l...)
+	body = append(body, "EXIF"...)
+	body = binary.LittleEndian.AppendUint32(body, uint32(len(exif)))
+	body = append(body, exif...)
+	if len(exif)%2 == 1 {
+		body = append(body, 0)
+	}
+	original := append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(len(body)))...)
+	original = append(original, body...)
+
+	result, err := NewSanitizer(false).Sanitize(original)
+	require.NoError(t, err)
+	assert.NotContains(t, string(result.Data), gpsMarker)
+	assert.Equal(t, uint32(len(result.Data)-8), binary.LittleEndian.Uint32(result.Data[4:8]))
+	assert.Zero(t, result.Data[20]&webpFlagEXIF)
+
+	_, format, err := image.Decode(bytes.NewReader(result.Data))
+	require.NoError(t, err)
+	assert.Equal(t, "webp", format)
+}
+
+func TestSanitize_UnsupportedFormat(t *testing.T) {
+	_, err := NewSanitizer(false).Sanitize([]byte("GIF89a......"))
+	assert.Error(t, err)
+}
+
+func TestSanitize_TruncatedJPEG(t *testing.T) {
+	original := withSegment(encodeJPEG(splitImage(16, 16)), 0xE1, exifWithGPS(1))
+
+	_, err := NewSanitizer(false).Sanitize(original[:20])
+	assert.Error(t, err)
+}
@@ -0,0 +1,115 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+)
+
+// ifdEntry is a raw TIFF IFD entry used to build EXIF fixtures
+type ifdEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+func asciiEntry(tag uint16, value string) ifdEntry {
+	return ifdEntry{tag: tag, typ: typeASCII, count: uint32(len(value) + 1), value: append([]byte(value), 0)}
+}
+
+func shortEntry(tag uint16, value uint16) ifdEntry {
+	return ifdEntry{tag: tag, typ: typeShort, count: 1, value: binary.LittleEndian.AppendUint16(nil, value)}
+}
+
+func longEntry(tag uint16, value uint32) ifdEntry {
+	return ifdEntry{tag: tag, typ: typeLong, count: 1, value: binary.LittleEndian.AppendUint32(nil, value)}
+}
+
+func ifdSize(entries []ifdEntry) int {
+	size := 2 + 12*len(entries) + 4
+	for _, e := range entries {
+		if len(e.value) > 4 {
+			size += len(e.value) + len(e.value)%2
+		}
+	}
+	return size
+}
+
+// serializeIFD writes an IFD starting at offset start, followed by its out-of-line values
+func serializeIFD(entries []ifdEntry, start int) []byte {
+	out := binary.LittleEndian.AppendUint16(nil, uint16(len(entries)))
+	dataOffset := start + 2 + 12*len(entries) + 4
+	var data []byte
+	for _, e := range entries {
+		out = binary.LittleEndian.AppendUint16(out, e.tag)
+		out = binary.LittleEndian.AppendUint16(out, e.typ)
+		out = binary.LittleEndian.AppendUint32(out, e.count)
+		if len(e.value) <= 4 {
+			inline := make([]byte, 4)
+			copy(inline, e.value)
+			out = append(out, inline...)
+			continue
+		}
+		out = binary.LittleEndian.AppendUint32(out, uint32(dataOffset+len(data)))
+		data = append(data, e.value...)
+		if len(e.value)%2 == 1 {
+			data = append(data, 0)
+		}
+	}
+	out = binary.LittleEndian.AppendUint32(out, 0) // no next IFD
+	return append(out, data...)
+}
+
+// buildExif builds an APP1 Exif payload from IFD0 entries and Exif sub-IFD entries
+func buildExif(ifd0 []ifdEntry, exifIFD []ifdEntry) []byte {
+	if len(exifIFD) > 0 {
+		// Placeholder pointer; the real offset is known once IFD0's size is
+		ifd0 = append(ifd0, longEntry(tagExifIFDPointer, 0))
+		exifStart := 8 + ifdSize(ifd0)
+		ifd0[len(ifd0)-1] = longEntry(tagExifIFDPointer, uint32(exifStart))
+	}
+
+	tiff := []byte("II")
+	tiff = binary.LittleEndian.AppendUint16(tiff, 42)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 8)
+	tiff = append(tiff, serializeIFD(ifd0, 8)...)
+	if len(exifIFD) > 0 {
+		tiff = append(tiff, serializeIFD(exifIFD, len(tiff))...)
+	}
+	return append([]byte("Exif\x00\x00"), tiff...)
+}
+
+// withSegment inserts a JPEG segment directly after the SOI marker
+func withSegment(jpegData []byte, marker byte, payload []byte) []byte {
+	segment := []byte{0xFF, marker}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	result := append([]byte{}, jpegData[:2]...)
+	result = append(result, segment...)
+	return append(result, jpegData[2:]...)
+}
+
+// splitImage returns a w x h image whose left half is red and right half is blue
+func splitImage(w, h int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if x < w/2 {
+				img.Set(x, y, color.RGBA{255, 0, 0, 255})
+			} else {
+				img.Set(x, y, color.RGBA{0, 0, 255, 255})
+			}
+		}
+	}
+	return img
+}
+
+func encodeJPEG(img image.Image) []byte {
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95})
+	return buf.Bytes()
+}
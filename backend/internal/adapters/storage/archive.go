@@ -0,0 +1,536 @@
+package storage
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"rechtebank/backend/internal/core/domain"
+)
+
+// ArchiveFormatVersion is the version of the archive layout written by
+// ExportArchive. ImportArchive rejects archives with a newer version.
+const ArchiveFormatVersion = 1
+
+// manifestName is the archive entry holding the manifest; it is always the
+// first entry, so a tar archive can be validated while it streams
+const manifestName = "manifest.json"
+
+// casesPrefix is the archive directory stored case files are written under
+const casesPrefix = "cases/"
+
+// ArchiveFormat is the container format of an archive
+type ArchiveFormat string
+
+// Supported archive formats
+const (
+	ArchiveTarGz ArchiveFormat = "tar.gz"
+	ArchiveZip   ArchiveFormat = "zip"
+)
+
+// ArchiveManifest describes the contents of an archive
+type ArchiveManifest struct {
+	FormatVersion int    `json:"formatVersion"`
+	CreatedAt     string `json:"createdAt"`
+
+	// Since and Until are the date range the export was filtered on
+	Since string `json:"since,omitempty"`
+	Until string `json:"until,omitempty"`
+
+	Cases []ArchivedCase `json:"cases"`
+}
+
+// ArchivedCase is a stored case in an archive, with the verdict metadata
+// needed to inspect an archive without unpacking it
+type ArchivedCase struct {
+	ID          string         `json:"id"`
+	RequestID   string         `json:"requestId"`
+	Timestamp   string         `json:"timestamp,omitempty"`
+	Admissible  bool           `json:"admissible"`
+	Score       int            `json:"score"`
+	VerdictType string         `json:"verdictType,omitempty"`
+	Withheld    bool           `json:"withheld,omitempty"`
+	Files       []ArchivedFile `json:"files"`
+}
+
+// ArchivedFile is a file of an archived case. Name is relative to the
+// storage root, e.g. "2026-02-01/153045_abc.jpg".
+type ArchivedFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// ExportFilter selects the cases to export by the day they were stored on;
+// zero dates are unbounded
+type ExportFilter struct {
+	Since time.Time
+	Until time.Time
+}
+
+func (f ExportFilter) includes(c StoredCase) bool {
+	return (f.Since.IsZero() || !c.Date.Before(f.Since)) && (f.Until.IsZero() || !c.Date.After(f.Until))
+}
+
+// ImportResult counts the cases an import added and skipped
+type ImportResult struct {
+	Imported   []string
+	Duplicates []string
+}
+
+// ExportArchive writes the verdicts and original photos of the selected cases
+// to w, preceded by a manifest with their checksums. Variants are left out;
+// they are regenerated on import.
+func (s *PhotoStorage) ExportArchive(w io.Writer, format ArchiveFormat, filter ExportFilter) (*ArchiveManifest, error) {
+	cases, err := s.ListCases()
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &ArchiveManifest{
+		FormatVersion: ArchiveFormatVersion,
+		CreatedAt:     time.Now().UTC().Format(time.RFC3339),
+	}
+	if !filter.Since.IsZero() {
+		manifest.Since = filter.Since.Format(dateDirLayout)
+	}
+	if !filter.Until.IsZero() {
+		manifest.Until = filter.Until.Format(dateDirLayout)
+	}
+
+	// Checksums go in the manifest, which is written first
+	for _, c := range cases {
+		if !filter.includes(c) {
+			continue
+		}
+		archived, err := s.archiveCase(c)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Cases = append(manifest.Cases, archived)
+	}
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	aw, err := newArchiveWriter(w, format)
+	if err != nil {
+		return nil, err
+	}
+	if err := aw.add(manifestName, int64(len(manifestJSON)), bytes.NewReader(manifestJSON)); err != nil {
+		return nil, err
+	}
+	for _, c := range manifest.Cases {
+		for _, file := range c.Files {
+			if err := s.addToArchive(aw, file); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := aw.close(); err != nil {
+		return nil, fmt.Errorf("failed to finish archive: %w", err)
+	}
+	return manifest, nil
+}
+
+// archiveCase describes a case and checksums its verdict and original photo
+func (s *PhotoStorage) archiveCase(c StoredCase) (ArchivedCase, error) {
+	data, err := os.ReadFile(c.JSONPath)
+	if err != nil {
+		return ArchivedCase{}, fmt.Errorf("failed to read verdict %s: %w", c.ID, err)
+	}
+	archived := ArchivedCase{ID: c.ID, RequestID: c.RequestID()}
+	if verdict, err := domain.ParseFlatJSON(data); err == nil {
+		archived.Timestamp = verdict.Timestamp
+		archived.Admissible = verdict.Admissible
+		archived.Score = verdict.Score
+		archived.VerdictType = verdict.Verdict.VerdictType
+		archived.Withheld = verdict.Moderation != nil && verdict.Moderation.Flagged
+	}
+
+	paths := []string{c.JSONPath}
+	if c.PhotoPath != "" {
+		paths = append(paths, c.PhotoPath)
+	}
+	for _, p := range paths {
+		file, err := checksumFile(p)
+		if err != nil {
+			return ArchivedCase{}, err
+		}
+		file.Name = c.ID + filepath.Ext(p)
+		archived.Files = append(archived.Files, file)
+	}
+	return archived, nil
+}
+
+func (s *PhotoStorage) addToArchive(aw archiveWriter, file ArchivedFile) error {
+	f, err := os.Open(filepath.Join(s.basePath, filepath.FromSlash(file.Name)))
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", file.Name, err)
+	}
+	defer f.Close()
+	// The size is taken from the manifest: a file that changed since it was
+	// checksummed fails the import rather than the export
+	return aw.add(casesPrefix+file.Name, file.Size, io.LimitReader(f, file.Size))
+}
+
+// checksumFile returns the size and SHA-256 of a file
+func checksumFile(p string) (ArchivedFile, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return ArchivedFile{}, fmt.Errorf("failed to open %s: %w", p, err)
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return ArchivedFile{}, fmt.Errorf("failed to read %s: %w", p, err)
+	}
+	return ArchivedFile{Size: size, SHA256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+// ImportArchive adds the cases in an archive written by ExportArchive.
+// Every file is checked against the manifest before anything is added, so a
+// corrupt archive changes nothing. Cases whose request ID is already stored
+// are skipped. Each case must fit in the quota; the import stops with
+// ErrStorageFull at the first one that does not.
+func (s *PhotoStorage) ImportArchive(archivePath string) (*ImportResult, error) {
+	staging, err := os.MkdirTemp(s.basePath, ".import-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	manifest, err := extractArchive(archivePath, staging)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.ListCases()
+	if err != nil {
+		return nil, err
+	}
+	stored := map[string]bool{}
+	for _, c := range existing {
+		stored[c.RequestID()] = true
+	}
+
+	result := &ImportResult{}
+	for _, c := range manifest.Cases {
+		if stored[c.RequestID] {
+			result.Duplicates = append(result.Duplicates, c.ID)
+			continue
+		}
+		var size int64
+		for _, file := range c.Files {
+			size += file.Size
+		}
+		if !s.admit(size, int64(len(c.Files))) {
+			return result, ErrStorageFull
+		}
+		if err := s.importCase(staging, c); err != nil {
+			return result, err
+		}
+		stored[c.RequestID] = true
+		result.Imported = append(result.Imported, c.ID)
+	}
+	return result, nil
+}
+
+// importCase moves a verified case from the staging directory into storage.
+// The verdict is moved last, so an interrupted import leaves an orphaned
+// photo rather than a verdict without one.
+func (s *PhotoStorage) importCase(staging string, c ArchivedCase) error {
+	if err := os.MkdirAll(filepath.Join(s.basePath, filepath.Dir(c.ID)), 0755); err != nil {
+		return fmt.Errorf("failed to create date directory: %w", err)
+	}
+
+	files := slices.Clone(c.Files)
+	slices.SortStableFunc(files, func(a, b ArchivedFile) int {
+		return boolToInt(strings.HasSuffix(a.Name, ".json")) - boolToInt(strings.HasSuffix(b.Name, ".json"))
+	})
+	for _, file := range files {
+		target := filepath.Join(s.basePath, filepath.FromSlash(file.Name))
+		if err := os.Rename(filepath.Join(staging, filepath.FromSlash(file.Name)), target); err != nil {
+			return fmt.Errorf("failed to import %s: %w", file.Name, err)
+		}
+		s.addUsage(file.Size, 1)
+		if !strings.HasSuffix(file.Name, ".json") {
+			if data, err := os.ReadFile(target); err == nil {
+				s.generateVariants(strings.TrimSuffix(target, filepath.Ext(target)), data)
+			}
+		}
+	}
+	return nil
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// extractArchive validates the manifest and unpacks every listed file into
+// dir, checking its size and checksum
+func extractArchive(archivePath, dir string) (*ArchiveManifest, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer f.Close()
+
+	ar, err := newArchiveReader(f)
+	if err != nil {
+		return nil, err
+	}
+
+	name, r, err := ar.next()
+	if err != nil || name != manifestName {
+		return nil, errors.New("invalid archive: manifest.json must be the first entry")
+	}
+	var manifest ArchiveManifest
+	if err := json.NewDecoder(r).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("invalid archive manifest: %w", err)
+	}
+	if manifest.FormatVersion < 1 || manifest.FormatVersion > ArchiveFormatVersion {
+		return nil, fmt.Errorf("unsupported archive format version %d", manifest.FormatVersion)
+	}
+
+	expected := map[string]ArchivedFile{}
+	for _, c := range manifest.Cases {
+		if err := validateArchivedCase(c); err != nil {
+			return nil, err
+		}
+		for _, file := range c.Files {
+			expected[casesPrefix+file.Name] = file
+		}
+	}
+
+	for {
+		name, r, err := ar.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		file, ok := expected[name]
+		if !ok {
+			return nil, fmt.Errorf("invalid archive: %s is not in the manifest", name)
+		}
+		delete(expected, name)
+		if err := extractFile(r, filepath.Join(dir, filepath.FromSlash(file.Name)), file); err != nil {
+			return nil, err
+		}
+	}
+
+	for name := range expected {
+		return nil, fmt.Errorf("invalid archive: %s is missing", name)
+	}
+	return &manifest, nil
+}
+
+// validateArchivedCase rejects manifest entries that would write outside the
+// case's own files
+func validateArchivedCase(c ArchivedCase) error {
+	dateDir, name, ok := strings.Cut(c.ID, "/")
+	if _, err := time.Parse(dateDirLayout, dateDir); !ok || err != nil || name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("invalid archive: bad case ID %q", c.ID)
+	}
+	if c.RequestID == "" || !strings.HasSuffix(name, "_"+c.RequestID) {
+		return fmt.Errorf("invalid archive: case %s has request ID %q", c.ID, c.RequestID)
+	}
+
+	hasVerdict := false
+	for _, file := range c.Files {
+		ext := path.Ext(file.Name)
+		if file.Name != c.ID+ext || !isCaseExtension(ext) {
+			return fmt.Errorf("invalid archive: bad file %q for case %s", file.Name, c.ID)
+		}
+		hasVerdict = hasVerdict || ext == ".json"
+	}
+	if !hasVerdict {
+		return fmt.Errorf("invalid archive: case %s has no verdict", c.ID)
+	}
+	return nil
+}
+
+func isCaseExtension(ext string) bool {
+	return ext == ".json" || slices.ContainsFunc(domain.PhotoFormats, func(f domain.PhotoFormat) bool { return f.Extension == ext })
+}
+
+// extractFile writes an archive entry to target and checks it against the
+// manifest
+func extractFile(r io.Reader, target string, file ArchivedFile) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to extract %s: %w", file.Name, err)
+	}
+	defer out.Close()
+
+	hash := sha256.New()
+	// Read one byte past the expected size to detect entries that are too long
+	size, err := io.Copy(io.MultiWriter(out, hash), io.LimitReader(r, file.Size+1))
+	if err != nil {
+		return fmt.Errorf("failed to extract %s: %w", file.Name, err)
+	}
+	if size != file.Size || hex.EncodeToString(hash.Sum(nil)) != file.SHA256 {
+		return fmt.Errorf("invalid archive: checksum mismatch for %s", file.Name)
+	}
+	return out.Close()
+}
+
+// archiveWriter adds files to a tar.gz or zip archive
+type archiveWriter interface {
+	add(name string, size int64, r io.Reader) error
+	close() error
+}
+
+func newArchiveWriter(w io.Writer, format ArchiveFormat) (archiveWriter, error) {
+	switch format {
+	case ArchiveTarGz:
+		gz := gzip.NewWriter(w)
+		return &tarWriter{gz: gz, tw: tar.NewWriter(gz)}, nil
+	case ArchiveZip:
+		return &zipWriter{zw: zip.NewWriter(w)}, nil
+	}
+	return nil, fmt.Errorf("unsupported archive format %q", format)
+}
+
+type tarWriter struct {
+	gz *gzip.Writer
+	tw *tar.Writer
+}
+
+func (w *tarWriter) add(name string, size int64, r io.Reader) error {
+	header := &tar.Header{Name: name, Mode: 0644, Size: size, ModTime: time.Now(), Typeflag: tar.TypeReg}
+	if err := w.tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if _, err := io.Copy(w.tw, r); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+func (w *tarWriter) close() error {
+	if err := w.tw.Close(); err != nil {
+		return err
+	}
+	return w.gz.Close()
+}
+
+type zipWriter struct {
+	zw *zip.Writer
+}
+
+func (w *zipWriter) add(name string, size int64, r io.Reader) error {
+	entry, err := w.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if _, err := io.Copy(entry, r); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+func (w *zipWriter) close() error {
+	return w.zw.Close()
+}
+
+// archiveReader iterates over the files in a tar.gz or zip archive
+type archiveReader interface {
+	// next returns the next file, or io.EOF after the last one
+	next() (string, io.Reader, error)
+}
+
+// newArchiveReader detects the archive format from its first bytes
+func newArchiveReader(f *os.File) (archiveReader, error) {
+	header, err := readHeader(f.Name(), 4)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	switch {
+	case bytes.HasPrefix(header, []byte{0x1F, 0x8B}):
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		return &tarReader{tr: tar.NewReader(gz)}, nil
+	case bytes.HasPrefix(header, []byte("PK\x03\x04")):
+		info, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		zr, err := zip.NewReader(f, info.Size())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		return &zipReader{files: zr.File}, nil
+	}
+	return nil, errors.New("unsupported archive: expected tar.gz or zip")
+}
+
+type tarReader struct {
+	tr *tar.Reader
+}
+
+func (r *tarReader) next() (string, io.Reader, error) {
+	for {
+		header, err := r.tr.Next()
+		if err != nil {
+			return "", nil, err
+		}
+		if header.Typeflag == tar.TypeReg {
+			return header.Name, r.tr, nil
+		}
+	}
+}
+
+type zipReader struct {
+	files   []*zip.File
+	current io.ReadCloser
+}
+
+func (r *zipReader) next() (string, io.Reader, error) {
+	if r.current != nil {
+		r.current.Close()
+		r.current = nil
+	}
+	for len(r.files) > 0 {
+		file := r.files[0]
+		r.files = r.files[1:]
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return "", nil, err
+		}
+		r.current = rc
+		return file.Name, rc, nil
+	}
+	return "", nil, io.EOF
+}
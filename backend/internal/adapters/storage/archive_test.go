@@ -0,0 +1,207 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newArchiveSource(t *testing.T) *PhotoStorage {
+	t.Helper()
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string][]byte{
+		"2026-02-01/153045_req-1.json":     []byte(`{"admissible":true,"score":8,"verdictType":"vrijspraak","requestId":"req-1","timestamp":"2026-02-01T15:30:45Z"}`),
+		"2026-02-01/153045_req-1.jpg":      jpegData,
+		"2026-02-01/153045_req-1.w400.jpg": jpegData,
+		"2026-02-01/160000_req-2.json":     []byte(`{"admissible":true,"score":3,"verdictType":"schuldig","moderation":{"flagged":true}}`),
+		"2026-02-02/090000_req-3.json":     []byte(`{"admissible":false,"score":0,"verdictType":"niet-ontvankelijk"}`),
+		"2026-02-02/090000_req-3.png":      pngData,
+		"2026-02-03/090000_req-4.json":     []byte(`{"admissible":true,"score":5}`),
+		"2026-02-03/090000_req-4.webp":     webpData,
+	})
+	s, err := NewPhotoStorage(dir)
+	require.NoError(t, err)
+	return s
+}
+
+// writeArchive exports from s to a file in a new directory
+func writeArchive(t *testing.T, s *PhotoStorage, format ArchiveFormat, filter ExportFilter) (string, *ArchiveManifest) {
+	t.Helper()
+	var buf bytes.Buffer
+	manifest, err := s.ExportArchive(&buf, format, filter)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "export."+string(format))
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0644))
+	return path, manifest
+}
+
+// craftArchive writes an archive with the given manifest and entries
+func craftArchive(t *testing.T, manifest ArchiveManifest, entries map[string][]byte) string {
+	t.Helper()
+	var buf bytes.Buffer
+	aw, err := newArchiveWriter(&buf, ArchiveTarGz)
+	require.NoError(t, err)
+	data, err := json.Marshal(manifest)
+	require.NoError(t, err)
+	require.NoError(t, aw.add(manifestName, int64(len(data)), bytes.NewReader(data)))
+	for name, data := range entries {
+		require.NoError(t, aw.add(name, int64(len(data)), bytes.NewReader(data)))
+	}
+	require.NoError(t, aw.close())
+	path := filepath.Join(t.TempDir(), "crafted.tar.gz")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0644))
+	return path
+}
+
+func TestExportArchive_Manifest(t *testing.T) {
+	s := newArchiveSource(t)
+
+	_, manifest := writeArchive(t, s, ArchiveTarGz, ExportFilter{
+		Since: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+		Until: time.Date(2026, 2, 2, 0, 0, 0, 0, time.UTC),
+	})
+
+	assert.Equal(t, ArchiveFormatVersion, manifest.FormatVersion)
+	assert.Equal(t, "2026-02-01", manifest.Since)
+	assert.Equal(t, "2026-02-02", manifest.Until)
+	require.Len(t, manifest.Cases, 3)
+
+	first := manifest.Cases[0]
+	assert.Equal(t, "2026-02-01/153045_req-1", first.ID)
+	assert.Equal(t, "req-1", first.RequestID)
+	assert.Equal(t, "2026-02-01T15:30:45Z", first.Timestamp)
+	assert.True(t, first.Admissible)
+	assert.Equal(t, 8, first.Score)
+	assert.Equal(t, "vrijspraak", first.VerdictType)
+	// Variants are regenerated on import, not exported
+	require.Len(t, first.Files, 2)
+	assert.Equal(t, "2026-02-01/153045_req-1.jpg", first.Files[1].Name)
+	assert.Equal(t, int64(len(jpegData)), first.Files[1].Size)
+	assert.Len(t, first.Files[1].SHA256, 64)
+
+	assert.True(t, manifest.Cases[1].Withheld)
+	assert.Len(t, manifest.Cases[1].Files, 1)
+}
+
+func TestImportArchive_RoundTrip(t *testing.T) {
+	for _, format := range []ArchiveFormat{ArchiveTarGz, ArchiveZip} {
+		t.Run(string(format), func(t *testing.T) {
+			path, _ := writeArchive(t, newArchiveSource(t), format, ExportFilter{})
+
+			target, err := NewPhotoStorage(t.TempDir())
+			require.NoError(t, err)
+			result, err := target.ImportArchive(path)
+			require.NoError(t, err)
+
+			assert.Len(t, result.Imported, 4)
+			assert.Empty(t, result.Duplicates)
+			data, err := os.ReadFile(filepath.Join(target.basePath, "2026-02-02/090000_req-3.png"))
+			require.NoError(t, err)
+			assert.Equal(t, pngData, data)
+
+			cases, err := target.ListCases()
+			require.NoError(t, err)
+			assert.Len(t, cases, 4)
+			entries, err := os.ReadDir(target.basePath)
+			require.NoError(t, err)
+			assert.Len(t, entries, 3, "the staging directory should be removed")
+		})
+	}
+}
+
+func TestImportArchive_SkipsDuplicateRequestIDs(t *testing.T) {
+	path, _ := writeArchive(t, newArchiveSource(t), ArchiveTarGz, ExportFilter{})
+
+	dir := t.TempDir()
+	// Same request, stored at another time on this host
+	writeFiles(t, dir, map[string][]byte{
+		"2026-03-01/120000_req-3.json": []byte(`{"score":1}`),
+	})
+	target, err := NewPhotoStorage(dir)
+	require.NoError(t, err)
+
+	result, err := target.ImportArchive(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2026-02-02/090000_req-3"}, result.Duplicates)
+	assert.Len(t, result.Imported, 3)
+
+	// Importing again adds nothing
+	result, err = target.ImportArchive(path)
+	require.NoError(t, err)
+	assert.Empty(t, result.Imported)
+	assert.Len(t, result.Duplicates, 4)
+}
+
+func TestImportArchive_StopsAtQuota(t *testing.T) {
+	path, _ := writeArchive(t, newArchiveSource(t), ArchiveTarGz, ExportFilter{})
+
+	target, err := NewPhotoStorage(t.TempDir())
+	require.NoError(t, err)
+	// Room for the first case (verdict and photo) and the second (verdict only)
+	_, err = target.EnableQuota(QuotaConfig{MaxFiles: 4})
+	require.NoError(t, err)
+
+	result, err := target.ImportArchive(path)
+
+	assert.ErrorIs(t, err, ErrStorageFull)
+	assert.Equal(t, []string{"2026-02-01/153045_req-1", "2026-02-01/160000_req-2"}, result.Imported)
+	cases, err := target.ListCases()
+	require.NoError(t, err)
+	assert.Len(t, cases, 2)
+	assert.Equal(t, int64(3), target.Usage().Files)
+}
+
+func TestImportArchive_RejectsInvalidArchives(t *testing.T) {
+	jsonData := []byte(`{"score":5}`)
+	validCase := ArchivedCase{ID: "2026-02-01/153045_req-1", RequestID: "req-1", Files: []ArchivedFile{
+		{Name: "2026-02-01/153045_req-1.json", Size: int64(len(jsonData)), SHA256: "0000"},
+	}}
+	withCase := func(c ArchivedCase) ArchiveManifest {
+		return ArchiveManifest{FormatVersion: ArchiveFormatVersion, Cases: []ArchivedCase{c}}
+	}
+	escaping := validCase
+	escaping.ID = "2026-02-01/../../etc"
+	escaping.Files = []ArchivedFile{{Name: "2026-02-01/../../etc.json"}}
+
+	tests := []struct {
+		name     string
+		manifest ArchiveManifest
+		entries  map[string][]byte
+	}{
+		{"checksum mismatch", withCase(validCase), map[string][]byte{"cases/2026-02-01/153045_req-1.json": jsonData}},
+		{"missing file", withCase(validCase), nil},
+		{"unlisted file", ArchiveManifest{FormatVersion: ArchiveFormatVersion}, map[string][]byte{"cases/evil.json": jsonData}},
+		{"path traversal", withCase(escaping), nil},
+		{"newer format", ArchiveManifest{FormatVersion: ArchiveFormatVersion + 1}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := craftArchive(t, tt.manifest, tt.entries)
+			target, err := NewPhotoStorage(t.TempDir())
+			require.NoError(t, err)
+
+			_, err = target.ImportArchive(path)
+			assert.Error(t, err)
+
+			entries, err := os.ReadDir(target.basePath)
+			require.NoError(t, err)
+			assert.Empty(t, entries, "a rejected archive should change nothing")
+		})
+	}
+}
+
+func TestImportArchive_NotAnArchive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "photo.jpg")
+	require.NoError(t, os.WriteFile(path, jpegData, 0644))
+	s, err := NewPhotoStorage(t.TempDir())
+	require.NoError(t, err)
+
+	_, err = s.ImportArchive(path)
+	assert.ErrorContains(t, err, "unsupported archive")
+}
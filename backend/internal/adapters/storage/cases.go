@@ -0,0 +1,283 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"rechtebank/backend/internal/core/domain"
+)
+
+// dateDirLayout is the layout of the per-day directories cases are stored in
+const dateDirLayout = domain.StorageKeyDateLayout
+
+// StoredCase is a verdict in storage, with its photo when one was kept
+type StoredCase struct {
+	// ID is the case's path relative to the storage root without extension,
+	// "YYYY-MM-DD/HHMMSS_<requestID>", as encoded in verdict IDs
+	ID string
+
+	// Date is the day directory the case is stored in
+	Date time.Time
+
+	JSONPath string
+
+	// PhotoPath is empty when the photo was withheld by moderation or is missing
+	PhotoPath string
+}
+
+// RequestID returns the request ID the case was stored under
+func (c StoredCase) RequestID() string {
+	_, requestID, _ := strings.Cut(filepath.Base(c.ID), "_")
+	return requestID
+}
+
+// ListCases returns every stored verdict, oldest first
+func (s *PhotoStorage) ListCases() ([]StoredCase, error) {
+	entries, err := os.ReadDir(s.basePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read storage directory: %w", err)
+	}
+
+	var cases []StoredCase
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		date, err := time.Parse(dateDirLayout, entry.Name())
+		if err != nil {
+			continue
+		}
+
+		jsonPaths, err := filepath.Glob(filepath.Join(s.basePath, entry.Name(), "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		for _, jsonPath := range jsonPaths {
+			basePath := strings.TrimSuffix(jsonPath, ".json")
+			photoPath, _ := findOriginal(basePath)
+			cases = append(cases, StoredCase{
+				ID:        entry.Name() + "/" + filepath.Base(basePath),
+				Date:      date,
+				JSONPath:  jsonPath,
+				PhotoPath: photoPath,
+			})
+		}
+	}
+
+	sort.Slice(cases, func(i, j int) bool { return cases[i].ID < cases[j].ID })
+	return cases, nil
+}
+
+// Case returns the stored case with the given ID ("YYYY-MM-DD/HHMMSS_<requestID>")
+func (s *PhotoStorage) Case(id string) (StoredCase, bool) {
+	dateDir, name, ok := strings.Cut(id, "/")
+	date, err := time.Parse(dateDirLayout, dateDir)
+	if !ok || err != nil || name == "" || strings.ContainsAny(name, `/\`) {
+		return StoredCase{}, false
+	}
+
+	basePath := filepath.Join(s.basePath, dateDir, name)
+	if _, err := os.Stat(basePath + ".json"); err != nil {
+		return StoredCase{}, false
+	}
+	photoPath, _ := findOriginal(basePath)
+	return StoredCase{ID: id, Date: date, JSONPath: basePath + ".json", PhotoPath: photoPath}, true
+}
+
+// CaseByRequestID returns the stored case with the given request ID
+func (s *PhotoStorage) CaseByRequestID(requestID string) (StoredCase, bool, error) {
+	cases, err := s.ListCases()
+	if err != nil {
+		return StoredCase{}, false, err
+	}
+	for _, c := range cases {
+		if c.RequestID() == requestID {
+			return c, true, nil
+		}
+	}
+	return StoredCase{}, false, nil
+}
+
+// CaseFiles returns every file stored for a case: the verdict JSON, the
+// photo and its variants
+func (s *PhotoStorage) CaseFiles(c StoredCase) ([]string, error) {
+	return filepath.Glob(filepath.Join(s.basePath, c.ID) + ".*")
+}
+
+// DeleteCase removes every file stored for a case and returns their paths
+func (s *PhotoStorage) DeleteCase(c StoredCase) ([]string, error) {
+	files, err := s.CaseFiles(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for _, file := range files {
+		if err := s.removeCaseFile(file); err != nil {
+			return removed, fmt.Errorf("failed to remove %s: %w", file, err)
+		}
+		removed = append(removed, file)
+	}
+	return removed, nil
+}
+
+// OrphanReason describes why a stored file does not belong to a complete case
+type OrphanReason string
+
+// Reasons a file is reported as orphaned
+const (
+	OrphanPhotoWithoutVerdict   OrphanReason = "photo without verdict"
+	OrphanVerdictWithoutPhoto   OrphanReason = "verdict without photo"
+	OrphanVariantWithoutPhoto   OrphanReason = "variant without photo"
+	OrphanLeftoverTemporaryFile OrphanReason = "leftover temporary file"
+)
+
+// Orphan is a stored file left behind by an interrupted save or delete
+type Orphan struct {
+	Path   string
+	Reason OrphanReason
+}
+
+// FindOrphans returns photos without a verdict, verdicts without a photo
+// (unless the photo was withheld by moderation), variants without an original
+// and leftover temporary files. Files modified within minAge are skipped, as
+// they may belong to a save that is still in progress.
+func (s *PhotoStorage) FindOrphans(minAge time.Duration) ([]Orphan, error) {
+	entries, err := os.ReadDir(s.basePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read storage directory: %w", err)
+	}
+
+	cutoff := time.Now().Add(-minAge)
+	var orphans []Orphan
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := time.Parse(dateDirLayout, entry.Name()); err != nil {
+			continue
+		}
+
+		dir := filepath.Join(s.basePath, entry.Name())
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+		}
+		for _, file := range files {
+			if file.IsDir() {
+				continue
+			}
+			if info, err := file.Info(); err != nil || info.ModTime().After(cutoff) {
+				continue
+			}
+			path := filepath.Join(dir, file.Name())
+			if reason, orphaned := orphanReason(path); orphaned {
+				orphans = append(orphans, Orphan{Path: path, Reason: reason})
+			}
+		}
+	}
+	return orphans, nil
+}
+
+// quarantineDir is where QuarantinePartialCases moves the files of partial
+// cases, under their date directory; the leading dot keeps it out of listings
+const quarantineDir = ".quarantine"
+
+// QuarantinePartialCases moves the files of incomplete cases out of the date
+// directories, so they are never served or shared, and deletes leftover
+// temporary files. Quarantined files keep their name under
+// <base>/.quarantine/<date>/ for an operator to inspect. Files modified
+// within minAge are left alone, as in FindOrphans. It stops between files
+// when ctx is done.
+func (s *PhotoStorage) QuarantinePartialCases(ctx context.Context, minAge time.Duration) ([]Orphan, error) {
+	orphans, err := s.FindOrphans(minAge)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, o := range orphans {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if o.Reason == OrphanLeftoverTemporaryFile {
+			if err := os.Remove(o.Path); err != nil && !os.IsNotExist(err) {
+				return nil, fmt.Errorf("failed to remove %s: %w", o.Path, err)
+			}
+			continue
+		}
+
+		target := filepath.Join(s.basePath, quarantineDir, filepath.Base(filepath.Dir(o.Path)), filepath.Base(o.Path))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create quarantine directory: %w", err)
+		}
+		info, statErr := os.Stat(o.Path)
+		if err := os.Rename(o.Path, target); err != nil {
+			return nil, fmt.Errorf("failed to quarantine %s: %w", o.Path, err)
+		}
+		if statErr == nil {
+			s.addUsage(-info.Size(), -1)
+		}
+	}
+	return orphans, nil
+}
+
+// orphanReason checks whether the file at path belongs to a complete case
+func orphanReason(path string) (OrphanReason, bool) {
+	name := filepath.Base(path)
+	if strings.HasPrefix(name, ".") {
+		return OrphanLeftoverTemporaryFile, true
+	}
+
+	ext := filepath.Ext(name)
+	basePath := strings.TrimSuffix(path, ext)
+
+	if ext == ".json" {
+		if _, ok := findOriginal(basePath); ok {
+			return "", false
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", false
+		}
+		if verdict, err := domain.ParseFlatJSON(data); err == nil && verdict.Moderation != nil && verdict.Moderation.Flagged {
+			return "", false
+		}
+		return OrphanVerdictWithoutPhoto, true
+	}
+
+	if !slices.ContainsFunc(domain.PhotoFormats, func(f domain.PhotoFormat) bool { return f.Extension == ext }) {
+		return "", false
+	}
+
+	// Variants are named <base>.w<width><ext>
+	if variantBase, width, ok := cutLast(basePath, ".w"); ok && isDigits(width) {
+		if _, ok := findOriginal(variantBase); !ok {
+			return OrphanVariantWithoutPhoto, true
+		}
+		return "", false
+	}
+
+	if _, err := os.Stat(basePath + ".json"); os.IsNotExist(err) {
+		return OrphanPhotoWithoutVerdict, true
+	}
+	return "", false
+}
+
+// cutLast slices s around the last instance of sep
+func cutLast(s, sep string) (before, after string, found bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return s, "", false
+	}
+	return s[:i], s[i+len(sep):], true
+}
+
+func isDigits(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789") == ""
+}
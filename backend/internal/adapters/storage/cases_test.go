@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFiles writes files relative to dir
+func writeFiles(t *testing.T, dir string, files map[string][]byte) {
+	t.Helper()
+	for path, data := range files {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(path)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, path), data, 0644))
+	}
+}
+
+func TestListCases(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string][]byte{
+		"2026-02-02/090000_req-3.json":      []byte(`{}`),
+		"2026-02-02/090000_req-3.webp":      webpData,
+		"2026-02-02/090000_req-3.w400.webp": webpData,
+		"2026-02-01/153045_req-1.json":      []byte(`{}`),
+		"2026-02-01/153045_req-1.jpg":       jpegData,
+		"2026-02-01/160000_req-2.json":      []byte(`{"moderation":{"flagged":true}}`),
+		"not-a-date/120000_req-4.json":      []byte(`{}`),
+	})
+
+	s, err := NewPhotoStorage(dir)
+	require.NoError(t, err)
+
+	cases, err := s.ListCases()
+	require.NoError(t, err)
+	require.Len(t, cases, 3)
+
+	assert.Equal(t, "2026-02-01/153045_req-1", cases[0].ID)
+	assert.Equal(t, "req-1", cases[0].RequestID())
+	assert.Equal(t, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), cases[0].Date)
+	assert.Equal(t, filepath.Join(dir, "2026-02-01/153045_req-1.jpg"), cases[0].PhotoPath)
+
+	assert.Equal(t, "2026-02-01/160000_req-2", cases[1].ID)
+	assert.Empty(t, cases[1].PhotoPath)
+
+	assert.Equal(t, "2026-02-02/090000_req-3", cases[2].ID)
+	assert.Equal(t, filepath.Join(dir, "2026-02-02/090000_req-3.json"), cases[2].JSONPath)
+	assert.Equal(t, filepath.Join(dir, "2026-02-02/090000_req-3.webp"), cases[2].PhotoPath)
+}
+
+func TestCase(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string][]byte{
+		"2026-02-01/153045_req-1.json": []byte(`{}`),
+		"2026-02-01/153045_req-1.png":  pngData,
+	})
+	s, err := NewPhotoStorage(dir)
+	require.NoError(t, err)
+
+	c, ok := s.Case("2026-02-01/153045_req-1")
+	require.True(t, ok)
+	assert.Equal(t, filepath.Join(dir, "2026-02-01/153045_req-1.png"), c.PhotoPath)
+
+	for _, id := range []string{"2026-02-01/153045_req-2", "2026-02-01", "../2026-02-01/153045_req-1", "2026-02-01/x/../153045_req-1"} {
+		_, ok := s.Case(id)
+		assert.False(t, ok, id)
+	}
+
+	c, ok, err = s.CaseByRequestID("req-1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "2026-02-01/153045_req-1", c.ID)
+
+	_, ok, err = s.CaseByRequestID("req-2")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestDeleteCase(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string][]byte{
+		"2026-02-01/153045_req-1.json":      []byte(`{}`),
+		"2026-02-01/153045_req-1.jpg":       jpegData,
+		"2026-02-01/153045_req-1.w400.webp": webpData,
+		"2026-02-01/160000_req-2.json":      []byte(`{}`),
+	})
+	s, err := NewPhotoStorage(dir)
+	require.NoError(t, err)
+	c, ok := s.Case("2026-02-01/153045_req-1")
+	require.True(t, ok)
+
+	removed, err := s.DeleteCase(c)
+	require.NoError(t, err)
+	assert.Len(t, removed, 3)
+
+	cases, err := s.ListCases()
+	require.NoError(t, err)
+	require.Len(t, cases, 1)
+	assert.Equal(t, "2026-02-01/160000_req-2", cases[0].ID)
+}
+
+func TestFindOrphans(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string][]byte{
+		"2026-02-01/100000_complete.json":      []byte(`{}`),
+		"2026-02-01/100000_complete.jpg":       jpegData,
+		"2026-02-01/100000_complete.w400.webp": webpData,
+		"2026-02-01/110000_withheld.json":      []byte(`{"moderation":{"flagged":true}}`),
+		"2026-02-01/120000_nojson.png":         pngData,
+		"2026-02-01/130000_nophoto.json":       []byte(`{}`),
+		"2026-02-01/140000_gone.w1200.jpg":     jpegData,
+		"2026-02-01/.variant-123":              webpData,
+		"2026-02-01/notes.txt":                 []byte("keep"),
+	})
+	s, err := NewPhotoStorage(dir)
+	require.NoError(t, err)
+
+	orphans, err := s.FindOrphans(0)
+	require.NoError(t, err)
+
+	reasons := map[string]OrphanReason{}
+	for _, o := range orphans {
+		rel, _ := filepath.Rel(dir, o.Path)
+		reasons[rel] = o.Reason
+	}
+	assert.Equal(t, map[string]OrphanReason{
+		"2026-02-01/.variant-123":          OrphanLeftoverTemporaryFile,
+		"2026-02-01/120000_nojson.png":     OrphanPhotoWithoutVerdict,
+		"2026-02-01/130000_nophoto.json":   OrphanVerdictWithoutPhoto,
+		"2026-02-01/140000_gone.w1200.jpg": OrphanVariantWithoutPhoto,
+	}, reasons)
+
+	// Files that may belong to a save in progress are skipped
+	orphans, err = s.FindOrphans(time.Hour)
+	require.NoError(t, err)
+	assert.Empty(t, orphans)
+}
+
+func TestQuarantinePartialCases(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string][]byte{
+		"2026-02-01/100000_complete.json": []byte(`{}`),
+		"2026-02-01/100000_complete.jpg":  jpegData,
+		"2026-02-01/120000_nojson.png":    pngData,
+		"2026-02-01/130000_nophoto.json":  []byte(`{}`),
+		"2026-02-01/.tmp-123":             jpegData,
+	})
+	s, err := NewPhotoStorage(dir)
+	require.NoError(t, err)
+
+	// Recent files may belong to a save in progress
+	quarantined, err := s.QuarantinePartialCases(context.Background(), time.Hour)
+	require.NoError(t, err)
+	assert.Empty(t, quarantined)
+
+	quarantined, err = s.QuarantinePartialCases(context.Background(), 0)
+	require.NoError(t, err)
+	assert.Len(t, quarantined, 3)
+
+	assert.NoFileExists(t, filepath.Join(dir, "2026-02-01/.tmp-123"))
+	assert.NoFileExists(t, filepath.Join(dir, "2026-02-01/120000_nojson.png"))
+	assert.FileExists(t, filepath.Join(dir, ".quarantine/2026-02-01/120000_nojson.png"))
+	assert.FileExists(t, filepath.Join(dir, ".quarantine/2026-02-01/130000_nophoto.json"))
+	assert.FileExists(t, filepath.Join(dir, "2026-02-01/100000_complete.jpg"))
+
+	cases, err := s.ListCases()
+	require.NoError(t, err)
+	require.Len(t, cases, 1)
+	assert.Equal(t, "2026-02-01/100000_complete", cases[0].ID)
+
+	orphans, err := s.FindOrphans(0)
+	require.NoError(t, err)
+	assert.Empty(t, orphans)
+}
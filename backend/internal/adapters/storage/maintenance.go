@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"rechtebank/backend/internal/adapters/imaging"
+)
+
+// BackfillVariants generates the configured variants missing from stored
+// photos, such as those of photos stored before a width or format was added,
+// and returns how many it wrote. It does nothing while storage is full, and
+// stops early when ctx is done or storage fills up.
+func (s *PhotoStorage) BackfillVariants(ctx context.Context) (int, error) {
+	if len(s.variants.Widths) == 0 || len(s.variants.Formats) == 0 {
+		return 0, nil
+	}
+	if s.Degraded() {
+		return 0, ErrStorageFull
+	}
+	cases, err := s.ListCases()
+	if err != nil {
+		return 0, err
+	}
+
+	written := 0
+	for _, c := range cases {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+		if s.Degraded() {
+			return written, ErrStorageFull
+		}
+		if c.PhotoPath == "" {
+			continue
+		}
+
+		basePath := strings.TrimSuffix(c.PhotoPath, filepath.Ext(c.PhotoPath))
+		n, err := s.backfillCase(basePath, c.PhotoPath)
+		written += n
+		if errors.Is(err, ErrStorageFull) {
+			return written, err
+		}
+		if err != nil {
+			logger.Warn("Failed to backfill photo variants", slog.String("id", c.ID), slog.Any("error", err))
+		}
+	}
+	return written, nil
+}
+
+// backfillCase writes the missing variants of one photo, decoding it only
+// when there are any
+func (s *PhotoStorage) backfillCase(basePath, photoPath string) (int, error) {
+	var img image.Image
+	written := 0
+	for _, width := range s.variants.Widths {
+		var resized image.Image
+		for _, format := range s.variants.Formats {
+			path := variantPath(basePath, width, format)
+			if _, err := os.Stat(path); err == nil {
+				continue
+			}
+
+			if img == nil {
+				data, err := os.ReadFile(photoPath)
+				if err != nil {
+					return written, fmt.Errorf("failed to read photo: %w", err)
+				}
+				if img, _, err = image.Decode(bytes.NewReader(data)); err != nil {
+					return written, fmt.Errorf("failed to decode photo: %w", err)
+				}
+			}
+			if resized == nil {
+				resized = imaging.ResizeToWidth(img, width)
+			}
+			if err := s.writeVariant(path, resized, format); err != nil {
+				return written, err
+			}
+			written++
+		}
+	}
+	return written, nil
+}
+
+// RemoveEmptyDays removes the date directories left without any file, and
+// returns how many it removed
+func (s *PhotoStorage) RemoveEmptyDays() (int, error) {
+	entries, err := os.ReadDir(s.basePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read storage directory: %w", err)
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := time.Parse(dateDirLayout, entry.Name()); err != nil {
+			continue
+		}
+		dir := filepath.Join(s.basePath, entry.Name())
+		if files, err := os.ReadDir(dir); err != nil || len(files) > 0 {
+			continue
+		}
+		// Fails harmlessly when a save has just created a file in it
+		if err := os.Remove(dir); err == nil {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// statsRollupName is the file in the storage directory holding the latest
+// stats rollup
+const statsRollupName = ".stats.json"
+
+// StatsRollup counts the stored cases at a point in time
+type StatsRollup struct {
+	GeneratedAt  time.Time              `json:"generatedAt"`
+	Cases        int                    `json:"cases"`
+	Usage        Usage                  `json:"usage"`
+	Classes      map[RetentionClass]int `json:"classes"`
+	VerdictTypes map[string]int         `json:"verdictTypes"`
+	PerDay       map[string]int         `json:"perDay"`
+}
+
+// RollupStats counts the stored cases by retention class, verdict type and
+// day, and stores the result as the latest rollup. It gives up when ctx is
+// done, without storing a partial rollup.
+func (s *PhotoStorage) RollupStats(ctx context.Context, now time.Time) (StatsRollup, error) {
+	rollup := StatsRollup{
+		GeneratedAt:  now.UTC(),
+		Classes:      map[RetentionClass]int{},
+		VerdictTypes: map[string]int{},
+		PerDay:       map[string]int{},
+	}
+	cases, err := s.ListCases()
+	if err != nil {
+		return rollup, err
+	}
+	for _, c := range cases {
+		if err := ctx.Err(); err != nil {
+			return rollup, err
+		}
+		fields, err := readRetentionFields(c)
+		if err != nil {
+			// Removed since it was listed
+			continue
+		}
+		rollup.Cases++
+		rollup.Classes[fields.class()]++
+		rollup.VerdictTypes[fields.VerdictType]++
+		rollup.PerDay[c.Date.Format(dateDirLayout)]++
+	}
+	rollup.Usage = s.Usage()
+
+	data, err := json.MarshalIndent(rollup, "", "  ")
+	if err != nil {
+		return rollup, err
+	}
+	if err := writeFileAtomic(filepath.Join(s.basePath, statsRollupName), data); err != nil {
+		return rollup, fmt.Errorf("failed to write stats rollup: %w", err)
+	}
+	return rollup, nil
+}
+
+// LatestStats returns the latest stats rollup, and false when there is none
+// yet
+func (s *PhotoStorage) LatestStats() (StatsRollup, bool, error) {
+	var rollup StatsRollup
+	data, err := os.ReadFile(filepath.Join(s.basePath, statsRollupName))
+	if os.IsNotExist(err) {
+		return rollup, false, nil
+	}
+	if err != nil {
+		return rollup, false, fmt.Errorf("failed to read stats rollup: %w", err)
+	}
+	if err := json.Unmarshal(data, &rollup); err != nil {
+		return rollup, false, fmt.Errorf("failed to parse stats rollup: %w", err)
+	}
+	return rollup, true, nil
+}
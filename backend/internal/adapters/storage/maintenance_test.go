@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"rechtebank/backend/internal/core/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackfillVariants(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string][]byte{
+		"2026-02-01/153045_req-1.json": []byte(`{"admissible":true}`),
+		"2026-02-01/160000_req-2.json": []byte(`{"admissible":false}`),
+	})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "2026-02-01/153045_req-1.jpg"), encodeJPEG(t, 200, 100), 0644))
+	s, err := NewPhotoStorageWithVariants(dir, testVariants)
+	require.NoError(t, err)
+
+	written, err := s.BackfillVariants(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, len(testVariants.Widths)*len(testVariants.Formats), written)
+	assert.FileExists(t, variantPath(filepath.Join(dir, "2026-02-01/153045_req-1"), 120, domain.PhotoFormatWebP))
+
+	// Only missing variants are written
+	require.NoError(t, os.Remove(variantPath(filepath.Join(dir, "2026-02-01/153045_req-1"), 40, domain.PhotoFormatJPEG)))
+	written, err = s.BackfillVariants(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, written)
+}
+
+func TestBackfillVariants_StopsWhenCancelled(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string][]byte{"2026-02-01/153045_req-1.json": []byte(`{}`)})
+	s, err := NewPhotoStorageWithVariants(dir, testVariants)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = s.BackfillVariants(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestBackfillVariants_SkippedWhileStorageIsFull(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string][]byte{
+		"2026-02-01/153045_req-1.json": []byte(`{"admissible":true,"publishedAt":"2026-02-02T10:00:00Z"}`),
+	})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "2026-02-01/153045_req-1.jpg"), encodeJPEG(t, 200, 100), 0644))
+	s, err := NewPhotoStorageWithVariants(dir, testVariants)
+	require.NoError(t, err)
+	_, err = s.EnableQuota(QuotaConfig{MaxFiles: 2})
+	require.NoError(t, err)
+	require.True(t, s.Degraded())
+
+	written, err := s.BackfillVariants(context.Background())
+	assert.ErrorIs(t, err, ErrStorageFull)
+	assert.Zero(t, written)
+	assert.NoFileExists(t, variantPath(filepath.Join(dir, "2026-02-01/153045_req-1"), 40, domain.PhotoFormatWebP))
+}
+
+func TestBackfillVariants_StopsAtQuota(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string][]byte{
+		"2026-02-01/153045_req-1.json": []byte(`{"admissible":true,"publishedAt":"2026-02-02T10:00:00Z"}`),
+	})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "2026-02-01/153045_req-1.jpg"), encodeJPEG(t, 200, 100), 0644))
+	s, err := NewPhotoStorageWithVariants(dir, testVariants)
+	require.NoError(t, err)
+	// Room for one variant, with the high-water mark out of the way
+	_, err = s.EnableQuota(QuotaConfig{MaxFiles: 3, HighWater: 1, LowWater: 1})
+	require.NoError(t, err)
+
+	written, err := s.BackfillVariants(context.Background())
+	assert.ErrorIs(t, err, ErrStorageFull)
+	assert.Equal(t, 1, written)
+	assert.Equal(t, int64(3), s.Usage().Files)
+}
+
+func TestRemoveEmptyDays(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string][]byte{"2026-02-01/153045_req-1.json": []byte(`{}`)})
+	for _, d := range []string{"2026-01-30", "2026-01-31", ".quarantine", "not-a-day"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, d), 0755))
+	}
+	s, err := NewPhotoStorage(dir)
+	require.NoError(t, err)
+
+	removed, err := s.RemoveEmptyDays()
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+	assert.NoDirExists(t, filepath.Join(dir, "2026-01-30"))
+	assert.DirExists(t, filepath.Join(dir, "2026-02-01"))
+	assert.DirExists(t, filepath.Join(dir, ".quarantine"))
+	assert.DirExists(t, filepath.Join(dir, "not-a-day"))
+}
+
+func TestRollupStats(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string][]byte{
+		"2026-02-01/153045_req-1.json": []byte(`{"admissible":true,"verdictType":"schuldig"}`),
+		"2026-02-01/160000_req-2.json": []byte(`{"admissible":false,"verdictType":"niet-ontvankelijk"}`),
+		"2026-02-02/090000_req-3.json": []byte(`{"admissible":true,"verdictType":"schuldig","publishedAt":"2026-02-03T10:00:00Z"}`),
+	})
+	s, err := NewPhotoStorage(dir)
+	require.NoError(t, err)
+	_, err = s.EnableQuota(QuotaConfig{})
+	require.NoError(t, err)
+
+	_, ok, err := s.LatestStats()
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	now := time.Date(2026, 2, 3, 12, 0, 0, 0, time.UTC)
+	rollup, err := s.RollupStats(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, 3, rollup.Cases)
+	assert.Equal(t, int64(3), rollup.Usage.Files)
+	assert.Equal(t, map[RetentionClass]int{RetentionVerdict: 1, RetentionInadmissible: 1, RetentionPublished: 1}, rollup.Classes)
+	assert.Equal(t, map[string]int{"schuldig": 2, "niet-ontvankelijk": 1}, rollup.VerdictTypes)
+	assert.Equal(t, map[string]int{"2026-02-01": 2, "2026-02-02": 1}, rollup.PerDay)
+
+	latest, ok, err := s.LatestStats()
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, rollup, latest)
+
+	// The rollup is not a case file
+	usage, err := s.RecountUsage()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), usage.Files)
+}
@@ -1,105 +1,235 @@
 package storage
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
+
+	"rechtebank/backend/internal/core/domain"
 )
 
 // PhotoStorage handles saving and managing photo files
 type PhotoStorage struct {
 	basePath string
+	variants VariantConfig
+	quota    quotaState
 }
 
-// NewPhotoStorage creates a new PhotoStorage instance
+// NewPhotoStorage creates a new PhotoStorage instance that stores originals only
 func NewPhotoStorage(basePath string) (*PhotoStorage, error) {
+	return NewPhotoStorageWithVariants(basePath, VariantConfig{})
+}
+
+// NewPhotoStorageWithVariants creates a PhotoStorage that also stores resized
+// variants of every photo
+func NewPhotoStorageWithVariants(basePath string, variants VariantConfig) (*PhotoStorage, error) {
 	// Create base directory if it doesn't exist
 	if err := os.MkdirAll(basePath, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create storage directory: %w", err)
 	}
 
+	if variants.Quality <= 0 {
+		variants.Quality = DefaultVariantQuality
+	}
+
 	return &PhotoStorage{
 		basePath: basePath,
+		variants: variants,
 	}, nil
 }
 
-// SavePhoto saves a photo to disk with timestamp-based naming
-func (s *PhotoStorage) SavePhoto(imageData []byte, llmResponse []byte, requestID string, timestampISO string) (string, error) {
-	// Create subdirectory based on current date (YYYY-MM-DD)
-	now := time.Now()
-	dateDir := now.Format("2006-01-02")
-	fullDir := filepath.Join(s.basePath, dateDir)
-
-	if err := os.MkdirAll(fullDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create date directory: %w", err)
+// ErrInvalidSave is returned by SavePhoto for a save that can never succeed,
+// such as one with an invalid storage key or verdict JSON
+var ErrInvalidSave = errors.New("invalid save")
+
+// SavePhoto saves a photo to disk under its storage key
+// ("YYYY-MM-DD/HHMMSS_<requestID>", see domain.NewStorageKey). The file
+// extension follows the photo's format (JPEG when it cannot be detected), and
+// the MIME type is recorded in the verdict JSON. With nil imageData only the
+// verdict JSON is written, for photos withheld by moderation, and its path is
+// returned.
+//
+// Both files are written atomically, photo first. The verdict JSON is the
+// commit point: a case exists once its JSON does, so an interrupted save
+// leaves at most a photo without a verdict, which QuarantinePartialCases
+// moves aside.
+func (s *PhotoStorage) SavePhoto(imageData []byte, llmResponse []byte, storageKey string, requestID string, timestampISO string) (string, error) {
+	if storageKey == "" {
+		// Saves queued before verdicts carried a storage key
+		storageKey = domain.NewStorageKey(time.Now(), requestID)
+	}
+	if !domain.ValidStorageKey(storageKey, requestID) {
+		return "", fmt.Errorf("%w: invalid storage key %q", ErrInvalidSave, storageKey)
 	}
 
-	// Generate filename: timestamp_requestID.jpg
-	timestamp := now.Format("150405") // HHMMSS
-	filename := fmt.Sprintf("%s_%s.jpg", timestamp, requestID)
-	filePath := filepath.Join(fullDir, filename)
-
-	// Write photo
-	if err := os.WriteFile(filePath, imageData, 0644); err != nil {
-		return "", fmt.Errorf("failed to write photo: %w", err)
+	format, ok := domain.DetectPhotoFormat(imageData)
+	if !ok {
+		format = domain.PhotoFormatJPEG
 	}
-	// Write LLM response as JSON with added metadata
-	filenameJSON := fmt.Sprintf("%s_%s.json", now.Format("150405"), requestID)
-	filePathJSON := filepath.Join(fullDir, filenameJSON)
 
-	// Parse the raw JSON and add requestId and timestamp
+	// Build the verdict JSON before touching the disk, so malformed analyzer
+	// output leaves nothing behind
 	var jsonData map[string]interface{}
 	if err := json.Unmarshal(llmResponse, &jsonData); err != nil {
-		return "", fmt.Errorf("failed to parse JSON: %w", err)
+		return "", fmt.Errorf("%w: failed to parse JSON: %w", ErrInvalidSave, err)
 	}
 	jsonData["requestId"] = requestID
 	jsonData["timestamp"] = timestampISO
-
-	// Marshal back to JSON
+	if imageData != nil {
+		jsonData["mimeType"] = format.MIMEType
+	}
 	completeJSON, err := json.MarshalIndent(jsonData, "", "  ")
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal JSON: %w", err)
 	}
 
-	if err := os.WriteFile(filePathJSON, completeJSON, 0644); err != nil {
+	size, files := int64(len(completeJSON)), int64(1)
+	if imageData != nil {
+		size, files = size+int64(len(imageData)), files+1
+	}
+	if !s.admit(size, files) {
+		return "", ErrStorageFull
+	}
+
+	basePath := filepath.Join(s.basePath, filepath.FromSlash(storageKey))
+	fullDir := filepath.Dir(basePath)
+	if err := os.MkdirAll(fullDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create date directory: %w", err)
+	}
+
+	filePath := basePath + format.Extension
+	filePathJSON := basePath + ".json"
+
+	if imageData != nil {
+		if err := s.writeCaseFile(filePath, imageData); err != nil {
+			return "", fmt.Errorf("failed to write photo: %w", err)
+		}
+	}
+	if err := s.writeCaseFile(filePathJSON, completeJSON); err != nil {
+		if imageData != nil {
+			s.removeCaseFile(filePath)
+		}
 		return "", fmt.Errorf("failed to write JSON: %w", err)
 	}
+	if err := syncDir(fullDir); err != nil {
+		logger.Warn("Failed to sync date directory", slog.String("path", fullDir), slog.Any("error", err))
+	}
+
+	if imageData == nil {
+		return filePathJSON, nil
+	}
+
+	s.generateVariants(basePath, imageData)
 
 	return filePath, nil
 }
 
-// CleanupOldPhotos removes photos older than the specified number of days
+// CleanupOldPhotos removes unpublished cases, with their photos and
+// variants, stored more than retentionDays ago. Published cases and cases on
+// legal hold are kept; see ApplyRetention for per-class periods.
 func (s *PhotoStorage) CleanupOldPhotos(retentionDays int) error {
-	cutoffDate := time.Now().AddDate(0, 0, -retentionDays)
+	policy := RetentionPolicy{InadmissibleDays: retentionDays, VerdictDays: retentionDays, SharedDays: retentionDays}
+	_, err := s.ApplyRetention(context.Background(), policy, time.Now(), false)
+	return err
+}
 
-	// Walk through all date directories
+// MigratePhotoFormats renames photos that were stored as .jpg although they
+// are PNG or WebP, and records the real MIME type in their verdict JSON.
+// It is safe to run repeatedly and returns the number of photos renamed. A
+// photo that cannot be migrated, e.g. because its verdict JSON is corrupt, is
+// logged and left as it is.
+func (s *PhotoStorage) MigratePhotoFormats() (int, error) {
 	entries, err := os.ReadDir(s.basePath)
 	if err != nil {
-		return fmt.Errorf("failed to read storage directory: %w", err)
+		return 0, fmt.Errorf("failed to read storage directory: %w", err)
 	}
 
+	migrated := 0
 	for _, entry := range entries {
 		if !entry.IsDir() {
 			continue
 		}
-
-		// Parse directory name as date (YYYY-MM-DD)
-		dirDate, err := time.Parse("2006-01-02", entry.Name())
-		if err != nil {
-			// Skip directories that don't match the date format
+		if _, err := time.Parse(dateDirLayout, entry.Name()); err != nil {
 			continue
 		}
 
-		// Remove directory if older than retention period
-		if dirDate.Before(cutoffDate) {
-			dirPath := filepath.Join(s.basePath, entry.Name())
-			if err := os.RemoveAll(dirPath); err != nil {
-				return fmt.Errorf("failed to remove old directory %s: %w", dirPath, err)
+		photos, err := filepath.Glob(filepath.Join(s.basePath, entry.Name(), "*"+domain.PhotoFormatJPEG.Extension))
+		if err != nil {
+			return migrated, err
+		}
+		for _, photoPath := range photos {
+			renamed, err := s.migratePhotoFormat(photoPath)
+			if err != nil {
+				logger.Warn("Skipping photo that cannot be migrated", slog.String("path", photoPath), slog.Any("error", err))
+				continue
+			}
+			if renamed {
+				migrated++
 			}
 		}
 	}
 
-	return nil
+	return migrated, nil
+}
+
+// migratePhotoFormat fixes the extension of a single mislabelled photo
+func (s *PhotoStorage) migratePhotoFormat(photoPath string) (bool, error) {
+	header, err := readHeader(photoPath, 12)
+	if err != nil {
+		return false, fmt.Errorf("failed to read photo %s: %w", photoPath, err)
+	}
+	format, ok := domain.DetectPhotoFormat(header)
+	if !ok || format == domain.PhotoFormatJPEG {
+		return false, nil
+	}
+
+	basePath := strings.TrimSuffix(photoPath, domain.PhotoFormatJPEG.Extension)
+
+	// Record the MIME type first: a verdict with a MIME type but a .jpg photo
+	// is still served correctly if the rename below fails
+	jsonPath := basePath + ".json"
+	if data, err := os.ReadFile(jsonPath); err == nil {
+		var jsonData map[string]interface{}
+		if err := json.Unmarshal(data, &jsonData); err != nil {
+			return false, fmt.Errorf("failed to parse JSON %s: %w", jsonPath, err)
+		}
+		jsonData["mimeType"] = format.MIMEType
+		updated, err := json.MarshalIndent(jsonData, "", "  ")
+		if err != nil {
+			return false, fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		if err := s.writeCaseFile(jsonPath, updated); err != nil {
+			return false, fmt.Errorf("failed to write JSON %s: %w", jsonPath, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return false, fmt.Errorf("failed to read JSON %s: %w", jsonPath, err)
+	}
+
+	if err := os.Rename(photoPath, basePath+format.Extension); err != nil {
+		return false, fmt.Errorf("failed to rename photo %s: %w", photoPath, err)
+	}
+	return true, nil
+}
+
+// readHeader reads up to n bytes from the start of a file
+func readHeader(path string, n int) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	header := make([]byte, n)
+	read, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	return header[:read], nil
 }
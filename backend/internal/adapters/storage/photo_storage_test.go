@@ -0,0 +1,227 @@
+package storage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	jpegData = []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10, 'J', 'F', 'I', 'F', 0x00, 0x01}
+	pngData  = []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A, 0x00, 0x00, 0x00, 0x0D}
+	webpData = []byte("RIFF\x1a\x00\x00\x00WEBPVP8L")
+)
+
+// readJSON reads a stored verdict JSON file into a map
+func readJSON(t *testing.T, path string) map[string]interface{} {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var result map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &result))
+	return result
+}
+
+func TestSavePhoto_UsesDetectedFormat(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      []byte
+		extension string
+		mimeType  string
+	}{
+		{"JPEG", jpegData, ".jpg", "image/jpeg"},
+		{"PNG", pngData, ".png", "image/png"},
+		{"WebP", webpData, ".webp", "image/webp"},
+		{"unknown falls back to JPEG", []byte("not an image"), ".jpg", "image/jpeg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewPhotoStorage(t.TempDir())
+			require.NoError(t, err)
+
+			photoPath, err := s.SavePhoto(tt.data, []byte(`{"admissible":true}`), "2026-02-01/153045_req-1", "req-1", "2026-02-01T15:30:45Z")
+			require.NoError(t, err)
+			assert.Equal(t, tt.extension, filepath.Ext(photoPath))
+
+			stored, err := os.ReadFile(photoPath)
+			require.NoError(t, err)
+			assert.Equal(t, tt.data, stored)
+
+			verdict := readJSON(t, strings.TrimSuffix(photoPath, tt.extension)+".json")
+			assert.Equal(t, tt.mimeType, verdict["mimeType"])
+			assert.Equal(t, "req-1", verdict["requestId"])
+		})
+	}
+}
+
+func TestMigratePhotoFormats(t *testing.T) {
+	base := t.TempDir()
+	dir := filepath.Join(base, "2026-01-15")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+
+	write := func(name string, data []byte) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0644))
+	}
+	write("100000_png.jpg", pngData)
+	write("100000_png.json", []byte(`{"score":7,"requestId":"png"}`))
+	write("110000_webp.jpg", webpData)
+	write("110000_webp.json", []byte(`{"score":5,"requestId":"webp"}`))
+	write("120000_jpeg.jpg", jpegData)
+	write("120000_jpeg.json", []byte(`{"score":9,"requestId":"jpeg"}`))
+
+	// Directories that are not date directories are left alone
+	other := filepath.Join(base, "lost+found")
+	require.NoError(t, os.MkdirAll(other, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(other, "x.jpg"), pngData, 0644))
+
+	s, err := NewPhotoStorage(base)
+	require.NoError(t, err)
+
+	migrated, err := s.MigratePhotoFormats()
+	require.NoError(t, err)
+	assert.Equal(t, 2, migrated)
+
+	assert.FileExists(t, filepath.Join(dir, "100000_png.png"))
+	assert.NoFileExists(t, filepath.Join(dir, "100000_png.jpg"))
+	assert.FileExists(t, filepath.Join(dir, "110000_webp.webp"))
+	assert.FileExists(t, filepath.Join(dir, "120000_jpeg.jpg"))
+	assert.FileExists(t, filepath.Join(other, "x.jpg"))
+
+	pngVerdict := readJSON(t, filepath.Join(dir, "100000_png.json"))
+	assert.Equal(t, "image/png", pngVerdict["mimeType"])
+	assert.Equal(t, float64(7), pngVerdict["score"])
+	assert.Equal(t, "image/webp", readJSON(t, filepath.Join(dir, "110000_webp.json"))["mimeType"])
+	assert.NotContains(t, readJSON(t, filepath.Join(dir, "120000_jpeg.json")), "mimeType")
+
+	// Running again changes nothing
+	migrated, err = s.MigratePhotoFormats()
+	require.NoError(t, err)
+	assert.Zero(t, migrated)
+}
+
+func TestMigratePhotoFormats_PhotoWithoutJSON(t *testing.T) {
+	base := t.TempDir()
+	dir := filepath.Join(base, "2026-01-15")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "100000_orphan.jpg"), pngData, 0644))
+
+	s, err := NewPhotoStorage(base)
+	require.NoError(t, err)
+
+	migrated, err := s.MigratePhotoFormats()
+	require.NoError(t, err)
+	assert.Equal(t, 1, migrated)
+	assert.FileExists(t, filepath.Join(dir, "100000_orphan.png"))
+}
+
+func TestMigratePhotoFormats_SkipsCorruptJSON(t *testing.T) {
+	base := t.TempDir()
+	dir := filepath.Join(base, "2026-01-15")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+
+	write := func(name string, data []byte) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0644))
+	}
+	write("100000_bad.jpg", pngData)
+	write("100000_bad.json", []byte(`{"score":`))
+	write("110000_good.jpg", webpData)
+	write("110000_good.json", []byte(`{"score":5}`))
+
+	s, err := NewPhotoStorage(base)
+	require.NoError(t, err)
+
+	migrated, err := s.MigratePhotoFormats()
+	require.NoError(t, err)
+	assert.Equal(t, 1, migrated)
+
+	// The corrupt case is left untouched, the other one is migrated
+	assert.FileExists(t, filepath.Join(dir, "100000_bad.jpg"))
+	data, err := os.ReadFile(filepath.Join(dir, "100000_bad.json"))
+	require.NoError(t, err)
+	assert.Equal(t, `{"score":`, string(data))
+	assert.FileExists(t, filepath.Join(dir, "110000_good.webp"))
+	assert.Equal(t, "image/webp", readJSON(t, filepath.Join(dir, "110000_good.json"))["mimeType"])
+}
+
+func TestSavePhoto_WithoutPhotoWritesOnlyJSON(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewPhotoStorage(dir)
+	require.NoError(t, err)
+
+	jsonPath, err := s.SavePhoto(nil, []byte(`{"admissible":false,"moderation":{"flagged":true}}`), "2026-02-01/153045_req-1", "req-1", "2026-02-01T15:30:45Z")
+	require.NoError(t, err)
+	assert.Equal(t, ".json", filepath.Ext(jsonPath))
+
+	verdict := readJSON(t, jsonPath)
+	assert.Equal(t, "req-1", verdict["requestId"])
+	assert.NotContains(t, verdict, "mimeType")
+
+	entries, err := os.ReadDir(filepath.Dir(jsonPath))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestSavePhoto_InvalidJSONWritesNothing(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewPhotoStorage(dir)
+	require.NoError(t, err)
+
+	_, err = s.SavePhoto(jpegData, []byte(`{"admissible":`), "2026-02-01/153045_req-1", "req-1", "2026-02-01T15:30:45Z")
+	require.ErrorIs(t, err, ErrInvalidSave)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "a failed save should not leave a photo behind")
+}
+
+func TestSavePhoto_LeavesNoTemporaryFiles(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewPhotoStorage(dir)
+	require.NoError(t, err)
+
+	photoPath, err := s.SavePhoto(pngData, []byte(`{"admissible":true}`), "2026-02-01/153045_req-1", "req-1", "2026-02-01T15:30:45Z")
+	require.NoError(t, err)
+
+	entries, err := os.ReadDir(filepath.Dir(photoPath))
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	for _, entry := range entries {
+		assert.False(t, strings.HasPrefix(entry.Name(), "."), "temporary file %s left behind", entry.Name())
+		info, err := entry.Info()
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+	}
+}
+
+func TestSavePhoto_UsesStorageKey(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewPhotoStorage(dir)
+	require.NoError(t, err)
+
+	// The key, not the wall clock at save time, decides where the case lands
+	photoPath, err := s.SavePhoto(jpegData, []byte(`{"admissible":true}`), "2026-01-31/235959_req-1", "req-1", "2026-01-31T23:59:59Z")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "2026-01-31", "235959_req-1.jpg"), photoPath)
+	assert.FileExists(t, filepath.Join(dir, "2026-01-31", "235959_req-1.json"))
+}
+
+func TestSavePhoto_RejectsInvalidStorageKey(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewPhotoStorage(dir)
+	require.NoError(t, err)
+
+	for _, key := range []string{"2026-02-01/153045_other", "../153045_req-1", "2026-02-01/../../153045_req-1"} {
+		_, err := s.SavePhoto(jpegData, []byte(`{"admissible":true}`), key, "req-1", "2026-02-01T15:30:45Z")
+		assert.ErrorIs(t, err, ErrInvalidSave, key)
+	}
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
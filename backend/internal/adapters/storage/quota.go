@@ -0,0 +1,303 @@
+package storage
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"rechtebank/backend/internal/core/domain"
+)
+
+// Default quota watermarks, as fractions of the limits
+const (
+	DefaultQuotaHighWater = 0.9
+	DefaultQuotaLowWater  = 0.8
+)
+
+// quotaCleanupInterval is the least time between two early cleanups, so a
+// quota filled with cases that cannot be purged is not rescanned every save
+const quotaCleanupInterval = time.Minute
+
+// ErrStorageFull is returned by SavePhoto when a save would exceed the quota
+var ErrStorageFull = domain.NewError(domain.ErrCodeStorageFull, "storage quota reached")
+
+// QuotaConfig limits the space stored cases may take. A zero limit is
+// unlimited.
+type QuotaConfig struct {
+	MaxBytes int64
+	MaxFiles int64
+
+	// HighWater is the fraction of a limit at which the oldest unpublished
+	// cases are purged early, until usage is below LowWater again
+	HighWater float64
+	LowWater  float64
+}
+
+// reached reports whether usage has reached fraction of either limit
+func (c QuotaConfig) reached(u Usage, fraction float64) bool {
+	return (c.MaxBytes > 0 && float64(u.Bytes) >= fraction*float64(c.MaxBytes)) ||
+		(c.MaxFiles > 0 && float64(u.Files) >= fraction*float64(c.MaxFiles))
+}
+
+// Usage is the space taken by stored cases: verdicts, photos and variants
+type Usage struct {
+	Bytes int64 `json:"bytes"`
+	Files int64 `json:"files"`
+}
+
+// quotaState is the quota of a PhotoStorage and the usage counted against it
+type quotaState struct {
+	mu          sync.Mutex
+	config      QuotaConfig
+	usage       Usage
+	degraded    bool
+	cleaning    bool
+	lastCleanup time.Time
+}
+
+// EnableQuota counts the stored cases and enforces config from then on.
+// Usage is kept up to date by every change made through this PhotoStorage;
+// changes made by other processes, such as the admin CLI, are picked up by
+// RecountUsage.
+func (s *PhotoStorage) EnableQuota(config QuotaConfig) (Usage, error) {
+	if config.HighWater <= 0 || config.HighWater > 1 {
+		config.HighWater = DefaultQuotaHighWater
+	}
+	if config.LowWater <= 0 || config.LowWater > config.HighWater {
+		config.LowWater = min(DefaultQuotaLowWater, config.HighWater)
+	}
+
+	s.quota.mu.Lock()
+	s.quota.config = config
+	s.quota.mu.Unlock()
+	return s.RecountUsage()
+}
+
+// RecountUsage counts the files of the stored cases from scratch
+func (s *PhotoStorage) RecountUsage() (Usage, error) {
+	entries, err := os.ReadDir(s.basePath)
+	if err != nil {
+		return Usage{}, err
+	}
+
+	var usage Usage
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := time.Parse(dateDirLayout, entry.Name()); err != nil {
+			continue
+		}
+		dirUsage, err := countDir(filepath.Join(s.basePath, entry.Name()))
+		if err != nil {
+			return Usage{}, err
+		}
+		usage.Bytes += dirUsage.Bytes
+		usage.Files += dirUsage.Files
+	}
+
+	s.quota.mu.Lock()
+	s.quota.usage = usage
+	s.quota.mu.Unlock()
+	s.checkQuota()
+	return usage, nil
+}
+
+// countDir counts the case files in a date directory, leaving out
+// temporary files
+func countDir(dir string) (Usage, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return Usage{}, err
+	}
+	var usage Usage
+	for _, file := range files {
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+		if info, err := file.Info(); err == nil {
+			usage.Bytes += info.Size()
+			usage.Files++
+		}
+	}
+	return usage, nil
+}
+
+// Usage returns the space taken by stored cases
+func (s *PhotoStorage) Usage() Usage {
+	s.quota.mu.Lock()
+	defer s.quota.mu.Unlock()
+	return s.quota.usage
+}
+
+// Degraded reports whether storage is full: it has reached its quota and
+// has not been cleaned up below the low-water mark since
+func (s *PhotoStorage) Degraded() bool {
+	s.quota.mu.Lock()
+	defer s.quota.mu.Unlock()
+	return s.quota.degraded
+}
+
+// admit reports whether a save of the given size fits in the quota, and
+// starts an early cleanup when it does not
+func (s *PhotoStorage) admit(bytes, files int64) bool {
+	s.quota.mu.Lock()
+	defer s.quota.mu.Unlock()
+	c, u := s.quota.config, s.quota.usage
+	if (c.MaxBytes > 0 && u.Bytes+bytes > c.MaxBytes) || (c.MaxFiles > 0 && u.Files+files > c.MaxFiles) {
+		s.startCleanup()
+		return false
+	}
+	return true
+}
+
+// addUsage accounts for files written (positive) or removed (negative)
+func (s *PhotoStorage) addUsage(bytes, files int64) {
+	s.quota.mu.Lock()
+	s.quota.usage.Bytes += bytes
+	s.quota.usage.Files += files
+	s.quota.mu.Unlock()
+	s.checkQuota()
+}
+
+// checkQuota updates the degraded state and starts an early cleanup once
+// usage reaches the high-water mark
+func (s *PhotoStorage) checkQuota() {
+	s.quota.mu.Lock()
+	defer s.quota.mu.Unlock()
+	c, u := s.quota.config, s.quota.usage
+	switch {
+	case c.reached(u, 1):
+		s.setDegraded(true)
+	case !c.reached(u, c.LowWater):
+		s.setDegraded(false)
+	}
+	if c.reached(u, c.HighWater) {
+		s.startCleanup()
+	}
+}
+
+// setDegraded changes the degraded state; the caller holds quota.mu
+func (s *PhotoStorage) setDegraded(degraded bool) {
+	if degraded == s.quota.degraded {
+		return
+	}
+	s.quota.degraded = degraded
+	u := s.quota.usage
+	if degraded {
+		logger.Warn("Storage quota reached, verdicts are not stored",
+			slog.Int64("bytes", u.Bytes), slog.Int64("files", u.Files))
+	} else {
+		logger.Info("Storage back under quota",
+			slog.Int64("bytes", u.Bytes), slog.Int64("files", u.Files))
+	}
+}
+
+// startCleanup runs freeSpace in the background unless it is running or ran
+// recently; the caller holds quota.mu
+func (s *PhotoStorage) startCleanup() {
+	if s.quota.cleaning || time.Since(s.quota.lastCleanup) < quotaCleanupInterval {
+		return
+	}
+	s.quota.cleaning = true
+	go func() {
+		purged, err := s.freeSpace(time.Now())
+		if err != nil {
+			logger.Error("Early cleanup failed", slog.Any("error", err))
+		} else {
+			logger.Info("Early cleanup completed", slog.Int("purged", len(purged)))
+		}
+
+		s.quota.mu.Lock()
+		s.quota.cleaning = false
+		s.quota.lastCleanup = time.Now()
+		s.quota.mu.Unlock()
+	}()
+}
+
+// belowLowWater reports whether usage is below the low-water mark
+func (s *PhotoStorage) belowLowWater() bool {
+	s.quota.mu.Lock()
+	defer s.quota.mu.Unlock()
+	return !s.quota.config.reached(s.quota.usage, s.quota.config.LowWater)
+}
+
+// freeSpace purges the oldest unpublished cases until usage is below the
+// low-water mark, recording each in the purge log. Published cases and cases
+// on legal hold are kept, even if that leaves storage full.
+func (s *PhotoStorage) freeSpace(now time.Time) ([]PurgedCase, error) {
+	cases, err := s.ListCases()
+	if err != nil {
+		return nil, err
+	}
+
+	var purged []PurgedCase
+	for _, c := range cases {
+		if s.belowLowWater() {
+			break
+		}
+		class, err := s.RetentionClassOf(c)
+		if err != nil {
+			// Removed since it was listed
+			continue
+		}
+		if class == RetentionPublished || class == RetentionLegalHold {
+			continue
+		}
+
+		removed, err := s.DeleteCase(c)
+		entry := PurgedCase{
+			ID:        c.ID,
+			RequestID: c.RequestID(),
+			Class:     class,
+			Reason:    PurgeReasonQuota,
+			StoredOn:  c.Date.Format(dateDirLayout),
+			PurgedAt:  now.UTC(),
+			Files:     len(removed),
+		}
+		if err != nil {
+			return purged, err
+		}
+		if err := s.logPurge(entry); err != nil {
+			return purged, err
+		}
+		purged = append(purged, entry)
+	}
+	return purged, nil
+}
+
+// writeCaseFile writes a file of a case atomically and accounts for it
+func (s *PhotoStorage) writeCaseFile(path string, data []byte) error {
+	info, statErr := os.Stat(path)
+	if err := writeFileAtomic(path, data); err != nil {
+		return err
+	}
+	if statErr == nil {
+		s.addUsage(int64(len(data))-info.Size(), 0)
+	} else {
+		s.addUsage(int64(len(data)), 1)
+	}
+	return nil
+}
+
+// removeCaseFile removes a file of a case and accounts for it. A file that
+// does not exist is not an error.
+func (s *PhotoStorage) removeCaseFile(path string) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if info != nil {
+		s.addUsage(-info.Size(), -1)
+	}
+	return nil
+}
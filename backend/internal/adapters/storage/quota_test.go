@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"rechtebank/backend/internal/core/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnableQuota_CountsStoredCases(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string][]byte{
+		"2026-02-01/153045_req-1.json":       []byte(`{"admissible":true}`),
+		"2026-02-01/153045_req-1.jpg":        jpegData,
+		"2026-02-01/.tmp-123":                []byte("partial"),
+		".quarantine/2026-02-01/lost.jpg":    jpegData,
+		".queue/00000000000000000001_x.json": []byte(`{}`),
+	})
+	s, err := NewPhotoStorage(dir)
+	require.NoError(t, err)
+
+	usage, err := s.EnableQuota(QuotaConfig{MaxBytes: 1 << 20})
+	require.NoError(t, err)
+	assert.Equal(t, Usage{Bytes: int64(len(`{"admissible":true}`) + len(jpegData)), Files: 2}, usage)
+	assert.False(t, s.Degraded())
+}
+
+func TestQuota_AccountsIncrementally(t *testing.T) {
+	s, err := NewPhotoStorageWithVariants(t.TempDir(), testVariants)
+	require.NoError(t, err)
+	_, err = s.EnableQuota(QuotaConfig{MaxBytes: 1 << 30})
+	require.NoError(t, err)
+
+	// Every change keeps the counters equal to a full recount
+	assertCounted := func(msg string) {
+		t.Helper()
+		counted := s.Usage()
+		recounted, err := s.RecountUsage()
+		require.NoError(t, err)
+		assert.Equal(t, recounted, counted, msg)
+	}
+
+	_, err = s.SavePhoto(encodeJPEG(t, 200, 100), []byte(`{"admissible":true}`), "2026-02-01/153045_req-1", "req-1", "2026-02-01T15:30:45Z")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2+len(testVariants.Widths)*len(testVariants.Formats)), s.Usage().Files)
+	assertCounted("after save")
+
+	c, ok := s.Case("2026-02-01/153045_req-1")
+	require.True(t, ok)
+	require.NoError(t, s.SetPublished(c, true, time.Now()))
+	assertCounted("after publish")
+
+	_, err = s.DeleteCase(c)
+	require.NoError(t, err)
+	assert.Equal(t, Usage{}, s.Usage())
+	assertCounted("after delete")
+}
+
+func TestSavePhoto_RefusedOverQuota(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewPhotoStorage(dir)
+	require.NoError(t, err)
+	_, err = s.EnableQuota(QuotaConfig{MaxFiles: 3})
+	require.NoError(t, err)
+
+	_, err = s.SavePhoto(jpegData, []byte(`{"admissible":true}`), "2026-02-01/153045_req-1", "req-1", "2026-02-01T15:30:45Z")
+	require.NoError(t, err)
+
+	// A photo and its verdict do not fit in the one file left
+	_, err = s.SavePhoto(jpegData, []byte(`{"admissible":true}`), "2026-02-01/153046_req-2", "req-2", "2026-02-01T15:30:46Z")
+	assert.ErrorIs(t, err, ErrStorageFull)
+	assert.Equal(t, domain.ErrCodeStorageFull, domain.ErrorCodeOf(err))
+	_, ok := s.Case("2026-02-01/153046_req-2")
+	assert.False(t, ok)
+	assert.Equal(t, int64(2), s.Usage().Files)
+}
+
+func TestQuota_DegradedWhenNothingCanBePurged(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string][]byte{
+		"2026-02-01/153045_req-1.json": []byte(`{"admissible":true,"publishedAt":"2026-02-02T10:00:00Z"}`),
+		"2026-02-01/153045_req-1.jpg":  jpegData,
+	})
+	s, err := NewPhotoStorage(dir)
+	require.NoError(t, err)
+
+	_, err = s.EnableQuota(QuotaConfig{MaxFiles: 2})
+	require.NoError(t, err)
+	assert.True(t, s.Degraded())
+
+	_, err = s.SavePhoto(nil, []byte(`{"admissible":true}`), "2026-02-01/160000_req-2", "req-2", "2026-02-01T16:00:00Z")
+	assert.ErrorIs(t, err, ErrStorageFull)
+
+	// The published case is kept however full storage is
+	time.Sleep(10 * time.Millisecond)
+	_, ok := s.Case("2026-02-01/153045_req-1")
+	assert.True(t, ok)
+}
+
+func TestQuota_EarlyCleanupPurgesOldestUnpublished(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string][]byte{
+		"2026-01-01/100000_published.json": []byte(`{"admissible":true,"publishedAt":"2026-01-02T10:00:00Z"}`),
+		"2026-01-02/100000_held.json":      []byte(`{"admissible":true,"legalHold":true}`),
+		"2026-01-03/100000_a.json":         []byte(`{"admissible":true}`),
+		"2026-01-04/100000_b.json":         []byte(`{"admissible":false}`),
+		"2026-01-05/100000_c.json":         []byte(`{"admissible":true}`),
+		"2026-01-06/100000_d.json":         []byte(`{"admissible":true}`),
+	})
+	s, err := NewPhotoStorage(dir)
+	require.NoError(t, err)
+
+	// Six of six files: over the high-water mark, cleaned up to below 3.6
+	_, err = s.EnableQuota(QuotaConfig{MaxFiles: 6, HighWater: 0.9, LowWater: 0.6})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return !s.Degraded() }, 2*time.Second, 5*time.Millisecond)
+
+	log, err := s.PurgeLog()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2026-01-03/100000_a", "2026-01-04/100000_b", "2026-01-05/100000_c"}, purgedIDs(log))
+	for _, entry := range log {
+		assert.Equal(t, PurgeReasonQuota, entry.Reason)
+	}
+	assert.Equal(t, RetentionInadmissible, log[1].Class)
+	assert.Equal(t, int64(3), s.Usage().Files)
+
+	for _, id := range []string{"2026-01-01/100000_published", "2026-01-02/100000_held", "2026-01-06/100000_d"} {
+		_, ok := s.Case(id)
+		assert.True(t, ok, id)
+	}
+}
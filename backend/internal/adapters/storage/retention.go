@@ -0,0 +1,360 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"rechtebank/backend/internal/core/domain"
+)
+
+// RetentionClass is the retention tier a stored case falls in
+type RetentionClass string
+
+// Retention classes, from most to least protected
+const (
+	// RetentionLegalHold cases are never deleted by retention
+	RetentionLegalHold RetentionClass = "legal-hold"
+
+	// RetentionPublished cases are kept until they are unpublished
+	RetentionPublished RetentionClass = "published"
+
+	// RetentionShared cases are unpublished cases whose share ID was handed
+	// out; they are kept for a period counted from the first share
+	RetentionShared RetentionClass = "shared"
+
+	// RetentionVerdict cases are unpublished verdicts on admissible furniture
+	RetentionVerdict RetentionClass = "verdict"
+
+	// RetentionInadmissible cases are unpublished cases the court declared
+	// inadmissible
+	RetentionInadmissible RetentionClass = "inadmissible"
+)
+
+// Default retention periods of unpublished cases
+const (
+	DefaultInadmissibleRetentionDays = 7
+	DefaultVerdictRetentionDays      = 90
+	DefaultSharedRetentionDays       = 365
+)
+
+// RetentionPolicy sets how many days unpublished cases are kept, counted from
+// the day they were stored, or for shared cases from the day they were first
+// shared. Published cases and cases on legal hold are kept whatever the
+// policy.
+type RetentionPolicy struct {
+	InadmissibleDays int
+	VerdictDays      int
+	SharedDays       int
+}
+
+// retentionDays returns how many days cases of class are kept, and false for
+// classes that never expire
+func (p RetentionPolicy) retentionDays(class RetentionClass) (int, bool) {
+	switch class {
+	case RetentionInadmissible:
+		return p.InadmissibleDays, true
+	case RetentionVerdict:
+		return p.VerdictDays, true
+	case RetentionShared:
+		return p.SharedDays, true
+	default:
+		return 0, false
+	}
+}
+
+// retentionFields are the fields of a verdict JSON that decide its class
+type retentionFields struct {
+	Admissible  *bool  `json:"admissible"`
+	VerdictType string `json:"verdictType"`
+	PublishedAt string `json:"publishedAt"`
+	SharedAt    string `json:"sharedAt"`
+	LegalHold   bool   `json:"legalHold"`
+}
+
+// RetentionClassOf returns the retention class of a stored case. Cases whose
+// verdict cannot be parsed are treated as unpublished verdicts.
+func (s *PhotoStorage) RetentionClassOf(c StoredCase) (RetentionClass, error) {
+	fields, err := readRetentionFields(c)
+	if err != nil {
+		return "", err
+	}
+	return fields.class(), nil
+}
+
+// readRetentionFields reads the fields of a case's verdict that decide its
+// class; they are zero when the verdict cannot be parsed
+func readRetentionFields(c StoredCase) (retentionFields, error) {
+	data, err := os.ReadFile(c.JSONPath)
+	if err != nil {
+		return retentionFields{}, fmt.Errorf("failed to read verdict %s: %w", c.ID, err)
+	}
+	var fields retentionFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return retentionFields{}, nil
+	}
+	return fields, nil
+}
+
+// class returns the retention class of a verdict with these fields
+func (fields retentionFields) class() RetentionClass {
+	switch {
+	case fields.LegalHold:
+		return RetentionLegalHold
+	case fields.PublishedAt != "":
+		return RetentionPublished
+	case fields.SharedAt != "":
+		return RetentionShared
+	case (fields.Admissible != nil && !*fields.Admissible) || fields.VerdictType == domain.VerdictTypeNietOntvankelijk:
+		return RetentionInadmissible
+	default:
+		return RetentionVerdict
+	}
+}
+
+// retentionStart returns the day a case's retention period is counted from:
+// the day it was first shared for shared cases, else the day it was stored
+func (fields retentionFields) retentionStart(c StoredCase) time.Time {
+	if fields.class() == RetentionShared {
+		if sharedAt, err := time.Parse(time.RFC3339, fields.SharedAt); err == nil {
+			return sharedAt.UTC().Truncate(24 * time.Hour)
+		}
+	}
+	return c.Date
+}
+
+// SetPublished publishes a case as of at, or unpublishes it, which makes it
+// subject to retention again
+func (s *PhotoStorage) SetPublished(c StoredCase, published bool, at time.Time) error {
+	return s.updateVerdictJSON(c.JSONPath, func(fields map[string]any) {
+		if published {
+			fields["publishedAt"] = at.UTC().Format(time.RFC3339)
+		} else {
+			delete(fields, "publishedAt")
+		}
+	})
+}
+
+// MarkShared records that the share ID of the case stored under key was
+// handed out at at, moving it to the shared retention class. Only the first
+// share is recorded, so sharing again does not extend its retention.
+func (s *PhotoStorage) MarkShared(key string, at time.Time) error {
+	c, ok := s.Case(key)
+	if !ok {
+		return fmt.Errorf("case %s: %w", key, os.ErrNotExist)
+	}
+	fields, err := readRetentionFields(c)
+	if err != nil {
+		return err
+	}
+	if fields.SharedAt != "" {
+		return nil
+	}
+	return s.updateVerdictJSON(c.JSONPath, func(fields map[string]any) {
+		fields["sharedAt"] = at.UTC().Format(time.RFC3339)
+	})
+}
+
+// SetLegalHold places a case on legal hold, or releases it
+func (s *PhotoStorage) SetLegalHold(c StoredCase, hold bool) error {
+	return s.updateVerdictJSON(c.JSONPath, func(fields map[string]any) {
+		if hold {
+			fields["legalHold"] = true
+		} else {
+			delete(fields, "legalHold")
+		}
+	})
+}
+
+// updateVerdictJSON rewrites a verdict JSON atomically after applying update
+func (s *PhotoStorage) updateVerdictJSON(jsonPath string, update func(map[string]any)) error {
+	data, err := os.ReadFile(jsonPath)
+	if err != nil {
+		return fmt.Errorf("failed to read JSON %s: %w", jsonPath, err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("failed to parse JSON %s: %w", jsonPath, err)
+	}
+	update(fields)
+	updated, err := json.MarshalIndent(fields, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	if err := s.writeCaseFile(jsonPath, updated); err != nil {
+		return fmt.Errorf("failed to write JSON %s: %w", jsonPath, err)
+	}
+	return nil
+}
+
+// purgeLogName is the file in the storage directory that records every case
+// removed by retention, one JSON object per line
+const purgeLogName = ".purge.log"
+
+// PurgeReason records why a case was purged
+type PurgeReason string
+
+// Reasons a case is purged
+const (
+	// PurgeReasonRetention cases were past their retention period
+	PurgeReasonRetention PurgeReason = "retention"
+
+	// PurgeReasonQuota cases were purged early to bring storage back under
+	// its quota
+	PurgeReasonQuota PurgeReason = "quota"
+)
+
+// PurgedCase is a case removed by retention or early cleanup, as recorded
+// in the purge log
+type PurgedCase struct {
+	ID        string         `json:"id"`
+	RequestID string         `json:"requestId"`
+	Class     RetentionClass `json:"class"`
+	Reason    PurgeReason    `json:"reason"`
+	StoredOn  string         `json:"storedOn"`
+	PurgedAt  time.Time      `json:"purgedAt"`
+	Files     int            `json:"files"`
+}
+
+// ApplyRetention removes the cases policy has expired as of now, records
+// each in the purge log and returns them, oldest first. With dryRun nothing
+// is removed or logged, and the cases that would be removed are returned.
+// It stops between cases when ctx is done, returning the cases purged so far.
+// A case whose class cannot be read is logged and kept.
+func (s *PhotoStorage) ApplyRetention(ctx context.Context, policy RetentionPolicy, now time.Time, dryRun bool) ([]PurgedCase, error) {
+	cases, err := s.ListCases()
+	if err != nil {
+		return nil, err
+	}
+
+	var purged []PurgedCase
+	kept := map[string]bool{}
+	for _, c := range cases {
+		if err := ctx.Err(); err != nil {
+			return purged, err
+		}
+		fields, err := readRetentionFields(c)
+		if err != nil {
+			logger.Warn("Skipping case in retention", slog.String("id", c.ID), slog.Any("error", err))
+			kept[c.Date.Format(dateDirLayout)] = true
+			continue
+		}
+		class := fields.class()
+		days, expires := policy.retentionDays(class)
+		if !expires || !fields.retentionStart(c).Before(now.AddDate(0, 0, -days)) {
+			kept[c.Date.Format(dateDirLayout)] = true
+			continue
+		}
+
+		entry := PurgedCase{
+			ID:        c.ID,
+			RequestID: c.RequestID(),
+			Class:     class,
+			Reason:    PurgeReasonRetention,
+			StoredOn:  c.Date.Format(dateDirLayout),
+			PurgedAt:  now.UTC(),
+		}
+		if dryRun {
+			files, err := s.CaseFiles(c)
+			if err != nil {
+				return purged, err
+			}
+			entry.Files = len(files)
+			purged = append(purged, entry)
+			continue
+		}
+
+		removed, err := s.DeleteCase(c)
+		entry.Files = len(removed)
+		if err != nil {
+			return purged, err
+		}
+		if err := s.logPurge(entry); err != nil {
+			return purged, err
+		}
+		purged = append(purged, entry)
+	}
+
+	if !dryRun {
+		if err := s.removeExpiredDays(policy, now, kept); err != nil {
+			return purged, err
+		}
+	}
+	return purged, nil
+}
+
+// removeExpiredDays removes the day directories past every retention period
+// that hold no kept case. What is left in them are the remains of
+// interrupted saves and deletes.
+func (s *PhotoStorage) removeExpiredDays(policy RetentionPolicy, now time.Time, kept map[string]bool) error {
+	cutoff := now.AddDate(0, 0, -max(policy.InadmissibleDays, policy.VerdictDays))
+	entries, err := os.ReadDir(s.basePath)
+	if err != nil {
+		return fmt.Errorf("failed to read storage directory: %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || kept[entry.Name()] {
+			continue
+		}
+		date, err := time.Parse(dateDirLayout, entry.Name())
+		if err != nil || !date.Before(cutoff) {
+			continue
+		}
+		dir := filepath.Join(s.basePath, entry.Name())
+		leftovers, _ := countDir(dir)
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("failed to remove old directory %s: %w", dir, err)
+		}
+		s.addUsage(-leftovers.Bytes, -leftovers.Files)
+	}
+	return nil
+}
+
+// logPurge appends a purged case to the purge log
+func (s *PhotoStorage) logPurge(entry PurgedCase) error {
+	logger.Info("Purged case",
+		slog.String("id", entry.ID),
+		slog.String("class", string(entry.Class)),
+		slog.Int("files", entry.Files))
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(s.basePath, purgeLogName), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open purge log: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write purge log: %w", err)
+	}
+	return f.Close()
+}
+
+// PurgeLog returns the purge log, oldest first
+func (s *PhotoStorage) PurgeLog() ([]PurgedCase, error) {
+	data, err := os.ReadFile(filepath.Join(s.basePath, purgeLogName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read purge log: %w", err)
+	}
+
+	var entries []PurgedCase
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for decoder.More() {
+		var entry PurgedCase
+		if err := decoder.Decode(&entry); err != nil {
+			return entries, fmt.Errorf("failed to parse purge log: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
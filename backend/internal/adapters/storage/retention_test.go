@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testPolicy = RetentionPolicy{InadmissibleDays: 7, VerdictDays: 90, SharedDays: 30}
+
+func TestRetentionClassOf(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewPhotoStorage(dir)
+	require.NoError(t, err)
+
+	tests := []struct {
+		verdict string
+		want    RetentionClass
+	}{
+		{`{"admissible":true,"verdictType":"schuldig"}`, RetentionVerdict},
+		{`{"admissible":false,"verdictType":"niet-ontvankelijk"}`, RetentionInadmissible},
+		{`{"verdictType":"niet-ontvankelijk"}`, RetentionInadmissible},
+		{`{"admissible":false,"publishedAt":"2026-02-01T15:30:45Z"}`, RetentionPublished},
+		{`{"admissible":true,"publishedAt":"2026-02-01T15:30:45Z","legalHold":true}`, RetentionLegalHold},
+		{`{"admissible":false,"sharedAt":"2026-02-01T15:30:45Z"}`, RetentionShared},
+		{`{"admissible":true,"sharedAt":"2026-02-01T15:30:45Z","publishedAt":"2026-02-01T15:30:45Z"}`, RetentionPublished},
+		{`{"admissible":`, RetentionVerdict},
+	}
+	for _, tt := range tests {
+		writeFiles(t, dir, map[string][]byte{"2026-02-01/153045_req-1.json": []byte(tt.verdict)})
+		c, ok := s.Case("2026-02-01/153045_req-1")
+		require.True(t, ok)
+
+		class, err := s.RetentionClassOf(c)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, class, tt.verdict)
+	}
+}
+
+func TestSetPublishedAndLegalHold(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewPhotoStorage(dir)
+	require.NoError(t, err)
+	writeFiles(t, dir, map[string][]byte{"2026-02-01/153045_req-1.json": []byte(`{"admissible":false,"score":0}`)})
+	c, _ := s.Case("2026-02-01/153045_req-1")
+
+	require.NoError(t, s.SetPublished(c, true, time.Date(2026, 2, 2, 10, 0, 0, 0, time.UTC)))
+	class, _ := s.RetentionClassOf(c)
+	assert.Equal(t, RetentionPublished, class)
+	verdict := readJSON(t, c.JSONPath)
+	assert.Equal(t, "2026-02-02T10:00:00Z", verdict["publishedAt"])
+	assert.Equal(t, float64(0), verdict["score"])
+
+	require.NoError(t, s.SetLegalHold(c, true))
+	class, _ = s.RetentionClassOf(c)
+	assert.Equal(t, RetentionLegalHold, class)
+
+	require.NoError(t, s.SetLegalHold(c, false))
+	require.NoError(t, s.SetPublished(c, false, time.Time{}))
+	class, _ = s.RetentionClassOf(c)
+	assert.Equal(t, RetentionInadmissible, class)
+	assert.NotContains(t, readJSON(t, c.JSONPath), "publishedAt")
+}
+
+func TestMarkShared(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewPhotoStorage(dir)
+	require.NoError(t, err)
+	writeFiles(t, dir, map[string][]byte{
+		"2026-02-01/153045_req-1.json": []byte(`{"admissible":false}`),
+	})
+
+	require.NoError(t, s.MarkShared("2026-02-01/153045_req-1", time.Date(2026, 2, 2, 10, 0, 0, 0, time.UTC)))
+	c, _ := s.Case("2026-02-01/153045_req-1")
+	class, _ := s.RetentionClassOf(c)
+	assert.Equal(t, RetentionShared, class)
+	assert.NotContains(t, readJSON(t, c.JSONPath), "publishedAt")
+
+	// Sharing again does not extend retention
+	require.NoError(t, s.MarkShared("2026-02-01/153045_req-1", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, "2026-02-02T10:00:00Z", readJSON(t, c.JSONPath)["sharedAt"])
+
+	assert.ErrorIs(t, s.MarkShared("2026-02-01/170000_missing", time.Now()), os.ErrNotExist)
+}
+
+func TestApplyRetention(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewPhotoStorage(dir)
+	require.NoError(t, err)
+
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	recent := now.AddDate(0, 0, -10).Format(dateDirLayout)
+	old := now.AddDate(0, 0, -100).Format(dateDirLayout)
+	writeFiles(t, dir, map[string][]byte{
+		recent + "/100000_inadmissible.json": []byte(`{"admissible":false}`),
+		recent + "/100000_inadmissible.jpg":  jpegData,
+		recent + "/110000_verdict.json":      []byte(`{"admissible":true}`),
+		recent + "/120000_published.json":    []byte(`{"admissible":false,"publishedAt":"2026-05-23T10:00:00Z"}`),
+		old + "/100000_old-verdict.json":     []byte(`{"admissible":true}`),
+		old + "/110000_old-held.json":        []byte(`{"admissible":true,"legalHold":true}`),
+		old + "/120000_old-published.json":   []byte(`{"admissible":true,"publishedAt":"2026-02-22T10:00:00Z"}`),
+		old + "/130000_old-shared.json":      []byte(`{"admissible":true,"sharedAt":"2026-02-22T10:00:00Z"}`),
+		old + "/140000_recently-shared.json": []byte(`{"admissible":true,"sharedAt":"2026-05-20T10:00:00Z"}`),
+		recent + "/130000_shared.json":       []byte(`{"admissible":false,"sharedAt":"2026-05-23T10:00:00Z"}`),
+	})
+	// Shared cases are kept for 30 days from their first share, whatever their class
+	expired := []string{old + "/100000_old-verdict", old + "/130000_old-shared", recent + "/100000_inadmissible"}
+
+	// A dry run reports without removing or logging anything
+	wouldPurge, err := s.ApplyRetention(context.Background(), testPolicy, now, true)
+	require.NoError(t, err)
+	assert.Equal(t, expired, purgedIDs(wouldPurge))
+	assert.Equal(t, RetentionShared, wouldPurge[1].Class)
+	assert.Equal(t, RetentionInadmissible, wouldPurge[2].Class)
+	assert.Equal(t, 2, wouldPurge[2].Files)
+	assert.FileExists(t, filepath.Join(dir, recent, "100000_inadmissible.json"))
+	log, err := s.PurgeLog()
+	require.NoError(t, err)
+	assert.Empty(t, log)
+
+	purged, err := s.ApplyRetention(context.Background(), testPolicy, now, false)
+	require.NoError(t, err)
+	assert.Equal(t, wouldPurge, purged)
+
+	cases, err := s.ListCases()
+	require.NoError(t, err)
+	var left []string
+	for _, c := range cases {
+		left = append(left, c.ID)
+	}
+	assert.ElementsMatch(t, []string{
+		recent + "/110000_verdict",
+		recent + "/120000_published",
+		old + "/110000_old-held",
+		old + "/120000_old-published",
+		old + "/140000_recently-shared",
+		recent + "/130000_shared",
+	}, left)
+
+	log, err = s.PurgeLog()
+	require.NoError(t, err)
+	assert.Equal(t, expired, purgedIDs(log))
+	assert.Equal(t, now, log[0].PurgedAt)
+
+	// Nothing left to purge
+	purged, err = s.ApplyRetention(context.Background(), testPolicy, now, false)
+	require.NoError(t, err)
+	assert.Empty(t, purged)
+}
+
+func TestApplyRetention_SkipsUnreadableCases(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewPhotoStorage(dir)
+	require.NoError(t, err)
+
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	old := now.AddDate(0, 0, -100).Format(dateDirLayout)
+	writeFiles(t, dir, map[string][]byte{
+		old + "/100000_old-verdict.json": []byte(`{"admissible":true}`),
+		old + "/120000_later.json":       []byte(`{"admissible":true}`),
+	})
+	// A verdict that cannot be read
+	require.NoError(t, os.Mkdir(filepath.Join(dir, old, "110000_broken.json"), 0755))
+
+	purged, err := s.ApplyRetention(context.Background(), testPolicy, now, false)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{old + "/100000_old-verdict", old + "/120000_later"}, purgedIDs(purged))
+	assert.DirExists(t, filepath.Join(dir, old, "110000_broken.json"), "the day of a skipped case stays intact")
+}
+
+func TestApplyRetention_StopsWhenCancelled(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewPhotoStorage(dir)
+	require.NoError(t, err)
+
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	old := now.AddDate(0, 0, -100).Format(dateDirLayout)
+	writeFiles(t, dir, map[string][]byte{old + "/100000_old-verdict.json": []byte(`{"admissible":true}`)})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	purged, err := s.ApplyRetention(ctx, testPolicy, now, false)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, purged)
+	assert.FileExists(t, filepath.Join(dir, old, "100000_old-verdict.json"))
+}
+
+func TestApplyRetention_RemovesLeftoversOfExpiredDays(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewPhotoStorage(dir)
+	require.NoError(t, err)
+
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	old := now.AddDate(0, 0, -100).Format(dateDirLayout)
+	held := now.AddDate(0, 0, -200).Format(dateDirLayout)
+	writeFiles(t, dir, map[string][]byte{
+		old + "/100000_lost.jpg":      jpegData,
+		held + "/100000_held.json":    []byte(`{"legalHold":true}`),
+		held + "/110000_orphan.jpg":   jpegData,
+		".quarantine/x/100000_x.json": []byte(`{}`),
+	})
+
+	_, err = s.ApplyRetention(context.Background(), testPolicy, now, false)
+	require.NoError(t, err)
+
+	_, err = os.Stat(filepath.Join(dir, old))
+	assert.True(t, os.IsNotExist(err))
+	assert.FileExists(t, filepath.Join(dir, held, "110000_orphan.jpg"), "a day with a kept case stays intact")
+	assert.FileExists(t, filepath.Join(dir, ".quarantine/x/100000_x.json"))
+}
+
+func purgedIDs(purged []PurgedCase) []string {
+	var ids []string
+	for _, p := range purged {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
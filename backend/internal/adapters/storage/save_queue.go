@@ -0,0 +1,414 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// deadLetterDir is the directory under the queue directory that holds saves
+// which kept failing
+const deadLetterDir = "dead-letter"
+
+// saveLeaseTTL is how long a replica may hold a queued save it is saving
+const saveLeaseTTL = time.Minute
+
+// PhotoSaver writes a verdict and its photo to permanent storage
+type PhotoSaver interface {
+	SavePhoto(imageData []byte, llmResponse []byte, storageKey string, requestID string, timestamp string) (string, error)
+}
+
+// degradable is implemented by savers that can run out of space
+type degradable interface {
+	Degraded() bool
+}
+
+// QueueLocker keeps replicas sharing a queue directory from saving the same
+// queued save at once; scheduler.FileLocker implements it
+type QueueLocker interface {
+	// TryLock takes the lock on name for at most ttl. It reports false when
+	// another holder has it; unlock gives it up early.
+	TryLock(name string, ttl time.Duration) (unlock func(), ok bool, err error)
+}
+
+// QueueConfig configures retries of the save queue
+type QueueConfig struct {
+	// MaxAttempts is how often a save is tried before it is dead-lettered
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry; it doubles with
+	// every further attempt up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// Locker claims a queued save before it is attempted. It is required
+	// when several replicas share the queue directory; without it every
+	// queued save is attempted by this process.
+	Locker QueueLocker
+}
+
+// Default save queue settings
+const (
+	DefaultQueueMaxAttempts    = 8
+	DefaultQueueInitialBackoff = time.Second
+	DefaultQueueMaxBackoff     = 5 * time.Minute
+)
+
+// queuedSave is a pending save as written to the queue directory
+type queuedSave struct {
+	StorageKey  string    `json:"storageKey,omitempty"`
+	RequestID   string    `json:"requestId"`
+	Timestamp   string    `json:"timestamp"`
+	Verdict     []byte    `json:"verdict"`
+	Photo       []byte    `json:"photo,omitempty"`
+	EnqueuedAt  time.Time `json:"enqueuedAt"`
+	Attempts    int       `json:"attempts"`
+	NextAttempt time.Time `json:"nextAttempt"`
+	LastError   string    `json:"lastError,omitempty"`
+}
+
+// SaveQueue is a write-ahead queue in front of a PhotoSaver. SavePhoto only
+// persists the save to the queue directory; a worker started with Start
+// hands it to the saver, retrying failures with exponential backoff, and
+// moves saves that keep failing, or can never succeed, to the dead-letter
+// directory. Saves still queued when the process stops are picked up again on
+// the next start, so a save may reach the saver more than once after a crash.
+// The queue directory is the only state, so replicas sharing it also share
+// their saves, claiming each one through QueueConfig.Locker.
+type SaveQueue struct {
+	dir    string
+	saver  PhotoSaver
+	config QueueConfig
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+
+	now func() time.Time
+}
+
+// NewSaveQueue opens the queue in dir, creating it if needed. Saves left there
+// by a previous run are attempted once the worker starts.
+func NewSaveQueue(dir string, saver PhotoSaver, config QueueConfig) (*SaveQueue, error) {
+	if err := os.MkdirAll(filepath.Join(dir, deadLetterDir), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create queue directory: %w", err)
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = DefaultQueueMaxAttempts
+	}
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = DefaultQueueInitialBackoff
+	}
+	if config.MaxBackoff < config.InitialBackoff {
+		config.MaxBackoff = max(DefaultQueueMaxBackoff, config.InitialBackoff)
+	}
+
+	return &SaveQueue{
+		dir:    dir,
+		saver:  saver,
+		config: config,
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		now:    time.Now,
+	}, nil
+}
+
+// SavePhoto queues a save. It returns once the save is durably written to
+// the queue directory; the returned path is that of the queue file.
+func (q *SaveQueue) SavePhoto(imageData []byte, llmResponse []byte, storageKey string, requestID string, timestamp string) (string, error) {
+	if requestID == "" || strings.ContainsAny(requestID, `/\`) || strings.HasPrefix(requestID, ".") {
+		return "", fmt.Errorf("invalid request ID %q", requestID)
+	}
+	// Queued saves would only fill the disk further
+	if q.Degraded() {
+		return "", ErrStorageFull
+	}
+
+	now := q.now()
+	item := queuedSave{
+		StorageKey:  storageKey,
+		RequestID:   requestID,
+		Timestamp:   timestamp,
+		Verdict:     llmResponse,
+		Photo:       imageData,
+		EnqueuedAt:  now.UTC(),
+		NextAttempt: now.UTC(),
+	}
+	// The enqueue time keeps the files in arrival order
+	name := fmt.Sprintf("%020d_%s.json", now.UnixNano(), requestID)
+	if err := q.write(name, item); err != nil {
+		return "", fmt.Errorf("failed to queue save: %w", err)
+	}
+	if err := syncDir(q.dir); err != nil {
+		logger.Warn("Failed to sync queue directory", slog.Any("error", err))
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return filepath.Join(q.dir, name), nil
+}
+
+// Degraded reports whether the saver is full, in which case SavePhoto
+// refuses new saves
+func (q *SaveQueue) Degraded() bool {
+	d, ok := q.saver.(degradable)
+	return ok && d.Degraded()
+}
+
+// IsPending reports whether a save for requestID is still queued, by any
+// replica sharing the queue directory
+func (q *SaveQueue) IsPending(requestID string) bool {
+	files, err := q.queuedFiles()
+	if err != nil {
+		logger.Error("Failed to read save queue", slog.Any("error", err))
+		return false
+	}
+	for _, name := range files {
+		if requestIDOfQueueFile(name) == requestID {
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns the number of queued saves
+func (q *SaveQueue) Len() int {
+	files, err := q.queuedFiles()
+	if err != nil {
+		logger.Error("Failed to read save queue", slog.Any("error", err))
+		return 0
+	}
+	return len(files)
+}
+
+// Start runs the worker that hands queued saves to the saver
+func (q *SaveQueue) Start() {
+	go q.run()
+}
+
+// Close stops the worker after a last attempt at every queued save that is
+// due, or when ctx is done. Saves that are left stay in the queue directory.
+func (q *SaveQueue) Close(ctx context.Context) error {
+	close(q.stop)
+	select {
+	case <-q.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if left := q.Len(); left > 0 {
+		logger.Warn("Saves left in queue", slog.Int("count", left))
+	}
+	return nil
+}
+
+func (q *SaveQueue) run() {
+	defer close(q.done)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		wait := q.processDue()
+
+		timer.Reset(wait)
+		select {
+		case <-q.stop:
+			q.processDue()
+			return
+		case <-q.wake:
+		case <-timer.C:
+		}
+	}
+}
+
+// processDue tries every save whose next attempt is due and returns how long
+// to wait for the next one
+func (q *SaveQueue) processDue() time.Duration {
+	files, err := q.queuedFiles()
+	if err != nil {
+		logger.Error("Failed to read save queue", slog.Any("error", err))
+		return q.config.InitialBackoff
+	}
+
+	wait := q.config.MaxBackoff
+	for _, name := range files {
+		if next, retry := q.process(name); retry {
+			wait = min(wait, next)
+		}
+	}
+	return wait
+}
+
+// process claims a queued save and attempts it if it is due. It reports
+// whether the save stays queued, and when it is due again.
+func (q *SaveQueue) process(name string) (time.Duration, bool) {
+	unlock, ok, err := q.claim(name)
+	if err != nil {
+		logger.Error("Failed to claim queued save", slog.String("file", name), slog.Any("error", err))
+		return q.config.InitialBackoff, true
+	}
+	if !ok {
+		// Another replica is saving it
+		return 0, false
+	}
+	defer unlock()
+
+	item, err := q.read(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Saved by another replica since the queue was listed
+		return 0, false
+	}
+	if err != nil {
+		// An unreadable entry can never be saved
+		logger.Error("Unreadable queued save", slog.String("file", name), slog.Any("error", err))
+		q.deadLetter(name)
+		return 0, false
+	}
+
+	if until := item.NextAttempt.Sub(q.now()); until > 0 {
+		return until, true
+	}
+	return q.attempt(name, item)
+}
+
+// claim takes the lease on a queued save, so no other replica attempts it
+// meanwhile
+func (q *SaveQueue) claim(name string) (func(), bool, error) {
+	if q.config.Locker == nil {
+		return func() {}, true, nil
+	}
+	return q.config.Locker.TryLock(strings.TrimSuffix(name, ".json"), saveLeaseTTL)
+}
+
+// attempt hands one queued save to the saver. It reports whether the save
+// stays queued, and when it is due again.
+func (q *SaveQueue) attempt(name string, item queuedSave) (time.Duration, bool) {
+	// The key fixed when the verdict was reached keeps retried saves findable
+	_, err := q.saver.SavePhoto(item.Photo, item.Verdict, item.StorageKey, item.RequestID, item.Timestamp)
+	if err == nil {
+		if err := os.Remove(filepath.Join(q.dir, name)); err != nil && !os.IsNotExist(err) {
+			logger.Error("Failed to remove saved queue entry", slog.String("file", name), slog.Any("error", err))
+		}
+		return 0, false
+	}
+
+	item.LastError = err.Error()
+	if errors.Is(err, ErrStorageFull) {
+		// Not the save's fault: hold it until cleanup has made room
+		item.NextAttempt = q.now().Add(q.config.MaxBackoff).UTC()
+		logger.Warn("Storage full, holding save",
+			slog.String("request_id", item.RequestID),
+			slog.Duration("retry_in", q.config.MaxBackoff))
+		if err := q.write(name, item); err != nil {
+			logger.Error("Failed to update queued save", slog.String("file", name), slog.Any("error", err))
+		}
+		return q.config.MaxBackoff, true
+	}
+
+	item.Attempts++
+	if item.Attempts >= q.config.MaxAttempts || errors.Is(err, ErrInvalidSave) {
+		logger.Error("Giving up on save",
+			slog.String("request_id", item.RequestID),
+			slog.Int("attempts", item.Attempts),
+			slog.Any("error", err))
+		if err := q.write(name, item); err != nil {
+			logger.Error("Failed to update queued save", slog.String("file", name), slog.Any("error", err))
+		}
+		q.deadLetter(name)
+		return 0, false
+	}
+
+	backoff := q.backoff(item.Attempts)
+	item.NextAttempt = q.now().Add(backoff).UTC()
+	logger.Warn("Save failed, will retry",
+		slog.String("request_id", item.RequestID),
+		slog.Int("attempt", item.Attempts),
+		slog.Duration("backoff", backoff),
+		slog.Any("error", err))
+	if err := q.write(name, item); err != nil {
+		logger.Error("Failed to update queued save", slog.String("file", name), slog.Any("error", err))
+	}
+	return backoff, true
+}
+
+// backoff returns the delay after the given number of failed attempts
+func (q *SaveQueue) backoff(attempts int) time.Duration {
+	backoff := q.config.InitialBackoff
+	for i := 1; i < attempts && backoff < q.config.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, q.config.MaxBackoff)
+}
+
+// deadLetter moves a queue file to the dead-letter directory
+func (q *SaveQueue) deadLetter(name string) {
+	if err := os.Rename(filepath.Join(q.dir, name), filepath.Join(q.dir, deadLetterDir, name)); err != nil {
+		logger.Error("Failed to dead-letter queued save", slog.String("file", name), slog.Any("error", err))
+	}
+}
+
+// DeadLetters returns the names of the saves that were given up on
+func (q *SaveQueue) DeadLetters() ([]string, error) {
+	return listQueueFiles(filepath.Join(q.dir, deadLetterDir))
+}
+
+func (q *SaveQueue) queuedFiles() ([]string, error) {
+	return listQueueFiles(q.dir)
+}
+
+func (q *SaveQueue) read(name string) (queuedSave, error) {
+	var item queuedSave
+	data, err := os.ReadFile(filepath.Join(q.dir, name))
+	if err != nil {
+		return item, err
+	}
+	if err := json.Unmarshal(data, &item); err != nil {
+		return item, err
+	}
+	if item.RequestID == "" {
+		return item, errors.New("missing request ID")
+	}
+	return item, nil
+}
+
+func (q *SaveQueue) write(name string, item queuedSave) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(q.dir, name), data)
+}
+
+// listQueueFiles returns the queue files in dir, oldest first
+func listQueueFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read queue directory: %w", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != ".json" {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// requestIDOfQueueFile extracts the request ID from "<nanos>_<requestID>.json"
+func requestIDOfQueueFile(name string) string {
+	_, requestID, _ := strings.Cut(strings.TrimSuffix(name, ".json"), "_")
+	return requestID
+}
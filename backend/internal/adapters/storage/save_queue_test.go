@@ -0,0 +1,278 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"rechtebank/backend/internal/scheduler"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakySaver fails the first failures saves of every request, with err or
+// a transient error
+type flakySaver struct {
+	failures int
+	err      error
+
+	mu       sync.Mutex
+	attempts map[string]int
+	saved    []string
+	keys     []string
+}
+
+func (s *flakySaver) SavePhoto(imageData []byte, llmResponse []byte, storageKey string, requestID string, timestamp string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.attempts == nil {
+		s.attempts = map[string]int{}
+	}
+	s.attempts[requestID]++
+	if s.attempts[requestID] <= s.failures {
+		if s.err != nil {
+			return "", s.err
+		}
+		return "", errors.New("disk full")
+	}
+	s.saved = append(s.saved, requestID)
+	s.keys = append(s.keys, storageKey)
+	return "", nil
+}
+
+func (s *flakySaver) attemptsOf(requestID string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attempts[requestID]
+}
+
+func (s *flakySaver) savedRequests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.saved...)
+}
+
+var fastRetries = QueueConfig{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+
+func TestSaveQueue_SavesQueuedPhotos(t *testing.T) {
+	saver := &flakySaver{}
+	q, err := NewSaveQueue(t.TempDir(), saver, fastRetries)
+	require.NoError(t, err)
+
+	path, err := q.SavePhoto(jpegData, []byte(`{"score":7}`), "2026-02-01/153045_req-1", "req-1", "2026-02-01T15:30:45Z")
+	require.NoError(t, err)
+	assert.FileExists(t, path)
+	assert.True(t, q.IsPending("req-1"))
+
+	q.Start()
+	require.Eventually(t, func() bool { return !q.IsPending("req-1") }, 2*time.Second, 5*time.Millisecond)
+	require.NoError(t, q.Close(context.Background()))
+
+	assert.Equal(t, []string{"req-1"}, saver.savedRequests())
+	assert.Equal(t, []string{"2026-02-01/153045_req-1"}, saver.keys)
+	assert.NoFileExists(t, path)
+}
+
+func TestSaveQueue_RetriesFailedSaves(t *testing.T) {
+	saver := &flakySaver{failures: 2}
+	q, err := NewSaveQueue(t.TempDir(), saver, fastRetries)
+	require.NoError(t, err)
+	q.Start()
+	defer q.Close(context.Background())
+
+	_, err = q.SavePhoto(jpegData, []byte(`{}`), "2026-02-01/153045_req-1", "req-1", "2026-02-01T15:30:45Z")
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool { return !q.IsPending("req-1") }, 2*time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"req-1"}, saver.savedRequests())
+	dead, err := q.DeadLetters()
+	require.NoError(t, err)
+	assert.Empty(t, dead)
+}
+
+func TestSaveQueue_DeadLettersSavesThatKeepFailing(t *testing.T) {
+	dir := t.TempDir()
+	q, err := NewSaveQueue(dir, &flakySaver{failures: 100}, fastRetries)
+	require.NoError(t, err)
+	q.Start()
+	defer q.Close(context.Background())
+
+	_, err = q.SavePhoto(jpegData, []byte(`{}`), "2026-02-01/153045_req-1", "req-1", "2026-02-01T15:30:45Z")
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool { return !q.IsPending("req-1") }, 2*time.Second, 5*time.Millisecond)
+	dead, err := q.DeadLetters()
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+
+	data, err := os.ReadFile(filepath.Join(dir, deadLetterDir, dead[0]))
+	require.NoError(t, err)
+	var item queuedSave
+	require.NoError(t, json.Unmarshal(data, &item))
+	assert.Equal(t, "req-1", item.RequestID)
+	assert.Equal(t, 3, item.Attempts)
+	assert.Equal(t, "disk full", item.LastError)
+	assert.Equal(t, jpegData, item.Photo)
+}
+
+func TestSaveQueue_DeadLettersInvalidSavesAtOnce(t *testing.T) {
+	dir := t.TempDir()
+	saver := &flakySaver{failures: 100, err: fmt.Errorf("%w: invalid storage key", ErrInvalidSave)}
+	q, err := NewSaveQueue(dir, saver, fastRetries)
+	require.NoError(t, err)
+	q.Start()
+	defer q.Close(context.Background())
+
+	_, err = q.SavePhoto(jpegData, []byte(`{}`), "2026-02-01/153045_req-1", "req-1", "2026-02-01T15:30:45Z")
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool { return !q.IsPending("req-1") }, 2*time.Second, 5*time.Millisecond)
+	assert.Equal(t, 1, saver.attemptsOf("req-1"))
+	dead, err := q.DeadLetters()
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	data, err := os.ReadFile(filepath.Join(dir, deadLetterDir, dead[0]))
+	require.NoError(t, err)
+	var item queuedSave
+	require.NoError(t, json.Unmarshal(data, &item))
+	assert.Equal(t, 1, item.Attempts)
+}
+
+func TestSaveQueue_HoldsSavesWhileStorageIsFull(t *testing.T) {
+	dir := t.TempDir()
+	saver := &flakySaver{failures: 100, err: ErrStorageFull}
+	q, err := NewSaveQueue(dir, saver, fastRetries)
+	require.NoError(t, err)
+	q.Start()
+	defer q.Close(context.Background())
+
+	path, err := q.SavePhoto(jpegData, []byte(`{}`), "2026-02-01/153045_req-1", "req-1", "2026-02-01T15:30:45Z")
+	require.NoError(t, err)
+
+	// Well past MaxAttempts, the save is still queued with no attempt counted
+	require.Eventually(t, func() bool { return saver.attemptsOf("req-1") > 2*fastRetries.MaxAttempts }, 2*time.Second, time.Millisecond)
+	assert.True(t, q.IsPending("req-1"))
+	dead, err := q.DeadLetters()
+	require.NoError(t, err)
+	assert.Empty(t, dead)
+	item, err := q.read(filepath.Base(path))
+	require.NoError(t, err)
+	assert.Zero(t, item.Attempts)
+	assert.Equal(t, ErrStorageFull.Error(), item.LastError)
+}
+
+func TestSaveQueue_SkipsSavesClaimedByAnotherReplica(t *testing.T) {
+	dir := t.TempDir()
+	leases := filepath.Join(dir, ".leases")
+	other, err := scheduler.NewFileLocker(leases, "other-replica")
+	require.NoError(t, err)
+	ours, err := scheduler.NewFileLocker(leases, "this-replica")
+	require.NoError(t, err)
+
+	saver := &flakySaver{}
+	q, err := NewSaveQueue(dir, saver, QueueConfig{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond, Locker: ours})
+	require.NoError(t, err)
+	path, err := q.SavePhoto(jpegData, []byte(`{}`), "2026-02-01/153045_req-1", "req-1", "2026-02-01T15:30:45Z")
+	require.NoError(t, err)
+
+	unlock, ok, err := other.TryLock(strings.TrimSuffix(filepath.Base(path), ".json"), time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	q.processDue()
+	assert.Zero(t, saver.attemptsOf("req-1"))
+	assert.True(t, q.IsPending("req-1"))
+
+	unlock()
+	q.processDue()
+	assert.Equal(t, []string{"req-1"}, saver.savedRequests())
+	assert.False(t, q.IsPending("req-1"))
+}
+
+func TestSaveQueue_IsPendingReadsSharedDirectory(t *testing.T) {
+	dir := t.TempDir()
+	first, err := NewSaveQueue(dir, &flakySaver{}, fastRetries)
+	require.NoError(t, err)
+	second, err := NewSaveQueue(dir, &flakySaver{}, fastRetries)
+	require.NoError(t, err)
+
+	// Queued on the first replica after the second opened the queue
+	_, err = first.SavePhoto(jpegData, []byte(`{}`), "2026-02-01/153045_req-1", "req-1", "2026-02-01T15:30:45Z")
+	require.NoError(t, err)
+	assert.True(t, second.IsPending("req-1"))
+	assert.Equal(t, 1, second.Len())
+
+	// Saved by the second replica
+	second.processDue()
+	assert.False(t, first.IsPending("req-1"))
+	assert.Zero(t, first.Len())
+}
+
+func TestSaveQueue_ResumesAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	first, err := NewSaveQueue(dir, &flakySaver{}, fastRetries)
+	require.NoError(t, err)
+	// Never started: the process stops before the save is handed on
+	_, err = first.SavePhoto(nil, []byte(`{"moderation":{"flagged":true}}`), "2026-02-01/153045_req-1", "req-1", "2026-02-01T15:30:45Z")
+	require.NoError(t, err)
+
+	saver := &flakySaver{}
+	second, err := NewSaveQueue(dir, saver, fastRetries)
+	require.NoError(t, err)
+	assert.True(t, second.IsPending("req-1"))
+
+	second.Start()
+	require.NoError(t, second.Close(context.Background()))
+	assert.Equal(t, []string{"req-1"}, saver.savedRequests())
+	assert.False(t, second.IsPending("req-1"))
+}
+
+func TestSaveQueue_RejectsUnsafeRequestIDs(t *testing.T) {
+	q, err := NewSaveQueue(t.TempDir(), &flakySaver{}, fastRetries)
+	require.NoError(t, err)
+
+	for _, id := range []string{"", "../escape", ".hidden"} {
+		_, err := q.SavePhoto(jpegData, []byte(`{}`), "2026-02-01/153045_"+id, id, "2026-02-01T15:30:45Z")
+		assert.Error(t, err, "request ID %q", id)
+	}
+}
+
+// fullSaver is a saver whose storage is over quota
+type fullSaver struct{ flakySaver }
+
+func (s *fullSaver) Degraded() bool { return true }
+
+func TestSaveQueue_RefusesSavesWhileStorageIsFull(t *testing.T) {
+	dir := t.TempDir()
+	q, err := NewSaveQueue(dir, &fullSaver{}, fastRetries)
+	require.NoError(t, err)
+	assert.True(t, q.Degraded())
+
+	_, err = q.SavePhoto(jpegData, []byte(`{}`), "2026-02-01/153045_req-1", "req-1", "2026-02-01T15:30:45Z")
+	assert.ErrorIs(t, err, ErrStorageFull)
+	assert.False(t, q.IsPending("req-1"))
+
+	queued, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	assert.Empty(t, queued)
+}
+
+func TestSaveQueue_Backoff(t *testing.T) {
+	q, err := NewSaveQueue(t.TempDir(), &flakySaver{}, QueueConfig{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second})
+	require.NoError(t, err)
+
+	assert.Equal(t, DefaultQueueMaxAttempts, q.config.MaxAttempts)
+	assert.Equal(t, time.Second, q.backoff(1))
+	assert.Equal(t, 2*time.Second, q.backoff(2))
+	assert.Equal(t, 4*time.Second, q.backoff(3))
+	assert.Equal(t, 5*time.Second, q.backoff(4))
+	assert.Equal(t, 5*time.Second, q.backoff(50))
+}
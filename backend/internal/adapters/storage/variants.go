@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"rechtebank/backend/internal/adapters/imaging"
+	"rechtebank/backend/internal/core/domain"
+	"rechtebank/backend/internal/logging"
+)
+
+var logger = logging.Component(logging.ComponentStorage)
+
+// DefaultVariantQuality is the JPEG/WebP quality of generated variants
+const DefaultVariantQuality = 80
+
+// VariantConfig configures the resized copies stored next to each photo
+type VariantConfig struct {
+	// Widths are the variant widths in pixels; none disables variants
+	Widths []int
+
+	// Formats are the encodings generated for every width (JPEG or WebP)
+	Formats []domain.PhotoFormat
+
+	// Quality is the JPEG/WebP encoding quality
+	Quality int
+}
+
+// variantPath returns the path of a variant of the photo at basePath (path
+// without extension), e.g. "153045_abc.w400.webp"
+func variantPath(basePath string, width int, format domain.PhotoFormat) string {
+	return fmt.Sprintf("%s.w%d%s", basePath, width, format.Extension)
+}
+
+// generateVariants writes every configured variant of a photo. Failures are
+// logged rather than returned: the original is what matters, and missing
+// variants are regenerated on demand.
+func (s *PhotoStorage) generateVariants(basePath string, imageData []byte) {
+	if len(s.variants.Widths) == 0 || len(s.variants.Formats) == 0 {
+		return
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(imageData))
+	if err != nil {
+		logger.Warn("Failed to decode photo for variants", slog.String("path", basePath), slog.Any("error", err))
+		return
+	}
+
+	for _, width := range s.variants.Widths {
+		resized := imaging.ResizeToWidth(img, width)
+		for _, format := range s.variants.Formats {
+			err := s.writeVariant(variantPath(basePath, width, format), resized, format)
+			if errors.Is(err, ErrStorageFull) {
+				logger.Warn("Skipping photo variants, storage is full", slog.String("path", basePath))
+				return
+			}
+			if err != nil {
+				logger.Warn("Failed to write photo variant", slog.String("path", basePath), slog.Int("width", width), slog.Any("error", err))
+			}
+		}
+	}
+}
+
+// writeVariant encodes img and writes it atomically, so a concurrent reader
+// never sees a partial variant. Variants count against the quota like any
+// other case file: ErrStorageFull is returned when one does not fit.
+func (s *PhotoStorage) writeVariant(path string, img image.Image, format domain.PhotoFormat) error {
+	data, err := imaging.Encode(img, format, s.variants.Quality)
+	if err != nil {
+		return err
+	}
+	if !s.admit(int64(len(data)), 1) {
+		return ErrStorageFull
+	}
+
+	if err := s.writeCaseFile(path, data); err != nil {
+		return fmt.Errorf("failed to write variant: %w", err)
+	}
+	return nil
+}
+
+// Variant returns the path of the stored variant of a verdict's photo,
+// generating it first when it is missing. verdictPath is the verdict's
+// storage path without extension ("2026-02-01/153045_abc"). An empty path
+// is returned when no such variant is configured.
+func (s *PhotoStorage) Variant(verdictPath string, width int, format domain.PhotoFormat) (string, error) {
+	if !slices.Contains(s.variants.Widths, width) || !slices.Contains(s.variants.Formats, format) {
+		return "", nil
+	}
+	if _, ok := domain.StorageKeyRequestID(verdictPath); !ok {
+		return "", fmt.Errorf("invalid storage key %q", verdictPath)
+	}
+
+	basePath := filepath.Join(s.basePath, verdictPath)
+	path := variantPath(basePath, width, format)
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	originalPath, ok := findOriginal(basePath)
+	if !ok {
+		return "", fmt.Errorf("photo %s: %w", verdictPath, os.ErrNotExist)
+	}
+	original, err := os.ReadFile(originalPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read photo: %w", err)
+	}
+	img, _, err := image.Decode(bytes.NewReader(original))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode photo: %w", err)
+	}
+
+	if err := s.writeVariant(path, imaging.ResizeToWidth(img, width), format); err != nil {
+		return "", err
+	}
+	logger.Info("Regenerated missing photo variant", slog.String("path", verdictPath), slog.Int("width", width), slog.String("format", format.MIMEType))
+	return path, nil
+}
+
+// VariantWidth returns the configured variant width to serve for a requested
+// width: the narrowest one at least as wide, or else the widest. Without
+// configured widths the requested width is returned as is.
+func (s *PhotoStorage) VariantWidth(width int) int {
+	narrowest, widest := 0, 0
+	for _, w := range s.variants.Widths {
+		if w >= width && (narrowest == 0 || w < narrowest) {
+			narrowest = w
+		}
+		widest = max(widest, w)
+	}
+	switch {
+	case narrowest > 0:
+		return narrowest
+	case widest > 0:
+		return widest
+	default:
+		return width
+	}
+}
+
+// findOriginal returns the path of the original photo at basePath
+func findOriginal(basePath string) (string, bool) {
+	for _, format := range domain.PhotoFormats {
+		if _, err := os.Stat(basePath + format.Extension); err == nil {
+			return basePath + format.Extension, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"rechtebank/backend/internal/core/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "golang.org/x/image/webp"
+)
+
+var testVariants = VariantConfig{
+	Widths:  []int{40, 120},
+	Formats: []domain.PhotoFormat{domain.PhotoFormatWebP, domain.PhotoFormatJPEG},
+}
+
+func encodeJPEG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height)), nil))
+	return buf.Bytes()
+}
+
+// savedPhoto saves a 200x100 JPEG and returns its verdict path (relative,
+// without extension) and its absolute path without extension
+func savedPhoto(t *testing.T, s *PhotoStorage) (string, string) {
+	t.Helper()
+	photoPath, err := s.SavePhoto(encodeJPEG(t, 200, 100), []byte(`{"admissible":true}`), "2026-02-01/153045_req-1", "req-1", "2026-02-01T15:30:45Z")
+	require.NoError(t, err)
+	base := strings.TrimSuffix(photoPath, ".jpg")
+	verdictPath, err := filepath.Rel(s.basePath, base)
+	require.NoError(t, err)
+	return filepath.ToSlash(verdictPath), base
+}
+
+func TestSavePhoto_GeneratesVariants(t *testing.T) {
+	s, err := NewPhotoStorageWithVariants(t.TempDir(), testVariants)
+	require.NoError(t, err)
+	_, base := savedPhoto(t, s)
+
+	for _, width := range testVariants.Widths {
+		for _, format := range testVariants.Formats {
+			data, err := os.ReadFile(variantPath(base, width, format))
+			require.NoError(t, err, "w%d%s", width, format.Extension)
+
+			detected, ok := domain.DetectPhotoFormat(data)
+			require.True(t, ok)
+			assert.Equal(t, format, detected)
+
+			config, _, err := image.DecodeConfig(bytes.NewReader(data))
+			require.NoError(t, err)
+			assert.Equal(t, width, config.Width)
+			assert.Equal(t, width/2, config.Height)
+		}
+	}
+}
+
+func TestSavePhoto_VariantsCountAgainstQuota(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewPhotoStorageWithVariants(dir, testVariants)
+	require.NoError(t, err)
+	// Room for the case and one variant, with the high-water mark out of the way
+	_, err = s.EnableQuota(QuotaConfig{MaxFiles: 3, HighWater: 1, LowWater: 1})
+	require.NoError(t, err)
+
+	_, base := savedPhoto(t, s)
+
+	assert.FileExists(t, variantPath(base, 40, domain.PhotoFormatWebP))
+	assert.NoFileExists(t, variantPath(base, 40, domain.PhotoFormatJPEG))
+	assert.NoFileExists(t, variantPath(base, 120, domain.PhotoFormatWebP))
+	usage, err := s.RecountUsage()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), usage.Files)
+}
+
+func TestSavePhoto_NoVariantsByDefault(t *testing.T) {
+	s, err := NewPhotoStorage(t.TempDir())
+	require.NoError(t, err)
+	_, base := savedPhoto(t, s)
+
+	matches, err := filepath.Glob(base + ".w*")
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+}
+
+func TestVariant_RegeneratesMissingVariant(t *testing.T) {
+	s, err := NewPhotoStorageWithVariants(t.TempDir(), testVariants)
+	require.NoError(t, err)
+	verdictPath, base := savedPhoto(t, s)
+
+	expected := variantPath(base, 120, domain.PhotoFormatWebP)
+	require.NoError(t, os.Remove(expected))
+
+	path, err := s.Variant(verdictPath, 120, domain.PhotoFormatWebP)
+	require.NoError(t, err)
+	assert.Equal(t, expected, path)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 120, config.Width)
+}
+
+func TestVariant_NotConfigured(t *testing.T) {
+	s, err := NewPhotoStorageWithVariants(t.TempDir(), testVariants)
+	require.NoError(t, err)
+	verdictPath, _ := savedPhoto(t, s)
+
+	path, err := s.Variant(verdictPath, 300, domain.PhotoFormatJPEG)
+	require.NoError(t, err)
+	assert.Empty(t, path)
+
+	path, err = s.Variant(verdictPath, 40, domain.PhotoFormatPNG)
+	require.NoError(t, err)
+	assert.Empty(t, path)
+}
+
+func TestVariant_MissingPhoto(t *testing.T) {
+	s, err := NewPhotoStorageWithVariants(t.TempDir(), testVariants)
+	require.NoError(t, err)
+
+	_, err = s.Variant("2026-02-01/000000_missing", 40, domain.PhotoFormatJPEG)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestVariant_RejectsInvalidKey(t *testing.T) {
+	base := t.TempDir()
+	s, err := NewPhotoStorageWithVariants(filepath.Join(base, "storage"), testVariants)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(base, "secret"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(base, "secret", "private.jpg"), encodeJPEG(t, 200, 100), 0644))
+
+	_, err = s.Variant("../secret/private", 40, domain.PhotoFormatJPEG)
+	assert.Error(t, err)
+	assert.NoFileExists(t, filepath.Join(base, "secret", "private.w40.jpg"))
+}
+
+func TestVariantWidth(t *testing.T) {
+	s, err := NewPhotoStorageWithVariants(t.TempDir(), VariantConfig{Widths: []int{1200, 400}})
+	require.NoError(t, err)
+
+	assert.Equal(t, 400, s.VariantWidth(1))
+	assert.Equal(t, 400, s.VariantWidth(400))
+	assert.Equal(t, 1200, s.VariantWidth(401))
+	assert.Equal(t, 1200, s.VariantWidth(2048))
+
+	// Without configured widths the requested width is kept
+	s, err = NewPhotoStorage(t.TempDir())
+	require.NoError(t, err)
+	assert.Equal(t, 333, s.VariantWidth(333))
+}
+
+func TestCleanupOldPhotos_RemovesVariants(t *testing.T) {
+	s, err := NewPhotoStorageWithVariants(t.TempDir(), testVariants)
+	require.NoError(t, err)
+
+	// Variants live next to the photo, so removing a date directory removes them
+	oldDir := filepath.Join(s.basePath, time.Now().AddDate(0, 0, -100).Format("2006-01-02"))
+	require.NoError(t, os.MkdirAll(oldDir, 0755))
+	base := filepath.Join(oldDir, "153045_old")
+	require.NoError(t, os.WriteFile(base+".jpg", encodeJPEG(t, 200, 100), 0644))
+	_, err = s.Variant(filepath.Base(oldDir)+"/153045_old", 40, domain.PhotoFormatWebP)
+	require.NoError(t, err)
+	require.FileExists(t, variantPath(base, 40, domain.PhotoFormatWebP))
+
+	require.NoError(t, s.CleanupOldPhotos(90))
+
+	_, err = os.Stat(oldDir)
+	assert.True(t, os.IsNotExist(err))
+}
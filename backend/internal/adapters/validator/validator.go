@@ -2,33 +2,187 @@ package validator
 
 import (
 	"bytes"
-	"errors"
+	"encoding/binary"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
 
+	"rechtebank/backend/internal/adapters/imaging"
 	"rechtebank/backend/internal/core/domain"
+
+	_ "golang.org/x/image/webp"
 )
 
-const maxFileSize = 10 * 1024 * 1024 // 10MB
+// DefaultMaxFileSize is the upload size limit used when none is configured
+const DefaultMaxFileSize = 10 * 1024 * 1024 // 10MB
+
+// Default decoding limits. A 50 megapixel photo decodes to roughly 200MB of
+// RGBA, which bounds the memory a single upload can claim.
+const (
+	DefaultMaxPixels    = 50_000_000
+	DefaultMaxDimension = 10_000
+)
+
+// Limits bounds the images the validator accepts
+type Limits struct {
+	// MaxFileSize is the maximum upload size in bytes
+	MaxFileSize int64
+
+	// MaxPixels is the maximum width*height
+	MaxPixels int64
+
+	// MaxDimension is the maximum width or height in pixels
+	MaxDimension int
+
+	// Quality rejects photos too poor to judge; the zero value disables it
+	Quality QualityThresholds
+}
+
+// QualityThresholds bound the measured quality of a photo. A zero threshold
+// disables its check.
+type QualityThresholds struct {
+	// MinBrightness and MaxBrightness bound the mean luma (0-255)
+	MinBrightness float64
+	MaxBrightness float64
+
+	// MinContrast is the minimum standard deviation of the luma
+	MinContrast float64
+
+	// MinSharpness is the minimum variance of the Laplacian
+	MinSharpness float64
+}
+
+// enabled reports whether any quality check is configured
+func (q QualityThresholds) enabled() bool {
+	return q != QualityThresholds{}
+}
+
+// DefaultLimits returns the limits used by NewPhotoValidator
+func DefaultLimits() Limits {
+	return Limits{
+		MaxFileSize:  DefaultMaxFileSize,
+		MaxPixels:    DefaultMaxPixels,
+		MaxDimension: DefaultMaxDimension,
+	}
+}
 
-type PhotoValidator struct{}
+type PhotoValidator struct {
+	limits Limits
+}
 
 func NewPhotoValidator() *PhotoValidator {
-	return &PhotoValidator{}
+	return NewPhotoValidatorWithLimits(DefaultLimits())
+}
+
+// NewPhotoValidatorWithLimits creates a validator with custom decoding limits
+func NewPhotoValidatorWithLimits(limits Limits) *PhotoValidator {
+	return &PhotoValidator{limits: limits}
 }
 
 func (v *PhotoValidator) ValidatePhoto(imageData []byte, metadata domain.PhotoMetadata) error {
 	// Validate file size
-	if metadata.Size > maxFileSize {
-		return errors.New("Photo file size must not exceed 10MB")
+	if v.limits.MaxFileSize > 0 && metadata.Size > v.limits.MaxFileSize {
+		return domain.NewError(domain.ErrCodePhotoTooLarge,
+			fmt.Sprintf("Photo file size must not exceed %s", formatSize(v.limits.MaxFileSize)))
 	}
 
 	// Validate format based on magic bytes
 	if !v.isSupportedFormat(imageData) {
-		return errors.New("Unsupported image format. Use JPEG, PNG, or WebP")
+		return domain.NewError(domain.ErrCodeUnsupportedFormat, "Unsupported image format. Use JPEG, PNG, WebP, HEIC, or AVIF")
+	}
+
+	// Only the first frame would ever be judged, so reject animations outright
+	if isAnimated(imageData) {
+		return domain.NewError(domain.ErrCodeAnimatedImage, "Animated images are not supported")
 	}
 
+	// Check dimensions from the header before anything is decoded
+	config, err := decodeConfig(imageData)
+	if err != nil {
+		return domain.WrapError(domain.ErrCodeImageCorrupt, "Image header could not be read", err)
+	}
+	if err := v.checkDimensions(config.Width, config.Height); err != nil {
+		return err
+	}
+
+	// Verify the whole file decodes, so truncated images never reach the analyzer
+	img, err := decode(imageData)
+	if err != nil {
+		return domain.WrapError(domain.ErrCodeImageCorrupt, "Image could not be decoded", err)
+	}
+
+	// Photos nobody could judge are rejected without asking the analyzer
+	if v.limits.Quality.enabled() {
+		if err := v.limits.Quality.check(imaging.MeasureQuality(img)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// check returns an UnreadablePhotoError for the first threshold the metrics
+// fail. Contrast is checked before sharpness, because a blank photo has no
+// edges either and is better described as blank than as blurry.
+func (q QualityThresholds) check(metrics imaging.QualityMetrics) error {
+	switch {
+	case q.MinBrightness > 0 && metrics.Brightness < q.MinBrightness:
+		return &domain.UnreadablePhotoError{Issue: domain.PhotoTooDark, Value: metrics.Brightness, Threshold: q.MinBrightness}
+	case q.MaxBrightness > 0 && metrics.Brightness > q.MaxBrightness:
+		return &domain.UnreadablePhotoError{Issue: domain.PhotoTooBright, Value: metrics.Brightness, Threshold: q.MaxBrightness}
+	case q.MinContrast > 0 && metrics.Contrast < q.MinContrast:
+		return &domain.UnreadablePhotoError{Issue: domain.PhotoLowContrast, Value: metrics.Contrast, Threshold: q.MinContrast}
+	case q.MinSharpness > 0 && metrics.Sharpness < q.MinSharpness:
+		return &domain.UnreadablePhotoError{Issue: domain.PhotoBlurry, Value: metrics.Sharpness, Threshold: q.MinSharpness}
+	}
 	return nil
 }
 
+// decodeConfig reads the image header. HEIF-family files go through the
+// imaging package because their brands are not registered with image.
+func decodeConfig(data []byte) (image.Config, error) {
+	if imaging.HEIFFormat(data) != "" {
+		return imaging.DecodeHEIFConfig(data)
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	return config, err
+}
+
+// decode fully decodes the image
+func decode(data []byte) (image.Image, error) {
+	if imaging.HEIFFormat(data) != "" {
+		return imaging.DecodeHEIF(data)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// checkDimensions enforces the pixel and dimension limits
+func (v *PhotoValidator) checkDimensions(width, height int) error {
+	if width <= 0 || height <= 0 {
+		return domain.NewError(domain.ErrCodeImageCorrupt, fmt.Sprintf("Invalid image dimensions %dx%d", width, height))
+	}
+	if v.limits.MaxDimension > 0 && (width > v.limits.MaxDimension || height > v.limits.MaxDimension) {
+		return domain.NewError(domain.ErrCodeImageTooLarge,
+			fmt.Sprintf("Image dimensions %dx%d exceed %d pixels", width, height, v.limits.MaxDimension))
+	}
+	if pixels := int64(width) * int64(height); v.limits.MaxPixels > 0 && pixels > v.limits.MaxPixels {
+		return domain.NewError(domain.ErrCodeImageTooLarge,
+			fmt.Sprintf("Image has %d pixels, limit is %d", pixels, v.limits.MaxPixels))
+	}
+	return nil
+}
+
+// formatSize renders a byte count as whole megabytes when it is one
+func formatSize(bytes int64) string {
+	const mb = 1024 * 1024
+	if bytes%mb == 0 {
+		return fmt.Sprintf("%dMB", bytes/mb)
+	}
+	return fmt.Sprintf("%d bytes", bytes)
+}
+
 func (v *PhotoValidator) isSupportedFormat(data []byte) bool {
 	if len(data) < 12 {
 		return false
@@ -40,15 +194,79 @@ func (v *PhotoValidator) isSupportedFormat(data []byte) bool {
 	}
 
 	// PNG: 89 50 4E 47 0D 0A 1A 0A
-	if bytes.HasPrefix(data, []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A}) {
+	if bytes.HasPrefix(data, pngSignature) {
 		return true
 	}
 
 	// WebP: RIFF....WEBP
-	if bytes.HasPrefix(data, []byte{0x52, 0x49, 0x46, 0x46}) && 
+	if bytes.HasPrefix(data, []byte{0x52, 0x49, 0x46, 0x46}) &&
 	   bytes.HasPrefix(data[8:], []byte{0x57, 0x45, 0x42, 0x50}) {
 		return true
 	}
 
+	// HEIC, HEIF and AVIF: ISO base media file with an image brand
+	if imaging.HEIFFormat(data) != "" {
+		return true
+	}
+
+	return false
+}
+
+var pngSignature = []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A}
+
+// isAnimated reports whether a PNG (APNG), WebP, HEIF or AVIF file contains
+// an animation. JPEG files can carry extra images (MPF depth maps), but only
+// the primary image is ever decoded, so they are not treated as multi-frame.
+func isAnimated(data []byte) bool {
+	if imaging.HEIFFormat(data) != "" {
+		return imaging.IsHEIFSequence(data)
+	}
+	if bytes.HasPrefix(data, pngSignature) {
+		return hasPNGChunk(data, "acTL")
+	}
+	if bytes.HasPrefix(data, []byte("RIFF")) && len(data) >= 12 && string(data[8:12]) == "WEBP" {
+		return isAnimatedWebP(data)
+	}
+	return false
+}
+
+// hasPNGChunk reports whether the PNG contains a chunk of the given type
+// before the image data starts
+func hasPNGChunk(data []byte, chunkType string) bool {
+	offset := len(pngSignature)
+	for offset+8 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[offset : offset+4]))
+		typ := string(data[offset+4 : offset+8])
+		if typ == chunkType {
+			return true
+		}
+		// Animation control must precede the first IDAT chunk
+		if typ == "IDAT" || typ == "IEND" {
+			return false
+		}
+		offset += 12 + length // length + type + data + CRC
+	}
+	return false
+}
+
+// isAnimatedWebP checks the VP8X animation flag and looks for ANIM/ANMF chunks
+func isAnimatedWebP(data []byte) bool {
+	const animationFlag = 0x02
+
+	offset := 12
+	for offset+8 <= len(data) {
+		typ := string(data[offset : offset+4])
+		length := int(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
+		switch typ {
+		case "VP8X":
+			if offset+8 < len(data) && data[offset+8]&animationFlag != 0 {
+				return true
+			}
+		case "ANIM", "ANMF":
+			return true
+		}
+		// Chunks are padded to an even length
+		offset += 8 + length + length%2
+	}
 	return false
 }
@@ -1,20 +1,63 @@
 package validator
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"rechtebank/backend/internal/core/domain"
 )
 
 // JPEG magic bytes: FF D8 FF
 var jpegHeader = []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10, 0x4A, 0x46, 0x49, 0x46, 0x00, 0x01}
 
-// PNG magic bytes: 89 50 4E 47 0D 0A 1A 0A
-var pngHeader = []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A, 0x00, 0x00, 0x00, 0x0D}
+// Decodable images used where validation must pass
+var (
+	validJPEG = encodeTestJPEG(16, 16)
+	validPNG  = encodeTestPNG(16, 16)
 
-// WebP magic bytes: RIFF....WEBP
-var webpHeader = []byte{0x52, 0x49, 0x46, 0x46, 0x00, 0x00, 0x00, 0x00, 0x57, 0x45, 0x42, 0x50}
+	// 1x1 lossless WebP (Go has no WebP encoder)
+	validWebP, _ = base64.StdEncoding.DecodeString("UklGRhoAAABXRUJQVlA4TA0AAAAvAAAAEAcQERGIiP4HAA==")
+)
+
+// readFixture loads a sample photo shared with the imaging package tests
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile("../imaging/testdata/" + name)
+	require.NoError(t, err)
+	return data
+}
+
+func testImage(width, height int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 128, 255})
+		}
+	}
+	return img
+}
+
+func encodeTestJPEG(width, height int) []byte {
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, testImage(width, height), nil)
+	return buf.Bytes()
+}
+
+func encodeTestPNG(width, height int) []byte {
+	var buf bytes.Buffer
+	png.Encode(&buf, testImage(width, height))
+	return buf.Bytes()
+}
 
 // GIF magic bytes: GIF89a
 var gifHeader = []byte{0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
@@ -27,10 +70,10 @@ func TestPhotoValidator_FileFormatValidation_JPEG(t *testing.T) {
 	metadata := domain.PhotoMetadata{
 		Filename:    "test.jpg",
 		ContentType: "image/jpeg",
-		Size:        int64(len(jpegHeader)),
+		Size:        int64(len(validJPEG)),
 	}
 
-	err := v.ValidatePhoto(jpegHeader, metadata)
+	err := v.ValidatePhoto(validJPEG, metadata)
 	assert.NoError(t, err)
 }
 
@@ -39,10 +82,10 @@ func TestPhotoValidator_FileFormatValidation_PNG(t *testing.T) {
 	metadata := domain.PhotoMetadata{
 		Filename:    "test.png",
 		ContentType: "image/png",
-		Size:        int64(len(pngHeader)),
+		Size:        int64(len(validPNG)),
 	}
 
-	err := v.ValidatePhoto(pngHeader, metadata)
+	err := v.ValidatePhoto(validPNG, metadata)
 	assert.NoError(t, err)
 }
 
@@ -51,10 +94,10 @@ func TestPhotoValidator_FileFormatValidation_WebP(t *testing.T) {
 	metadata := domain.PhotoMetadata{
 		Filename:    "test.webp",
 		ContentType: "image/webp",
-		Size:        int64(len(webpHeader)),
+		Size:        int64(len(validWebP)),
 	}
 
-	err := v.ValidatePhoto(webpHeader, metadata)
+	err := v.ValidatePhoto(validWebP, metadata)
 	assert.NoError(t, err)
 }
 
@@ -68,7 +111,7 @@ func TestPhotoValidator_UnsupportedFormat_GIF(t *testing.T) {
 
 	err := v.ValidatePhoto(gifHeader, metadata)
 	assert.Error(t, err)
-	assert.Equal(t, "Unsupported image format. Use JPEG, PNG, or WebP", err.Error())
+	assert.Equal(t, "Unsupported image format. Use JPEG, PNG, WebP, HEIC, or AVIF", err.Error())
 }
 
 func TestPhotoValidator_UnsupportedFormat_BMP(t *testing.T) {
@@ -81,7 +124,7 @@ func TestPhotoValidator_UnsupportedFormat_BMP(t *testing.T) {
 
 	err := v.ValidatePhoto(bmpHeader, metadata)
 	assert.Error(t, err)
-	assert.Equal(t, "Unsupported image format. Use JPEG, PNG, or WebP", err.Error())
+	assert.Equal(t, "Unsupported image format. Use JPEG, PNG, WebP, HEIC, or AVIF", err.Error())
 }
 
 func TestPhotoValidator_FileSizeValidation_WithinLimit(t *testing.T) {
@@ -94,7 +137,7 @@ func TestPhotoValidator_FileSizeValidation_WithinLimit(t *testing.T) {
 		Size:        size,
 	}
 
-	err := v.ValidatePhoto(jpegHeader, metadata)
+	err := v.ValidatePhoto(validJPEG, metadata)
 	assert.NoError(t, err)
 }
 
@@ -108,7 +151,7 @@ func TestPhotoValidator_FileSizeValidation_AtLimit(t *testing.T) {
 		Size:        size,
 	}
 
-	err := v.ValidatePhoto(jpegHeader, metadata)
+	err := v.ValidatePhoto(validJPEG, metadata)
 	assert.NoError(t, err)
 }
 
@@ -125,6 +168,7 @@ func TestPhotoValidator_FileSizeValidation_ExceedsLimit(t *testing.T) {
 	err := v.ValidatePhoto(jpegHeader, metadata)
 	assert.Error(t, err)
 	assert.Equal(t, "Photo file size must not exceed 10MB", err.Error())
+	assert.Equal(t, domain.ErrCodePhotoTooLarge, domain.ErrorCodeOf(err))
 }
 
 func TestPhotoValidator_FileSizeValidation_VeryLarge(t *testing.T) {
@@ -153,5 +197,222 @@ func TestPhotoValidator_InvalidData_TooShort(t *testing.T) {
 
 	err := v.ValidatePhoto(shortData, metadata)
 	assert.Error(t, err)
-	assert.Equal(t, "Unsupported image format. Use JPEG, PNG, or WebP", err.Error())
+	assert.Equal(t, "Unsupported image format. Use JPEG, PNG, WebP, HEIC, or AVIF", err.Error())
+	assert.Equal(t, domain.ErrCodeUnsupportedFormat, domain.ErrorCodeOf(err))
+}
+
+func validate(v *PhotoValidator, data []byte) error {
+	return v.ValidatePhoto(data, domain.PhotoMetadata{Size: int64(len(data))})
+}
+
+func TestPhotoValidator_TruncatedJPEG(t *testing.T) {
+	truncated := validJPEG[:len(validJPEG)/2]
+
+	err := validate(NewPhotoValidator(), truncated)
+	assert.Error(t, err)
+	assert.Equal(t, domain.ErrCodeImageCorrupt, domain.ErrorCodeOf(err))
+}
+
+func TestPhotoValidator_MagicBytesOnly(t *testing.T) {
+	err := validate(NewPhotoValidator(), jpegHeader)
+	assert.Error(t, err)
+	assert.Equal(t, domain.ErrCodeImageCorrupt, domain.ErrorCodeOf(err))
+}
+
+func TestPhotoValidator_DimensionLimit(t *testing.T) {
+	v := NewPhotoValidatorWithLimits(Limits{MaxPixels: 1_000_000, MaxDimension: 100})
+
+	err := validate(v, encodeTestPNG(101, 10))
+	assert.Error(t, err)
+	assert.Equal(t, domain.ErrCodeImageTooLarge, domain.ErrorCodeOf(err))
+
+	err = validate(v, encodeTestPNG(10, 101))
+	assert.Equal(t, domain.ErrCodeImageTooLarge, domain.ErrorCodeOf(err))
+
+	assert.NoError(t, validate(v, encodeTestPNG(100, 100)))
+}
+
+func TestPhotoValidator_PixelLimit(t *testing.T) {
+	v := NewPhotoValidatorWithLimits(Limits{MaxPixels: 2500, MaxDimension: 1000})
+
+	err := validate(v, encodeTestJPEG(60, 50))
+	assert.Error(t, err)
+	assert.Equal(t, domain.ErrCodeImageTooLarge, domain.ErrorCodeOf(err))
+
+	assert.NoError(t, validate(v, encodeTestJPEG(50, 50)))
+}
+
+// TestPhotoValidator_DecompressionBomb checks that a huge image declared in a
+// small file is rejected from its header alone
+func TestPhotoValidator_DecompressionBomb(t *testing.T) {
+	// A 30000x30000 PNG header followed by a tiny IDAT; never fully decodable
+	bomb := encodeTestPNG(1, 1)
+	binary.BigEndian.PutUint32(bomb[16:20], 30000)
+	binary.BigEndian.PutUint32(bomb[20:24], 30000)
+	binary.BigEndian.PutUint32(bomb[29:33], crc32.ChecksumIEEE(bomb[12:29]))
+
+	err := validate(NewPhotoValidator(), bomb)
+	assert.Error(t, err)
+	assert.Equal(t, domain.ErrCodeImageTooLarge, domain.ErrorCodeOf(err))
+}
+
+// insertPNGChunk inserts a chunk directly after the IHDR chunk
+func insertPNGChunk(data []byte, chunkType string, payload []byte) []byte {
+	const ihdrEnd = 8 + 4 + 4 + 13 + 4
+
+	chunk := make([]byte, 0, 12+len(payload))
+	chunk = binary.BigEndian.AppendUint32(chunk, uint32(len(payload)))
+	chunk = append(chunk, chunkType...)
+	chunk = append(chunk, payload...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+
+	result := append([]byte{}, data[:ihdrEnd]...)
+	result = append(result, chunk...)
+	return append(result, data[ihdrEnd:]...)
+}
+
+func TestPhotoValidator_AnimatedPNG(t *testing.T) {
+	// acTL: num_frames=2, num_plays=0
+	apng := insertPNGChunk(validPNG, "acTL", []byte{0, 0, 0, 2, 0, 0, 0, 0})
+
+	// The first frame alone still decodes as a regular PNG
+	_, _, err := image.Decode(bytes.NewReader(apng))
+	require.NoError(t, err)
+
+	err = validate(NewPhotoValidator(), apng)
+	assert.Error(t, err)
+	assert.Equal(t, domain.ErrCodeAnimatedImage, domain.ErrorCodeOf(err))
+}
+
+func TestPhotoValidator_PNGWithAncillaryChunk(t *testing.T) {
+	withText := insertPNGChunk(validPNG, "tEXt", []byte("Comment\x00stoel"))
+	assert.NoError(t, validate(NewPhotoValidator(), withText))
+}
+
+func TestPhotoValidator_AnimatedWebP(t *testing.T) {
+	// RIFF header, VP8X chunk with the animation flag set, empty ANIM chunk
+	var webp []byte
+	webp = append(webp, "RIFF"...)
+	webp = binary.LittleEndian.AppendUint32(webp, 4+18+14)
+	webp = append(webp, "WEBP"...)
+	webp = append(webp, "VP8X"...)
+	webp = binary.LittleEndian.AppendUint32(webp, 10)
+	webp = append(webp, 0x02, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+	webp = append(webp, "ANIM"...)
+	webp = binary.LittleEndian.AppendUint32(webp, 6)
+	webp = append(webp, 0, 0, 0, 0, 0, 0)
+
+	err := validate(NewPhotoValidator(), webp)
+	assert.Error(t, err)
+	assert.Equal(t, domain.ErrCodeAnimatedImage, domain.ErrorCodeOf(err))
+}
+
+func TestPhotoValidator_StillWebPIsNotAnimated(t *testing.T) {
+	assert.False(t, isAnimated(validWebP))
+	assert.False(t, isAnimated(validJPEG))
+	assert.False(t, isAnimated(validPNG))
+}
+
+func TestPhotoValidator_ConfiguredFileSize(t *testing.T) {
+	limits := DefaultLimits()
+	limits.MaxFileSize = 2 * 1024 * 1024
+	v := NewPhotoValidatorWithLimits(limits)
+
+	err := v.ValidatePhoto(validJPEG, domain.PhotoMetadata{Size: 3 * 1024 * 1024})
+	assert.Error(t, err)
+	assert.Equal(t, "Photo file size must not exceed 2MB", err.Error())
+	assert.Equal(t, domain.ErrCodePhotoTooLarge, domain.ErrorCodeOf(err))
+
+	assert.NoError(t, v.ValidatePhoto(validJPEG, domain.PhotoMetadata{Size: int64(len(validJPEG))}))
+}
+
+func TestPhotoValidator_FileFormatValidation_HEIC(t *testing.T) {
+	assert.NoError(t, validate(NewPhotoValidator(), readFixture(t, "sample.heic")))
+}
+
+func TestPhotoValidator_FileFormatValidation_AVIF(t *testing.T) {
+	assert.NoError(t, validate(NewPhotoValidator(), readFixture(t, "sample.avif")))
+}
+
+func TestPhotoValidator_AnimatedAVIF(t *testing.T) {
+	err := validate(NewPhotoValidator(), readFixture(t, "animated.avifs"))
+	assert.Error(t, err)
+	assert.Equal(t, domain.ErrCodeAnimatedImage, domain.ErrorCodeOf(err))
+}
+
+func TestPhotoValidator_TruncatedHEIC(t *testing.T) {
+	heic := readFixture(t, "sample.heic")
+
+	err := validate(NewPhotoValidator(), heic[:len(heic)/2])
+	assert.Error(t, err)
+	assert.Equal(t, domain.ErrCodeImageCorrupt, domain.ErrorCodeOf(err))
+}
+
+func TestPhotoValidator_HEICDimensionLimit(t *testing.T) {
+	limits := DefaultLimits()
+	limits.MaxDimension = 8
+
+	err := validate(NewPhotoValidatorWithLimits(limits), readFixture(t, "sample.heic"))
+	assert.Error(t, err)
+	assert.Equal(t, domain.ErrCodeImageTooLarge, domain.ErrorCodeOf(err))
+}
+
+// encodeUniformJPEG encodes a single-colour image
+func encodeUniformJPEG(c color.Gray) []byte {
+	img := image.NewGray(image.Rect(0, 0, 64, 64))
+	for i := range img.Pix {
+		img.Pix[i] = c.Y
+	}
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, img, nil)
+	return buf.Bytes()
+}
+
+// encodeCheckerboardJPEG encodes a sharp black and white checkerboard
+func encodeCheckerboardJPEG() []byte {
+	img := image.NewGray(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			if (x/4+y/4)%2 == 0 {
+				img.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95})
+	return buf.Bytes()
+}
+
+var testQuality = QualityThresholds{MinBrightness: 15, MaxBrightness: 245, MinContrast: 8, MinSharpness: 10}
+
+func TestPhotoValidator_QualityGate(t *testing.T) {
+	limits := DefaultLimits()
+	limits.Quality = testQuality
+	v := NewPhotoValidatorWithLimits(limits)
+
+	tests := []struct {
+		name  string
+		data  []byte
+		issue domain.PhotoQualityIssue
+	}{
+		{"pitch black", encodeUniformJPEG(color.Gray{Y: 2}), domain.PhotoTooDark},
+		{"blown out", encodeUniformJPEG(color.Gray{Y: 254}), domain.PhotoTooBright},
+		{"blank", encodeUniformJPEG(color.Gray{Y: 128}), domain.PhotoLowContrast},
+		{"blurry", encodeTestJPEG(64, 64), domain.PhotoBlurry}, // smooth gradient, no edges
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate(v, tt.data)
+
+			var unreadable *domain.UnreadablePhotoError
+			require.ErrorAs(t, err, &unreadable)
+			assert.Equal(t, tt.issue, unreadable.Issue)
+		})
+	}
+
+	assert.NoError(t, validate(v, encodeCheckerboardJPEG()))
+}
+
+func TestPhotoValidator_QualityGateDisabledByDefault(t *testing.T) {
+	assert.NoError(t, validate(NewPhotoValidator(), encodeUniformJPEG(color.Gray{Y: 0})))
 }
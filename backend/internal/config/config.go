@@ -3,7 +3,9 @@ package config
 import (
 	"errors"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -19,12 +21,82 @@ type Config struct {
 	GeminiAPIKey  string
 	GeminiTimeout time.Duration
 
+	// Analyzer compression settings
+	CompressionMaxDimension int
+	CompressionQuality      int
+	CompressionMinQuality   int
+	CompressionMaxBytes     int
+	CompressionMaxTokens    int
+	CompressionConvertJPEG  bool
+	CompressionAdaptive     bool
+
 	// File upload settings
-	MaxFileSize int64
+	MaxFileSize       int64
+	MaxImagePixels    int64
+	MaxImageDimension int
+
+	// Photo quality gate settings
+	QualityMinBrightness float64
+	QualityMaxBrightness float64
+	QualityMinContrast   float64
+	QualityMinSharpness  float64
+
+	// Content moderation settings
+	ModerationEnabled   bool
+	ModerationThreshold string
 
 	// Photo storage settings
-	PhotoStoragePath   string
-	PhotoRetentionDays int
+	PhotoStoragePath      string
+	PhotoRetentionDays    int
+	RecordCaptureMetadata bool
+
+	// Retention settings: PhotoRetentionDays applies to unpublished
+	// verdicts, InadmissibleRetentionDays to unpublished inadmissible cases,
+	// SharedRetentionDays to unpublished shared cases from their first share
+	InadmissibleRetentionDays int
+	SharedRetentionDays       int
+	RetentionDryRun           bool
+	PhotoVariantWidths        []int
+	PhotoVariantFormats       []string
+
+	// Storage quota settings: a zero limit is unlimited, the watermarks are
+	// fractions of the limits
+	StorageQuotaBytes     int64
+	StorageQuotaFiles     int64
+	StorageQuotaHighWater float64
+	StorageQuotaLowWater  float64
+
+	// Background job settings: schedules are cron expressions or
+	// descriptors such as @daily, and "off" disables a job
+	CleanupSchedule         string
+	CompactionSchedule      string
+	VariantBackfillSchedule string
+	StatsRollupSchedule     string
+	JobJitter               time.Duration
+	JobLockPath             string
+
+	// AdminToken enables the /admin endpoints for requests bearing it
+	AdminToken string
+
+	// Save queue settings
+	SaveQueuePath           string
+	SaveQueueMaxAttempts    int
+	SaveQueueInitialBackoff time.Duration
+	SaveQueueMaxBackoff     time.Duration
+
+	// Health check settings
+	HealthMinFreeDiskBytes int64
+	HealthAnalyzerProbeTTL time.Duration
+
+	// Logging settings
+	LogFormat          string
+	LogLevel           string
+	LogComponentLevels string
+
+	// Tracing settings
+	OTLPEndpoint     string
+	TraceServiceName string
+	TraceSampleRatio float64
 
 	// Environment
 	Environment string
@@ -33,14 +105,76 @@ type Config struct {
 // Load reads configuration from environment variables
 func Load() (*Config, error) {
 	config := &Config{
-		Port:               getEnvOrDefault("PORT", "8080"),
-		CORSOrigin:         getEnvOrDefault("CORS_ORIGIN", "*"),
-		GeminiAPIKey:       os.Getenv("GEMINI_API_KEY"),
-		GeminiTimeout:      getDurationOrDefault("GEMINI_TIMEOUT", 30*time.Second),
-		MaxFileSize:        getInt64OrDefault("MAX_FILE_SIZE", 10*1024*1024), // 10MB
-		PhotoStoragePath:   getEnvOrDefault("PHOTO_STORAGE_PATH", "./photos"),
-		PhotoRetentionDays: getIntOrDefault("PHOTO_RETENTION_DAYS", 90),
-		Environment:        getEnvOrDefault("ENV", "development"),
+		Port:                      getEnvOrDefault("PORT", "8080"),
+		CORSOrigin:                getEnvOrDefault("CORS_ORIGIN", "*"),
+		GeminiAPIKey:              os.Getenv("GEMINI_API_KEY"),
+		GeminiTimeout:             getDurationOrDefault("GEMINI_TIMEOUT", 30*time.Second),
+		CompressionMaxDimension:   getIntOrDefault("COMPRESSION_MAX_DIMENSION", 1600),
+		CompressionQuality:        getIntOrDefault("COMPRESSION_QUALITY", 75),
+		CompressionMinQuality:     getIntOrDefault("COMPRESSION_MIN_QUALITY", 40),
+		CompressionMaxBytes:       getIntOrDefault("COMPRESSION_MAX_BYTES", 0),
+		CompressionMaxTokens:      getIntOrDefault("COMPRESSION_MAX_TOKENS", 0),
+		CompressionConvertJPEG:    getBoolOrDefault("COMPRESSION_CONVERT_TO_JPEG", false),
+		CompressionAdaptive:       getBoolOrDefault("COMPRESSION_ADAPTIVE", false),
+		MaxFileSize:               getInt64OrDefault("MAX_FILE_SIZE", 10*1024*1024), // 10MB
+		MaxImagePixels:            getInt64OrDefault("MAX_IMAGE_PIXELS", 50_000_000),
+		MaxImageDimension:         getIntOrDefault("MAX_IMAGE_DIMENSION", 10_000),
+		QualityMinBrightness:      getFloat64OrDefault("QUALITY_MIN_BRIGHTNESS", 15),
+		QualityMaxBrightness:      getFloat64OrDefault("QUALITY_MAX_BRIGHTNESS", 245),
+		QualityMinContrast:        getFloat64OrDefault("QUALITY_MIN_CONTRAST", 8),
+		QualityMinSharpness:       getFloat64OrDefault("QUALITY_MIN_SHARPNESS", 10),
+		ModerationEnabled:         getBoolOrDefault("MODERATION_ENABLED", true),
+		ModerationThreshold:       getEnvOrDefault("MODERATION_THRESHOLD", "medium"),
+		PhotoStoragePath:          getEnvOrDefault("PHOTO_STORAGE_PATH", "./photos"),
+		PhotoRetentionDays:        getIntOrDefault("PHOTO_RETENTION_DAYS", 90),
+		RecordCaptureMetadata:     getBoolOrDefault("RECORD_CAPTURE_METADATA", false),
+		InadmissibleRetentionDays: getIntOrDefault("INADMISSIBLE_RETENTION_DAYS", 7),
+		SharedRetentionDays:       getIntOrDefault("SHARED_RETENTION_DAYS", 365),
+		RetentionDryRun:           getBoolOrDefault("RETENTION_DRY_RUN", false),
+		PhotoVariantWidths:        getIntListOrDefault("PHOTO_VARIANT_WIDTHS", []int{400, 1200}),
+		PhotoVariantFormats:       getListOrDefault("PHOTO_VARIANT_FORMATS", []string{"webp", "jpeg"}),
+		StorageQuotaBytes:         getInt64OrDefault("STORAGE_QUOTA_BYTES", 0),
+		StorageQuotaFiles:         getInt64OrDefault("STORAGE_QUOTA_FILES", 0),
+		StorageQuotaHighWater:     getFloat64OrDefault("STORAGE_QUOTA_HIGH_WATER", 0.9),
+		StorageQuotaLowWater:      getFloat64OrDefault("STORAGE_QUOTA_LOW_WATER", 0.8),
+		CleanupSchedule:           getEnvOrDefault("CLEANUP_SCHEDULE", "0 3 * * *"),
+		CompactionSchedule:        getEnvOrDefault("COMPACTION_SCHEDULE", "30 3 * * *"),
+		VariantBackfillSchedule:   getEnvOrDefault("VARIANT_BACKFILL_SCHEDULE", "0 4 * * *"),
+		StatsRollupSchedule:       getEnvOrDefault("STATS_ROLLUP_SCHEDULE", "0 * * * *"),
+		JobJitter:                 getDurationOrDefault("JOB_JITTER", 5*time.Minute),
+		JobLockPath:               os.Getenv("JOB_LOCK_PATH"),
+		AdminToken:                os.Getenv("ADMIN_TOKEN"),
+		SaveQueuePath:             os.Getenv("SAVE_QUEUE_PATH"),
+		SaveQueueMaxAttempts:      getIntOrDefault("SAVE_QUEUE_MAX_ATTEMPTS", 8),
+		SaveQueueInitialBackoff:   getDurationOrDefault("SAVE_QUEUE_INITIAL_BACKOFF", time.Second),
+		SaveQueueMaxBackoff:       getDurationOrDefault("SAVE_QUEUE_MAX_BACKOFF", 5*time.Minute),
+		HealthMinFreeDiskBytes:    getInt64OrDefault("HEALTH_MIN_FREE_DISK_BYTES", 100*1024*1024), // 100MB
+		HealthAnalyzerProbeTTL:    getDurationOrDefault("HEALTH_ANALYZER_PROBE_TTL", 5*time.Minute),
+		LogFormat:                 os.Getenv("LOG_FORMAT"),
+		LogLevel:                  getEnvOrDefault("LOG_LEVEL", "info"),
+		LogComponentLevels:        os.Getenv("LOG_LEVELS"),
+		OTLPEndpoint:              os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"),
+		TraceServiceName:          getEnvOrDefault("OTEL_SERVICE_NAME", "rechtebank-backend"),
+		TraceSampleRatio:          getFloat64OrDefault("OTEL_TRACES_SAMPLE_RATIO", 1.0),
+		Environment:               getEnvOrDefault("ENV", "development"),
+	}
+
+	// The queue lives next to the photos unless placed elsewhere
+	if config.SaveQueuePath == "" {
+		config.SaveQueuePath = filepath.Join(config.PhotoStoragePath, ".queue")
+	}
+
+	// Job locks must be on storage every replica shares
+	if config.JobLockPath == "" {
+		config.JobLockPath = filepath.Join(config.PhotoStoragePath, ".locks")
+	}
+
+	// JSON logs in production unless explicitly overridden
+	if config.LogFormat == "" {
+		config.LogFormat = "text"
+		if !config.IsDevelopment() {
+			config.LogFormat = "json"
+		}
 	}
 
 	// Validate required fields
@@ -56,6 +190,12 @@ func (c *Config) Validate() error {
 	if c.GeminiAPIKey == "" {
 		return errors.New("GEMINI_API_KEY environment variable is required")
 	}
+	if c.CompressionQuality < 1 || c.CompressionQuality > 100 {
+		return errors.New("COMPRESSION_QUALITY must be between 1 and 100")
+	}
+	if c.CompressionMinQuality < 1 || c.CompressionMinQuality > c.CompressionQuality {
+		return errors.New("COMPRESSION_MIN_QUALITY must be between 1 and COMPRESSION_QUALITY")
+	}
 	return nil
 }
 
@@ -97,3 +237,50 @@ func getIntOrDefault(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getFloat64OrDefault(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
+func getBoolOrDefault(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
+// getListOrDefault parses a comma-separated list. A variable that is set but
+// empty (or only commas) yields an empty list, which lets lists be disabled.
+func getListOrDefault(key string, defaultValue []string) []string {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return defaultValue
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func getIntListOrDefault(key string, defaultValue []int) []int {
+	if _, ok := os.LookupEnv(key); !ok {
+		return defaultValue
+	}
+	var list []int
+	for _, item := range getListOrDefault(key, nil) {
+		if intValue, err := strconv.Atoi(item); err == nil && intValue > 0 {
+			list = append(list, intValue)
+		}
+	}
+	return list
+}
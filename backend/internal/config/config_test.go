@@ -0,0 +1,37 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_QualityThresholdDefaults(t *testing.T) {
+	t.Setenv("GEMINI_API_KEY", "test-key")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+
+	assert.Equal(t, 15.0, cfg.QualityMinBrightness)
+	assert.Equal(t, 245.0, cfg.QualityMaxBrightness)
+	assert.Equal(t, 8.0, cfg.QualityMinContrast)
+	assert.Equal(t, 10.0, cfg.QualityMinSharpness)
+}
+
+func TestLoad_QualityThresholdsFromEnvironment(t *testing.T) {
+	t.Setenv("GEMINI_API_KEY", "test-key")
+	t.Setenv("QUALITY_MIN_BRIGHTNESS", "20.5")
+	t.Setenv("QUALITY_MAX_BRIGHTNESS", "230")
+	t.Setenv("QUALITY_MIN_CONTRAST", "0")
+	t.Setenv("QUALITY_MIN_SHARPNESS", "not-a-number")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+
+	assert.Equal(t, 20.5, cfg.QualityMinBrightness)
+	assert.Equal(t, 230.0, cfg.QualityMaxBrightness)
+	assert.Zero(t, cfg.QualityMinContrast)
+	// Unparsable values fall back to the default
+	assert.Equal(t, 10.0, cfg.QualityMinSharpness)
+}
@@ -0,0 +1,94 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrorCode is a stable, machine-readable identifier for an error returned by the API
+type ErrorCode string
+
+// Error codes shared by the core and the adapters
+const (
+	ErrCodeInvalidRequest          ErrorCode = "INVALID_REQUEST"
+	ErrCodePhotoRequired           ErrorCode = "PHOTO_REQUIRED"
+	ErrCodePhotoTooLarge           ErrorCode = "PHOTO_TOO_LARGE"
+	ErrCodeUnsupportedFormat       ErrorCode = "UNSUPPORTED_FORMAT"
+	ErrCodeImageTooLarge           ErrorCode = "IMAGE_TOO_LARGE"
+	ErrCodeImageCorrupt            ErrorCode = "IMAGE_CORRUPT"
+	ErrCodeAnimatedImage           ErrorCode = "ANIMATED_IMAGE"
+	ErrCodeAnalyzerTimeout         ErrorCode = "ANALYZER_TIMEOUT"
+	ErrCodeAnalyzerUnavailable     ErrorCode = "ANALYZER_UNAVAILABLE"
+	ErrCodeAnalyzerInvalidResponse ErrorCode = "ANALYZER_INVALID_RESPONSE"
+	ErrCodeInvalidVerdictID        ErrorCode = "INVALID_VERDICT_ID"
+	ErrCodeVerdictNotFound         ErrorCode = "VERDICT_NOT_FOUND"
+	ErrCodeVerdictWithheld         ErrorCode = "VERDICT_WITHHELD"
+	ErrCodeStorageFailure          ErrorCode = "STORAGE_FAILURE"
+	ErrCodeStorageFull             ErrorCode = "STORAGE_FULL"
+	ErrCodeUnauthorized            ErrorCode = "UNAUTHORIZED"
+	ErrCodeInternal                ErrorCode = "INTERNAL_ERROR"
+)
+
+// ErrorCodes lists every error code the API can return
+var ErrorCodes = []ErrorCode{
+	ErrCodeInvalidRequest,
+	ErrCodePhotoRequired,
+	ErrCodePhotoTooLarge,
+	ErrCodeUnsupportedFormat,
+	ErrCodeImageTooLarge,
+	ErrCodeImageCorrupt,
+	ErrCodeAnimatedImage,
+	ErrCodeAnalyzerTimeout,
+	ErrCodeAnalyzerUnavailable,
+	ErrCodeAnalyzerInvalidResponse,
+	ErrCodeInvalidVerdictID,
+	ErrCodeVerdictNotFound,
+	ErrCodeVerdictWithheld,
+	ErrCodeStorageFailure,
+	ErrCodeStorageFull,
+	ErrCodeUnauthorized,
+	ErrCodeInternal,
+}
+
+// Error is an error carrying an ErrorCode. Message is meant for logs; clients
+// receive a localized message for the code instead.
+type Error struct {
+	Code    ErrorCode
+	Message string
+
+	// RetryAfter is set when the client may retry after a known delay
+	RetryAfter time.Duration
+
+	Err error
+}
+
+// NewError creates an Error with the given code and message
+func NewError(code ErrorCode, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// WrapError creates an Error with the given code that wraps a cause
+func WrapError(code ErrorCode, message string, err error) *Error {
+	return &Error{Code: code, Message: message, Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrorCodeOf returns the code of the first Error in err's chain, or
+// ErrCodeInternal if there is none
+func ErrorCodeOf(err error) ErrorCode {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr.Code
+	}
+	return ErrCodeInternal
+}
@@ -0,0 +1,21 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorCodeOf(t *testing.T) {
+	cause := errors.New("deadline exceeded")
+	err := WrapError(ErrCodeAnalyzerTimeout, "AI analysis timeout", cause)
+
+	assert.Equal(t, ErrCodeAnalyzerTimeout, ErrorCodeOf(err))
+	assert.Equal(t, ErrCodeAnalyzerTimeout, ErrorCodeOf(fmt.Errorf("judging: %w", err)))
+	assert.Equal(t, ErrCodeInternal, ErrorCodeOf(cause))
+	assert.True(t, errors.Is(err, cause))
+	assert.Equal(t, "AI analysis timeout: deadline exceeded", err.Error())
+	assert.Equal(t, "Verdict not found", NewError(ErrCodeVerdictNotFound, "Verdict not found").Error())
+}
@@ -0,0 +1,79 @@
+package domain
+
+import "strings"
+
+// HarmProbability is how likely content belongs to a harm category, ordered
+// from negligible to high
+type HarmProbability int
+
+// Harm probabilities reported by content classifiers
+const (
+	HarmProbabilityUnknown HarmProbability = iota
+	HarmProbabilityNegligible
+	HarmProbabilityLow
+	HarmProbabilityMedium
+	HarmProbabilityHigh
+)
+
+var harmProbabilityNames = map[HarmProbability]string{
+	HarmProbabilityUnknown:    "unknown",
+	HarmProbabilityNegligible: "negligible",
+	HarmProbabilityLow:        "low",
+	HarmProbabilityMedium:     "medium",
+	HarmProbabilityHigh:       "high",
+}
+
+func (p HarmProbability) String() string {
+	if name, ok := harmProbabilityNames[p]; ok {
+		return name
+	}
+	return harmProbabilityNames[HarmProbabilityUnknown]
+}
+
+// ParseHarmProbability parses "negligible", "low", "medium" or "high"
+func ParseHarmProbability(name string) (HarmProbability, bool) {
+	for p, n := range harmProbabilityNames {
+		if p != HarmProbabilityUnknown && strings.EqualFold(n, name) {
+			return p, true
+		}
+	}
+	return HarmProbabilityUnknown, false
+}
+
+// SafetyRating is a content classification reported alongside an analysis
+type SafetyRating struct {
+	// Category is the harm category, e.g. "sexually_explicit"
+	Category    string
+	Probability HarmProbability
+
+	// Blocked is set when the classifier refused the content outright
+	Blocked bool
+}
+
+// ModerationUnavailable is the category recorded when no moderation decision
+// could be made; such photos are withheld like flagged ones
+const ModerationUnavailable = "moderation_unavailable"
+
+// ModerationResult records whether a photo may be stored and shared
+type ModerationResult struct {
+	Flagged bool `json:"flagged"`
+
+	// Categories lists the harm categories that caused the flag
+	Categories []string `json:"categories,omitempty"`
+}
+
+// ContentBlockedVerdict returns the procedural rejection for a photo the
+// analyzer refused to judge on safety grounds
+func ContentBlockedVerdict() *VerdictResponse {
+	return &VerdictResponse{
+		Admissible: false,
+		Score:      0,
+		Verdict: VerdictDetails{
+			Crime:       "Bewijsmateriaal ontoelaatbaar",
+			Sentence:    "Zaak niet-ontvankelijk verklaard. Het bewijsmateriaal is verzegeld en wordt niet in het openbaar register opgenomen.",
+			Reasoning:   "Krachtens Artikel 0.1 van het Wetboek van Fatsoen neemt dit Hof uitsluitend meubilair in behandeling. Het ingediende stuk valt daar naar het oordeel van de griffie niet onder.",
+			Observation: "De griffie heeft het ingediende stuk onmiddellijk afgedekt.",
+			VerdictType: VerdictTypeNietOntvankelijk,
+		},
+	}
+}
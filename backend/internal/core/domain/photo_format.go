@@ -0,0 +1,46 @@
+package domain
+
+import "bytes"
+
+// PhotoFormat describes how a stored photo is named on disk and served
+type PhotoFormat struct {
+	// MIMEType is the content type served for the photo, e.g. "image/png"
+	MIMEType string
+
+	// Extension is the file extension including the dot, e.g. ".png"
+	Extension string
+}
+
+// Formats photos are stored in. Uploads in other formats are converted to
+// JPEG before storage.
+var (
+	PhotoFormatJPEG = PhotoFormat{MIMEType: "image/jpeg", Extension: ".jpg"}
+	PhotoFormatPNG  = PhotoFormat{MIMEType: "image/png", Extension: ".png"}
+	PhotoFormatWebP = PhotoFormat{MIMEType: "image/webp", Extension: ".webp"}
+)
+
+// PhotoFormats lists every stored photo format, JPEG first
+var PhotoFormats = []PhotoFormat{PhotoFormatJPEG, PhotoFormatPNG, PhotoFormatWebP}
+
+// DetectPhotoFormat identifies a stored photo format from its magic bytes
+func DetectPhotoFormat(data []byte) (PhotoFormat, bool) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF}):
+		return PhotoFormatJPEG, true
+	case bytes.HasPrefix(data, []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A}):
+		return PhotoFormatPNG, true
+	case len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return PhotoFormatWebP, true
+	}
+	return PhotoFormat{}, false
+}
+
+// PhotoFormatForMIMEType returns the stored photo format with the given MIME type
+func PhotoFormatForMIMEType(mimeType string) (PhotoFormat, bool) {
+	for _, format := range PhotoFormats {
+		if format.MIMEType == mimeType {
+			return format, true
+		}
+	}
+	return PhotoFormat{}, false
+}
@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectPhotoFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		expected PhotoFormat
+		ok       bool
+	}{
+		{"JPEG", []byte{0xFF, 0xD8, 0xFF, 0xE0}, PhotoFormatJPEG, true},
+		{"PNG", []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A, 0, 0, 0, 0x0D}, PhotoFormatPNG, true},
+		{"WebP", []byte("RIFF\x1a\x00\x00\x00WEBPVP8L"), PhotoFormatWebP, true},
+		{"RIFF but not WebP", []byte("RIFF\x1a\x00\x00\x00WAVEfmt "), PhotoFormat{}, false},
+		{"unknown", []byte("GIF89a"), PhotoFormat{}, false},
+		{"empty", nil, PhotoFormat{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, ok := DetectPhotoFormat(tt.data)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, format)
+		})
+	}
+}
+
+func TestPhotoFormatForMIMEType(t *testing.T) {
+	for _, format := range PhotoFormats {
+		found, ok := PhotoFormatForMIMEType(format.MIMEType)
+		assert.True(t, ok)
+		assert.Equal(t, format, found)
+	}
+
+	_, ok := PhotoFormatForMIMEType("image/gif")
+	assert.False(t, ok)
+}
@@ -0,0 +1,68 @@
+package domain
+
+import "fmt"
+
+// PhotoQualityIssue names why a photo cannot be judged
+type PhotoQualityIssue string
+
+// Photo quality issues found by the quality gate
+const (
+	PhotoTooDark     PhotoQualityIssue = "too_dark"
+	PhotoTooBright   PhotoQualityIssue = "too_bright"
+	PhotoLowContrast PhotoQualityIssue = "low_contrast"
+	PhotoBlurry      PhotoQualityIssue = "blurry"
+)
+
+// UnreadablePhotoError is returned by validation for a photo that decodes
+// fine but is too dark, too bright, too flat or too blurry to judge
+type UnreadablePhotoError struct {
+	Issue PhotoQualityIssue
+
+	// Value is the measured metric and Threshold the limit it failed
+	Value     float64
+	Threshold float64
+}
+
+func (e *UnreadablePhotoError) Error() string {
+	return fmt.Sprintf("photo unreadable: %s (measured %.1f, threshold %.1f)", e.Issue, e.Value, e.Threshold)
+}
+
+// unreadableReasoning holds the court's explanation for each quality issue
+var unreadableReasoning = map[PhotoQualityIssue]struct{ observation, reasoning string }{
+	PhotoTooDark: {
+		"Het Hof tuurde langdurig in volstrekte duisternis.",
+		"Het Hof heeft het bewijsmateriaal met een zaklamp doorzocht, doch trof slechts duisternis aan. Artikel 1.1 van het Wetboek van Belichting schrijft voor dat de verdachte ten minste zichtbaar dient te zijn.",
+	},
+	PhotoTooBright: {
+		"Het Hof werd verblind door een zee van wit licht.",
+		"Het bewijsmateriaal is dermate overbelicht dat de griffier een zonnebril moest aanvragen. Overeenkomstig Artikel 1.2 van het Wetboek van Belichting kan een meubelstuk dat in het licht is opgelost niet worden berecht.",
+	},
+	PhotoLowContrast: {
+		"Het Hof ontving een nagenoeg blanco vel.",
+		"Op het ingediende stuk is niets te onderscheiden. Een meubelstuk dat niet van zijn omgeving te onderscheiden is, geniet krachtens Artikel 2.7 van het Wetboek van Stoelgang onschendbaarheid wegens onvindbaarheid.",
+	},
+	PhotoBlurry: {
+		"Het Hof zag slechts vage vormen, als door beslagen glas.",
+		"Het bewijsmateriaal is zo wazig dat het Hof zijn leesbril driemaal tevergeefs heeft opgepoetst. Artikel 3.14 van het Wetboek van Scherpstelling eist dat de verdachte stilstaat terwijl het vonnis wordt vastgelegd.",
+	},
+}
+
+// UnreadableEvidenceVerdict returns the procedural rejection for a photo that
+// failed the quality gate. The analyzer is never consulted for it.
+func UnreadableEvidenceVerdict(issue PhotoQualityIssue) *VerdictResponse {
+	text, ok := unreadableReasoning[issue]
+	if !ok {
+		text = unreadableReasoning[PhotoBlurry]
+	}
+	return &VerdictResponse{
+		Admissible: false,
+		Score:      0,
+		Verdict: VerdictDetails{
+			Crime:       "Bewijsmateriaal onleesbaar",
+			Sentence:    "Zaak niet-ontvankelijk verklaard. Indiener wordt verzocht een scherpe, goed belichte foto te overleggen.",
+			Reasoning:   text.reasoning,
+			Observation: text.observation,
+			VerdictType: VerdictTypeNietOntvankelijk,
+		},
+	}
+}
@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// Storage key layouts: cases are filed per UTC day, named after the UTC time
+const (
+	StorageKeyDateLayout = "2006-01-02"
+	StorageKeyTimeLayout = "150405"
+)
+
+// NewStorageKey returns the key a verdict is stored under: its UTC date
+// directory and file name without extension, e.g. "2026-02-01/153045_abc123".
+// The key is what EncodeVerdictID turns into a share ID.
+func NewStorageKey(at time.Time, requestID string) string {
+	utc := at.UTC()
+	return utc.Format(StorageKeyDateLayout) + "/" + utc.Format(StorageKeyTimeLayout) + "_" + requestID
+}
+
+// ValidStorageKey reports whether key is a storage key for requestID that
+// stays inside its date directory
+func ValidStorageKey(key, requestID string) bool {
+	dateDir, name, ok := strings.Cut(key, "/")
+	if !ok || requestID == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return false
+	}
+	if _, err := time.Parse(StorageKeyDateLayout, dateDir); err != nil {
+		return false
+	}
+	timePart, ok := strings.CutSuffix(name, "_"+requestID)
+	if !ok {
+		return false
+	}
+	_, err := time.Parse(StorageKeyTimeLayout, timePart)
+	return err == nil
+}
+
+// StorageKeyRequestID returns the request ID a storage key names, and false
+// when key is not a valid storage key, such as a decoded share ID that points
+// outside its date directory
+func StorageKeyRequestID(key string) (string, bool) {
+	_, name, ok := strings.Cut(key, "/")
+	if !ok {
+		return "", false
+	}
+	_, requestID, ok := strings.Cut(name, "_")
+	if !ok || !ValidStorageKey(key, requestID) {
+		return "", false
+	}
+	return requestID, true
+}
@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewStorageKey_UsesUTC(t *testing.T) {
+	amsterdam, err := time.LoadLocation("Europe/Amsterdam")
+	if err != nil {
+		t.Skip("time zone database not available")
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone database not available")
+	}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want string
+	}{
+		{"UTC", time.Date(2026, 2, 1, 15, 30, 45, 0, time.UTC), "2026-02-01/153045_abc"},
+		{"ahead of UTC", time.Date(2026, 2, 1, 16, 30, 45, 0, amsterdam), "2026-02-01/153045_abc"},
+		// Already the next day in Amsterdam, still the previous one in UTC
+		{"after local midnight", time.Date(2026, 2, 2, 0, 30, 0, 0, amsterdam), "2026-02-01/233000_abc"},
+		// Still the previous day in New York, already the next one in UTC
+		{"before local midnight", time.Date(2026, 1, 31, 23, 30, 0, 0, newYork), "2026-02-01/043000_abc"},
+		{"UTC midnight", time.Date(2026, 2, 1, 23, 59, 59, 999_999_999, time.UTC), "2026-02-01/235959_abc"},
+		{"fixed offset", time.Date(2026, 2, 1, 5, 0, 0, 0, time.FixedZone("UTC+5:30", 5*3600+1800)), "2026-01-31/233000_abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NewStorageKey(tt.at, "abc"))
+		})
+	}
+}
+
+func TestNewStorageKey_MatchesRFC3339Timestamp(t *testing.T) {
+	// The key and the verdict's timestamp describe the same second
+	at := time.Date(2026, 2, 1, 23, 59, 59, 900_000_000, time.FixedZone("CET", 3600))
+	timestamp := at.UTC().Format(time.RFC3339)
+
+	assert.Equal(t, "2026-02-01T22:59:59Z", timestamp)
+	assert.Equal(t, "2026-02-01/225959_abc", NewStorageKey(at, "abc"))
+}
+
+func TestValidStorageKey(t *testing.T) {
+	tests := []struct {
+		key       string
+		requestID string
+		want      bool
+	}{
+		{"2026-02-01/153045_abc", "abc", true},
+		{"2026-02-01/153045_abc", "other", false},
+		{"2026-02-01/abc", "abc", false},
+		{"2026-02-01/_abc", "abc", false},
+		{"2026-02-01/256045_abc", "abc", false},
+		{"not-a-date/153045_abc", "abc", false},
+		{"2026-02-01/../153045_abc", "abc", false},
+		{"2026-02-01/.153045_abc", "abc", false},
+		{"153045_abc", "abc", false},
+		{"2026-02-01/153045_", "", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ValidStorageKey(tt.key, tt.requestID), "%q for %q", tt.key, tt.requestID)
+	}
+}
+
+func TestStorageKeyRequestID(t *testing.T) {
+	requestID, ok := StorageKeyRequestID("2026-02-01/153045_550e8400-e29b-41d4-a716-446655440000")
+	assert.True(t, ok)
+	assert.Equal(t, "550e8400-e29b-41d4-a716-446655440000", requestID)
+
+	for _, key := range []string{
+		"../secret/private",
+		"2026-02-01/../../secret",
+		"2026-02-01/153045_abc/../../x",
+		"2026-02-01/153045",
+		"/etc/passwd",
+		"",
+	} {
+		_, ok := StorageKeyRequestID(key)
+		assert.False(t, ok, key)
+	}
+}
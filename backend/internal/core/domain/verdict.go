@@ -1,13 +1,117 @@
 package domain
 
+import "encoding/json"
+
 // VerdictResponse represents the full verdict response from the API
 type VerdictResponse struct {
-	Admissible bool           `json:"admissible"`
-	Score      int            `json:"score"`
-	Verdict    VerdictDetails `json:"verdict"`
-	RequestID  string         `json:"requestId"`
-	Timestamp  string         `json:"timestamp"`
-	RawJSON    string         `json:"-"` // Raw JSON from Gemini (not serialized in API responses)
+	Admissible bool              `json:"admissible"`
+	Score      int               `json:"score"`
+	Verdict    VerdictDetails    `json:"verdict"`
+	RequestID  string            `json:"requestId"`
+	Timestamp  string            `json:"timestamp"`
+	Capture    *CaptureMetadata  `json:"capture,omitempty"`
+	Moderation *ModerationResult `json:"moderation,omitempty"`
+
+	// StorageDegraded is set when the verdict could not be stored, e.g.
+	// because storage is full; it cannot be shared
+	StorageDegraded bool `json:"storageDegraded,omitempty"`
+
+	RawJSON   string `json:"-"` // Raw JSON from Gemini (not serialized in API responses)
+	ImageData []byte `json:"-"` // Sanitized photo to store (not serialized in API responses)
+
+	// SafetyRatings are the analyzer's content classifications, used for
+	// moderation (not serialized in API responses)
+	SafetyRatings []SafetyRating `json:"-"`
+
+	// Usage is the number of tokens the analysis consumed, when the analyzer
+	// reports it (not serialized in API responses)
+	Usage *TokenUsage `json:"-"`
+
+	// StorageKey is where the verdict is stored, derived from RequestID and
+	// the instant of Timestamp (not serialized in API responses)
+	StorageKey string `json:"-"`
+}
+
+// TokenUsage is the number of tokens an analysis consumed
+type TokenUsage struct {
+	PromptTokens   int `json:"promptTokens"`
+	ResponseTokens int `json:"responseTokens"`
+}
+
+// FlatJSON encodes the verdict in the flat shape the analyzer returns and
+// storage keeps, with extra fields added. It is used for verdicts that were
+// not produced by the analyzer.
+func (v *VerdictResponse) FlatJSON(extra map[string]any) (string, error) {
+	fields := map[string]any{
+		"admissible":  v.Admissible,
+		"score":       v.Score,
+		"crime":       v.Verdict.Crime,
+		"sentence":    v.Verdict.Sentence,
+		"reasoning":   v.Verdict.Reasoning,
+		"observation": v.Verdict.Observation,
+		"verdictType": v.Verdict.VerdictType,
+	}
+	for key, value := range extra {
+		fields[key] = value
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// ParseFlatJSON decodes a verdict in the flat shape storage keeps, including
+// the metadata storage adds to it
+func ParseFlatJSON(data []byte) (*VerdictResponse, error) {
+	var flat struct {
+		Admissible  bool              `json:"admissible"`
+		Score       int               `json:"score"`
+		Crime       string            `json:"crime"`
+		Sentence    string            `json:"sentence"`
+		Reasoning   string            `json:"reasoning"`
+		Observation string            `json:"observation"`
+		VerdictType string            `json:"verdictType"`
+		RequestID   string            `json:"requestId"`
+		Timestamp   string            `json:"timestamp"`
+		Capture     *CaptureMetadata  `json:"capture"`
+		Moderation  *ModerationResult `json:"moderation"`
+	}
+	if err := json.Unmarshal(data, &flat); err != nil {
+		return nil, err
+	}
+	return &VerdictResponse{
+		Admissible: flat.Admissible,
+		Score:      flat.Score,
+		Verdict: VerdictDetails{
+			Crime:       flat.Crime,
+			Sentence:    flat.Sentence,
+			Reasoning:   flat.Reasoning,
+			Observation: flat.Observation,
+			VerdictType: flat.VerdictType,
+		},
+		RequestID:  flat.RequestID,
+		Timestamp:  flat.Timestamp,
+		Capture:    flat.Capture,
+		Moderation: flat.Moderation,
+		RawJSON:    string(data),
+	}, nil
+}
+
+// Verdict classifications used in VerdictDetails.VerdictType
+const (
+	VerdictTypeVrijspraak       = "vrijspraak"
+	VerdictTypeWaarschuwing     = "waarschuwing"
+	VerdictTypeSchuldig         = "schuldig"
+	VerdictTypeNietOntvankelijk = "niet-ontvankelijk"
+)
+
+// VerdictTypes lists every valid verdict classification
+var VerdictTypes = []string{
+	VerdictTypeVrijspraak,
+	VerdictTypeWaarschuwing,
+	VerdictTypeSchuldig,
+	VerdictTypeNietOntvankelijk,
 }
 
 // VerdictDetails contains the structured components of the legal verdict
@@ -16,7 +120,7 @@ type VerdictDetails struct {
 	Sentence    string `json:"sentence"`    // The punishment
 	Reasoning   string `json:"reasoning"`   // Legal justification
 	Observation string `json:"observation"` // What the judge observed
-	VerdictType string `json:"verdictType"` // The verdict classification: vrijspraak, waarschuwing, schuldig
+	VerdictType string `json:"verdictType"` // The verdict classification: vrijspraak, waarschuwing, schuldig, niet-ontvankelijk
 }
 
 // PhotoMetadata contains information about an uploaded photo
@@ -25,3 +129,16 @@ type PhotoMetadata struct {
 	ContentType string `json:"contentType"`
 	Size        int64  `json:"size"`
 }
+
+// CaptureMetadata holds non-identifying fields kept from a photo's EXIF data
+type CaptureMetadata struct {
+	CameraModel string `json:"cameraModel,omitempty"`
+	CapturedAt  string `json:"capturedAt,omitempty"` // RFC 3339, without offset if the camera recorded none
+}
+
+// SanitizedPhoto is an uploaded photo with its metadata removed and its
+// orientation applied
+type SanitizedPhoto struct {
+	Data    []byte
+	Capture *CaptureMetadata // nil unless capture metadata is recorded
+}
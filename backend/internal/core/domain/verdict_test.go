@@ -75,3 +75,66 @@ func TestPhotoMetadata_Marshaling(t *testing.T) {
 	assert.Equal(t, metadata.ContentType, decoded.ContentType)
 	assert.Equal(t, metadata.Size, decoded.Size)
 }
+
+func TestVerdictResponse_FlatJSON(t *testing.T) {
+	verdict := VerdictResponse{
+		Admissible: false,
+		Score:      0,
+		Verdict: VerdictDetails{
+			Crime:       "Bewijsmateriaal onleesbaar",
+			VerdictType: VerdictTypeNietOntvankelijk,
+		},
+		RequestID: "ignored",
+	}
+
+	raw, err := verdict.FlatJSON(map[string]any{"qualityIssue": "too_dark"})
+	assert.NoError(t, err)
+
+	var fields map[string]any
+	assert.NoError(t, json.Unmarshal([]byte(raw), &fields))
+	assert.Equal(t, false, fields["admissible"])
+	assert.Equal(t, "Bewijsmateriaal onleesbaar", fields["crime"])
+	assert.Equal(t, VerdictTypeNietOntvankelijk, fields["verdictType"])
+	assert.Equal(t, "too_dark", fields["qualityIssue"])
+	assert.NotContains(t, fields, "requestId")
+}
+
+func TestParseHarmProbability(t *testing.T) {
+	for _, p := range []HarmProbability{HarmProbabilityNegligible, HarmProbabilityLow, HarmProbabilityMedium, HarmProbabilityHigh} {
+		parsed, ok := ParseHarmProbability(p.String())
+		assert.True(t, ok)
+		assert.Equal(t, p, parsed)
+	}
+
+	parsed, ok := ParseHarmProbability("HIGH")
+	assert.True(t, ok)
+	assert.Equal(t, HarmProbabilityHigh, parsed)
+
+	_, ok = ParseHarmProbability("unknown")
+	assert.False(t, ok)
+	_, ok = ParseHarmProbability("extreme")
+	assert.False(t, ok)
+}
+
+func TestParseFlatJSON(t *testing.T) {
+	data := []byte(`{"admissible":true,"score":6,"crime":"Scheefstand","sentence":"Berisping","reasoning":"Artikel 42","observation":"Een stoel","verdictType":"waarschuwing","requestId":"req-1","timestamp":"2026-02-01T15:30:45Z","mimeType":"image/jpeg","moderation":{"flagged":true,"categories":["violence"]}}`)
+
+	verdict, err := ParseFlatJSON(data)
+	assert.NoError(t, err)
+	assert.True(t, verdict.Admissible)
+	assert.Equal(t, 6, verdict.Score)
+	assert.Equal(t, VerdictDetails{
+		Crime:       "Scheefstand",
+		Sentence:    "Berisping",
+		Reasoning:   "Artikel 42",
+		Observation: "Een stoel",
+		VerdictType: VerdictTypeWaarschuwing,
+	}, verdict.Verdict)
+	assert.Equal(t, "req-1", verdict.RequestID)
+	assert.Equal(t, "2026-02-01T15:30:45Z", verdict.Timestamp)
+	assert.Equal(t, &ModerationResult{Flagged: true, Categories: []string{"violence"}}, verdict.Moderation)
+	assert.Equal(t, string(data), verdict.RawJSON)
+
+	_, err = ParseFlatJSON([]byte(`not json`))
+	assert.Error(t, err)
+}
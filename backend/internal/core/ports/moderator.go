@@ -0,0 +1,15 @@
+package ports
+
+import (
+	"context"
+
+	"rechtebank/backend/internal/core/domain"
+)
+
+// IContentModerator defines the interface for deciding whether a judged photo
+// may be stored and shared
+type IContentModerator interface {
+	// Moderate classifies a sanitized photo. The verdict carries any safety
+	// ratings the analyzer reported for it.
+	Moderate(ctx context.Context, imageData []byte, verdict *domain.VerdictResponse) (*domain.ModerationResult, error)
+}
@@ -0,0 +1,10 @@
+package ports
+
+import "rechtebank/backend/internal/core/domain"
+
+// IPhotoSanitizer defines the interface for removing metadata from photos
+type IPhotoSanitizer interface {
+	// Sanitize strips metadata (including GPS) and applies the orientation,
+	// returning the image to analyze and store
+	Sanitize(imageData []byte) (*domain.SanitizedPhoto, error)
+}
@@ -2,43 +2,162 @@ package services
 
 import (
 	"context"
+	"errors"
+	"log/slog"
 	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 	"rechtebank/backend/internal/core/domain"
 	"rechtebank/backend/internal/core/ports"
+	"rechtebank/backend/internal/logging"
+	"rechtebank/backend/internal/telemetry"
 )
 
+var logger = logging.Component(logging.ComponentJudge)
+
 // VerdictService orchestrates photo validation and analysis
 type VerdictService struct {
 	analyzer  ports.IPhotoAnalyzer
 	validator ports.IPhotoValidator
+	sanitizer ports.IPhotoSanitizer
+	moderator ports.IContentModerator
+
+	// now is the clock verdicts are timestamped with
+	now func() time.Time
 }
 
-// NewVerdictService creates a new VerdictService with the given dependencies
-func NewVerdictService(analyzer ports.IPhotoAnalyzer, validator ports.IPhotoValidator) *VerdictService {
+// NewVerdictService creates a new VerdictService with the given dependencies.
+// A nil sanitizer passes photos through unchanged; a nil moderator leaves
+// verdicts unmoderated.
+func NewVerdictService(analyzer ports.IPhotoAnalyzer, validator ports.IPhotoValidator, sanitizer ports.IPhotoSanitizer, moderator ports.IContentModerator) *VerdictService {
 	return &VerdictService{
 		analyzer:  analyzer,
 		validator: validator,
+		sanitizer: sanitizer,
+		moderator: moderator,
+		now:       time.Now,
 	}
 }
 
 // JudgePhoto validates and analyzes a photo, returning a verdict
 func (s *VerdictService) JudgePhoto(ctx context.Context, imageData []byte, metadata domain.PhotoMetadata) (*domain.VerdictResponse, error) {
-	// Step 1: Validate the photo
-	if err := s.validator.ValidatePhoto(imageData, metadata); err != nil {
+	ctx, span := telemetry.StartSpan(ctx, "VerdictService.JudgePhoto")
+	defer span.End()
+
+	// Step 1: Validate the photo. Photos failing the quality gate are still
+	// sanitized and stored, but judged without the analyzer.
+	var unreadable *domain.UnreadablePhotoError
+	if err := s.validatePhoto(ctx, imageData, metadata); err != nil && !errors.As(err, &unreadable) {
+		telemetry.RecordError(span, err)
 		return nil, err
 	}
 
-	// Step 2: Analyze the photo with AI
-	result, err := s.analyzer.AnalyzePhoto(ctx, imageData)
+	// Step 2: Strip metadata and apply the orientation before anything else sees the photo
+	photo, err := s.sanitizePhoto(ctx, imageData)
 	if err != nil {
+		telemetry.RecordError(span, err)
 		return nil, err
 	}
 
-	// Step 3: Add request metadata
-	result.RequestID = uuid.New().String()
-	result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	// Step 3: Analyze the photo with AI, unless it is unreadable
+	var result *domain.VerdictResponse
+	if unreadable != nil {
+		span.SetAttributes(attribute.String("photo.quality_issue", string(unreadable.Issue)))
+		result, err = unreadableVerdict(unreadable)
+	} else {
+		result, err = s.analyzer.AnalyzePhoto(ctx, photo.Data)
+	}
+	if err != nil {
+		telemetry.RecordError(span, err)
+		return nil, err
+	}
+	result.ImageData = photo.Data
+	result.Capture = photo.Capture
+
+	// Step 4: Decide whether the photo may be stored and shared
+	result.Moderation = s.moderate(ctx, photo.Data, result)
+
+	// Step 5: Add request metadata, reusing the HTTP request ID when there is one
+	result.RequestID = logging.RequestIDFromContext(ctx)
+	if result.RequestID == "" {
+		result.RequestID = uuid.New().String()
+	}
+	// The timestamp and the storage key are taken from the same instant, so
+	// the stored file names always match the timestamp the client sees
+	now := s.now().UTC()
+	result.Timestamp = now.Format(time.RFC3339)
+	result.StorageKey = domain.NewStorageKey(now, result.RequestID)
+
+	span.SetAttributes(
+		attribute.String("request.id", result.RequestID),
+		attribute.Bool("verdict.admissible", result.Admissible),
+		attribute.Int("verdict.score", result.Score),
+	)
+	if result.Moderation != nil {
+		span.SetAttributes(attribute.Bool("moderation.flagged", result.Moderation.Flagged))
+	}
+
+	return result, nil
+}
+
+// validatePhoto runs the validator inside its own span
+func (s *VerdictService) validatePhoto(ctx context.Context, imageData []byte, metadata domain.PhotoMetadata) error {
+	_, span := telemetry.StartSpan(ctx, "PhotoValidator.ValidatePhoto",
+		attribute.Int64("photo.size", metadata.Size))
+	defer span.End()
+
+	err := s.validator.ValidatePhoto(imageData, metadata)
+	telemetry.RecordError(span, err)
+	return err
+}
+
+// sanitizePhoto runs the sanitizer inside its own span
+func (s *VerdictService) sanitizePhoto(ctx context.Context, imageData []byte) (*domain.SanitizedPhoto, error) {
+	if s.sanitizer == nil {
+		return &domain.SanitizedPhoto{Data: imageData}, nil
+	}
+
+	_, span := telemetry.StartSpan(ctx, "PhotoSanitizer.Sanitize")
+	defer span.End()
 
+	photo, err := s.sanitizer.Sanitize(imageData)
+	if err != nil {
+		telemetry.RecordError(span, err)
+		return nil, domain.WrapError(domain.ErrCodeImageCorrupt, "failed to sanitize photo", err)
+	}
+	return photo, nil
+}
+
+// moderate runs the moderator inside its own span. A failing moderator flags
+// the photo: without a decision it must not become public.
+func (s *VerdictService) moderate(ctx context.Context, imageData []byte, verdict *domain.VerdictResponse) *domain.ModerationResult {
+	if s.moderator == nil {
+		return nil
+	}
+
+	ctx, span := telemetry.StartSpan(ctx, "ContentModerator.Moderate")
+	defer span.End()
+
+	result, err := s.moderator.Moderate(ctx, imageData, verdict)
+	if err != nil {
+		telemetry.RecordError(span, err)
+		logger.WarnContext(ctx, "Moderation failed, withholding photo", slog.Any("error", err))
+		return &domain.ModerationResult{Flagged: true, Categories: []string{domain.ModerationUnavailable}}
+	}
+	span.SetAttributes(attribute.Bool("moderation.flagged", result.Flagged))
+	return result
+}
+
+// unreadableVerdict returns the procedural rejection for a photo that failed
+// the quality gate. Its RawJSON has the analyzer's flat shape, so it is stored
+// like any other verdict, plus the quality issue that caused it.
+func unreadableVerdict(unreadable *domain.UnreadablePhotoError) (*domain.VerdictResponse, error) {
+	result := domain.UnreadableEvidenceVerdict(unreadable.Issue)
+	raw, err := result.FlatJSON(map[string]any{"qualityIssue": unreadable.Issue})
+	if err != nil {
+		return nil, domain.WrapError(domain.ErrCodeInternal, "failed to encode verdict", err)
+	}
+	result.RawJSON = raw
 	return result, nil
 }
@@ -8,9 +8,14 @@ import (
 	"time"
 
 	"rechtebank/backend/internal/core/domain"
+	"rechtebank/backend/internal/logging"
+	"rechtebank/backend/internal/telemetry"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 // MockAnalyzer mocks the IPhotoAnalyzer interface
@@ -26,6 +31,19 @@ func (m *MockAnalyzer) AnalyzePhoto(ctx context.Context, imageData []byte) (*dom
 	return args.Get(0).(*domain.VerdictResponse), args.Error(1)
 }
 
+// MockModerator mocks the IContentModerator interface
+type MockModerator struct {
+	mock.Mock
+}
+
+func (m *MockModerator) Moderate(ctx context.Context, imageData []byte, verdict *domain.VerdictResponse) (*domain.ModerationResult, error) {
+	args := m.Called(ctx, imageData, verdict)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ModerationResult), args.Error(1)
+}
+
 // MockValidator mocks the IPhotoValidator interface
 type MockValidator struct {
 	mock.Mock
@@ -36,10 +54,23 @@ func (m *MockValidator) ValidatePhoto(imageData []byte, metadata domain.PhotoMet
 	return args.Error(0)
 }
 
+// MockSanitizer mocks the IPhotoSanitizer interface
+type MockSanitizer struct {
+	mock.Mock
+}
+
+func (m *MockSanitizer) Sanitize(imageData []byte) (*domain.SanitizedPhoto, error) {
+	args := m.Called(imageData)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.SanitizedPhoto), args.Error(1)
+}
+
 func TestVerdictService_JudgePhoto_Success(t *testing.T) {
 	mockAnalyzer := new(MockAnalyzer)
 	mockValidator := new(MockValidator)
-	service := NewVerdictService(mockAnalyzer, mockValidator)
+	service := NewVerdictService(mockAnalyzer, mockValidator, nil, nil)
 
 	imageData := []byte{0xFF, 0xD8, 0xFF}
 	metadata := domain.PhotoMetadata{
@@ -79,7 +110,7 @@ func TestVerdictService_JudgePhoto_Success(t *testing.T) {
 func TestVerdictService_JudgePhoto_ValidationError(t *testing.T) {
 	mockAnalyzer := new(MockAnalyzer)
 	mockValidator := new(MockValidator)
-	service := NewVerdictService(mockAnalyzer, mockValidator)
+	service := NewVerdictService(mockAnalyzer, mockValidator, nil, nil)
 
 	imageData := []byte{0x00, 0x00, 0x00}
 	metadata := domain.PhotoMetadata{
@@ -103,7 +134,7 @@ func TestVerdictService_JudgePhoto_ValidationError(t *testing.T) {
 func TestVerdictService_JudgePhoto_AnalysisError(t *testing.T) {
 	mockAnalyzer := new(MockAnalyzer)
 	mockValidator := new(MockValidator)
-	service := NewVerdictService(mockAnalyzer, mockValidator)
+	service := NewVerdictService(mockAnalyzer, mockValidator, nil, nil)
 
 	imageData := []byte{0xFF, 0xD8, 0xFF}
 	metadata := domain.PhotoMetadata{
@@ -128,7 +159,7 @@ func TestVerdictService_JudgePhoto_AnalysisError(t *testing.T) {
 func TestVerdictService_JudgePhoto_RequestIDFormat(t *testing.T) {
 	mockAnalyzer := new(MockAnalyzer)
 	mockValidator := new(MockValidator)
-	service := NewVerdictService(mockAnalyzer, mockValidator)
+	service := NewVerdictService(mockAnalyzer, mockValidator, nil, nil)
 
 	imageData := []byte{0xFF, 0xD8, 0xFF}
 	metadata := domain.PhotoMetadata{
@@ -164,7 +195,7 @@ func TestVerdictService_JudgePhoto_RequestIDFormat(t *testing.T) {
 func TestVerdictService_JudgePhoto_TimestampFormat(t *testing.T) {
 	mockAnalyzer := new(MockAnalyzer)
 	mockValidator := new(MockValidator)
-	service := NewVerdictService(mockAnalyzer, mockValidator)
+	service := NewVerdictService(mockAnalyzer, mockValidator, nil, nil)
 
 	imageData := []byte{0xFF, 0xD8, 0xFF}
 	metadata := domain.PhotoMetadata{
@@ -203,10 +234,47 @@ func TestVerdictService_JudgePhoto_TimestampFormat(t *testing.T) {
 	assert.True(t, !parsedTime.After(afterTime), "Timestamp should be before test ended, got: %s, expected before: %s", parsedTime, afterTime)
 }
 
+func TestVerdictService_JudgePhoto_StorageKeyMatchesTimestamp(t *testing.T) {
+	amsterdam, err := time.LoadLocation("Europe/Amsterdam")
+	if err != nil {
+		t.Skip("time zone database not available")
+	}
+
+	tests := []struct {
+		name          string
+		now           time.Time
+		wantTimestamp string
+		wantKey       string
+	}{
+		{"local clock past midnight", time.Date(2026, 2, 2, 0, 15, 0, 0, amsterdam), "2026-02-01T23:15:00Z", "2026-02-01/231500_req-1"},
+		{"last instant of the UTC day", time.Date(2026, 2, 1, 23, 59, 59, 999_000_000, time.UTC), "2026-02-01T23:59:59Z", "2026-02-01/235959_req-1"},
+		{"first instant of the UTC day", time.Date(2026, 2, 2, 1, 0, 0, 0, amsterdam), "2026-02-02T00:00:00Z", "2026-02-02/000000_req-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAnalyzer := new(MockAnalyzer)
+			mockValidator := new(MockValidator)
+			service := NewVerdictService(mockAnalyzer, mockValidator, nil, nil)
+			service.now = func() time.Time { return tt.now }
+
+			imageData := []byte{0xFF, 0xD8, 0xFF}
+			mockValidator.On("ValidatePhoto", imageData, mock.Anything).Return(nil)
+			mockAnalyzer.On("AnalyzePhoto", mock.Anything, imageData).Return(&domain.VerdictResponse{Admissible: true, Score: 7}, nil)
+
+			ctx := logging.WithRequestID(context.Background(), "req-1")
+			result, err := service.JudgePhoto(ctx, imageData, domain.PhotoMetadata{Size: 3})
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantTimestamp, result.Timestamp)
+			assert.Equal(t, tt.wantKey, result.StorageKey)
+		})
+	}
+}
+
 func TestVerdictService_JudgePhoto_UniqueRequestIDs(t *testing.T) {
 	mockAnalyzer := new(MockAnalyzer)
 	mockValidator := new(MockValidator)
-	service := NewVerdictService(mockAnalyzer, mockValidator)
+	service := NewVerdictService(mockAnalyzer, mockValidator, nil, nil)
 
 	imageData := []byte{0xFF, 0xD8, 0xFF}
 	metadata := domain.PhotoMetadata{
@@ -238,3 +306,202 @@ func TestVerdictService_JudgePhoto_UniqueRequestIDs(t *testing.T) {
 		requestIDs[result.RequestID] = true
 	}
 }
+
+func TestVerdictService_JudgePhoto_CreatesSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(telemetry.NewTracerProvider(telemetry.Config{SampleRatio: 1.0}, sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	mockAnalyzer := new(MockAnalyzer)
+	mockValidator := new(MockValidator)
+	service := NewVerdictService(mockAnalyzer, mockValidator, nil, nil)
+
+	imageData := []byte{0xFF, 0xD8, 0xFF}
+	metadata := domain.PhotoMetadata{Filename: "test.jpg", Size: 3}
+
+	mockValidator.On("ValidatePhoto", imageData, metadata).Return(nil)
+	mockAnalyzer.On("AnalyzePhoto", mock.Anything, imageData).Return(&domain.VerdictResponse{Admissible: true, Score: 7}, nil)
+
+	_, err := service.JudgePhoto(context.Background(), imageData, metadata)
+	assert.NoError(t, err)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "PhotoValidator.ValidatePhoto", spans[0].Name())
+	assert.Equal(t, "VerdictService.JudgePhoto", spans[1].Name())
+	assert.Equal(t, spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+}
+
+func TestVerdictService_JudgePhoto_UsesContextRequestID(t *testing.T) {
+	mockAnalyzer := new(MockAnalyzer)
+	mockValidator := new(MockValidator)
+	service := NewVerdictService(mockAnalyzer, mockValidator, nil, nil)
+
+	imageData := []byte{0xFF, 0xD8, 0xFF}
+	metadata := domain.PhotoMetadata{Filename: "test.jpg", Size: 3}
+
+	mockValidator.On("ValidatePhoto", imageData, metadata).Return(nil)
+	mockAnalyzer.On("AnalyzePhoto", mock.Anything, imageData).Return(&domain.VerdictResponse{Admissible: true, Score: 7}, nil)
+
+	ctx := logging.WithRequestID(context.Background(), "550e8400-e29b-41d4-a716-446655440000")
+	result, err := service.JudgePhoto(ctx, imageData, metadata)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "550e8400-e29b-41d4-a716-446655440000", result.RequestID)
+}
+
+func TestVerdictService_JudgePhoto_AnalyzesSanitizedPhoto(t *testing.T) {
+	mockAnalyzer := new(MockAnalyzer)
+	mockValidator := new(MockValidator)
+	mockSanitizer := new(MockSanitizer)
+	service := NewVerdictService(mockAnalyzer, mockValidator, mockSanitizer, nil)
+
+	imageData := []byte{0xFF, 0xD8, 0xFF, 0xE1}
+	sanitized := []byte{0xFF, 0xD8, 0xFF, 0xDB}
+	capture := &domain.CaptureMetadata{CameraModel: "Pixel 8"}
+	metadata := domain.PhotoMetadata{Filename: "test.jpg", ContentType: "image/jpeg", Size: 4}
+
+	mockValidator.On("ValidatePhoto", imageData, metadata).Return(nil)
+	mockSanitizer.On("Sanitize", imageData).Return(&domain.SanitizedPhoto{Data: sanitized, Capture: capture}, nil)
+	mockAnalyzer.On("AnalyzePhoto", mock.Anything, sanitized).Return(&domain.VerdictResponse{Admissible: true, Score: 9}, nil)
+
+	result, err := service.JudgePhoto(context.Background(), imageData, metadata)
+
+	assert.NoError(t, err)
+	assert.Equal(t, sanitized, result.ImageData)
+	assert.Equal(t, capture, result.Capture)
+	mockSanitizer.AssertExpectations(t)
+	mockAnalyzer.AssertExpectations(t)
+}
+
+func TestVerdictService_JudgePhoto_SanitizeError(t *testing.T) {
+	mockAnalyzer := new(MockAnalyzer)
+	mockValidator := new(MockValidator)
+	mockSanitizer := new(MockSanitizer)
+	service := NewVerdictService(mockAnalyzer, mockValidator, mockSanitizer, nil)
+
+	imageData := []byte{0xFF, 0xD8, 0xFF}
+	metadata := domain.PhotoMetadata{Filename: "test.jpg", ContentType: "image/jpeg", Size: 3}
+
+	mockValidator.On("ValidatePhoto", imageData, metadata).Return(nil)
+	mockSanitizer.On("Sanitize", imageData).Return(nil, errors.New("truncated JPEG segment"))
+
+	result, err := service.JudgePhoto(context.Background(), imageData, metadata)
+
+	assert.Nil(t, result)
+	assert.Equal(t, domain.ErrCodeImageCorrupt, domain.ErrorCodeOf(err))
+	mockAnalyzer.AssertNotCalled(t, "AnalyzePhoto", mock.Anything, mock.Anything)
+}
+
+func TestVerdictService_JudgePhoto_UnreadablePhotoSkipsAnalyzer(t *testing.T) {
+	mockAnalyzer := new(MockAnalyzer)
+	mockValidator := new(MockValidator)
+	mockSanitizer := new(MockSanitizer)
+	service := NewVerdictService(mockAnalyzer, mockValidator, mockSanitizer, nil)
+
+	imageData := []byte{0xFF, 0xD8, 0xFF, 0xE1}
+	sanitized := []byte{0xFF, 0xD8, 0xFF, 0xDB}
+	metadata := domain.PhotoMetadata{Filename: "test.jpg", ContentType: "image/jpeg", Size: 4}
+
+	mockValidator.On("ValidatePhoto", imageData, metadata).
+		Return(&domain.UnreadablePhotoError{Issue: domain.PhotoTooDark, Value: 3, Threshold: 15})
+	mockSanitizer.On("Sanitize", imageData).Return(&domain.SanitizedPhoto{Data: sanitized}, nil)
+
+	result, err := service.JudgePhoto(context.Background(), imageData, metadata)
+
+	assert.NoError(t, err)
+	assert.False(t, result.Admissible)
+	assert.Equal(t, 0, result.Score)
+	assert.Equal(t, "Bewijsmateriaal onleesbaar", result.Verdict.Crime)
+	assert.Equal(t, domain.VerdictTypeNietOntvankelijk, result.Verdict.VerdictType)
+	assert.NotEmpty(t, result.RequestID)
+	assert.NotEmpty(t, result.Timestamp)
+	assert.Equal(t, sanitized, result.ImageData)
+	assert.Contains(t, result.RawJSON, `"qualityIssue":"too_dark"`)
+	assert.Contains(t, result.RawJSON, `"verdictType":"niet-ontvankelijk"`)
+	mockAnalyzer.AssertNotCalled(t, "AnalyzePhoto", mock.Anything, mock.Anything)
+}
+
+func TestVerdictService_JudgePhoto_AttachesModeration(t *testing.T) {
+	mockAnalyzer := new(MockAnalyzer)
+	mockValidator := new(MockValidator)
+	mockModerator := new(MockModerator)
+	service := NewVerdictService(mockAnalyzer, mockValidator, nil, mockModerator)
+
+	imageData := []byte{0xFF, 0xD8, 0xFF}
+	metadata := domain.PhotoMetadata{Filename: "test.jpg", ContentType: "image/jpeg", Size: 3}
+	moderation := &domain.ModerationResult{Flagged: true, Categories: []string{"sexually_explicit"}}
+
+	mockValidator.On("ValidatePhoto", imageData, metadata).Return(nil)
+	mockAnalyzer.On("AnalyzePhoto", mock.Anything, imageData).Return(&domain.VerdictResponse{Admissible: true, Score: 7}, nil)
+	mockModerator.On("Moderate", mock.Anything, imageData, mock.Anything).Return(moderation, nil)
+
+	result, err := service.JudgePhoto(context.Background(), imageData, metadata)
+
+	assert.NoError(t, err)
+	assert.Equal(t, moderation, result.Moderation)
+	mockModerator.AssertExpectations(t)
+}
+
+func TestVerdictService_JudgePhoto_ModeratesUnreadablePhoto(t *testing.T) {
+	mockAnalyzer := new(MockAnalyzer)
+	mockValidator := new(MockValidator)
+	mockSanitizer := new(MockSanitizer)
+	mockModerator := new(MockModerator)
+	service := NewVerdictService(mockAnalyzer, mockValidator, mockSanitizer, mockModerator)
+
+	imageData := []byte{0xFF, 0xD8, 0xFF, 0xE1}
+	sanitized := []byte{0xFF, 0xD8, 0xFF, 0xDB}
+	metadata := domain.PhotoMetadata{Filename: "test.jpg", ContentType: "image/jpeg", Size: 4}
+	moderation := &domain.ModerationResult{Flagged: true, Categories: []string{"violence"}}
+
+	mockValidator.On("ValidatePhoto", imageData, metadata).
+		Return(&domain.UnreadablePhotoError{Issue: domain.PhotoBlurry, Value: 2, Threshold: 10})
+	mockSanitizer.On("Sanitize", imageData).Return(&domain.SanitizedPhoto{Data: sanitized}, nil)
+	mockModerator.On("Moderate", mock.Anything, sanitized, mock.Anything).Return(moderation, nil)
+
+	result, err := service.JudgePhoto(context.Background(), imageData, metadata)
+
+	assert.NoError(t, err)
+	assert.Equal(t, moderation, result.Moderation)
+	mockAnalyzer.AssertNotCalled(t, "AnalyzePhoto", mock.Anything, mock.Anything)
+	mockModerator.AssertExpectations(t)
+}
+
+func TestVerdictService_JudgePhoto_ModerationFailsClosed(t *testing.T) {
+	mockAnalyzer := new(MockAnalyzer)
+	mockValidator := new(MockValidator)
+	mockModerator := new(MockModerator)
+	service := NewVerdictService(mockAnalyzer, mockValidator, nil, mockModerator)
+
+	imageData := []byte{0xFF, 0xD8, 0xFF}
+	metadata := domain.PhotoMetadata{Filename: "test.jpg", ContentType: "image/jpeg", Size: 3}
+
+	mockValidator.On("ValidatePhoto", imageData, metadata).Return(nil)
+	mockAnalyzer.On("AnalyzePhoto", mock.Anything, imageData).Return(&domain.VerdictResponse{Admissible: true, Score: 7}, nil)
+	mockModerator.On("Moderate", mock.Anything, imageData, mock.Anything).Return(nil, errors.New("classifier offline"))
+
+	result, err := service.JudgePhoto(context.Background(), imageData, metadata)
+
+	assert.NoError(t, err)
+	assert.True(t, result.Moderation.Flagged)
+	assert.Equal(t, []string{domain.ModerationUnavailable}, result.Moderation.Categories)
+}
+
+func TestVerdictService_JudgePhoto_WithoutModerator(t *testing.T) {
+	mockAnalyzer := new(MockAnalyzer)
+	mockValidator := new(MockValidator)
+	service := NewVerdictService(mockAnalyzer, mockValidator, nil, nil)
+
+	imageData := []byte{0xFF, 0xD8, 0xFF}
+	metadata := domain.PhotoMetadata{Filename: "test.jpg", ContentType: "image/jpeg", Size: 3}
+
+	mockValidator.On("ValidatePhoto", imageData, metadata).Return(nil)
+	mockAnalyzer.On("AnalyzePhoto", mock.Anything, imageData).Return(&domain.VerdictResponse{Admissible: true, Score: 7}, nil)
+
+	result, err := service.JudgePhoto(context.Background(), imageData, metadata)
+
+	assert.NoError(t, err)
+	assert.Nil(t, result.Moderation)
+}
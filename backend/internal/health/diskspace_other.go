@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package health
+
+import "errors"
+
+// freeBytes is not implemented on this platform
+func freeBytes(string) (uint64, error) {
+	return 0, errors.New("free disk space check not supported on this platform")
+}
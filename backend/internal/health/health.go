@@ -0,0 +1,264 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Check status values reported per dependency
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Check verifies a single dependency
+type Check interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+// CheckResult is the outcome of a single check in a readiness report
+type CheckResult struct {
+	Status   string `json:"status"`
+	Message  string `json:"message,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report is the readiness report returned by /readyz
+type Report struct {
+	Status    string                 `json:"status"`
+	Timestamp string                 `json:"timestamp"`
+	Checks    map[string]CheckResult `json:"checks"`
+}
+
+// Ready returns true if every check passed
+func (r Report) Ready() bool {
+	return r.Status == StatusOK
+}
+
+// Checker runs a set of checks concurrently with a shared timeout
+type Checker struct {
+	checks  []Check
+	timeout time.Duration
+}
+
+// NewChecker creates a Checker for the given checks
+func NewChecker(timeout time.Duration, checks ...Check) *Checker {
+	return &Checker{
+		checks:  checks,
+		timeout: timeout,
+	}
+}
+
+// Run executes all checks and returns a per-check breakdown
+func (c *Checker) Run(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	report := Report{
+		Status:    StatusOK,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Checks:    make(map[string]CheckResult, len(c.checks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range c.checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+
+			start := time.Now()
+			err := check.Check(ctx)
+			result := CheckResult{
+				Status:   StatusOK,
+				Duration: time.Since(start).Round(time.Microsecond).String(),
+			}
+			if err != nil {
+				result.Status = StatusFail
+				result.Message = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[check.Name()] = result
+			if err != nil {
+				report.Status = StatusFail
+			}
+		}(check)
+	}
+	wg.Wait()
+
+	return report
+}
+
+// CheckFunc adapts a function into a named Check
+type CheckFunc struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// NewCheckFunc creates a named Check from a function
+func NewCheckFunc(name string, fn func(ctx context.Context) error) *CheckFunc {
+	return &CheckFunc{name: name, fn: fn}
+}
+
+// Name returns the check name
+func (c *CheckFunc) Name() string { return c.name }
+
+// Check runs the function
+func (c *CheckFunc) Check(ctx context.Context) error { return c.fn(ctx) }
+
+// StorageWritable checks that a probe file can be created under the storage path
+func StorageWritable(basePath string) Check {
+	return NewCheckFunc("storage", func(context.Context) error {
+		probe, err := os.CreateTemp(basePath, ".readyz-*")
+		if err != nil {
+			return fmt.Errorf("storage not writable: %w", err)
+		}
+		name := probe.Name()
+		probe.Close()
+		if err := os.Remove(name); err != nil {
+			return fmt.Errorf("failed to remove probe file %s: %w", filepath.Base(name), err)
+		}
+		return nil
+	})
+}
+
+// DiskSpace checks that the filesystem holding path has at least minFreeBytes available
+func DiskSpace(path string, minFreeBytes uint64) Check {
+	return NewCheckFunc("disk", func(context.Context) error {
+		free, err := freeBytes(path)
+		if err != nil {
+			return fmt.Errorf("failed to read free disk space: %w", err)
+		}
+		if free < minFreeBytes {
+			return fmt.Errorf("free disk space %d bytes below threshold %d bytes", free, minFreeBytes)
+		}
+		return nil
+	})
+}
+
+// CachedProbe wraps an expensive probe (such as a call to the analyzer API) so
+// that it runs at most once per ttl; in between, the last result is reported.
+// A timed-out probe is cached as a failure for at most timeoutTTL, so a hung
+// dependency is probed again soon but not on every readiness request. Only a
+// probe cut short because the caller went away is not cached.
+type CachedProbe struct {
+	name  string
+	probe func(ctx context.Context) error
+	ttl   time.Duration
+	now   func() time.Time
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	cachedFor time.Duration
+	lastErr   error
+}
+
+// timeoutTTL bounds how long a timed-out probe is cached
+const timeoutTTL = 30 * time.Second
+
+// NewCachedProbe creates a Check that caches the probe result for ttl
+func NewCachedProbe(name string, ttl time.Duration, probe func(ctx context.Context) error) *CachedProbe {
+	return &CachedProbe{
+		name:  name,
+		probe: probe,
+		ttl:   ttl,
+		now:   time.Now,
+	}
+}
+
+// Name returns the check name
+func (p *CachedProbe) Name() string { return p.name }
+
+// Check returns the cached result, re-probing when it has expired
+func (p *CachedProbe) Check(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.checkedAt.IsZero() && p.now().Sub(p.checkedAt) < p.cachedFor {
+		return p.lastErr
+	}
+
+	err := p.probe(ctx)
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return err
+	}
+	p.lastErr = err
+	p.checkedAt = p.now()
+	p.cachedFor = p.ttl
+	if errors.Is(err, context.DeadlineExceeded) {
+		p.cachedFor = min(p.ttl, timeoutTTL)
+	}
+	return err
+}
+
+// JobTracker records the outcome of a recurring background job
+type JobTracker struct {
+	mu          sync.RWMutex
+	startedAt   time.Time
+	lastRun     time.Time
+	lastSuccess time.Time
+	lastErr     error
+}
+
+// NewJobTracker creates a tracker; the job is considered fresh until its first
+// expected run has had time to complete
+func NewJobTracker() *JobTracker {
+	return &JobTracker{startedAt: time.Now()}
+}
+
+// RecordRun records the result of a job run
+func (t *JobTracker) RecordRun(err error) {
+	t.RecordRunAt(time.Now(), err)
+}
+
+// RecordRunAt records the result of a job run that finished at the given
+// time, such as one run by another replica. Runs older than the last one
+// recorded are ignored.
+func (t *JobTracker) RecordRunAt(at time.Time, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !at.After(t.lastRun) {
+		return
+	}
+	t.lastRun = at
+	t.lastErr = err
+	if err == nil {
+		t.lastSuccess = at
+	}
+}
+
+// LastSuccess returns the time of the last successful run (zero if none)
+func (t *JobTracker) LastSuccess() time.Time {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.lastSuccess
+}
+
+// JobFreshness checks that the tracked job succeeded within maxAge
+func JobFreshness(name string, tracker *JobTracker, maxAge time.Duration) Check {
+	return NewCheckFunc(name, func(context.Context) error {
+		tracker.mu.RLock()
+		defer tracker.mu.RUnlock()
+
+		reference := tracker.lastSuccess
+		if reference.IsZero() {
+			reference = tracker.startedAt
+		}
+		if age := time.Since(reference); age > maxAge {
+			if tracker.lastErr != nil {
+				return fmt.Errorf("no successful run for %s (last error: %v)", age.Round(time.Second), tracker.lastErr)
+			}
+			return fmt.Errorf("no successful run for %s", age.Round(time.Second))
+		}
+		return nil
+	})
+}
@@ -0,0 +1,134 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecker_AllChecksPass(t *testing.T) {
+	checker := NewChecker(time.Second,
+		NewCheckFunc("a", func(context.Context) error { return nil }),
+		NewCheckFunc("b", func(context.Context) error { return nil }),
+	)
+
+	report := checker.Run(context.Background())
+
+	assert.True(t, report.Ready())
+	assert.Equal(t, StatusOK, report.Checks["a"].Status)
+	assert.Equal(t, StatusOK, report.Checks["b"].Status)
+	assert.NotEmpty(t, report.Timestamp)
+}
+
+func TestChecker_FailingCheckMakesReportNotReady(t *testing.T) {
+	checker := NewChecker(time.Second,
+		NewCheckFunc("ok", func(context.Context) error { return nil }),
+		NewCheckFunc("broken", func(context.Context) error { return errors.New("kapot") }),
+	)
+
+	report := checker.Run(context.Background())
+
+	assert.False(t, report.Ready())
+	assert.Equal(t, StatusFail, report.Status)
+	assert.Equal(t, StatusOK, report.Checks["ok"].Status)
+	assert.Equal(t, StatusFail, report.Checks["broken"].Status)
+	assert.Equal(t, "kapot", report.Checks["broken"].Message)
+}
+
+func TestStorageWritable(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, StorageWritable(dir).Check(context.Background()))
+
+	// No probe files are left behind
+	entries, _ := os.ReadDir(dir)
+	assert.Empty(t, entries)
+
+	assert.Error(t, StorageWritable(filepath.Join(dir, "missing")).Check(context.Background()))
+}
+
+func TestDiskSpace(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, DiskSpace(dir, 1).Check(context.Background()))
+	assert.Error(t, DiskSpace(dir, ^uint64(0)).Check(context.Background()))
+}
+
+func TestCachedProbe_CachesWithinTTL(t *testing.T) {
+	calls := 0
+	probe := NewCachedProbe("analyzer", time.Minute, func(context.Context) error {
+		calls++
+		return errors.New("unreachable")
+	})
+	now := time.Now()
+	probe.now = func() time.Time { return now }
+
+	assert.Error(t, probe.Check(context.Background()))
+	assert.Error(t, probe.Check(context.Background()))
+	assert.Equal(t, 1, calls)
+
+	now = now.Add(2 * time.Minute)
+	assert.Error(t, probe.Check(context.Background()))
+	assert.Equal(t, 2, calls)
+}
+
+func TestCachedProbe_CachesTimeoutsBriefly(t *testing.T) {
+	calls := 0
+	probe := NewCachedProbe("analyzer", 5*time.Minute, func(ctx context.Context) error {
+		calls++
+		return fmt.Errorf("probe: %w", context.DeadlineExceeded)
+	})
+	now := time.Now()
+	probe.now = func() time.Time { return now }
+
+	assert.Error(t, probe.Check(context.Background()))
+	assert.Error(t, probe.Check(context.Background()))
+	assert.Equal(t, 1, calls, "a hung dependency is not probed on every request")
+
+	now = now.Add(timeoutTTL)
+	assert.Error(t, probe.Check(context.Background()))
+	assert.Equal(t, 2, calls, "but probed again well before the ttl")
+}
+
+func TestCachedProbe_DoesNotCacheCancelledRequests(t *testing.T) {
+	calls := 0
+	probe := NewCachedProbe("analyzer", time.Minute, func(ctx context.Context) error {
+		calls++
+		return ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Error(t, probe.Check(ctx))
+	assert.True(t, probe.checkedAt.IsZero())
+
+	assert.NoError(t, probe.Check(context.Background()))
+	assert.Equal(t, 2, calls)
+}
+
+func TestJobFreshness(t *testing.T) {
+	tracker := NewJobTracker()
+	check := JobFreshness("cleanup", tracker, time.Hour)
+
+	// Fresh during the startup grace period
+	assert.NoError(t, check.Check(context.Background()))
+
+	// Stale when nothing has succeeded for longer than maxAge
+	tracker.startedAt = time.Now().Add(-2 * time.Hour)
+	tracker.RecordRun(errors.New("disk full"))
+	err := check.Check(context.Background())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "disk full")
+
+	tracker.RecordRun(nil)
+	assert.NoError(t, check.Check(context.Background()))
+	assert.False(t, tracker.LastSuccess().IsZero())
+
+	// A run older than the last one recorded changes nothing
+	tracker.RecordRunAt(time.Now().Add(-time.Minute), errors.New("disk full"))
+	assert.NoError(t, check.Check(context.Background()))
+}
@@ -0,0 +1,218 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// Component names used across the backend
+const (
+	ComponentApp         = "app"
+	ComponentHTTP        = "http"
+	ComponentJudge       = "judge"
+	ComponentGemini      = "gemini"
+	ComponentCompression = "compression"
+	ComponentStorage     = "storage"
+	ComponentJobs        = "jobs"
+)
+
+// redactedValue replaces sensitive values when debug logging is not enabled
+const redactedValue = "[redacted]"
+
+// Config holds configuration for the logger
+type Config struct {
+	// Format is either "json" or "text"
+	Format string
+
+	// Level is the default minimum level for all components
+	Level slog.Level
+
+	// ComponentLevels overrides Level for individual components
+	ComponentLevels map[string]slog.Level
+
+	// Output is where log lines are written (defaults to stderr)
+	Output io.Writer
+}
+
+// settings is the active logging configuration shared by all component loggers
+type settings struct {
+	handler    slog.Handler
+	level      slog.Level
+	components map[string]slog.Level
+}
+
+func (s *settings) levelFor(component string) slog.Level {
+	if level, ok := s.components[component]; ok {
+		return level
+	}
+	return s.level
+}
+
+var current atomic.Pointer[settings]
+
+func init() {
+	Setup(Config{Format: "text", Level: slog.LevelInfo})
+}
+
+// Setup installs the logging configuration. Loggers obtained from Component
+// before Setup is called pick up the new configuration immediately.
+func Setup(cfg Config) {
+	output := cfg.Output
+	if output == nil {
+		output = os.Stderr
+	}
+
+	// Filtering happens per component, so the underlying handler accepts everything
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug}
+
+	var handler slog.Handler
+	if cfg.Format == "json" {
+		handler = slog.NewJSONHandler(output, opts)
+	} else {
+		handler = slog.NewTextHandler(output, opts)
+	}
+
+	components := make(map[string]slog.Level, len(cfg.ComponentLevels))
+	for name, level := range cfg.ComponentLevels {
+		components[name] = level
+	}
+
+	current.Store(&settings{
+		handler:    handler,
+		level:      cfg.Level,
+		components: components,
+	})
+
+	slog.SetDefault(Component(ComponentApp))
+}
+
+// Component returns a logger for the named component. Each log line carries a
+// "component" attribute and, when logged with a request context, a "request_id".
+func Component(name string) *slog.Logger {
+	return slog.New(&componentHandler{component: name})
+}
+
+// Sensitive wraps a value that must not appear in logs unless debug logging is
+// enabled for the component (filenames, verdict text, raw model output).
+func Sensitive(key string, value any) slog.Attr {
+	return slog.Any(key, sensitive{value: value})
+}
+
+type sensitive struct {
+	value any
+}
+
+// ParseLevel converts a level name (debug, info, warn, error) into a slog.Level
+func ParseLevel(value string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(value))); err != nil {
+		return slog.LevelInfo, fmt.Errorf("invalid log level %q", value)
+	}
+	return level, nil
+}
+
+// ParseComponentLevels parses a comma-separated list of component=level pairs,
+// e.g. "gemini=debug,storage=warn"
+func ParseComponentLevels(spec string) (map[string]slog.Level, error) {
+	levels := make(map[string]slog.Level)
+	if strings.TrimSpace(spec) == "" {
+		return levels, nil
+	}
+
+	for _, pair := range strings.Split(spec, ",") {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid component log level %q (expected component=level)", pair)
+		}
+		level, err := ParseLevel(value)
+		if err != nil {
+			return nil, err
+		}
+		levels[strings.TrimSpace(name)] = level
+	}
+
+	return levels, nil
+}
+
+// componentHandler filters records by the component's level, redacts sensitive
+// attributes and adds the request ID from the context before delegating to the
+// active handler.
+type componentHandler struct {
+	component string
+	ops       []func(slog.Handler, bool) slog.Handler
+}
+
+func (h *componentHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= current.Load().levelFor(h.component)
+}
+
+func (h *componentHandler) Handle(ctx context.Context, record slog.Record) error {
+	s := current.Load()
+	reveal := s.levelFor(h.component) <= slog.LevelDebug
+
+	handler := s.handler.WithAttrs([]slog.Attr{slog.String("component", h.component)})
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		handler = handler.WithAttrs([]slog.Attr{slog.String("request_id", requestID)})
+	}
+	if clientRequestID := ClientRequestIDFromContext(ctx); clientRequestID != "" {
+		handler = handler.WithAttrs([]slog.Attr{slog.String("client_request_id", clientRequestID)})
+	}
+	for _, op := range h.ops {
+		handler = op(handler, reveal)
+	}
+
+	redacted := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(redact(attr, reveal))
+		return true
+	})
+
+	return handler.Handle(ctx, redacted)
+}
+
+func (h *componentHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(handler slog.Handler, reveal bool) slog.Handler {
+		redacted := make([]slog.Attr, len(attrs))
+		for i, attr := range attrs {
+			redacted[i] = redact(attr, reveal)
+		}
+		return handler.WithAttrs(redacted)
+	})
+}
+
+func (h *componentHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(handler slog.Handler, _ bool) slog.Handler {
+		return handler.WithGroup(name)
+	})
+}
+
+func (h *componentHandler) with(op func(slog.Handler, bool) slog.Handler) *componentHandler {
+	ops := make([]func(slog.Handler, bool) slog.Handler, len(h.ops), len(h.ops)+1)
+	copy(ops, h.ops)
+	return &componentHandler{component: h.component, ops: append(ops, op)}
+}
+
+func redact(attr slog.Attr, reveal bool) slog.Attr {
+	switch attr.Value.Kind() {
+	case slog.KindAny:
+		if s, ok := attr.Value.Any().(sensitive); ok {
+			if reveal {
+				return slog.Any(attr.Key, s.value)
+			}
+			return slog.String(attr.Key, redactedValue)
+		}
+	case slog.KindGroup:
+		group := attr.Value.Group()
+		redacted := make([]any, len(group))
+		for i, a := range group {
+			redacted[i] = redact(a, reveal)
+		}
+		return slog.Group(attr.Key, redacted...)
+	}
+	return attr
+}
@@ -0,0 +1,120 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setupBuffer configures JSON logging into a buffer and restores defaults afterwards
+func setupBuffer(t *testing.T, level slog.Level, components map[string]slog.Level) *bytes.Buffer {
+	var buf bytes.Buffer
+	Setup(Config{Format: "json", Level: level, ComponentLevels: components, Output: &buf})
+	t.Cleanup(func() { Setup(Config{Format: "text", Level: slog.LevelInfo}) })
+	return &buf
+}
+
+func decodeLine(t *testing.T, buf *bytes.Buffer) map[string]any {
+	var line map[string]any
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	return line
+}
+
+func TestComponent_AddsComponentAndRequestID(t *testing.T) {
+	buf := setupBuffer(t, slog.LevelInfo, nil)
+
+	ctx := WithRequestID(context.Background(), "req-123")
+	Component(ComponentJudge).InfoContext(ctx, "Incoming photo", slog.Int("size", 42))
+
+	line := decodeLine(t, buf)
+	assert.Equal(t, "Incoming photo", line["msg"])
+	assert.Equal(t, "judge", line["component"])
+	assert.Equal(t, "req-123", line["request_id"])
+	assert.Equal(t, float64(42), line["size"])
+}
+
+func TestComponent_PerComponentLevels(t *testing.T) {
+	buf := setupBuffer(t, slog.LevelWarn, map[string]slog.Level{ComponentGemini: slog.LevelDebug})
+
+	Component(ComponentStorage).Info("hidden")
+	assert.Empty(t, buf.String())
+
+	Component(ComponentGemini).Debug("visible")
+	assert.Contains(t, buf.String(), "visible")
+}
+
+func TestSensitive_RedactedUnlessDebug(t *testing.T) {
+	buf := setupBuffer(t, slog.LevelInfo, nil)
+
+	Component(ComponentJudge).Info("Incoming photo", Sensitive("filename", "mijn-huis.jpg"))
+
+	line := decodeLine(t, buf)
+	assert.Equal(t, "[redacted]", line["filename"])
+	assert.NotContains(t, buf.String(), "mijn-huis")
+}
+
+func TestSensitive_RevealedWhenComponentAtDebug(t *testing.T) {
+	buf := setupBuffer(t, slog.LevelInfo, map[string]slog.Level{ComponentJudge: slog.LevelDebug})
+
+	Component(ComponentJudge).Info("Incoming photo", Sensitive("filename", "mijn-huis.jpg"))
+
+	line := decodeLine(t, buf)
+	assert.Equal(t, "mijn-huis.jpg", line["filename"])
+}
+
+func TestSensitive_RedactedInWithAttrsAndGroups(t *testing.T) {
+	buf := setupBuffer(t, slog.LevelInfo, nil)
+
+	logger := Component(ComponentGemini).With(Sensitive("crime", "Scheve zitting"))
+	logger.Info("Parsed verdict", slog.Group("verdict", Sensitive("sentence", "Heroriëntatie")))
+
+	assert.NotContains(t, buf.String(), "Scheve zitting")
+	assert.NotContains(t, buf.String(), "Heroriëntatie")
+}
+
+func TestParseComponentLevels(t *testing.T) {
+	levels, err := ParseComponentLevels("gemini=debug, storage=warn")
+	assert.NoError(t, err)
+	assert.Equal(t, slog.LevelDebug, levels["gemini"])
+	assert.Equal(t, slog.LevelWarn, levels["storage"])
+
+	levels, err = ParseComponentLevels("")
+	assert.NoError(t, err)
+	assert.Empty(t, levels)
+
+	_, err = ParseComponentLevels("gemini")
+	assert.Error(t, err)
+
+	_, err = ParseComponentLevels("gemini=loud")
+	assert.Error(t, err)
+}
+
+func TestNewRequestID(t *testing.T) {
+	id := NewRequestID()
+	assert.Len(t, id, 36)
+	assert.NotEqual(t, id, NewRequestID())
+}
+
+func TestClientRequestID(t *testing.T) {
+	assert.Equal(t, "550e8400-e29b-41d4-a716-446655440000", ClientRequestID("550e8400-e29b-41d4-a716-446655440000"))
+	assert.Equal(t, "trace-42/abc", ClientRequestID("trace-42/abc"))
+	assert.Empty(t, ClientRequestID("line\nbreak"))
+	assert.Empty(t, ClientRequestID("has space"))
+	assert.Empty(t, ClientRequestID(strings.Repeat("a", 129)))
+}
+
+func TestComponent_AddsClientRequestID(t *testing.T) {
+	buf := setupBuffer(t, slog.LevelInfo, nil)
+
+	ctx := WithClientRequestID(WithRequestID(context.Background(), "req-123"), "client-7")
+	Component(ComponentHTTP).InfoContext(ctx, "Request handled")
+
+	line := decodeLine(t, buf)
+	assert.Equal(t, "req-123", line["request_id"])
+	assert.Equal(t, "client-7", line["client_request_id"])
+}
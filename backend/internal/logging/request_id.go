@@ -0,0 +1,68 @@
+package logging
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader is the HTTP header used to pass and return request IDs
+const RequestIDHeader = "X-Request-ID"
+
+// maxClientRequestIDLength bounds the client request IDs kept for logging
+const maxClientRequestIDLength = 128
+
+type requestIDKey struct{}
+
+type clientRequestIDKey struct{}
+
+// WithRequestID returns a context carrying the given request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID stored in the context, or "" if none
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// NewRequestID returns a new request ID. It is always generated by the
+// server: the ID ends up in storage filenames, share IDs and lookups by
+// request ID, so a client must not be able to pick one.
+func NewRequestID() string {
+	return uuid.New().String()
+}
+
+// WithClientRequestID returns a context carrying the request ID a client sent
+// in X-Request-ID, which is only logged, as client_request_id
+func WithClientRequestID(ctx context.Context, clientRequestID string) context.Context {
+	return context.WithValue(ctx, clientRequestIDKey{}, clientRequestID)
+}
+
+// ClientRequestIDFromContext returns the client request ID stored in the
+// context, or "" if none
+func ClientRequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	clientRequestID, _ := ctx.Value(clientRequestIDKey{}).(string)
+	return clientRequestID
+}
+
+// ClientRequestID returns the client-supplied request ID fit for logging, or
+// "" when it is too long or holds anything but printable ASCII
+func ClientRequestID(incoming string) string {
+	if len(incoming) > maxClientRequestIDLength {
+		return ""
+	}
+	for _, r := range incoming {
+		if r < 0x21 || r > 0x7e {
+			return ""
+		}
+	}
+	return incoming
+}
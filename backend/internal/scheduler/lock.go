@@ -0,0 +1,187 @@
+package scheduler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Locker keeps a job from running on more than one replica at a time
+type Locker interface {
+	// TryLock takes the lock on name for at most ttl. It reports false when
+	// another holder has it; unlock gives it up early.
+	TryLock(name string, ttl time.Duration) (unlock func(), ok bool, err error)
+}
+
+// RunRecorder shares the last finished run of every job between replicas,
+// so a replica that skipped a run for the lock learns how it went
+type RunRecorder interface {
+	// RecordRun stores run as the last run of its job
+	RecordRun(run Run) error
+
+	// LastRun returns the last run stored for the job name, if any
+	LastRun(name string) (Run, bool, error)
+}
+
+// FileLocker takes locks as lease files in a directory shared by every
+// replica, such as the photo storage volume. A lease names its holder and
+// expires, so the lock of a replica that crashed mid-run is taken over once
+// its lease has run out.
+type FileLocker struct {
+	dir    string
+	holder string
+	now    func() time.Time
+
+	// beforeTakeover, if set, runs between judging a lease expired and
+	// moving it away; tests use it to replace the lease in between
+	beforeTakeover func()
+}
+
+// lease is the content of a lock file
+type lease struct {
+	Holder    string    `json:"holder"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// NewFileLocker creates a FileLocker keeping its lease files in dir. holder
+// identifies this replica in the leases, e.g. its hostname and process ID.
+func NewFileLocker(dir, holder string) (*FileLocker, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+	return &FileLocker{dir: dir, holder: holder, now: time.Now}, nil
+}
+
+// TryLock implements Locker
+func (l *FileLocker) TryLock(name string, ttl time.Duration) (func(), bool, error) {
+	path := filepath.Join(l.dir, name+".lock")
+	ours := lease{Holder: l.holder, ExpiresAt: l.now().Add(ttl).UTC()}
+	data, err := json.Marshal(ours)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// One retry, after taking over an expired lease
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, writeErr := f.Write(data)
+			if closeErr := f.Close(); writeErr == nil {
+				writeErr = closeErr
+			}
+			if writeErr != nil {
+				os.Remove(path)
+				return nil, false, fmt.Errorf("failed to write lock %s: %w", name, writeErr)
+			}
+			return func() { l.unlock(path, ours) }, true, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, false, fmt.Errorf("failed to create lock %s: %w", name, err)
+		}
+
+		judged, expired := l.expired(path)
+		if !expired {
+			return nil, false, nil
+		}
+		if l.beforeTakeover != nil {
+			l.beforeTakeover()
+		}
+		// Renaming is atomic, so of several replicas finding the same
+		// expired lease only one moves it away and goes on to take the lock
+		stale := fmt.Sprintf("%s.stale-%d", path, l.now().UnixNano())
+		if err := os.Rename(path, stale); err != nil {
+			if os.IsNotExist(err) {
+				return nil, false, nil
+			}
+			return nil, false, fmt.Errorf("failed to take over lock %s: %w", name, err)
+		}
+		// Another replica may have taken over the expired lease between the
+		// check and the rename, in which case the fresh lease of that
+		// replica was moved away. Linking it back only succeeds while no
+		// lock has been taken since.
+		if moved, err := os.ReadFile(stale); err != nil || !bytes.Equal(moved, judged) {
+			os.Link(stale, path)
+			os.Remove(stale)
+			return nil, false, nil
+		}
+		os.Remove(stale)
+	}
+	return nil, false, nil
+}
+
+// expired reports whether the lease at path has run out, and returns the
+// content it judged. A lease that cannot be read is judged by its
+// modification time instead, in case its holder crashed while writing it.
+func (l *FileLocker) expired(path string) ([]byte, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var held lease
+	if json.Unmarshal(data, &held) == nil {
+		return data, l.now().After(held.ExpiresAt)
+	}
+	return data, l.now().Sub(info.ModTime()) > time.Hour
+}
+
+// RecordRun implements RunRecorder. The run is written next to the job's
+// lease, atomically, so a replica never reads a partial run.
+func (l *FileLocker) RecordRun(run Run) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(l.dir, "."+run.Job+".run-*")
+	if err != nil {
+		return fmt.Errorf("failed to record run of %s: %w", run.Job, err)
+	}
+	_, writeErr := f.Write(data)
+	if closeErr := f.Close(); writeErr == nil {
+		writeErr = closeErr
+	}
+	if writeErr == nil {
+		writeErr = os.Rename(f.Name(), filepath.Join(l.dir, run.Job+".run"))
+	}
+	if writeErr != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("failed to record run of %s: %w", run.Job, writeErr)
+	}
+	return nil
+}
+
+// LastRun implements RunRecorder
+func (l *FileLocker) LastRun(name string) (Run, bool, error) {
+	data, err := os.ReadFile(filepath.Join(l.dir, name+".run"))
+	if errors.Is(err, os.ErrNotExist) {
+		return Run{}, false, nil
+	}
+	if err != nil {
+		return Run{}, false, fmt.Errorf("failed to read last run of %s: %w", name, err)
+	}
+	var run Run
+	if err := json.Unmarshal(data, &run); err != nil {
+		return Run{}, false, fmt.Errorf("failed to parse last run of %s: %w", name, err)
+	}
+	return run, true, nil
+}
+
+// unlock removes the lease at path if it is still ours
+func (l *FileLocker) unlock(path string, ours lease) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	var held lease
+	if json.Unmarshal(data, &held) != nil || held.Holder != ours.Holder || !held.ExpiresAt.Equal(ours.ExpiresAt) {
+		return
+	}
+	os.Remove(path)
+}
@@ -0,0 +1,118 @@
+package scheduler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileLocker_SingleHolder(t *testing.T) {
+	dir := t.TempDir()
+	a, err := NewFileLocker(dir, "replica-a")
+	require.NoError(t, err)
+	b, err := NewFileLocker(dir, "replica-b")
+	require.NoError(t, err)
+
+	unlock, ok, err := a.TryLock("cleanup", time.Hour)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	_, ok, err = b.TryLock("cleanup", time.Hour)
+	require.NoError(t, err)
+	assert.False(t, ok, "held by replica-a")
+
+	// Other jobs have their own lock
+	unlockOther, ok, err := b.TryLock("compaction", time.Hour)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	unlockOther()
+
+	unlock()
+	unlock, ok, err = b.TryLock("cleanup", time.Hour)
+	require.NoError(t, err)
+	assert.True(t, ok, "released by replica-a")
+	unlock()
+}
+
+func TestFileLocker_TakesOverExpiredLease(t *testing.T) {
+	dir := t.TempDir()
+	a, _ := NewFileLocker(dir, "replica-a")
+	b, _ := NewFileLocker(dir, "replica-b")
+
+	unlockA, ok, err := a.TryLock("cleanup", time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	b.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	unlockB, ok, err := b.TryLock("cleanup", time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok, "replica-a's lease has run out")
+
+	// A late unlock by replica-a leaves replica-b's lease alone
+	unlockA()
+	assert.FileExists(t, filepath.Join(dir, "cleanup.lock"))
+	unlockB()
+	assert.NoFileExists(t, filepath.Join(dir, "cleanup.lock"))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "no stale leases left behind")
+}
+
+func TestFileLocker_KeepsLeaseReplacedDuringTakeover(t *testing.T) {
+	dir := t.TempDir()
+	a, _ := NewFileLocker(dir, "replica-a")
+	b, _ := NewFileLocker(dir, "replica-b")
+	c, _ := NewFileLocker(dir, "replica-c")
+
+	_, ok, err := a.TryLock("cleanup", time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// Replica-c takes over the expired lease after replica-b judged it
+	// expired, but before replica-b moves it away
+	later := func() time.Time { return time.Now().Add(2 * time.Minute) }
+	b.now, c.now = later, later
+	var unlockC func()
+	b.beforeTakeover = func() {
+		var ok bool
+		unlockC, ok, err = c.TryLock("cleanup", time.Minute)
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+
+	_, ok, err = b.TryLock("cleanup", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok, "replica-c's fresh lease must not be taken over")
+
+	data, err := os.ReadFile(filepath.Join(dir, "cleanup.lock"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "replica-c")
+
+	unlockC()
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "no stale leases left behind")
+}
+
+func TestFileLocker_UnreadableLease(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cleanup.lock")
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0644))
+	l, _ := NewFileLocker(dir, "replica-a")
+
+	_, ok, err := l.TryLock("cleanup", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok, "a fresh unreadable lease may still be being written")
+
+	old := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, os.Chtimes(path, old, old))
+	unlock, ok, err := l.TryLock("cleanup", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	unlock()
+}
@@ -0,0 +1,60 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSchedule_Next(t *testing.T) {
+	// A Wednesday
+	from := time.Date(2026, 2, 4, 10, 17, 30, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 2, 4, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 2, 4, 10, 30, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2026, 2, 5, 3, 0, 0, 0, time.UTC)},
+		{"30 9-17/4 * * *", time.Date(2026, 2, 4, 13, 30, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * 0", time.Date(2026, 2, 8, 12, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2026, 2, 8, 12, 0, 0, 0, time.UTC)},
+		{"0 12 * * 1-5", time.Date(2026, 2, 4, 12, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: either matches
+		{"0 0 15 * 5", time.Date(2026, 2, 6, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0,45 10 * * *", time.Date(2026, 2, 4, 10, 45, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, 2, 4, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2026, 2, 5, 0, 0, 0, 0, time.UTC)},
+		{"@every 90m", time.Date(2026, 2, 4, 11, 47, 30, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		schedule, err := ParseSchedule(tt.expr)
+		require.NoError(t, err, tt.expr)
+		assert.Equal(t, tt.want, schedule.Next(from), tt.expr)
+	}
+}
+
+func TestParseSchedule_Invalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "a * * * *", "@often", "@every 10ms"} {
+		_, err := ParseSchedule(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestNext_NeverMatches(t *testing.T) {
+	schedule, err := ParseSchedule("0 0 31 2 *")
+	require.NoError(t, err)
+	assert.True(t, schedule.Next(time.Now()).IsZero())
+}
+
+func TestPeriod(t *testing.T) {
+	from := time.Date(2026, 2, 4, 10, 17, 0, 0, time.UTC)
+	daily, _ := ParseSchedule("0 3 * * *")
+	assert.Equal(t, 24*time.Hour, Period(daily, from))
+	assert.Equal(t, 10*time.Minute, Period(Every(10*time.Minute), from))
+}
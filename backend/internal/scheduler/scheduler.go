@@ -0,0 +1,316 @@
+// Package scheduler runs background jobs on cron schedules, one replica at a
+// time, and keeps a history of their runs
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+
+	"rechtebank/backend/internal/health"
+	"rechtebank/backend/internal/logging"
+)
+
+var logger = logging.Component(logging.ComponentJobs)
+
+// DefaultTimeout bounds a run of a job without a Timeout. It is also how
+// long the job's lock is held at most.
+const DefaultTimeout = time.Hour
+
+// DefaultHistorySize is how many runs of each job the scheduler remembers
+const DefaultHistorySize = 20
+
+// Job is a background task run on a schedule
+type Job struct {
+	Name     string
+	Schedule Schedule
+
+	// Jitter delays each run by a random duration below it, so replicas and
+	// jobs sharing a schedule do not all start at the same moment
+	Jitter time.Duration
+
+	// Timeout cancels the context of a run that takes longer
+	Timeout time.Duration
+
+	// RunOnStart runs the job once when the scheduler starts, before its
+	// first scheduled run
+	RunOnStart bool
+
+	// Tracker, if set, records the outcome of every run for readiness checks
+	Tracker *health.JobTracker
+
+	// Run does the work and returns a one-line summary of what it did
+	Run func(ctx context.Context) (string, error)
+}
+
+// RunStatus is the outcome of a job run
+type RunStatus string
+
+// Outcomes of a job run
+const (
+	RunSucceeded RunStatus = "succeeded"
+	RunFailed    RunStatus = "failed"
+
+	// RunSkipped runs found the job locked by another replica
+	RunSkipped RunStatus = "skipped"
+)
+
+// Run is a recorded run of a job
+type Run struct {
+	Job        string    `json:"job"`
+	Status     RunStatus `json:"status"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	Summary    string    `json:"summary,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// JobStatus describes a scheduled job and its most recent runs, newest first
+type JobStatus struct {
+	Name     string    `json:"name"`
+	Schedule string    `json:"schedule"`
+	NextRun  time.Time `json:"nextRun"`
+	Running  bool      `json:"running"`
+	Runs     []Run     `json:"runs"`
+}
+
+// scheduledJob is a job with its state in the scheduler
+type scheduledJob struct {
+	Job
+	spec string
+
+	mu      sync.Mutex
+	nextRun time.Time
+	running bool
+	runs    []Run
+}
+
+// Scheduler runs jobs on their schedules, in UTC, until it is stopped
+type Scheduler struct {
+	locker      Locker
+	historySize int
+
+	mu      sync.Mutex
+	jobs    []*scheduledJob
+	started bool
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// New creates a Scheduler that takes a lock from locker for every run. With
+// a nil locker jobs run without one, which is only safe with a single
+// replica.
+func New(locker Locker) *Scheduler {
+	return &Scheduler{locker: locker, historySize: DefaultHistorySize}
+}
+
+// Add registers a job; spec is its schedule as shown in JobStatus. Jobs must
+// be added before Start.
+func (s *Scheduler) Add(job Job, spec string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case s.started:
+		return errors.New("scheduler already started")
+	case job.Name == "" || job.Schedule == nil || job.Run == nil:
+		return errors.New("job needs a name, a schedule and a run function")
+	}
+	for _, j := range s.jobs {
+		if j.Name == job.Name {
+			return fmt.Errorf("job %q added twice", job.Name)
+		}
+	}
+	if job.Timeout <= 0 {
+		job.Timeout = DefaultTimeout
+	}
+	s.jobs = append(s.jobs, &scheduledJob{Job: job, spec: spec})
+	return nil
+}
+
+// Start runs every job on its schedule in the background
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.loop(ctx, job)
+		}()
+	}
+}
+
+// Stop cancels running jobs and waits for them to return
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	s.wg.Wait()
+}
+
+// loop runs job whenever it is due until ctx is cancelled
+func (s *Scheduler) loop(ctx context.Context, job *scheduledJob) {
+	if job.RunOnStart {
+		s.run(ctx, job)
+	}
+	for {
+		next := job.Schedule.Next(time.Now().UTC())
+		if next.IsZero() {
+			logger.Warn("Job will never run again", slog.String("job", job.Name))
+			return
+		}
+		if job.Jitter > 0 {
+			next = next.Add(rand.N(job.Jitter))
+		}
+		job.mu.Lock()
+		job.nextRun = next
+		job.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			s.run(ctx, job)
+		}
+	}
+}
+
+// run runs job once under its lock and records the run
+func (s *Scheduler) run(ctx context.Context, job *scheduledJob) {
+	run := Run{Job: job.Name, StartedAt: time.Now().UTC()}
+
+	var err error
+	if s.locker != nil {
+		unlock, ok, lockErr := s.locker.TryLock(job.Name, job.Timeout)
+		switch {
+		case lockErr != nil:
+			err = fmt.Errorf("failed to take lock: %w", lockErr)
+		case !ok:
+			run.Status = RunSkipped
+			run.Summary = "locked by another replica"
+		default:
+			defer unlock()
+		}
+	}
+
+	if run.Status == "" && err == nil {
+		job.setRunning(true)
+		runCtx, cancel := context.WithTimeout(ctx, job.Timeout)
+		run.Summary, err = job.Run(runCtx)
+		cancel()
+		job.setRunning(false)
+	}
+
+	run.FinishedAt = time.Now().UTC()
+	attrs := []any{slog.String("job", job.Name), slog.Duration("duration", run.FinishedAt.Sub(run.StartedAt))}
+	switch {
+	case err != nil:
+		run.Status = RunFailed
+		run.Error = err.Error()
+		logger.Warn("Job failed", append(attrs, slog.Any("error", err))...)
+	case run.Status == RunSkipped:
+		logger.Debug("Job skipped, another replica holds its lock", attrs...)
+	default:
+		run.Status = RunSucceeded
+		logger.Info("Job completed", append(attrs, slog.String("summary", run.Summary))...)
+	}
+
+	s.share(job, run, err)
+	job.record(run, s.historySize)
+}
+
+// share records a finished run in the job's tracker and, when the locker is
+// a RunRecorder, for the other replicas while the lock is still held. A run
+// skipped for the lock is not a result of its own: the tracker gets the last
+// run shared by the replica that held the lock instead, or nothing.
+func (s *Scheduler) share(job *scheduledJob, run Run, err error) {
+	recorder, _ := s.locker.(RunRecorder)
+	if run.Status != RunSkipped {
+		if job.Tracker != nil {
+			job.Tracker.RecordRun(err)
+		}
+		if recorder != nil {
+			if err := recorder.RecordRun(run); err != nil {
+				logger.Warn("Failed to share job run", slog.String("job", job.Name), slog.Any("error", err))
+			}
+		}
+		return
+	}
+
+	if job.Tracker == nil || recorder == nil {
+		return
+	}
+	last, ok, err := recorder.LastRun(job.Name)
+	if err != nil {
+		logger.Warn("Failed to read shared job run", slog.String("job", job.Name), slog.Any("error", err))
+		return
+	}
+	if !ok || last.Status == RunSkipped {
+		return
+	}
+	var lastErr error
+	if last.Status == RunFailed {
+		lastErr = errors.New(last.Error)
+	}
+	job.Tracker.RecordRunAt(last.FinishedAt, lastErr)
+}
+
+func (j *scheduledJob) setRunning(running bool) {
+	j.mu.Lock()
+	j.running = running
+	j.mu.Unlock()
+}
+
+// record adds run to the history, dropping the oldest beyond size
+func (j *scheduledJob) record(run Run, size int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.runs = append(j.runs, run)
+	if len(j.runs) > size {
+		j.runs = j.runs[len(j.runs)-size:]
+	}
+}
+
+// Jobs returns the status of every job, by name
+func (s *Scheduler) Jobs() []JobStatus {
+	s.mu.Lock()
+	jobs := append([]*scheduledJob(nil), s.jobs...)
+	s.mu.Unlock()
+
+	statuses := make([]JobStatus, 0, len(jobs))
+	for _, job := range jobs {
+		job.mu.Lock()
+		status := JobStatus{
+			Name:     job.Name,
+			Schedule: job.spec,
+			NextRun:  job.nextRun,
+			Running:  job.running,
+			Runs:     make([]Run, 0, len(job.runs)),
+		}
+		for i := len(job.runs) - 1; i >= 0; i-- {
+			status.Runs = append(status.Runs, job.runs[i])
+		}
+		job.mu.Unlock()
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, k int) bool { return statuses[i].Name < statuses[k].Name })
+	return statuses
+}
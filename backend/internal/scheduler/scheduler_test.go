@@ -0,0 +1,218 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"rechtebank/backend/internal/health"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduler_RunsJobsAndKeepsHistory(t *testing.T) {
+	s := New(nil)
+	s.historySize = 3
+
+	var runs atomic.Int32
+	require.NoError(t, s.Add(Job{
+		Name:     "count",
+		Schedule: Every(5 * time.Millisecond),
+		Run: func(context.Context) (string, error) {
+			if runs.Add(1)%2 == 0 {
+				return "", errors.New("disk full")
+			}
+			return "counted", nil
+		},
+	}, "@every 5ms"))
+	s.Start()
+	require.Eventually(t, func() bool { return runs.Load() >= 5 }, 2*time.Second, time.Millisecond)
+	s.Stop()
+
+	jobs := s.Jobs()
+	require.Len(t, jobs, 1)
+	assert.Equal(t, "count", jobs[0].Name)
+	assert.Equal(t, "@every 5ms", jobs[0].Schedule)
+	require.Len(t, jobs[0].Runs, 3, "history is capped")
+
+	newest, older := jobs[0].Runs[0], jobs[0].Runs[1]
+	assert.False(t, newest.StartedAt.Before(older.StartedAt), "newest first")
+	for _, run := range jobs[0].Runs {
+		switch run.Status {
+		case RunSucceeded:
+			assert.Equal(t, "counted", run.Summary)
+		case RunFailed:
+			assert.Equal(t, "disk full", run.Error)
+		default:
+			t.Errorf("unexpected status %q", run.Status)
+		}
+	}
+}
+
+func TestScheduler_RunOnStart(t *testing.T) {
+	s := New(nil)
+	tracker := health.NewJobTracker()
+	done := make(chan struct{})
+	require.NoError(t, s.Add(Job{
+		Name:       "cleanup",
+		Schedule:   Every(time.Hour),
+		RunOnStart: true,
+		Tracker:    tracker,
+		Run: func(context.Context) (string, error) {
+			close(done)
+			return "purged 0 cases", nil
+		},
+	}, "@every 1h"))
+	s.Start()
+	defer s.Stop()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("job did not run on start")
+	}
+	require.Eventually(t, func() bool { return !tracker.LastSuccess().IsZero() }, time.Second, time.Millisecond)
+
+	jobs := s.Jobs()
+	assert.WithinDuration(t, time.Now().Add(time.Hour), jobs[0].NextRun, time.Minute)
+}
+
+func TestScheduler_SkipsLockedJobs(t *testing.T) {
+	dir := t.TempDir()
+	other, _ := NewFileLocker(dir, "replica-b")
+	unlock, ok, err := other.TryLock("cleanup", time.Hour)
+	require.NoError(t, err)
+	require.True(t, ok)
+	defer unlock()
+
+	locker, _ := NewFileLocker(dir, "replica-a")
+	s := New(locker)
+	var ran atomic.Bool
+	require.NoError(t, s.Add(Job{
+		Name:       "cleanup",
+		Schedule:   Every(time.Hour),
+		RunOnStart: true,
+		Run: func(context.Context) (string, error) {
+			ran.Store(true)
+			return "", nil
+		},
+	}, "@every 1h"))
+	s.Start()
+	require.Eventually(t, func() bool { return len(s.Jobs()[0].Runs) == 1 }, 2*time.Second, time.Millisecond)
+	s.Stop()
+
+	assert.False(t, ran.Load())
+	assert.Equal(t, RunSkipped, s.Jobs()[0].Runs[0].Status)
+}
+
+func TestScheduler_SkippedRunDoesNotRefreshTracker(t *testing.T) {
+	dir := t.TempDir()
+	other, _ := NewFileLocker(dir, "replica-b")
+	unlock, ok, err := other.TryLock("cleanup", time.Hour)
+	require.NoError(t, err)
+	require.True(t, ok)
+	defer unlock()
+
+	locker, _ := NewFileLocker(dir, "replica-a")
+	s := New(locker)
+	tracker := health.NewJobTracker()
+	require.NoError(t, s.Add(Job{
+		Name:       "cleanup",
+		Schedule:   Every(time.Hour),
+		RunOnStart: true,
+		Tracker:    tracker,
+		Run:        func(context.Context) (string, error) { return "", nil },
+	}, "@every 1h"))
+	s.Start()
+	require.Eventually(t, func() bool { return len(s.Jobs()[0].Runs) == 1 }, 2*time.Second, time.Millisecond)
+	s.Stop()
+
+	assert.Equal(t, RunSkipped, s.Jobs()[0].Runs[0].Status)
+	assert.True(t, tracker.LastSuccess().IsZero(), "a skipped run is no evidence the job ran")
+}
+
+func TestScheduler_SkippedRunTracksSharedRun(t *testing.T) {
+	dir := t.TempDir()
+	other, _ := NewFileLocker(dir, "replica-b")
+	unlock, ok, err := other.TryLock("cleanup", time.Hour)
+	require.NoError(t, err)
+	require.True(t, ok)
+	defer unlock()
+	finished := time.Now().Add(-time.Minute).UTC()
+	require.NoError(t, other.RecordRun(Run{Job: "cleanup", Status: RunFailed, FinishedAt: finished, Error: "disk full"}))
+
+	locker, _ := NewFileLocker(dir, "replica-a")
+	s := New(locker)
+	tracker := health.NewJobTracker()
+	require.NoError(t, s.Add(Job{
+		Name:       "cleanup",
+		Schedule:   Every(time.Hour),
+		RunOnStart: true,
+		Tracker:    tracker,
+		Run:        func(context.Context) (string, error) { return "", nil },
+	}, "@every 1h"))
+	s.Start()
+	require.Eventually(t, func() bool { return len(s.Jobs()[0].Runs) == 1 }, 2*time.Second, time.Millisecond)
+	s.Stop()
+
+	// The failure on the other replica is tracked, not a success
+	assert.True(t, tracker.LastSuccess().IsZero())
+	err = health.JobFreshness("cleanup", tracker, 0).Check(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "disk full")
+}
+
+func TestScheduler_SharesRuns(t *testing.T) {
+	locker, _ := NewFileLocker(t.TempDir(), "replica-a")
+	s := New(locker)
+	require.NoError(t, s.Add(Job{
+		Name:       "cleanup",
+		Schedule:   Every(time.Hour),
+		RunOnStart: true,
+		Run:        func(context.Context) (string, error) { return "removed 3 cases", nil },
+	}, "@every 1h"))
+	s.Start()
+	require.Eventually(t, func() bool { return len(s.Jobs()[0].Runs) == 1 }, 2*time.Second, time.Millisecond)
+	s.Stop()
+
+	last, ok, err := locker.LastRun("cleanup")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, s.Jobs()[0].Runs[0], last)
+}
+
+func TestScheduler_TimesOutRuns(t *testing.T) {
+	s := New(nil)
+	require.NoError(t, s.Add(Job{
+		Name:       "slow",
+		Schedule:   Every(time.Hour),
+		RunOnStart: true,
+		Timeout:    10 * time.Millisecond,
+		Run: func(ctx context.Context) (string, error) {
+			<-ctx.Done()
+			return "", ctx.Err()
+		},
+	}, "@every 1h"))
+	s.Start()
+	require.Eventually(t, func() bool { return len(s.Jobs()[0].Runs) == 1 }, 2*time.Second, time.Millisecond)
+	s.Stop()
+
+	assert.Equal(t, RunFailed, s.Jobs()[0].Runs[0].Status)
+	assert.Contains(t, s.Jobs()[0].Runs[0].Error, "deadline exceeded")
+}
+
+func TestScheduler_Add(t *testing.T) {
+	s := New(nil)
+	job := Job{Name: "cleanup", Schedule: Every(time.Hour), Run: func(context.Context) (string, error) { return "", nil }}
+	require.NoError(t, s.Add(job, "@every 1h"))
+	assert.Error(t, s.Add(job, "@every 1h"), "duplicate name")
+	assert.Error(t, s.Add(Job{Name: "empty"}, ""))
+
+	s.Start()
+	defer s.Stop()
+	job.Name = "late"
+	assert.Error(t, s.Add(job, "@every 1h"))
+}
@@ -0,0 +1,124 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the instrumentation scope used for all spans and metrics
+// created by the backend
+const TracerName = "rechtebank/backend"
+
+// Config holds configuration for the tracing and metrics pipelines
+type Config struct {
+	// Endpoint is the OTLP/HTTP collector endpoint (e.g. "http://localhost:4318").
+	// Trace and metric export are disabled when empty.
+	Endpoint string
+
+	// ServiceName is reported as the service.name resource attribute
+	ServiceName string
+
+	// SampleRatio is the fraction of new traces to sample (0.0-1.0).
+	// Traces started by an upstream caller follow the caller's sampling decision.
+	SampleRatio float64
+}
+
+// ShutdownFunc flushes pending spans and metrics and releases exporter resources
+type ShutdownFunc func(ctx context.Context) error
+
+// Setup installs the global W3C trace context propagator and, if an endpoint is
+// configured, a tracer provider and a meter provider exporting over OTLP/HTTP.
+func Setup(ctx context.Context, cfg Config) (ShutdownFunc, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if cfg.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	provider := NewTracerProvider(cfg, sdktrace.WithBatcher(exporter))
+	otel.SetTracerProvider(provider)
+
+	metricExporter, err := otlpmetrichttp.New(ctx, otlpmetrichttp.WithEndpointURL(cfg.Endpoint))
+	if err != nil {
+		provider.Shutdown(ctx)
+		return nil, fmt.Errorf("failed to create OTLP metric exporter: %w", err)
+	}
+	meterProvider := NewMeterProvider(cfg, sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter)))
+	otel.SetMeterProvider(meterProvider)
+
+	return func(ctx context.Context) error {
+		return errors.Join(provider.Shutdown(ctx), meterProvider.Shutdown(ctx))
+	}, nil
+}
+
+// NewTracerProvider creates a tracer provider for the given configuration.
+// Additional options (such as a span processor) can be supplied, which is how
+// tests attach an in-memory exporter.
+func NewTracerProvider(cfg Config, opts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	base := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(newResource(cfg)),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	}
+	return sdktrace.NewTracerProvider(append(base, opts...)...)
+}
+
+// NewMeterProvider creates a meter provider for the given configuration.
+// Tests attach a manual reader through opts.
+func NewMeterProvider(cfg Config, opts ...sdkmetric.Option) *sdkmetric.MeterProvider {
+	base := []sdkmetric.Option{sdkmetric.WithResource(newResource(cfg))}
+	return sdkmetric.NewMeterProvider(append(base, opts...)...)
+}
+
+// newResource describes the backend service to tracing and metrics backends
+func newResource(cfg Config) *resource.Resource {
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = "rechtebank-backend"
+	}
+	return resource.NewSchemaless(attribute.String("service.name", serviceName))
+}
+
+// Meter returns the backend meter from the global meter provider
+func Meter() metric.Meter {
+	return otel.Meter(TracerName)
+}
+
+// Tracer returns the backend tracer from the global tracer provider
+func Tracer() trace.Tracer {
+	return otel.Tracer(TracerName)
+}
+
+// StartSpan starts a span using the backend tracer
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// RecordError marks the span as failed with the given error
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,58 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSetup_WithoutEndpoint(t *testing.T) {
+	shutdown, err := Setup(context.Background(), Config{})
+	assert.NoError(t, err)
+	assert.NotNil(t, shutdown)
+	assert.NoError(t, shutdown(context.Background()))
+
+	// W3C trace context propagation is installed even when export is disabled
+	assert.Contains(t, otel.GetTextMapPropagator().Fields(), "traceparent")
+}
+
+func TestStartSpan_RecordsToInMemoryExporter(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := NewTracerProvider(Config{SampleRatio: 1.0}, sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	ctx, parent := StartSpan(context.Background(), "parent")
+	_, child := StartSpan(ctx, "child")
+	RecordError(child, errors.New("boom"))
+	child.End()
+	parent.End()
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "child", spans[0].Name())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+
+	serviceName, ok := spans[1].Resource().Set().Value("service.name")
+	assert.True(t, ok)
+	assert.Equal(t, "rechtebank-backend", serviceName.AsString())
+}
+
+func TestRecordError_NilErrorLeavesStatusUnset(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := NewTracerProvider(Config{SampleRatio: 1.0}, sdktrace.WithSpanProcessor(recorder))
+
+	_, span := provider.Tracer(TracerName).Start(context.Background(), "ok")
+	RecordError(span, nil)
+	span.End()
+
+	assert.Equal(t, codes.Unset, recorder.Ended()[0].Status().Code)
+}
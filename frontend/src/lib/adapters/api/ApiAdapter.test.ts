@@ -146,4 +146,38 @@ describe('ApiAdapter', () => {
         // These are covered by integration tests and manual testing
         // Unit tests for rotation logic are in rotation.test.ts
     });
+
+    describe('createShareURL', () => {
+        const request = { timestamp: '2026-02-01T15:30:45Z', requestId: 'test-id' };
+
+        it('should retry while the verdict is still being filed', async () => {
+            mockFetch
+                .mockResolvedValueOnce({
+                    ok: true,
+                    status: 202,
+                    headers: new Headers(),
+                    json: async () => ({ status: 'pending', requestId: 'test-id' })
+                })
+                .mockResolvedValueOnce({
+                    ok: true,
+                    status: 200,
+                    json: async () => ({ id: 'share-id' })
+                });
+
+            await expect(adapter.createShareURL(request)).resolves.toEqual({ id: 'share-id' });
+            expect(mockFetch).toHaveBeenCalledTimes(2);
+        });
+
+        it('should give up when the verdict stays pending', async () => {
+            mockFetch.mockResolvedValue({
+                ok: true,
+                status: 202,
+                headers: new Headers(),
+                text: async () => '{"status":"pending"}'
+            });
+
+            await expect(adapter.createShareURL(request)).rejects.toThrow('202');
+            expect(mockFetch).toHaveBeenCalledTimes(3);
+        });
+    });
 });
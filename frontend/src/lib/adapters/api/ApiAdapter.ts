@@ -1,6 +1,7 @@
 import type { IApiPort, PhotoMetadata } from '../ports/IApiPort';
 import type { Verdict } from '$lib/shared/types/Verdict';
 import type { ShareVerdictRequest, ShareVerdictResponse, VerdictWithImageResponse } from '$lib/shared/types/ShareTypes';
+import { createTraceparent } from '$lib/shared/utils/traceContext';
 
 export class ApiAdapter implements IApiPort {
     private apiBaseUrl: string;
@@ -167,6 +168,9 @@ export class ApiAdapter implements IApiPort {
             try {
                 const response = await fetch(`${this.apiBaseUrl}/v1/judge`, {
                     method: 'POST',
+                    headers: {
+                        traceparent: createTraceparent()
+                    },
                     body: formData,
                     signal: controller.signal
                 });
@@ -229,15 +233,15 @@ export class ApiAdapter implements IApiPort {
      */
     async createShareURL(request: ShareVerdictRequest): Promise<ShareVerdictResponse> {
         try {
-            const response = await fetch(`${this.apiBaseUrl}/v1/verdict/share`, {
-                method: 'POST',
-                headers: {
-                    'Content-Type': 'application/json'
-                },
-                body: JSON.stringify(request)
-            });
+            let response = await this.postShare(request);
 
-            if (!response.ok) {
+            // 202: the verdict is still being filed, ask again when told to
+            for (let attempt = 1; response.status === 202 && attempt < this.maxRetries; attempt++) {
+                await this.delay(this.retryAfter(response));
+                response = await this.postShare(request);
+            }
+
+            if (!response.ok || response.status === 202) {
                 const errorText = await response.text();
                 throw new Error(`Failed to create share URL (${response.status}): ${errorText}`);
             }
@@ -250,13 +254,42 @@ export class ApiAdapter implements IApiPort {
         }
     }
 
+    private postShare(request: ShareVerdictRequest): Promise<Response> {
+        return fetch(`${this.apiBaseUrl}/v1/verdict/share`, {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json',
+                traceparent: createTraceparent()
+            },
+            body: JSON.stringify(request)
+        });
+    }
+
+    /**
+     * Milliseconds to wait according to a Retry-After header, or the retry delay
+     */
+    private retryAfter(response: Response): number {
+        const seconds = Number(response.headers?.get('Retry-After'));
+        return Number.isFinite(seconds) && seconds > 0 ? seconds * 1000 : this.retryDelay;
+    }
+
+    /**
+     * Resolve a path returned by the API (e.g. a verdict's imageUrl) to a URL
+     */
+    resolveUrl(path: string): string {
+        return `${this.apiBaseUrl}${path}`;
+    }
+
     /**
      * Retrieve a verdict by its shareable ID
      */
     async getVerdictById(id: string): Promise<VerdictWithImageResponse> {
         try {
             const response = await fetch(`${this.apiBaseUrl}/v1/verdict/${id}`, {
-                method: 'GET'
+                method: 'GET',
+                headers: {
+                    traceparent: createTraceparent()
+                }
             });
 
             if (!response.ok) {
@@ -16,6 +16,8 @@ export interface ShareVerdictResponse {
 export interface VerdictWithImageResponse {
     /** The verdict data */
     verdict: import('./Verdict').Verdict;
-    /** Base64-encoded image data URL (e.g., "data:image/jpeg;base64,...") */
-    image: string;
+    /** Photo URL relative to the API root (e.g., "/v1/verdict/{id}/image") */
+    imageUrl: string;
+    /** @deprecated Base64 data URL, only returned with ?inline=true */
+    image?: string;
 }
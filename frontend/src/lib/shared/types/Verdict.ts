@@ -11,6 +11,18 @@ export interface Verdict {
     requestId: string;
     /** ISO 8601 timestamp of the verdict */
     timestamp: string;
+    /** Content moderation outcome; flagged verdicts cannot be shared */
+    moderation?: ModerationResult;
+    /** Set when storage is full: the verdict was not stored and cannot be shared */
+    storageDegraded?: boolean;
+}
+
+// Content moderation outcome
+// Matches Go's ModerationResult structure
+export interface ModerationResult {
+    flagged: boolean;
+    /** Harm categories that caused the flag */
+    categories?: string[];
 }
 
 // Detailed verdict components
@@ -25,5 +37,5 @@ export interface VerdictDetails {
     /** What the judge observed in the photo */
     observation: string;
     /** The verdict classification */
-    verdictType: "vrijspraak" | "waarschuwing" | "schuldig";
+    verdictType: "vrijspraak" | "waarschuwing" | "schuldig" | "niet-ontvankelijk";
 }
@@ -1,4 +1,4 @@
 // Central export for all shared types
-export type { Verdict, VerdictDetails } from './Verdict';
+export type { Verdict, VerdictDetails, ModerationResult } from './Verdict';
 export type { PhotoMetadata } from './PhotoMetadata';
 export type { ShareVerdictRequest, ShareVerdictResponse, VerdictWithImageResponse } from './ShareTypes';
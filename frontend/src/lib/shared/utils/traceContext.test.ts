@@ -0,0 +1,16 @@
+import { describe, it, expect } from 'vitest';
+import { createTraceparent } from './traceContext';
+
+describe('createTraceparent', () => {
+    it('should produce a valid W3C traceparent value', () => {
+        expect(createTraceparent()).toMatch(/^00-[0-9a-f]{32}-[0-9a-f]{16}-00$/);
+    });
+
+    it('should leave the sampling decision to the backend', () => {
+        expect(createTraceparent().endsWith('-00')).toBe(true);
+    });
+
+    it('should start a new trace on every call', () => {
+        expect(createTraceparent()).not.toEqual(createTraceparent());
+    });
+});
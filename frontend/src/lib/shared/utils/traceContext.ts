@@ -0,0 +1,24 @@
+/**
+ * Generate random bytes as a lowercase hex string.
+ *
+ * @param byteLength - Number of random bytes to generate
+ * @returns Hex string of length byteLength * 2
+ */
+function randomHex(byteLength: number): string {
+    const bytes = new Uint8Array(byteLength);
+    crypto.getRandomValues(bytes);
+    return Array.from(bytes, (b) => b.toString(16).padStart(2, '0')).join('');
+}
+
+/**
+ * Create a W3C Trace Context `traceparent` header value for a new trace.
+ * The sampled flag is left unset: the browser exports no spans, so the
+ * backend decides whether to sample and links its trace to this trace ID.
+ *
+ * @returns Header value in the form "00-<trace-id>-<parent-id>-00"
+ * @example
+ * createTraceparent() // "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"
+ */
+export function createTraceparent(): string {
+    return `00-${randomHex(16)}-${randomHex(8)}-00`;
+}
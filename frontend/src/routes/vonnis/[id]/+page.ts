@@ -16,7 +16,7 @@ export const load: PageLoad = async ({ params }) => {
 
         return {
             verdict: data.verdict,
-            imageData: data.image
+            imageData: data.image ?? api.resolveUrl(data.imageUrl)
         };
     } catch (err) {
         console.error('Failed to load verdict:', err);